@@ -0,0 +1,211 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// AlertHandlers contains handlers for user alerts and the alert inbox.
+type AlertHandlers struct {
+	repo models.AlertRepository
+}
+
+// NewAlertHandlers creates a new instance of alert handlers.
+func NewAlertHandlers(repo models.AlertRepository) *AlertHandlers {
+	return &AlertHandlers{repo: repo}
+}
+
+func parseAlertID(c echo.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// GetAlertInbox returns the user's triggered alerts, newest first
+func (h *AlertHandlers) GetAlertInbox(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetAlertInboxQuery)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	result, err := h.repo.ListInbox(userID, page, limit, query.Unread)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetAlertInbox").Msg("Error fetching alert inbox")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetAlertInbox"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// MarkAlertRead marks one inbox entry as read
+func (h *AlertHandlers) MarkAlertRead(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseAlertID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID notifikasi tidak valid", nil)
+	}
+
+	updated, err := h.repo.MarkInboxRead(id, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "MarkAlertRead").Msg("Error marking alert as read")
+		middleware.CaptureError(c, err, map[string]string{"handler": "MarkAlertRead"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !updated {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Notifikasi tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
+
+// MarkAllAlertsRead marks every unread inbox entry of the user as read
+func (h *AlertHandlers) MarkAllAlertsRead(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	count, err := h.repo.MarkAllInboxRead(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "MarkAllAlertsRead").Msg("Error marking alerts as read")
+		middleware.CaptureError(c, err, map[string]string{"handler": "MarkAllAlertsRead"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]interface{}{"marked": count})
+}
+
+// GetAlertRules returns the user's alert rules
+func (h *AlertHandlers) GetAlertRules(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	alerts, err := h.repo.ListAlerts(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetAlertRules").Msg("Error fetching alert rules")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetAlertRules"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, alerts)
+}
+
+// CreateAlertRule creates an alert rule; it is checked after the next ingestion run
+func (h *AlertHandlers) CreateAlertRule(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreateAlertRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	alert := &models.UserAlert{
+		UserID:          userID,
+		AlertType:       models.AlertType(req.AlertType),
+		PortfolioBondID: req.PortfolioBondID,
+		PortfolioCashID: req.PortfolioCashID,
+		Threshold:       req.Threshold,
+		Days:            req.Days,
+		Note:            req.Note,
+	}
+	if req.Ticker != nil {
+		ticker := strings.ToUpper(*req.Ticker)
+		alert.Ticker = &ticker
+	}
+
+	created, err := h.repo.CreateAlert(alert)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrAlertInvalid):
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Data alert tidak lengkap untuk tipe ini", err.Error())
+		case errors.Is(err, models.ErrAlertTargetNotFound):
+			return helper.ErrorResponse(c, http.StatusNotFound, "Saham atau portofolio tidak ditemukan", nil)
+		}
+		Logger.Error().Err(err).Str("api", "CreateAlertRule").Msg("Error creating alert rule")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateAlertRule"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusCreated, created)
+}
+
+// UpdateAlertRule changes an alert rule's parameters or pauses it
+func (h *AlertHandlers) UpdateAlertRule(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateAlertRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseAlertID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID alert tidak valid", nil)
+	}
+
+	alert, err := h.repo.UpdateAlert(id, userID, req.Threshold, req.Days, req.Note, req.Active)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateAlertRule").Msg("Error updating alert rule")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateAlertRule"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if alert == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Alert tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, alert)
+}
+
+// DeleteAlertRule deletes an alert rule; its inbox entries are kept
+func (h *AlertHandlers) DeleteAlertRule(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseAlertID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID alert tidak valid", nil)
+	}
+
+	deleted, err := h.repo.DeleteAlert(id, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "DeleteAlertRule").Msg("Error deleting alert rule")
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteAlertRule"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !deleted {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Alert tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
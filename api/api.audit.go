@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// AuditHandlers contains handlers for reading the audit log.
+type AuditHandlers struct {
+	repo models.AuditRepository
+}
+
+// NewAuditHandlers creates a new instance of audit handlers.
+func NewAuditHandlers(repo models.AuditRepository) *AuditHandlers {
+	return &AuditHandlers{repo: repo}
+}
+
+// GetAuditLogs returns audit log entries matching the query filters, newest first (admin only)
+func (h *AuditHandlers) GetAuditLogs(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetAuditLogsQuery)
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	filter := models.AuditFilter{
+		ActorID:            query.ActorID,
+		ImpersonatedUserID: query.ImpersonatedUserID,
+		Action:             query.Action,
+		TargetType:         query.TargetType,
+		TargetID:           query.TargetID,
+		RequestID:          query.RequestID,
+	}
+	if query.From != "" {
+		from, err := time.Parse(models.SQLDateFormat, query.From)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal awal tidak valid", nil)
+		}
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, err := time.Parse(models.SQLDateFormat, query.To)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+		}
+		// The end date is inclusive
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Tanggal akhir harus setelah tanggal awal", nil)
+	}
+
+	result, err := h.repo.ListAuditLogs(filter, page, limit)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetAuditLogs").Msg("Error fetching audit logs")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetAuditLogs"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
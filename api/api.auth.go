@@ -10,17 +10,19 @@ import (
 	"github.com/WahyuSiddarta/be_saham_go/middleware"
 	"github.com/WahyuSiddarta/be_saham_go/models"
 	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/WahyuSiddarta/be_saham_go/verification"
 	"github.com/labstack/echo/v4"
 )
 
 // AuthHandlers contains all authentication-related handlers
 type AuthHandlers struct {
-	repo models.UserRepository
+	repo         models.UserRepository
+	verification *verification.Service
 }
 
 // NewAuthHandlers creates a new instance of auth handlers
-func NewAuthHandlers(repo models.UserRepository) *AuthHandlers {
-	return &AuthHandlers{repo: repo}
+func NewAuthHandlers(repo models.UserRepository, verification *verification.Service) *AuthHandlers {
+	return &AuthHandlers{repo: repo, verification: verification}
 }
 
 // convertPaymentData converts payment request data to model
@@ -30,12 +32,11 @@ func convertPaymentData(reqPaymentData *validator.PaymentDataRequest) (*models.P
 	}
 
 	paymentData := &models.PaymentData{
-		OriginalPrice:  reqPaymentData.OriginalPrice,
-		PaidPrice:      reqPaymentData.PaidPrice,
 		DiscountAmount: reqPaymentData.DiscountAmount,
 		DiscountReason: reqPaymentData.DiscountReason,
 		PaymentMethod:  reqPaymentData.PaymentMethod,
 		Notes:          reqPaymentData.Notes,
+		PromoCode:      reqPaymentData.PromoCode,
 	}
 
 	// Parse payment date if provided
@@ -67,16 +68,23 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 		err := errors.New("[login] user not found")
 		middleware.CaptureException(c, err)
 		Logger.Error().Err(err).Str("api", "Login").Str("email", req.Email).Msg("Pengguna tidak ditemukan saat login")
+		middleware.RecordAudit(c, middleware.AuditEvent{
+			Action:     models.AuditActionLoginFailed,
+			ActorEmail: req.Email,
+			After:      map[string]string{"reason": "unknown_email"},
+		})
 		return helper.ErrorResponse(c, http.StatusBadRequest, "Email atau password tidak valid", nil)
 	}
 
 	// Validate password
 	if err := h.repo.ValidatePassword(req.Password, user.Password); err != nil {
+		recordLoginAudit(c, user, models.AuditActionLoginFailed, "invalid_password")
 		return helper.ErrorResponse(c, http.StatusBadRequest, "Email atau password tidak valid", nil)
 	}
 
-	// Check if user account is active
-	if user.Status != models.UserStatusActive {
+	// Check if user account may sign in; unverified accounts get restricted access
+	if !user.Status.CanSignIn() {
+		recordLoginAudit(c, user, models.AuditActionLoginFailed, "account_"+string(user.Status))
 		return helper.ErrorResponse(c, http.StatusForbidden, "Akses akun ditolak", nil)
 	}
 
@@ -92,6 +100,8 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan server", nil)
 	}
 
+	recordLoginAudit(c, user, models.AuditActionLogin, "")
+
 	// Remove password from response
 	user.Password = ""
 	return helper.JsonResponse(c, http.StatusOK, validator.LoginData{
@@ -100,6 +110,21 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 	})
 }
 
+// recordLoginAudit records a login attempt of a known user, with the failure reason if any
+func recordLoginAudit(c echo.Context, user *models.User, action, reason string) {
+	event := middleware.AuditEvent{
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		ActorID:    &user.ID,
+		ActorEmail: user.Email,
+	}
+	if reason != "" {
+		event.After = map[string]string{"reason": reason}
+	}
+	middleware.RecordAudit(c, event)
+}
+
 // Register handles user registration
 func (h *AuthHandlers) Register(c echo.Context) error {
 	// Get validated request from middleware
@@ -117,22 +142,11 @@ func (h *AuthHandlers) Register(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar", nil)
 	}
 
-	// Set default values if not provided
-	status := models.UserStatusActive
-	if req.Status != nil {
-		status = *req.Status
-	}
-
-	userLevel := models.UserLevelFree
-	if req.UserLevel != nil {
-		userLevel = *req.UserLevel
-	}
-
 	createReq := &models.CreateUserRequest{
 		Email:     req.Email,
 		Password:  req.Password,
-		Status:    status,
-		UserLevel: userLevel,
+		Status:    models.UserStatusUnverified,
+		UserLevel: models.UserLevelFree,
 	}
 
 	// Create new user
@@ -159,6 +173,12 @@ func (h *AuthHandlers) Register(c echo.Context) error {
 
 	middleware.SetUserContext(c, newUser.ID, newUser.Email)
 
+	// The account is created even when the email cannot be sent; the user can resend it
+	if err := h.verification.SendVerification(c.Request().Context(), newUser); err != nil {
+		Logger.Error().Err(err).Str("api", "Register").Int("user_id", newUser.ID).Msg("[Register] Gagal mengirim email verifikasi")
+		middleware.CaptureError(c, err, map[string]string{"handler": "Register", "action": "send_verification"}, nil)
+	}
+
 	return helper.JsonResponse(c, http.StatusCreated, validator.RegisterData{
 		User:  newUser,
 		Token: token,
@@ -176,6 +196,46 @@ func (h *AuthHandlers) GetProfile(c echo.Context) error {
 	return helper.JsonResponse(c, http.StatusOK, authUser)
 }
 
+// ChangePassword changes the current user's password after checking the current one
+func (h *AuthHandlers) ChangePassword(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.ChangePasswordRequest)
+
+	authUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
+	}
+	userID := authUser.ID
+
+	// FindByEmail is the lookup that loads the password hash
+	user, err := h.repo.FindByEmail(authUser.Email)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ChangePassword").Int("user_id", userID).Msg("Gagal mengambil data pengguna")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ChangePassword"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan server", nil)
+	}
+	if user == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+
+	if err := h.repo.ValidatePassword(req.CurrentPassword, user.Password); err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Password saat ini tidak valid", nil)
+	}
+
+	if _, err := h.repo.UpdatePassword(userID, req.NewPassword); err != nil {
+		Logger.Error().Err(err).Str("api", "ChangePassword").Int("user_id", userID).Msg("Gagal memperbarui password")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ChangePassword"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui password", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionPasswordChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]string{"message": "Password berhasil diperbarui"})
+}
+
 // UpdateUserLevel handles updating user subscription level (admin only)
 func (h *AuthHandlers) UpdateUserLevel(c echo.Context) error {
 	// Get user ID from path parameter
@@ -205,13 +265,36 @@ func (h *AuthHandlers) UpdateUserLevel(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal pembayaran tidak valid", nil)
 	}
 
+	before, err := h.repo.FindByID(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateUserLevel").Int("user_id", userID).Msg("[UpdateUserLevel] Gagal mengambil data pengguna")
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui level pengguna", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+
 	// Update user level
-	result, err := h.repo.UpdateUserLevel(userID, req.UserLevel, paymentData, &adminUser.ID)
+	result, err := h.repo.UpdateUserLevel(userID, req.UserLevel, req.PlanID, paymentData, &adminUser.ID)
 	if err != nil {
+		if handled, respErr := subscriptionPlanErrorResponse(c, err); handled {
+			return respErr
+		}
+		if handled, respErr := promoCodeErrorResponse(c, err); handled {
+			return respErr
+		}
 		Logger.Error().Err(err).Str("api", "UpdateUserLevel").Int("user_id", userID).Str("new_level", string(req.UserLevel)).Msg("[UpdateUserLevel] Gagal memperbarui level pengguna")
 		return helper.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui level pengguna", nil)
 	}
 
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionUserLevelUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Before:     before,
+		After:      result,
+	})
+
 	return helper.JsonResponse(c, http.StatusOK, result)
 }
 
@@ -227,11 +310,14 @@ func (h *AuthHandlers) UpdateUserStatus(c echo.Context) error {
 	// Get validated request from middleware
 	req := validator.GetValidatedRequest(c).(*validator.UpdateUserStatusRequest)
 
-	// Get admin user for logging
-	// adminUser, err := middleware.GetAuthUser(c)
-	// if err != nil {
-	// 	return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
-	// }
+	before, err := h.repo.FindByID(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateUserStatus").Int("user_id", userID).Msg("[UpdateUserStatus] Error fetching user")
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Error updating user status", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
 
 	// Update user status
 	updatedUser, err := h.repo.UpdateUserStatus(userID, req.Status)
@@ -240,6 +326,14 @@ func (h *AuthHandlers) UpdateUserStatus(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusInternalServerError, "Error updating user status", nil)
 	}
 
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionUserStatusUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Before:     before,
+		After:      updatedUser,
+	})
+
 	return helper.JsonResponse(c, http.StatusOK, updatedUser)
 }
 
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/WahyuSiddarta/be_saham_go/verification"
+	"github.com/labstack/echo/v4"
+)
+
+// VerifyEmail confirms the email address behind an emailed verification token and
+// activates the account
+func (h *AuthHandlers) VerifyEmail(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.VerifyEmailRequest)
+
+	user, err := h.verification.Verify(req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrVerificationTokenInvalid):
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Token verifikasi tidak valid", nil)
+		case errors.Is(err, models.ErrVerificationTokenExpired):
+			return helper.ErrorResponse(c, http.StatusGone, "Token verifikasi sudah kedaluwarsa, silakan kirim ulang email verifikasi", nil)
+		}
+		Logger.Error().Err(err).Str("api", "VerifyEmail").Msg("Error verifying email")
+		middleware.CaptureError(c, err, map[string]string{"handler": "VerifyEmail"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionEmailVerified,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		ActorID:    &user.ID,
+		ActorEmail: user.Email,
+		After:      map[string]string{"status": string(user.Status)},
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, user)
+}
+
+// ResendVerification emails a new verification link to the current unverified user,
+// throttled per user
+func (h *AuthHandlers) ResendVerification(c echo.Context) error {
+	authUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
+	}
+
+	user := &models.User{
+		ID:     authUser.ID,
+		Email:  authUser.Email,
+		Status: authUser.Status,
+	}
+	if err := h.verification.ResendVerification(c.Request().Context(), user); err != nil {
+		var throttled *verification.ThrottledError
+		switch {
+		case errors.Is(err, verification.ErrAlreadyVerified):
+			return helper.ErrorResponse(c, http.StatusConflict, "Email sudah terverifikasi", nil)
+		case errors.As(err, &throttled):
+			retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return helper.ErrorResponse(c, http.StatusTooManyRequests, "Email verifikasi baru saja dikirim, silakan coba lagi nanti", map[string]int{"retry_after_seconds": retryAfter})
+		}
+		Logger.Error().Err(err).Str("api", "ResendVerification").Int("user_id", authUser.ID).Msg("Error resending verification email")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ResendVerification"}, nil)
+		return helper.ErrorResponse(c, http.StatusBadGateway, "Gagal mengirim email verifikasi, silakan coba lagi", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]string{"message": "Email verifikasi telah dikirim"})
+}
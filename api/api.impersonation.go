@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// ImpersonationHandlers contains handlers for support staff acting as users.
+type ImpersonationHandlers struct {
+	users models.UserRepository
+	roles models.RoleRepository
+}
+
+// NewImpersonationHandlers creates a new instance of impersonation handlers.
+func NewImpersonationHandlers(users models.UserRepository, roles models.RoleRepository) *ImpersonationHandlers {
+	return &ImpersonationHandlers{users: users, roles: roles}
+}
+
+// ImpersonateUser issues a short-lived token acting as an active, non-staff user; read-only
+// unless allow_write is set, which needs users:impersonate_write
+func (h *ImpersonationHandlers) ImpersonateUser(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.ImpersonateUserRequest)
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID pengguna tidak valid", nil)
+	}
+
+	adminUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
+	}
+	if adminUser.ID == userID {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Tidak dapat melakukan impersonasi terhadap akun sendiri", nil)
+	}
+	if req.AllowWrite {
+		adminPermissions, err := middleware.GetPermissions(c)
+		if err != nil {
+			Logger.Error().Err(err).Str("api", "ImpersonateUser").Int("admin_id", adminUser.ID).Msg("Error fetching admin permissions")
+			middleware.CaptureError(c, err, map[string]string{"handler": "ImpersonateUser"}, nil)
+			return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+		}
+		if !adminPermissions[models.PermissionUsersImpersonateWrite] {
+			return helper.ErrorResponse(c, http.StatusForbidden, "Izin tidak mencukupi untuk impersonasi dengan akses tulis", nil)
+		}
+	}
+
+	user, err := h.users.FindByID(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImpersonateUser").Int("user_id", userID).Msg("Error fetching user")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ImpersonateUser"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if user == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+	if !user.Status.CanSignIn() {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Akun pengguna tidak aktif", nil)
+	}
+
+	// Staff accounts cannot be impersonated, so impersonation never widens admin access
+	permissions, err := h.roles.GetUserPermissions(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImpersonateUser").Int("user_id", userID).Msg("Error fetching user permissions")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ImpersonateUser"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if len(permissions) > 0 {
+		return helper.ErrorResponse(c, http.StatusForbidden, "Akun staf tidak dapat diimpersonasi", nil)
+	}
+
+	readOnly := !req.AllowWrite
+	token, expiresAt, err := middleware.GenerateImpersonationToken(userID, adminUser.ID, readOnly)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImpersonateUser").Int("user_id", userID).Msg("Error generating impersonation token")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ImpersonateUser"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionImpersonateStart,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		After: map[string]interface{}{
+			"reason":     req.Reason,
+			"read_only":  readOnly,
+			"expires_at": expiresAt,
+		},
+	})
+
+	return helper.JsonResponse(c, http.StatusCreated, validator.ImpersonationData{
+		User:      user,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		ReadOnly:  readOnly,
+	})
+}
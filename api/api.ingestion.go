@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// IngestionHandlers contains admin handlers for stock ingestion run history.
+type IngestionHandlers struct {
+	repo models.IngestionRunRepository
+}
+
+// NewIngestionHandlers creates a new instance of ingestion handlers.
+func NewIngestionHandlers(repo models.IngestionRunRepository) *IngestionHandlers {
+	return &IngestionHandlers{repo: repo}
+}
+
+// GetIngestionRuns returns a paginated list of ingestion runs (admin only)
+func (h *IngestionHandlers) GetIngestionRuns(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetIngestionRunsQuery)
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	result, err := h.repo.GetRuns(page, limit, query.Status, query.Trigger)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetIngestionRuns").Msg("Error fetching ingestion runs")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetIngestionRuns"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// GetIngestionRun returns a single run with its per-ticker items (admin only)
+func (h *IngestionHandlers) GetIngestionRun(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetIngestionRunQuery)
+
+	runID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID run tidak valid", nil)
+	}
+
+	run, err := h.repo.GetRunByID(runID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetIngestionRun").Msg("Error fetching ingestion run")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetIngestionRun"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if run == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Run ingestion tidak ditemukan", nil)
+	}
+
+	items, err := h.repo.GetRunItems(runID, query.Status)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetIngestionRun").Msg("Error fetching ingestion run items")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetIngestionRun"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	schemaDrifts, err := h.repo.GetRunSchemaDrifts(runID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetIngestionRun").Msg("Error fetching ingestion run schema drifts")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetIngestionRun"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, &models.IngestionRunDetail{
+		Run:          run,
+		Items:        items,
+		SchemaDrifts: schemaDrifts,
+	})
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// NotificationHandlers contains handlers for notification preferences and web push subscriptions.
+type NotificationHandlers struct {
+	repo models.NotificationRepository
+}
+
+// NewNotificationHandlers creates a new instance of notification handlers.
+func NewNotificationHandlers(repo models.NotificationRepository) *NotificationHandlers {
+	return &NotificationHandlers{repo: repo}
+}
+
+// GetNotificationPreferences returns the user's locale and channel preferences
+func (h *NotificationHandlers) GetNotificationPreferences(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	prefs, err := h.repo.GetPreferences(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetNotificationPreferences").Msg("Error fetching notification preferences")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetNotificationPreferences"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if prefs == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences changes the user's locale and channel preferences
+func (h *NotificationHandlers) UpdateNotificationPreferences(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateNotificationPreferencesRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	prefs, err := h.repo.GetPreferences(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateNotificationPreferences").Msg("Error fetching notification preferences")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateNotificationPreferences"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if prefs == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+	if req.EmailEnabled != nil {
+		prefs.EmailEnabled = *req.EmailEnabled
+	}
+	if req.TelegramEnabled != nil {
+		prefs.TelegramEnabled = *req.TelegramEnabled
+	}
+	if req.TelegramChatID != nil {
+		prefs.TelegramChatID = helper.StringPointerOrNil(strings.TrimSpace(*req.TelegramChatID))
+	}
+	if req.WebPushEnabled != nil {
+		prefs.WebPushEnabled = *req.WebPushEnabled
+	}
+	if req.InAppEnabled != nil {
+		prefs.InAppEnabled = *req.InAppEnabled
+	}
+	if prefs.TelegramEnabled && prefs.TelegramChatID == nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Telegram chat ID wajib diisi untuk mengaktifkan notifikasi Telegram", nil)
+	}
+
+	saved, err := h.repo.UpsertPreferences(prefs)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateNotificationPreferences").Msg("Error saving notification preferences")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateNotificationPreferences"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, saved)
+}
+
+// GetWebPushConfig returns the VAPID application server key browsers subscribe with
+func (h *NotificationHandlers) GetWebPushConfig(c echo.Context) error {
+	publicKey := notification.VAPIDPublicKey(config.Get().Notification.WebPush)
+	return helper.JsonResponse(c, http.StatusOK, map[string]interface{}{
+		"enabled":    publicKey != "",
+		"public_key": publicKey,
+	})
+}
+
+// SubscribeWebPush stores a browser push subscription for the user
+func (h *NotificationHandlers) SubscribeWebPush(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.SubscribeWebPushRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	sub, err := h.repo.SaveWebPushSubscription(&models.WebPushSubscription{
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: helper.StringPointerOrNil(truncate(c.Request().UserAgent(), 255)),
+	})
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "SubscribeWebPush").Msg("Error saving web push subscription")
+		middleware.CaptureError(c, err, map[string]string{"handler": "SubscribeWebPush"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusCreated, sub)
+}
+
+// UnsubscribeWebPush removes a browser push subscription of the user
+func (h *NotificationHandlers) UnsubscribeWebPush(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UnsubscribeWebPushRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	deleted, err := h.repo.DeleteWebPushSubscription(userID, req.Endpoint)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UnsubscribeWebPush").Msg("Error deleting web push subscription")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UnsubscribeWebPush"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !deleted {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Langganan web push tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookBodySize bounds the payment webhook body read into memory
+const maxWebhookBodySize = 1 << 20
+
+// PaymentHandlers contains handlers for gateway checkouts and payment webhooks.
+type PaymentHandlers struct {
+	service *payment.Service
+	repo    models.PaymentRepository
+}
+
+// NewPaymentHandlers creates a new instance of payment handlers. service is nil when
+// payments are disabled; checkouts and webhooks then answer 503.
+func NewPaymentHandlers(service *payment.Service, repo models.PaymentRepository) *PaymentHandlers {
+	return &PaymentHandlers{service: service, repo: repo}
+}
+
+func paymentsUnavailable(c echo.Context) error {
+	return helper.ErrorResponse(c, http.StatusServiceUnavailable, "Pembayaran sedang tidak tersedia", nil)
+}
+
+// Checkout opens a gateway checkout for a subscription plan and returns the pending payment
+func (h *PaymentHandlers) Checkout(c echo.Context) error {
+	if h.service == nil {
+		return paymentsUnavailable(c)
+	}
+	req := validator.GetValidatedRequest(c).(*validator.CheckoutRequest)
+
+	authUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	user := &models.User{
+		ID:               authUser.ID,
+		Email:            authUser.Email,
+		UserLevel:        authUser.UserLevel,
+		PremiumExpiresAt: authUser.PremiumExpiresAt,
+	}
+	record, err := h.service.Checkout(c.Request().Context(), user, req.PlanID, req.PromoCode)
+	if err != nil {
+		if handled, respErr := subscriptionPlanErrorResponse(c, err); handled {
+			return respErr
+		}
+		if handled, respErr := promoCodeErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "Checkout").Int("user_id", authUser.ID).Msg("Error creating checkout")
+		middleware.CaptureError(c, err, map[string]string{"handler": "Checkout"}, nil)
+		return helper.ErrorResponse(c, http.StatusBadGateway, "Gagal membuat pembayaran, silakan coba lagi", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusCreated, record)
+}
+
+// GetPayment returns one of the user's payments, e.g. to poll a checkout's status
+func (h *PaymentHandlers) GetPayment(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	paymentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || paymentID <= 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID pembayaran tidak valid", nil)
+	}
+
+	record, err := h.repo.FindUserPayment(userID, paymentID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPayment").Msg("Error fetching payment")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPayment"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if record == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pembayaran tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, record)
+}
+
+// PaymentWebhook receives signed payment notifications from the gateway named in the path
+func (h *PaymentHandlers) PaymentWebhook(c echo.Context) error {
+	if h.service == nil {
+		return paymentsUnavailable(c)
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBodySize))
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Body webhook tidak valid", nil)
+	}
+
+	return h.applyWebhook(c, c.Param("gateway"), c.Request().Header, body)
+}
+
+// SimulateFakePayment settles a fake gateway checkout by delivering the webhook the
+// gateway would send; only registered while the fake gateway is active outside production
+func (h *PaymentHandlers) SimulateFakePayment(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.SimulateFakePaymentRequest)
+
+	fake, ok := h.service.Gateway().(*payment.FakeGateway)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Gateway pembayaran tidak ditemukan", nil)
+	}
+
+	header, body, err := fake.Simulate(c.Param("reference"), req.Status, req.PaymentMethod)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pembayaran tidak ditemukan", nil)
+	}
+
+	return h.applyWebhook(c, fake.Name(), header, body)
+}
+
+func (h *PaymentHandlers) applyWebhook(c echo.Context, gateway string, header http.Header, body []byte) error {
+	result, err := h.service.HandleWebhook(c.Request().Context(), gateway, header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			Logger.Warn().Str("api", "PaymentWebhook").Str("gateway", gateway).Msg("Rejected payment webhook with invalid signature")
+			return helper.ErrorResponse(c, http.StatusUnauthorized, "Signature webhook tidak valid", nil)
+		case errors.Is(err, payment.ErrInvalidWebhook):
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Body webhook tidak valid", nil)
+		case errors.Is(err, payment.ErrUnknownGateway):
+			return helper.ErrorResponse(c, http.StatusNotFound, "Gateway pembayaran tidak ditemukan", nil)
+		case errors.Is(err, models.ErrPaymentNotFound), errors.Is(err, models.ErrPaymentGatewayMismatch):
+			Logger.Warn().Err(err).Str("api", "PaymentWebhook").Str("gateway", gateway).Msg("Payment webhook for unknown payment")
+			return helper.ErrorResponse(c, http.StatusNotFound, "Pembayaran tidak ditemukan", nil)
+		}
+		Logger.Error().Err(err).Str("api", "PaymentWebhook").Str("gateway", gateway).Msg("Error processing payment webhook")
+		middleware.CaptureError(c, err, map[string]string{"handler": "PaymentWebhook"}, map[string]interface{}{"gateway": gateway})
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	// Money was captured for a payment that granted nothing; alert staff to refund it
+	if result.Applied && result.Payment.PaymentStatus == models.PaymentStatusNeedsRefund {
+		refundErr := fmt.Errorf("payment %d needs refund", result.Payment.ID)
+		if result.Payment.FailureReason != nil {
+			refundErr = fmt.Errorf("%w: %s", refundErr, *result.Payment.FailureReason)
+		}
+		Logger.Error().Err(refundErr).Str("api", "PaymentWebhook").Str("gateway", gateway).Int("payment_id", result.Payment.ID).Msg("Payment captured without granting a subscription")
+		middleware.CaptureError(c, refundErr, map[string]string{"handler": "PaymentWebhook", "action": "needs_refund"}, map[string]interface{}{"gateway": gateway, "payment_id": result.Payment.ID})
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
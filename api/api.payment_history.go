@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// PaymentHistoryHandlers contains handlers for payment history and invoices.
+type PaymentHistoryHandlers struct {
+	repo models.PaymentHistoryRepository
+}
+
+// NewPaymentHistoryHandlers creates a new instance of payment history handlers.
+func NewPaymentHistoryHandlers(repo models.PaymentHistoryRepository) *PaymentHistoryHandlers {
+	return &PaymentHistoryHandlers{repo: repo}
+}
+
+// GetMyPayments returns the authenticated user's payments, newest first
+func (h *PaymentHistoryHandlers) GetMyPayments(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetPaymentsQuery)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	result, err := h.repo.ListUserPayments(userID, page, limit, query.Status)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetMyPayments").Msg("Error fetching payments")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetMyPayments"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// GetMyPaymentInvoice returns the HTML invoice of one of the user's paid payments
+func (h *PaymentHistoryHandlers) GetMyPaymentInvoice(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+	return h.renderInvoice(c, "GetMyPaymentInvoice", &userID)
+}
+
+// GetPayments returns payments across users with filters (admin only)
+func (h *PaymentHistoryHandlers) GetPayments(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetAdminPaymentsQuery)
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	filter := models.PaymentFilter{
+		UserID:             query.UserID,
+		Status:             query.Status,
+		PaymentMethod:      query.PaymentMethod,
+		ProcessedByAdminID: query.ProcessedByAdminID,
+	}
+	if query.From != "" {
+		from, err := time.Parse(models.SQLDateFormat, query.From)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal awal tidak valid", nil)
+		}
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, err := time.Parse(models.SQLDateFormat, query.To)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+		}
+		// The end date is inclusive
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Tanggal akhir harus setelah tanggal awal", nil)
+	}
+
+	result, err := h.repo.ListPayments(filter, page, limit)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPayments").Msg("Error fetching payments")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPayments"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// GetPaymentInvoice returns the HTML invoice of any paid payment (admin only)
+func (h *PaymentHistoryHandlers) GetPaymentInvoice(c echo.Context) error {
+	return h.renderInvoice(c, "GetPaymentInvoice", nil)
+}
+
+func (h *PaymentHistoryHandlers) renderInvoice(c echo.Context, handler string, userID *int) error {
+	paymentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || paymentID <= 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID pembayaran tidak valid", nil)
+	}
+
+	invoice, err := h.repo.GetPaymentInvoice(paymentID, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrInvoiceUnavailable) {
+			return helper.ErrorResponse(c, http.StatusConflict, "Invoice hanya tersedia untuk pembayaran yang sudah dibayar", nil)
+		}
+		Logger.Error().Err(err).Str("api", handler).Int("payment_id", paymentID).Msg("Error fetching invoice")
+		middleware.CaptureError(c, err, map[string]string{"handler": handler}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if invoice == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Pembayaran tidak ditemukan", nil)
+	}
+
+	body, err := payment.RenderInvoiceHTML(invoice, config.Get().Payment.InvoiceIssuer)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", handler).Int("payment_id", paymentID).Msg("Error rendering invoice")
+		middleware.CaptureError(c, err, map[string]string{"handler": handler}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", payment.InvoiceFilename(invoice)))
+	return c.HTMLBlob(http.StatusOK, body)
+}
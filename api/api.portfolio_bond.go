@@ -190,6 +190,16 @@ func (h *PortfolioBondHandlers) DeleteBondPortfolio(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusBadRequest, "ID portofolio tidak valid", nil)
 	}
 
+	before, err := h.bondRepo.FindByID(portfolioID, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "DeleteBondPortfolio").Msg("Error fetching bond portfolio")
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteBondPortfolio"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Portofolio tidak ditemukan", nil)
+	}
+
 	err = h.bondRepo.Delete(portfolioID, userID)
 	if err != nil {
 		Logger.Error().Err(err).Str("api", "DeleteBondPortfolio").Msg("Error deleting bond portfolio")
@@ -197,6 +207,13 @@ func (h *PortfolioBondHandlers) DeleteBondPortfolio(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
 	}
 
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionBondPortfolioDel,
+		TargetType: models.AuditTargetPortfolioBond,
+		TargetID:   strconv.Itoa(portfolioID),
+		Before:     before,
+	})
+
 	return helper.JsonResponse(c, http.StatusOK, nil)
 }
 
@@ -145,6 +145,16 @@ func (h *PortfolioCashHandlers) DeleteCashPortfolio(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusBadRequest, "ID portofolio tidak valid", nil)
 	}
 
+	before, err := h.repo.FindByID(portfolioID, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "DeleteCashPortfolio").Msg("Error fetching cash portfolio")
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteCashPortfolio"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Portofolio tidak ditemukan", nil)
+	}
+
 	err = h.repo.Delete(portfolioID, userID)
 	if err != nil {
 		Logger.Error().Err(err).Str("api", "DeleteCashPortfolio").Msg("Error deleting cash portfolio")
@@ -152,6 +162,13 @@ func (h *PortfolioCashHandlers) DeleteCashPortfolio(c echo.Context) error {
 		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
 	}
 
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionCashPortfolioDel,
+		TargetType: models.AuditTargetPortfolioCash,
+		TargetID:   strconv.Itoa(portfolioID),
+		Before:     before,
+	})
+
 	return helper.JsonResponse(c, http.StatusOK, nil)
 }
 
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// PromoCodeHandlers contains handlers for promo code management and checks.
+type PromoCodeHandlers struct {
+	repo  models.PromoCodeRepository
+	plans models.SubscriptionPlanRepository
+}
+
+// NewPromoCodeHandlers creates a new instance of promo code handlers.
+func NewPromoCodeHandlers(repo models.PromoCodeRepository, plans models.SubscriptionPlanRepository) *PromoCodeHandlers {
+	return &PromoCodeHandlers{repo: repo, plans: plans}
+}
+
+// promoCodeErrorResponse writes the response for promo code domain errors and reports
+// whether err was one.
+func promoCodeErrorResponse(c echo.Context, err error) (bool, error) {
+	switch {
+	case errors.Is(err, models.ErrPromoCodeNotFound):
+		return true, helper.ErrorResponse(c, http.StatusNotFound, "Kode promo tidak ditemukan", nil)
+	case errors.Is(err, models.ErrPromoCodeExists):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Kode promo sudah digunakan", nil)
+	case errors.Is(err, models.ErrPromoCodeInactive):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Kode promo tidak aktif atau sudah kedaluwarsa", nil)
+	case errors.Is(err, models.ErrPromoCodeNotEligible):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Kode promo tidak berlaku untuk paket ini", nil)
+	case errors.Is(err, models.ErrPromoCodeExhausted):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Kuota kode promo sudah habis", nil)
+	case errors.Is(err, models.ErrPromoCodeUserLimit):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Kode promo sudah pernah Anda gunakan", nil)
+	case errors.Is(err, models.ErrPromoCodeWithManualDiscount):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Kode promo tidak dapat digabung dengan diskon manual", nil)
+	}
+	return false, nil
+}
+
+func parsePromoCodeID(c echo.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// parseOptionalTime parses an optional RFC3339 timestamp.
+func parseOptionalTime(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// promoCodeTermsError returns the message for inconsistent promo code terms, or "".
+func promoCodeTermsError(promo *models.PromoCode) string {
+	if promo.DiscountType == models.PromoDiscountPercentage && promo.DiscountValue > 100 {
+		return "Diskon persentase tidak boleh lebih dari 100"
+	}
+	if promo.DiscountType == models.PromoDiscountFixed && promo.MaxDiscountAmount != nil {
+		return "Batas diskon hanya berlaku untuk diskon persentase"
+	}
+	if promo.ValidUntil != nil && !promo.ValidUntil.After(promo.ValidFrom) {
+		return "Tanggal akhir harus setelah tanggal mulai"
+	}
+	return ""
+}
+
+// GetPromoCodes returns promo codes with their redemption counts (admin only)
+func (h *PromoCodeHandlers) GetPromoCodes(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetPromoCodesQuery)
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	result, err := h.repo.ListPromoCodes(page, limit, query.Active)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPromoCodes").Msg("Error fetching promo codes")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPromoCodes"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// GetPromoCode returns one promo code (admin only)
+func (h *PromoCodeHandlers) GetPromoCode(c echo.Context) error {
+	id, ok := parsePromoCodeID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID kode promo tidak valid", nil)
+	}
+
+	promo, err := h.repo.FindPromoCode(id)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPromoCode").Msg("Error fetching promo code")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if promo == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Kode promo tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, promo)
+}
+
+// CreatePromoCode creates a promo code (admin only)
+func (h *PromoCodeHandlers) CreatePromoCode(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreatePromoCodeRequest)
+
+	adminUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
+	}
+
+	validFrom, err := parseOptionalTime(req.ValidFrom)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal mulai tidak valid", nil)
+	}
+	validUntil, err := parseOptionalTime(req.ValidUntil)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+	}
+
+	promo := &models.PromoCode{
+		Code:              strings.TrimSpace(req.Code),
+		Description:       req.Description,
+		DiscountType:      req.DiscountType,
+		DiscountValue:     req.DiscountValue,
+		MaxDiscountAmount: req.MaxDiscountAmount,
+		ValidFrom:         utime.Utime.Now().ToTime(),
+		ValidUntil:        validUntil,
+		MaxRedemptions:    req.MaxRedemptions,
+		PerUserLimit:      1,
+		EligibleLevels:    req.EligibleLevels,
+		Active:            true,
+		CreatedByAdminID:  &adminUser.ID,
+	}
+	if validFrom != nil {
+		promo.ValidFrom = *validFrom
+	}
+	if req.PerUserLimit != nil {
+		promo.PerUserLimit = *req.PerUserLimit
+	}
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+	if msg := promoCodeTermsError(promo); msg != "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, msg, nil)
+	}
+
+	created, err := h.repo.CreatePromoCode(promo)
+	if err != nil {
+		if handled, respErr := promoCodeErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "CreatePromoCode").Msg("Error creating promo code")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreatePromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionPromoCodeCreate,
+		TargetType: models.AuditTargetPromoCode,
+		TargetID:   strconv.Itoa(created.ID),
+		After:      created,
+	})
+
+	return helper.JsonResponse(c, http.StatusCreated, created)
+}
+
+// UpdatePromoCode replaces the terms of a promo code, e.g. to deactivate it (admin only)
+func (h *PromoCodeHandlers) UpdatePromoCode(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdatePromoCodeRequest)
+
+	id, ok := parsePromoCodeID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID kode promo tidak valid", nil)
+	}
+
+	validFrom, err := parseOptionalTime(req.ValidFrom)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal mulai tidak valid", nil)
+	}
+	validUntil, err := parseOptionalTime(req.ValidUntil)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+	}
+
+	promo, err := h.repo.FindPromoCode(id)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdatePromoCode").Msg("Error fetching promo code")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdatePromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if promo == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Kode promo tidak ditemukan", nil)
+	}
+	before := *promo
+
+	promo.Description = req.Description
+	promo.MaxDiscountAmount = req.MaxDiscountAmount
+	if validFrom != nil {
+		promo.ValidFrom = *validFrom
+	}
+	promo.ValidUntil = validUntil
+	promo.MaxRedemptions = req.MaxRedemptions
+	promo.PerUserLimit = req.PerUserLimit
+	promo.EligibleLevels = req.EligibleLevels
+	promo.Active = req.Active
+	if msg := promoCodeTermsError(promo); msg != "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, msg, nil)
+	}
+
+	updated, err := h.repo.UpdatePromoCode(promo)
+	if err != nil {
+		if handled, respErr := promoCodeErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "UpdatePromoCode").Msg("Error updating promo code")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdatePromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionPromoCodeUpdate,
+		TargetType: models.AuditTargetPromoCode,
+		TargetID:   strconv.Itoa(id),
+		Before:     before,
+		After:      updated,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, updated)
+}
+
+// GetPromoCodeRedemptions returns the payments that used a promo code (admin only)
+func (h *PromoCodeHandlers) GetPromoCodeRedemptions(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetPromoCodeRedemptionsQuery)
+
+	id, ok := parsePromoCodeID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID kode promo tidak valid", nil)
+	}
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	result, err := h.repo.ListPromoCodeRedemptions(id, page, limit)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPromoCodeRedemptions").Msg("Error fetching promo code redemptions")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPromoCodeRedemptions"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// CheckPromoCode previews the checkout price of a subscription with a promo code
+func (h *PromoCodeHandlers) CheckPromoCode(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CheckPromoCodeRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	plan, err := h.plans.FindPlan(req.PlanID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "CheckPromoCode").Msg("Error fetching subscription plan")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CheckPromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if plan == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Paket langganan tidak ditemukan", nil)
+	}
+	if !plan.Active {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Paket langganan sudah tidak tersedia", nil)
+	}
+
+	quote, err := h.repo.QuotePromoCode(req.PromoCode, userID, plan.UserLevel, plan.Price, utime.Utime.Now().ToTime())
+	if err != nil {
+		if handled, respErr := promoCodeErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "CheckPromoCode").Msg("Error checking promo code")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CheckPromoCode"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, quote)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// maxMonthlyReportMonths bounds the months of one monthly report
+const maxMonthlyReportMonths = 36
+
+// ReportHandlers contains handlers for admin subscription and revenue reports.
+type ReportHandlers struct {
+	repo models.ReportRepository
+}
+
+// NewReportHandlers creates a new instance of report handlers.
+func NewReportHandlers(repo models.ReportRepository) *ReportHandlers {
+	return &ReportHandlers{repo: repo}
+}
+
+// reportRange resolves the from/to dates of a report query, defaulting to defaultFrom
+// and today (WIB). It returns an error message for invalid ranges.
+func reportRange(query *validator.GetReportRangeQuery, defaultFrom func(today time.Time) time.Time) (time.Time, time.Time, string) {
+	today, _ := helper.TimeInWIB(utime.Utime.Now().ToTime())
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	from, to := defaultFrom(today), today
+	var err error
+	if query.From != "" {
+		if from, err = time.Parse(models.SQLDateFormat, query.From); err != nil {
+			return from, to, "Format tanggal awal tidak valid"
+		}
+	}
+	if query.To != "" {
+		if to, err = time.Parse(models.SQLDateFormat, query.To); err != nil {
+			return from, to, "Format tanggal akhir tidak valid"
+		}
+	}
+	if to.Before(from) {
+		return from, to, "Tanggal akhir harus setelah tanggal awal"
+	}
+	return from, to, ""
+}
+
+// GetSubscriptionMetrics returns current MRR, ARR and active subscribers by tier (admin only)
+func (h *ReportHandlers) GetSubscriptionMetrics(c echo.Context) error {
+	metrics, err := h.repo.GetSubscriptionMetrics(utime.Utime.Now().ToTime())
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetSubscriptionMetrics").Msg("Error computing subscription metrics")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetSubscriptionMetrics"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, metrics)
+}
+
+// GetMonthlySubscriptionStats returns revenue, new subscriptions, renewals and churn per
+// month; the last 12 months by default (admin only)
+func (h *ReportHandlers) GetMonthlySubscriptionStats(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetReportRangeQuery)
+
+	from, to, msg := reportRange(query, func(today time.Time) time.Time {
+		return time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	})
+	if msg != "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, msg, nil)
+	}
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	if months > maxMonthlyReportMonths {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Rentang laporan bulanan maksimal 36 bulan", nil)
+	}
+
+	stats, err := h.repo.GetMonthlySubscriptionStats(from.Format(models.SQLDateFormat), to.Format(models.SQLDateFormat), utime.Utime.Now().ToTime())
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetMonthlySubscriptionStats").Msg("Error computing monthly subscription stats")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetMonthlySubscriptionStats"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, stats)
+}
+
+// GetRevenueReport returns revenue by payment method and discount totals; the current
+// month by default (admin only)
+func (h *ReportHandlers) GetRevenueReport(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetReportRangeQuery)
+
+	from, to, msg := reportRange(query, func(today time.Time) time.Time {
+		return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	})
+	if msg != "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, msg, nil)
+	}
+
+	report, err := h.repo.GetRevenueReport(from.Format(models.SQLDateFormat), to.Format(models.SQLDateFormat))
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetRevenueReport").Msg("Error computing revenue report")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetRevenueReport"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, report)
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// RoleHandlers contains handlers for managing admin roles and permissions.
+type RoleHandlers struct {
+	repo models.RoleRepository
+}
+
+// NewRoleHandlers creates a new instance of role handlers.
+func NewRoleHandlers(repo models.RoleRepository) *RoleHandlers {
+	return &RoleHandlers{repo: repo}
+}
+
+// roleErrorResponse writes the response for role domain errors and reports whether err was one.
+func roleErrorResponse(c echo.Context, err error) (bool, error) {
+	switch {
+	case errors.Is(err, models.ErrRoleNotFound):
+		return true, helper.ErrorResponse(c, http.StatusNotFound, "Peran tidak ditemukan", nil)
+	case errors.Is(err, models.ErrRoleExists):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Nama peran sudah digunakan", nil)
+	case errors.Is(err, models.ErrUnknownPermission):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Izin tidak dikenal", map[string]string{"error": err.Error()})
+	case errors.Is(err, models.ErrUserNotFound):
+		return true, helper.ErrorResponse(c, http.StatusNotFound, "Pengguna tidak ditemukan", nil)
+	}
+	return false, nil
+}
+
+// GetPermissions lists the permission codes roles can grant (admin only)
+func (h *RoleHandlers) GetPermissions(c echo.Context) error {
+	permissions, err := h.repo.ListPermissions()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPermissions").Msg("Error fetching permissions")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPermissions"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, permissions)
+}
+
+// GetRoles lists roles with their permissions (admin only)
+func (h *RoleHandlers) GetRoles(c echo.Context) error {
+	roles, err := h.repo.ListRoles()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetRoles").Msg("Error fetching roles")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetRoles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, roles)
+}
+
+// CreateRole creates a role granting the requested permissions (admin only)
+func (h *RoleHandlers) CreateRole(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreateRoleRequest)
+
+	created, err := h.repo.CreateRole(&models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		if handled, respErr := roleErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "CreateRole").Msg("Error creating role")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateRole"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionRoleCreate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   strconv.Itoa(created.ID),
+		After:      created,
+	})
+
+	return helper.JsonResponse(c, http.StatusCreated, created)
+}
+
+// UpdateRole replaces a role's name, description and permissions (admin only)
+func (h *RoleHandlers) UpdateRole(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateRoleRequest)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID peran tidak valid", nil)
+	}
+
+	before, err := h.repo.FindRole(id)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateRole").Msg("Error fetching role")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateRole"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Peran tidak ditemukan", nil)
+	}
+
+	updated, err := h.repo.UpdateRole(&models.Role{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		if handled, respErr := roleErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "UpdateRole").Msg("Error updating role")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateRole"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionRoleUpdate,
+		TargetType: models.AuditTargetRole,
+		TargetID:   strconv.Itoa(id),
+		Before:     before,
+		After:      updated,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, updated)
+}
+
+// GetUserRoles lists the roles assigned to a user (admin only)
+func (h *RoleHandlers) GetUserRoles(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID pengguna tidak valid", nil)
+	}
+
+	roles, err := h.repo.GetUserRoles(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetUserRoles").Int("user_id", userID).Msg("Error fetching user roles")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetUserRoles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, roles)
+}
+
+// SetUserRoles replaces the roles assigned to a user (admin only)
+func (h *RoleHandlers) SetUserRoles(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.SetUserRolesRequest)
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID pengguna tidak valid", nil)
+	}
+
+	adminUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Autentikasi diperlukan", nil)
+	}
+
+	before, err := h.repo.GetUserRoles(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "SetUserRoles").Int("user_id", userID).Msg("Error fetching user roles")
+		middleware.CaptureError(c, err, map[string]string{"handler": "SetUserRoles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	roles, err := h.repo.SetUserRoles(userID, req.RoleIDs, adminUser.ID)
+	if err != nil {
+		if handled, respErr := roleErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "SetUserRoles").Int("user_id", userID).Msg("Error assigning user roles")
+		middleware.CaptureError(c, err, map[string]string{"handler": "SetUserRoles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionUserRolesUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Before:     before,
+		After:      roles,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, roles)
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultEarningsCalendarDays = 30
+	maxEarningsCalendarDays     = 92
+)
+
+// StockHandlers contains handlers for stock market data.
+type StockHandlers struct {
+	repo models.StockRepository
+}
+
+// NewStockHandlers creates a new instance of stock handlers.
+func NewStockHandlers(repo models.StockRepository) *StockHandlers {
+	return &StockHandlers{repo: repo}
+}
+
+// GetEarningsCalendar returns released and expected earnings between from and to (inclusive).
+func (h *StockHandlers) GetEarningsCalendar(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.EarningsCalendarQuery)
+
+	now := utime.Utime.Now().ToTime()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if query.From != "" {
+		parsed, err := time.Parse(models.SQLDateFormat, query.From)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal awal tidak valid", nil)
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, defaultEarningsCalendarDays)
+	if query.To != "" {
+		parsed, err := time.Parse(models.SQLDateFormat, query.To)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Tanggal akhir harus setelah tanggal awal", nil)
+	}
+	if to.Sub(from) > maxEarningsCalendarDays*24*time.Hour {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Rentang tanggal maksimal 92 hari", nil)
+	}
+
+	entries, err := h.repo.GetEarningsCalendar(from, to.AddDate(0, 0, 1))
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetEarningsCalendar").Msg("Error fetching earnings calendar")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetEarningsCalendar"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	if entries == nil {
+		entries = []models.EarningsCalendarEntry{}
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]interface{}{
+		"from":    from.Format(models.SQLDateFormat),
+		"to":      to.Format(models.SQLDateFormat),
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// GetEarningSurpriseStats returns beat/miss statistics computed from a symbol's quarterly history.
+func (h *StockHandlers) GetEarningSurpriseStats(c echo.Context) error {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Kode saham tidak valid", nil)
+	}
+
+	records, err := h.repo.GetStockEarningQuarterlyHistory(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetEarningSurpriseStats").Str("symbol", symbol).Msg("Error fetching quarterly history")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetEarningSurpriseStats"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	if len(records) == 0 {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Data earnings tidak ditemukan", nil)
+	}
+
+	stats := models.ComputeEarningSurpriseStats(symbol, records)
+
+	nextExpectedDate, err := h.repo.GetNextExpectedReportDate(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetEarningSurpriseStats").Str("symbol", symbol).Msg("Error fetching next expected report date")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetEarningSurpriseStats"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	stats.NextExpectedDate = nextExpectedDate
+
+	return helper.JsonResponse(c, http.StatusOK, stats)
+}
+
+// GetQuarterlyMetrics returns TTM, QoQ and YoY metrics derived from a symbol's quarterly history.
+func (h *StockHandlers) GetQuarterlyMetrics(c echo.Context) error {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Kode saham tidak valid", nil)
+	}
+
+	records, err := h.repo.GetStockEarningQuarterlyHistory(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetQuarterlyMetrics").Str("symbol", symbol).Msg("Error fetching quarterly history")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetQuarterlyMetrics"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	if len(records) == 0 {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Data earnings tidak ditemukan", nil)
+	}
+
+	metrics, err := models.ComputeStockQuarterlyMetrics(symbol, records)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetQuarterlyMetrics").Str("symbol", symbol).Msg("Error computing quarterly metrics")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetQuarterlyMetrics"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, metrics)
+}
+
+// GetOverviewMetricHistory returns the dated values of one overview metric (e.g. roe, der, npm) for a symbol.
+func (h *StockHandlers) GetOverviewMetricHistory(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.OverviewMetricHistoryQuery)
+
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Kode saham tidak valid", nil)
+	}
+
+	metric := strings.ToLower(strings.TrimSpace(c.Param("metric")))
+	if _, ok := models.ResolveOverviewMetricColumn(metric); !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Metrik tidak valid", map[string]interface{}{
+			"allowed_metrics": models.OverviewMetricNames(),
+		})
+	}
+
+	var from, to *time.Time
+	if query.From != "" {
+		parsed, err := time.Parse(models.SQLDateFormat, query.From)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal awal tidak valid", nil)
+		}
+		from = &parsed
+	}
+	if query.To != "" {
+		parsed, err := time.Parse(models.SQLDateFormat, query.To)
+		if err != nil {
+			return helper.ErrorResponse(c, http.StatusBadRequest, "Format tanggal akhir tidak valid", nil)
+		}
+		to = &parsed
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Tanggal akhir harus setelah tanggal awal", nil)
+	}
+
+	points, err := h.repo.GetStockOverviewMetricHistory(symbol, metric, from, to)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetOverviewMetricHistory").Str("symbol", symbol).Str("metric", metric).Msg("Error fetching overview metric history")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetOverviewMetricHistory"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	if points == nil {
+		points = []models.OverviewMetricPoint{}
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, models.StockOverviewMetricHistory{
+		Symbol: symbol,
+		Metric: metric,
+		Points: points,
+	})
+}
+
+// GetSectorAverages returns the average key ratios of each sector, or of one sector.
+func (h *StockHandlers) GetSectorAverages(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.SectorAveragesQuery)
+
+	averages, err := h.repo.GetSectorAverages(query.Sector)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetSectorAverages").Msg("Error fetching sector averages")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetSectorAverages"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]interface{}{
+		"sectors": averages,
+		"count":   len(averages),
+	})
+}
+
+// GetStockOverview returns a symbol's master attributes and key ratios compared with its sector average.
+func (h *StockHandlers) GetStockOverview(c echo.Context) error {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Kode saham tidak valid", nil)
+	}
+
+	profile, err := h.repo.GetStockProfile(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockOverview").Str("symbol", symbol).Msg("Error fetching stock profile")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockOverview"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if profile == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	metrics, err := h.repo.GetStockKeyMetrics(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockOverview").Str("symbol", symbol).Msg("Error fetching key metrics")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockOverview"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	overview := models.StockOverview{Profile: *profile, Metrics: metrics}
+	if profile.Sector != nil {
+		averages, err := h.repo.GetSectorAverages(profile.Sector)
+		if err != nil {
+			Logger.Error().Err(err).Str("api", "GetStockOverview").Str("symbol", symbol).Msg("Error fetching sector average")
+			middleware.CaptureError(c, err, map[string]string{"handler": "GetStockOverview"}, nil)
+			return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+		}
+		if len(averages) > 0 {
+			overview.SectorAverage = &averages[0]
+			overview.SectorRelative = models.CompareToSector(metrics, overview.SectorAverage)
+		}
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, overview)
+}
+
+// GetStockPeers returns a symbol's key ratios with the sector median and percentile rank of each.
+func (h *StockHandlers) GetStockPeers(c echo.Context) error {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Kode saham tidak valid", nil)
+	}
+
+	profile, err := h.repo.GetStockProfile(symbol)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockPeers").Str("symbol", symbol).Msg("Error fetching stock profile")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockPeers"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if profile == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+	if profile.Sector == nil {
+		return helper.ErrorResponse(c, http.StatusUnprocessableEntity, "Sektor saham belum diatur", nil)
+	}
+
+	peers, err := h.repo.GetSectorPeerMetrics(*profile.Sector)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockPeers").Str("symbol", symbol).Msg("Error fetching sector peers")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockPeers"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	hasMetrics := false
+	for _, peer := range peers {
+		if peer.Symbol == symbol {
+			hasMetrics = true
+			break
+		}
+	}
+	if !hasMetrics {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Data metrik saham tidak ditemukan", nil)
+	}
+
+	comparison, err := models.ComputeStockPeerComparison(*profile, peers)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockPeers").Str("symbol", symbol).Msg("Error computing peer comparison")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockPeers"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, comparison)
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/cron"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// StockIngestor starts stock ingestion for a single ticker on demand.
+type StockIngestor interface {
+	IngestTicker(ticker string) (*int64, error)
+}
+
+// AdminStockHandlers contains admin handlers for managing tracked tickers.
+type AdminStockHandlers struct {
+	repo     models.StockRepository
+	ingestor StockIngestor
+}
+
+// NewAdminStockHandlers creates a new instance of admin stock handlers.
+func NewAdminStockHandlers(repo models.StockRepository, ingestor StockIngestor) *AdminStockHandlers {
+	return &AdminStockHandlers{repo: repo, ingestor: ingestor}
+}
+
+// GetTrackedStocks returns a paginated list of tracked tickers (admin only)
+func (h *AdminStockHandlers) GetTrackedStocks(c echo.Context) error {
+	query := validator.GetValidatedQuery(c).(*validator.GetTrackedStocksQuery)
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var tickerFilter *string
+	if query.Ticker != nil {
+		upper := strings.ToUpper(*query.Ticker)
+		tickerFilter = &upper
+	}
+
+	result, err := h.repo.ListTrackedStocks(page, limit, query.Enabled, tickerFilter)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetTrackedStocks").Msg("Error fetching tracked stocks")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetTrackedStocks"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
+
+// GetTrackedStock returns a single tracked ticker (admin only)
+func (h *AdminStockHandlers) GetTrackedStock(c echo.Context) error {
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	stock, err := h.repo.GetTrackedStock(ticker)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetTrackedStock").Msg("Error fetching tracked stock")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetTrackedStock"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if stock == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, stock)
+}
+
+// CreateTrackedStock starts tracking a ticker (admin only)
+func (h *AdminStockHandlers) CreateTrackedStock(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreateTrackedStockRequest)
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	stock, err := h.repo.CreateTrackedStock(strings.ToUpper(req.Ticker), req.Name, req.ApiKey, enabled)
+	if err != nil {
+		if errors.Is(err, models.ErrTrackedStockExists) {
+			return helper.ErrorResponse(c, http.StatusConflict, "Saham sudah terdaftar", nil)
+		}
+		if errors.Is(err, helper.ErrEncryptionKeyMissing) {
+			return helper.ErrorResponse(c, http.StatusServiceUnavailable, "Kunci enkripsi API key belum dikonfigurasi", nil)
+		}
+		Logger.Error().Err(err).Str("api", "CreateTrackedStock").Msg("Error creating tracked stock")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateTrackedStock"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockCreate,
+		TargetType: models.AuditTargetStock,
+		TargetID:   stock.Ticker,
+		After:      stock,
+	})
+
+	return helper.JsonResponse(c, http.StatusCreated, stock)
+}
+
+// UpdateTrackedStockStatus enables or disables ingestion for a ticker (admin only)
+func (h *AdminStockHandlers) UpdateTrackedStockStatus(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateTrackedStockStatusRequest)
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	before := h.auditStockBefore(ticker)
+	stock, err := h.repo.UpdateTrackedStockEnabled(ticker, *req.Enabled)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateTrackedStockStatus").Msg("Error updating tracked stock")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateTrackedStockStatus"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if stock == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockStatus,
+		TargetType: models.AuditTargetStock,
+		TargetID:   ticker,
+		Before:     before,
+		After:      stock,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, stock)
+}
+
+// UpdateTrackedStockApiKey assigns or clears the API key of a ticker (admin only)
+func (h *AdminStockHandlers) UpdateTrackedStockApiKey(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateTrackedStockApiKeyRequest)
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	before := h.auditStockBefore(ticker)
+	stock, err := h.repo.UpdateTrackedStockApiKey(ticker, req.ApiKey)
+	if err != nil {
+		if errors.Is(err, helper.ErrEncryptionKeyMissing) {
+			return helper.ErrorResponse(c, http.StatusServiceUnavailable, "Kunci enkripsi API key belum dikonfigurasi", nil)
+		}
+		Logger.Error().Err(err).Str("api", "UpdateTrackedStockApiKey").Msg("Error updating API key")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateTrackedStockApiKey"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if stock == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	// Only the key hint is recorded, never the key itself
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockApiKey,
+		TargetType: models.AuditTargetStock,
+		TargetID:   ticker,
+		Before:     before,
+		After:      stock,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, stock)
+}
+
+// DeleteTrackedStock stops tracking a ticker (admin only)
+func (h *AdminStockHandlers) DeleteTrackedStock(c echo.Context) error {
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	before := h.auditStockBefore(ticker)
+	deleted, err := h.repo.DeleteTrackedStock(ticker)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "DeleteTrackedStock").Msg("Error deleting tracked stock")
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteTrackedStock"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !deleted {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockDelete,
+		TargetType: models.AuditTargetStock,
+		TargetID:   ticker,
+		Before:     before,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
+
+// IngestTrackedStock starts ingesting a single ticker in the background and
+// returns the run ID, browsable through the ingestion runs endpoint (admin only)
+func (h *AdminStockHandlers) IngestTrackedStock(c echo.Context) error {
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	runID, err := h.ingestor.IngestTicker(ticker)
+	if err != nil {
+		switch {
+		case errors.Is(err, cron.ErrStockNotTracked):
+			return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+		case errors.Is(err, cron.ErrStockMissingApiKey):
+			return helper.ErrorResponse(c, http.StatusUnprocessableEntity, "Saham belum memiliki API key", nil)
+		}
+		Logger.Error().Err(err).Str("api", "IngestTrackedStock").Msg("Error ingesting stock")
+		middleware.CaptureError(c, err, map[string]string{"handler": "IngestTrackedStock"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockIngest,
+		TargetType: models.AuditTargetStock,
+		TargetID:   ticker,
+		After:      map[string]interface{}{"run_id": runID},
+	})
+
+	return helper.JsonResponse(c, http.StatusAccepted, map[string]interface{}{
+		"ticker": ticker,
+		"run_id": runID,
+	})
+}
+
+// auditStockBefore returns the tracked ticker before a change, for the audit log. A failed
+// lookup only leaves the snapshot empty.
+func (h *AdminStockHandlers) auditStockBefore(ticker string) *models.TrackedStock {
+	stock, err := h.repo.GetTrackedStock(ticker)
+	if err != nil {
+		Logger.Warn().Err(err).Str("ticker", ticker).Msg("[auditStockBefore] Error fetching tracked stock")
+		return nil
+	}
+	return stock
+}
+
+// maxStockProfileImportSize caps the uploaded CSV for ImportStockProfiles.
+const maxStockProfileImportSize = 2 << 20
+
+// GetStockClassifications returns the sectors, industries and boards a profile may use (admin only)
+func (h *AdminStockHandlers) GetStockClassifications(c echo.Context) error {
+	options, err := h.repo.GetStockClassificationOptions()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetStockClassifications").Msg("Error fetching stock classifications")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetStockClassifications"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, options)
+}
+
+// UpdateStockProfile replaces the name, sector, industry, sub-industry and board of a ticker (admin only)
+func (h *AdminStockHandlers) UpdateStockProfile(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateStockProfileRequest)
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	profile := models.StockProfile{
+		Ticker:      ticker,
+		Name:        req.Name,
+		Sector:      req.Sector,
+		Industry:    req.Industry,
+		SubIndustry: req.SubIndustry,
+		Board:       req.Board,
+	}
+
+	options, err := h.repo.GetStockClassificationOptions()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateStockProfile").Msg("Error fetching stock classifications")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateStockProfile"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if err := options.Validate(profile); err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Sektor, industri atau papan pencatatan tidak valid", map[string]string{"error": err.Error()})
+	}
+
+	before := h.auditStockBefore(ticker)
+	updated, err := h.repo.UpdateStockProfile(profile)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateStockProfile").Msg("Error updating stock profile")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateStockProfile"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if updated == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockProfile,
+		TargetType: models.AuditTargetStock,
+		TargetID:   ticker,
+		Before:     before,
+		After:      updated,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, updated)
+}
+
+// ImportStockProfiles upserts stock master attributes from an uploaded CSV
+// (multipart field "file"). Nothing is written when any row is invalid (admin only)
+func (h *AdminStockHandlers) ImportStockProfiles(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "File CSV wajib diunggah", nil)
+	}
+	if fileHeader.Size > maxStockProfileImportSize {
+		return helper.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Ukuran file CSV maksimal 2 MB", nil)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImportStockProfiles").Msg("Error opening uploaded CSV")
+		return helper.ErrorResponse(c, http.StatusBadRequest, "File CSV tidak dapat dibaca", nil)
+	}
+	defer file.Close()
+
+	options, err := h.repo.GetStockClassificationOptions()
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImportStockProfiles").Msg("Error fetching stock classifications")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ImportStockProfiles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	profiles, rowErrors, err := models.ParseStockProfilesCSV(file, *options)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Format CSV tidak valid", map[string]string{"error": err.Error()})
+	}
+	if len(rowErrors) > 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "Terdapat baris CSV yang tidak valid", map[string]interface{}{
+			"errors": rowErrors,
+		})
+	}
+	if len(profiles) == 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "File CSV tidak berisi data", nil)
+	}
+
+	result, err := h.repo.ImportStockProfiles(profiles)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ImportStockProfiles").Msg("Error importing stock profiles")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ImportStockProfiles"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionStockImport,
+		TargetType: models.AuditTargetStock,
+		After:      result,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, result)
+}
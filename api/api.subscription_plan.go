@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// SubscriptionPlanHandlers contains handlers for subscription plans and pricing.
+type SubscriptionPlanHandlers struct {
+	repo models.SubscriptionPlanRepository
+}
+
+// NewSubscriptionPlanHandlers creates a new instance of subscription plan handlers.
+func NewSubscriptionPlanHandlers(repo models.SubscriptionPlanRepository) *SubscriptionPlanHandlers {
+	return &SubscriptionPlanHandlers{repo: repo}
+}
+
+// subscriptionPlanErrorResponse writes the response for subscription plan domain errors
+// and reports whether err was one.
+func subscriptionPlanErrorResponse(c echo.Context, err error) (bool, error) {
+	switch {
+	case errors.Is(err, models.ErrSubscriptionPlanNotFound):
+		return true, helper.ErrorResponse(c, http.StatusNotFound, "Paket langganan tidak ditemukan", nil)
+	case errors.Is(err, models.ErrSubscriptionPlanInactive):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Paket langganan sudah tidak tersedia", nil)
+	case errors.Is(err, models.ErrSubscriptionPlanLevelMismatch):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Paket langganan tidak sesuai dengan level pengguna", nil)
+	case errors.Is(err, models.ErrSubscriptionPlanDowngrade):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Tidak dapat membeli paket di bawah langganan aktif Anda", nil)
+	case errors.Is(err, models.ErrSubscriptionPlanExists):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Paket dengan tier dan durasi yang sama sudah ada", nil)
+	}
+	return false, nil
+}
+
+// GetPricing returns the active subscription plans and their prices
+func (h *SubscriptionPlanHandlers) GetPricing(c echo.Context) error {
+	plans, err := h.repo.ListPlans(true)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetPricing").Msg("Error fetching subscription plans")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetPricing"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, &models.PricingResponse{Currency: "IDR", Plans: plans})
+}
+
+// GetSubscriptionPlans returns every subscription plan, including inactive ones (admin only)
+func (h *SubscriptionPlanHandlers) GetSubscriptionPlans(c echo.Context) error {
+	plans, err := h.repo.ListPlans(false)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetSubscriptionPlans").Msg("Error fetching subscription plans")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetSubscriptionPlans"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, plans)
+}
+
+// CreateSubscriptionPlan offers a new subscription plan (admin only)
+func (h *SubscriptionPlanHandlers) CreateSubscriptionPlan(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreateSubscriptionPlanRequest)
+
+	plan := &models.SubscriptionPlan{
+		UserLevel:      req.UserLevel,
+		Name:           req.Name,
+		DurationMonths: req.DurationMonths,
+		Price:          req.Price,
+		Active:         true,
+	}
+	if req.Active != nil {
+		plan.Active = *req.Active
+	}
+
+	created, err := h.repo.CreatePlan(plan)
+	if err != nil {
+		if handled, respErr := subscriptionPlanErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "CreateSubscriptionPlan").Msg("Error creating subscription plan")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateSubscriptionPlan"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionPlanCreate,
+		TargetType: models.AuditTargetSubscriptionPlan,
+		TargetID:   strconv.Itoa(created.ID),
+		After:      created,
+	})
+
+	return helper.JsonResponse(c, http.StatusCreated, created)
+}
+
+// UpdateSubscriptionPlan changes a plan's name, price or availability (admin only)
+func (h *SubscriptionPlanHandlers) UpdateSubscriptionPlan(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateSubscriptionPlanRequest)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID paket langganan tidak valid", nil)
+	}
+
+	before, err := h.repo.FindPlan(id)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateSubscriptionPlan").Msg("Error fetching subscription plan")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateSubscriptionPlan"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if before == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Paket langganan tidak ditemukan", nil)
+	}
+
+	updated, err := h.repo.UpdatePlan(&models.SubscriptionPlan{
+		ID:     id,
+		Name:   req.Name,
+		Price:  req.Price,
+		Active: req.Active,
+	})
+	if err != nil {
+		if handled, respErr := subscriptionPlanErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "UpdateSubscriptionPlan").Msg("Error updating subscription plan")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateSubscriptionPlan"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	middleware.RecordAudit(c, middleware.AuditEvent{
+		Action:     models.AuditActionPlanUpdate,
+		TargetType: models.AuditTargetSubscriptionPlan,
+		TargetID:   strconv.Itoa(id),
+		Before:     before,
+		After:      updated,
+	})
+
+	return helper.JsonResponse(c, http.StatusOK, updated)
+}
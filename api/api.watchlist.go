@@ -0,0 +1,333 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// WatchlistHandlers contains handlers for user watchlists.
+type WatchlistHandlers struct {
+	repo models.WatchlistRepository
+}
+
+// NewWatchlistHandlers creates a new instance of watchlist handlers.
+func NewWatchlistHandlers(repo models.WatchlistRepository) *WatchlistHandlers {
+	return &WatchlistHandlers{repo: repo}
+}
+
+// watchlistLimit returns the plan limit of the authenticated user.
+func watchlistLimit(c echo.Context) models.WatchlistLimit {
+	authUser, err := middleware.GetAuthUser(c)
+	if err != nil {
+		return models.WatchlistLimitFor(models.UserLevelFree)
+	}
+	// Premium limits stay in force during the post-expiry grace period
+	now := utime.Utime.Now().ToTime().Add(-config.Get().Premium.GracePeriod)
+	level := models.EffectiveUserLevel(authUser.UserLevel, authUser.PremiumExpiresAt, now)
+	return models.WatchlistLimitFor(level)
+}
+
+// watchlistErrorResponse maps watchlist domain errors to client responses; it returns
+// false for unexpected errors.
+func watchlistErrorResponse(c echo.Context, err error) (bool, error) {
+	switch {
+	case errors.Is(err, models.ErrWatchlistLimitReached):
+		return true, helper.ErrorResponse(c, http.StatusForbidden, "Batas jumlah watchlist untuk paket Anda telah tercapai", watchlistLimit(c))
+	case errors.Is(err, models.ErrWatchlistItemLimitReached):
+		return true, helper.ErrorResponse(c, http.StatusForbidden, "Batas jumlah saham dalam watchlist untuk paket Anda telah tercapai", watchlistLimit(c))
+	case errors.Is(err, models.ErrWatchlistNameExists):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Nama watchlist sudah digunakan", nil)
+	case errors.Is(err, models.ErrWatchlistItemExists):
+		return true, helper.ErrorResponse(c, http.StatusConflict, "Saham sudah ada di watchlist", nil)
+	case errors.Is(err, models.ErrWatchlistUnknownTicker):
+		return true, helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan", nil)
+	case errors.Is(err, models.ErrWatchlistOrderMismatch):
+		return true, helper.ErrorResponse(c, http.StatusBadRequest, "Urutan harus memuat setiap data tepat satu kali", nil)
+	}
+	return false, nil
+}
+
+func parseWatchlistID(c echo.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// GetWatchlists returns the authenticated user's watchlists and plan limit
+func (h *WatchlistHandlers) GetWatchlists(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	watchlists, err := h.repo.ListWatchlists(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetWatchlists").Msg("Error fetching watchlists")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetWatchlists"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, map[string]interface{}{
+		"watchlists": watchlists,
+		"limit":      watchlistLimit(c),
+	})
+}
+
+// CreateWatchlist creates a named watchlist within the user's plan limit
+func (h *WatchlistHandlers) CreateWatchlist(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.CreateWatchlistRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	watchlist, err := h.repo.CreateWatchlist(userID, strings.TrimSpace(req.Name), watchlistLimit(c))
+	if err != nil {
+		if handled, respErr := watchlistErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "CreateWatchlist").Msg("Error creating watchlist")
+		middleware.CaptureError(c, err, map[string]string{"handler": "CreateWatchlist"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusCreated, watchlist)
+}
+
+// ReorderWatchlists sets the display order of all the user's watchlists
+func (h *WatchlistHandlers) ReorderWatchlists(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.ReorderWatchlistsRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	if err := h.repo.ReorderWatchlists(userID, req.WatchlistIDs); err != nil {
+		if handled, respErr := watchlistErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "ReorderWatchlists").Msg("Error reordering watchlists")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ReorderWatchlists"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	watchlists, err := h.repo.ListWatchlists(userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "ReorderWatchlists").Msg("Error fetching watchlists")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ReorderWatchlists"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, watchlists)
+}
+
+// GetWatchlist returns a watchlist with its tickers enriched with the latest overview metrics
+func (h *WatchlistHandlers) GetWatchlist(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	watchlist, err := h.repo.GetWatchlist(id, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "GetWatchlist").Msg("Error fetching watchlist")
+		middleware.CaptureError(c, err, map[string]string{"handler": "GetWatchlist"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if watchlist == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Watchlist tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, watchlist)
+}
+
+// RenameWatchlist changes the name of a watchlist
+func (h *WatchlistHandlers) RenameWatchlist(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.RenameWatchlistRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	watchlist, err := h.repo.RenameWatchlist(id, userID, strings.TrimSpace(req.Name))
+	if err != nil {
+		if handled, respErr := watchlistErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "RenameWatchlist").Msg("Error renaming watchlist")
+		middleware.CaptureError(c, err, map[string]string{"handler": "RenameWatchlist"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if watchlist == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Watchlist tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, watchlist)
+}
+
+// DeleteWatchlist deletes a watchlist and its tickers
+func (h *WatchlistHandlers) DeleteWatchlist(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	deleted, err := h.repo.DeleteWatchlist(id, userID)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "DeleteWatchlist").Msg("Error deleting watchlist")
+		middleware.CaptureError(c, err, map[string]string{"handler": "DeleteWatchlist"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !deleted {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Watchlist tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
+
+// AddWatchlistItem adds a ticker with an optional note to a watchlist
+func (h *WatchlistHandlers) AddWatchlistItem(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.AddWatchlistItemRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	item, err := h.repo.AddWatchlistItem(id, userID, strings.ToUpper(req.Ticker), req.Note, watchlistLimit(c))
+	if err != nil {
+		if handled, respErr := watchlistErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "AddWatchlistItem").Msg("Error adding watchlist item")
+		middleware.CaptureError(c, err, map[string]string{"handler": "AddWatchlistItem"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if item == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Watchlist tidak ditemukan", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusCreated, item)
+}
+
+// UpdateWatchlistItem changes the note of a ticker in a watchlist
+func (h *WatchlistHandlers) UpdateWatchlistItem(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.UpdateWatchlistItemRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	item, err := h.repo.UpdateWatchlistItemNote(id, userID, strings.ToUpper(c.Param("ticker")), req.Note)
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "UpdateWatchlistItem").Msg("Error updating watchlist item")
+		middleware.CaptureError(c, err, map[string]string{"handler": "UpdateWatchlistItem"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if item == nil {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan di watchlist", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, item)
+}
+
+// RemoveWatchlistItem removes a ticker from a watchlist
+func (h *WatchlistHandlers) RemoveWatchlistItem(c echo.Context) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	removed, err := h.repo.RemoveWatchlistItem(id, userID, strings.ToUpper(c.Param("ticker")))
+	if err != nil {
+		Logger.Error().Err(err).Str("api", "RemoveWatchlistItem").Msg("Error removing watchlist item")
+		middleware.CaptureError(c, err, map[string]string{"handler": "RemoveWatchlistItem"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !removed {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Saham tidak ditemukan di watchlist", nil)
+	}
+
+	return helper.JsonResponse(c, http.StatusOK, nil)
+}
+
+// ReorderWatchlistItems sets the display order of every ticker in a watchlist
+func (h *WatchlistHandlers) ReorderWatchlistItems(c echo.Context) error {
+	req := validator.GetValidatedRequest(c).(*validator.ReorderWatchlistItemsRequest)
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna belum diautentikasi", nil)
+	}
+
+	id, ok := parseWatchlistID(c)
+	if !ok {
+		return helper.ErrorResponse(c, http.StatusBadRequest, "ID watchlist tidak valid", nil)
+	}
+
+	tickers := make([]string, len(req.Tickers))
+	for i, ticker := range req.Tickers {
+		tickers[i] = strings.ToUpper(ticker)
+	}
+
+	found, err := h.repo.ReorderWatchlistItems(id, userID, tickers)
+	if err != nil {
+		if handled, respErr := watchlistErrorResponse(c, err); handled {
+			return respErr
+		}
+		Logger.Error().Err(err).Str("api", "ReorderWatchlistItems").Msg("Error reordering watchlist items")
+		middleware.CaptureError(c, err, map[string]string{"handler": "ReorderWatchlistItems"}, nil)
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+	}
+	if !found {
+		return helper.ErrorResponse(c, http.StatusNotFound, "Watchlist tidak ditemukan", nil)
+	}
+
+	return h.GetWatchlist(c)
+}
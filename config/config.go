@@ -21,6 +21,26 @@ type Config struct {
 	LogLevel   string
 	AppVersion string
 	Cron       CronConfig
+	MarketData MarketDataConfig
+
+	// TrustedProxies are the IPs/CIDRs of reverse proxies whose X-Forwarded-For is trusted
+	// for the client IP; when empty the connection address is used
+	TrustedProxies []string
+
+	// Raw datasource payload archive
+	PayloadArchive PayloadArchiveConfig
+
+	// Notification delivery
+	Notification NotificationConfig
+
+	// Sign-up email verification
+	EmailVerification EmailVerificationConfig
+
+	// Premium subscription expiry handling
+	Premium PremiumConfig
+
+	// Payment gateway checkout
+	Payment PaymentConfig
 
 	// Sentry Configuration
 	SentryDSN string
@@ -41,12 +61,115 @@ type Config struct {
 // CronConfig holds cron/scheduler configuration
 type CronConfig struct {
 	Interval time.Duration
+
+	// Stock ingestion worker pool
+	IngestWorkers     int
+	IngestKeyInterval time.Duration
+	IngestKeyBurst    int
+}
+
+// MarketDataConfig holds market data provider configuration
+type MarketDataConfig struct {
+	// Provider selects the implementation: "datasectors" (default) or "fixture"
+	Provider   string
+	BaseURL    string
+	FixtureDir string
+
+	// Retry and circuit breaker settings for live provider requests
+	RequestTimeout          time.Duration
+	RetryMaxAttempts        int
+	RetryBaseDelay          time.Duration
+	RetryMaxDelay           time.Duration
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+
+	// APIKeyEncryptionKey encrypts per-ticker API keys at rest (32 bytes, base64 or hex)
+	APIKeyEncryptionKey string
+}
+
+// PayloadArchiveConfig holds raw datasource payload archive configuration
+type PayloadArchiveConfig struct {
+	// Backend selects where payloads are kept: "filesystem" (default), "db" or "none"
+	Backend string
+	Dir     string
+}
+
+// NotificationConfig holds notification delivery configuration
+type NotificationConfig struct {
+	// Delivery selects "log" (default; email, Telegram and web push are only logged) or "live"
+	Delivery       string
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	// AppURL prefixes the relative links carried by notifications
+	AppURL string
+
+	SMTP     SMTPConfig
+	Telegram TelegramConfig
+	WebPush  WebPushConfig
+}
+
+// EmailVerificationConfig holds sign-up email verification configuration. Emails link to
+// Notification.AppURL and go out through Notification.SMTP when the smtp sender is used.
+type EmailVerificationConfig struct {
+	// Sender selects "log" (default; emails are only written to the log) or "smtp"
+	Sender   string
+	TokenTTL time.Duration
+	// ResendInterval is the minimum time between two verification emails to a user
+	ResendInterval time.Duration
+	// ResendMaxPerHour caps the verification emails sent to a user in any hour
+	ResendMaxPerHour int
+}
+
+// PremiumConfig holds premium subscription expiry configuration
+type PremiumConfig struct {
+	// ReminderDays are the days before expiry a renewal reminder is sent
+	ReminderDays []int
+	// GracePeriod keeps premium routes working after expiry, flagged with a response header
+	GracePeriod time.Duration
+}
+
+// PaymentConfig holds payment gateway and checkout configuration
+type PaymentConfig struct {
+	// Gateway selects the payment gateway adapter: "fake" (default; local simulation)
+	Gateway string
+	// WebhookSecret signs gateway webhooks; the fake gateway generates one per process when empty
+	WebhookSecret string
+	// CheckoutTTL is how long a pending checkout can be paid before it expires
+	CheckoutTTL time.Duration
+
+	// InvoiceIssuer is the seller name printed on invoices
+	InvoiceIssuer string
+}
+
+// SMTPConfig holds the SMTP server used by the email channel
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// TelegramConfig holds the bot used by the Telegram channel
+type TelegramConfig struct {
+	BotToken string
+	APIURL   string
+}
+
+// WebPushConfig holds the VAPID key pair (base64url) used by the web push channel
+type WebPushConfig struct {
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	// Subject is the mailto: or https: contact sent to push services
+	Subject string
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
 	Secret    string
 	ExpiresIn string
+	// ImpersonationTTL is the lifetime of admin impersonation tokens
+	ImpersonationTTL time.Duration
 }
 
 // DatabaseConfig holds database configuration
@@ -101,12 +224,74 @@ func Load() (*Config, error) {
 		LogLevel:   getEnv("LOG_LEVEL", "info"),
 		AppVersion: getEnv("APP_VERSION", "1.0.0"),
 		SentryDSN:  getEnv("SENTRY_DSN", ""),
+
+		TrustedProxies: parseListEnv("TRUSTED_PROXIES"),
 		Cron: CronConfig{
-			Interval: parseDurationEnv("CRON_INTERVAL", time.Minute),
+			Interval:          parseDurationEnv("CRON_INTERVAL", time.Minute),
+			IngestWorkers:     getEnvAsInt("INGEST_WORKERS", 4),
+			IngestKeyInterval: parseDurationEnv("INGEST_KEY_INTERVAL", 20*time.Second),
+			IngestKeyBurst:    getEnvAsInt("INGEST_KEY_BURST", 1),
+		},
+		MarketData: MarketDataConfig{
+			Provider:   strings.ToLower(strings.TrimSpace(getEnv("MARKET_DATA_PROVIDER", "datasectors"))),
+			BaseURL:    getEnv("MARKET_DATA_BASE_URL", "https://api.datasectors.com/api/stocks/v2/"),
+			FixtureDir: getEnv("MARKET_DATA_FIXTURE_DIR", "./datasource"),
+
+			RequestTimeout:          parseDurationEnv("MARKET_DATA_REQUEST_TIMEOUT", 30*time.Second),
+			RetryMaxAttempts:        getEnvAsInt("MARKET_DATA_RETRY_MAX_ATTEMPTS", 3),
+			RetryBaseDelay:          parseDurationEnv("MARKET_DATA_RETRY_BASE_DELAY", time.Second),
+			RetryMaxDelay:           parseDurationEnv("MARKET_DATA_RETRY_MAX_DELAY", 30*time.Second),
+			BreakerFailureThreshold: getEnvAsInt("MARKET_DATA_BREAKER_THRESHOLD", 5),
+			BreakerOpenTimeout:      parseDurationEnv("MARKET_DATA_BREAKER_OPEN_TIMEOUT", 2*time.Minute),
+
+			APIKeyEncryptionKey: getEnv("MARKET_DATA_API_KEY_ENCRYPTION_KEY", ""),
+		},
+		PayloadArchive: PayloadArchiveConfig{
+			Backend: strings.ToLower(strings.TrimSpace(getEnv("PAYLOAD_ARCHIVE_BACKEND", "filesystem"))),
+			Dir:     getEnv("PAYLOAD_ARCHIVE_DIR", "./payload_archive"),
+		},
+		Notification: NotificationConfig{
+			Delivery:       strings.ToLower(strings.TrimSpace(getEnv("NOTIFICATION_DELIVERY", "log"))),
+			MaxAttempts:    getEnvAsInt("NOTIFICATION_MAX_ATTEMPTS", 5),
+			RetryBaseDelay: parseDurationEnv("NOTIFICATION_RETRY_BASE_DELAY", time.Minute),
+			AppURL:         strings.TrimRight(getEnv("NOTIFICATION_APP_URL", "http://localhost:5173"), "/"),
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""),
+				Port:     getEnvAsInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				From:     getEnv("SMTP_FROM", ""),
+			},
+			Telegram: TelegramConfig{
+				BotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
+				APIURL:   strings.TrimRight(getEnv("TELEGRAM_API_URL", "https://api.telegram.org"), "/"),
+			},
+			WebPush: WebPushConfig{
+				VAPIDPublicKey:  getEnv("WEB_PUSH_VAPID_PUBLIC_KEY", ""),
+				VAPIDPrivateKey: getEnv("WEB_PUSH_VAPID_PRIVATE_KEY", ""),
+				Subject:         getEnv("WEB_PUSH_SUBJECT", ""),
+			},
+		},
+		EmailVerification: EmailVerificationConfig{
+			Sender:           strings.ToLower(strings.TrimSpace(getEnv("EMAIL_VERIFICATION_SENDER", "log"))),
+			TokenTTL:         parseDurationEnv("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour),
+			ResendInterval:   parseDurationEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+			ResendMaxPerHour: getEnvAsInt("EMAIL_VERIFICATION_RESEND_MAX_PER_HOUR", 5),
+		},
+		Premium: PremiumConfig{
+			ReminderDays: parseIntListEnv("PREMIUM_REMINDER_DAYS", []int{7, 3, 1}),
+			GracePeriod:  parseDurationEnv("PREMIUM_GRACE_PERIOD", 0),
+		},
+		Payment: PaymentConfig{
+			Gateway:       strings.ToLower(strings.TrimSpace(getEnv("PAYMENT_GATEWAY", "fake"))),
+			WebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+			CheckoutTTL:   parseDurationEnv("PAYMENT_CHECKOUT_TTL", 24*time.Hour),
+			InvoiceIssuer: getEnv("PAYMENT_INVOICE_ISSUER", "Be Saham"),
 		},
 		JWT: JWTConfig{
-			Secret:    getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-			ExpiresIn: getEnv("JWT_EXPIRES_IN", "24h"),
+			Secret:           getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
+			ExpiresIn:        getEnv("JWT_EXPIRES_IN", "24h"),
+			ImpersonationTTL: parseDurationEnv("JWT_IMPERSONATION_TTL", 15*time.Minute),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: parseCORSOrigins(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:5173")),
@@ -177,6 +362,38 @@ func getEnvAsInt(name string, defaultValue int) int {
 	return defaultValue
 }
 
+// parseIntListEnv parses a comma-separated list of non-negative integers with a fallback default value
+func parseIntListEnv(name string, defaultValue []int) []int {
+	valueStr := strings.TrimSpace(getEnv(name, ""))
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []int
+	for _, part := range strings.Split(valueStr, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || value < 0 {
+			if Logger != nil {
+				Logger.Warn().Str("env", name).Str("value", valueStr).Msg("Invalid integer list value, using default")
+			}
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// parseListEnv parses a comma-separated environment variable, skipping empty entries
+func parseListEnv(name string) []string {
+	var values []string
+	for _, part := range strings.Split(getEnv(name, ""), ",") {
+		if value := strings.TrimSpace(part); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // parseCORSOrigins parses a comma-separated string of CORS origins into a slice
 func parseCORSOrigins(originsStr string) []string {
 	if originsStr == "" {
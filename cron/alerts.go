@@ -0,0 +1,138 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const (
+	jobEvaluateAlerts = "evaluateAlerts"
+	// alertEvaluationBatchSize is the number of alerts loaded and written per transaction
+	alertEvaluationBatchSize = 500
+)
+
+// AlertEvaluationSummary reports the outcome of one alert evaluation pass.
+type AlertEvaluationSummary struct {
+	Evaluated int `json:"evaluated"`
+	Triggered int `json:"triggered"`
+}
+
+// EvaluateAlerts checks every active user alert against the freshly ingested data in
+// batches and records the ones that fired in the alert inbox. An alert fires once per
+// crossing; a failing batch is reported and skipped so later batches still run. Fired
+// alerts are also sent on the user's external notification channels.
+func (r *Runner) EvaluateAlerts(ctx context.Context) *AlertEvaluationSummary {
+	alertRepo := models.NewAlertRepository()
+	summary := &AlertEvaluationSummary{}
+
+	var afterID int64
+	for ctx.Err() == nil {
+		inputs, err := alertRepo.ListAlertsForEvaluation(afterID, alertEvaluationBatchSize)
+		if err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    jobEvaluateAlerts,
+				"action": "list_alerts",
+			}, map[string]interface{}{
+				"after_id": afterID,
+			})
+			r.logger.Error().Err(err).Str("job", jobEvaluateAlerts).Int64("afterId", afterID).Msg("Failed to load alerts for evaluation")
+			break
+		}
+		if len(inputs) == 0 {
+			break
+		}
+
+		now := utime.Utime.Now().ToTime()
+		evaluations := make([]models.AlertEvaluation, 0, len(inputs))
+		inputsByID := make(map[int64]models.AlertEvaluationInput, len(inputs))
+		for _, input := range inputs {
+			evaluations = append(evaluations, models.EvaluateAlert(input, now))
+			inputsByID[input.ID] = input
+		}
+		afterID = inputs[len(inputs)-1].ID
+		summary.Evaluated += len(inputs)
+
+		triggered, err := alertRepo.ApplyAlertEvaluations(evaluations, now)
+		if err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    jobEvaluateAlerts,
+				"action": "apply_evaluations",
+			}, map[string]interface{}{
+				"last_alert_id": afterID,
+			})
+			r.logger.Error().Err(err).Str("job", jobEvaluateAlerts).Int64("lastAlertId", afterID).Msg("Failed to record alert evaluations")
+			continue
+		}
+		summary.Triggered += len(triggered)
+
+		for _, entry := range triggered {
+			if entry.AlertID != nil {
+				r.notifyAlert(ctx, inputsByID[*entry.AlertID], entry)
+			}
+		}
+
+		if len(inputs) < alertEvaluationBatchSize {
+			break
+		}
+	}
+
+	r.logger.Info().
+		Str("job", jobEvaluateAlerts).
+		Int("evaluated", summary.Evaluated).
+		Int("triggered", summary.Triggered).
+		Msg("Alert evaluation completed")
+	return summary
+}
+
+// notifyAlert enqueues a fired alert on the external channels; the in-app copy is the
+// inbox entry already written with the alert state.
+func (r *Runner) notifyAlert(ctx context.Context, input models.AlertEvaluationInput, entry models.AlertInboxEntry) {
+	data := map[string]interface{}{
+		"AlertType": string(input.AlertType),
+		"Ticker":    "",
+		"Label":     "",
+		"Threshold": input.Threshold,
+		"Value":     entry.ObservedValue,
+		"Date":      "",
+	}
+	if input.Ticker != nil {
+		data["Ticker"] = *input.Ticker
+		data["Label"] = *input.Ticker
+	}
+	if input.TargetLabel != nil {
+		data["Label"] = *input.TargetLabel
+	}
+	if entry.EventDate != nil {
+		data["Date"] = entry.EventDate.Format(models.SQLDateFormat)
+	}
+
+	_, err := r.notifier.Notify(ctx, notification.Notification{
+		UserID:    entry.UserID,
+		Event:     notification.EventAlertTriggered,
+		Data:      data,
+		DedupeKey: fmt.Sprintf("alert_inbox:%d", entry.ID),
+		URL:       "/alerts",
+		Channels: []models.NotificationChannel{
+			models.NotificationChannelEmail,
+			models.NotificationChannelTelegram,
+			models.NotificationChannelWebPush,
+		},
+	})
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobEvaluateAlerts,
+			"action": "notify_alert",
+		}, map[string]interface{}{
+			"alert_id": input.ID,
+			"user_id":  entry.UserID,
+		})
+		r.logger.Error().Err(err).Str("job", jobEvaluateAlerts).Int64("alertId", input.ID).Msg("Failed to enqueue alert notification")
+	}
+}
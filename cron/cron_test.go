@@ -0,0 +1,140 @@
+package cron
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectSchemaDrift(t *testing.T) {
+	const expected = `{"success":true,"data":{"symbol":"BBCA","price":9000,"sector":null,"history":{"2023":{"eps":1}},"items":[{"id":1}]}}`
+
+	tests := []struct {
+		name   string
+		actual string
+		want   []SchemaDrift
+	}{
+		{
+			name:   "identical payload",
+			actual: expected,
+			want:   []SchemaDrift{},
+		},
+		{
+			name:   "null and empty containers are compatible",
+			actual: `{"success":true,"data":{"symbol":null,"price":9100,"sector":"Finance","history":{},"items":[]}}`,
+			want:   []SchemaDrift{},
+		},
+		{
+			name:   "dynamic keys collapse",
+			actual: `{"success":true,"data":{"symbol":"BBCA","price":9000,"sector":null,"history":{"2024":{"eps":2}},"items":[{"id":2}]}}`,
+			want:   []SchemaDrift{},
+		},
+		{
+			name:   "added and removed keys report the top-most path",
+			actual: `{"success":true,"data":{"symbol":"BBCA","sector":null,"history":{},"items":[],"extra":{"nested":1}}}`,
+			want: []SchemaDrift{
+				{Kind: SchemaDriftAddedKey, Path: "data.extra", Actual: "object"},
+				{Kind: SchemaDriftRemovedKey, Path: "data.price", Expected: "number"},
+			},
+		},
+		{
+			name:   "type change",
+			actual: `{"success":true,"data":{"symbol":"BBCA","price":"9000","sector":null,"history":{},"items":[{"id":"x"}]}}`,
+			want: []SchemaDrift{
+				{Kind: SchemaDriftTypeChanged, Path: "data.items[].id", Expected: "number", Actual: "string"},
+				{Kind: SchemaDriftTypeChanged, Path: "data.price", Expected: "number", Actual: "string"},
+			},
+		},
+	}
+
+	expectedSchema, err := BuildPayloadSchema([]byte(expected))
+	if err != nil {
+		t.Fatalf("error building expected schema: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualSchema, err := BuildPayloadSchema([]byte(tt.actual))
+			if err != nil {
+				t.Fatalf("error building actual schema: %v", err)
+			}
+			if got := DetectSchemaDrift(expectedSchema, actualSchema); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got drifts %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaDriftCollector(t *testing.T) {
+	removed := SchemaDrift{Kind: SchemaDriftRemovedKey, Path: "data.price", Expected: "number"}
+	added := SchemaDrift{Kind: SchemaDriftAddedKey, Path: "data.extra", Actual: "object"}
+	changed := SchemaDrift{Kind: SchemaDriftTypeChanged, Path: "data.price", Expected: "number", Actual: "string"}
+	changedAgain := SchemaDrift{Kind: SchemaDriftTypeChanged, Path: "data.price", Expected: "number", Actual: "boolean"}
+
+	type seen struct {
+		ticker  string
+		reports []*payloadDriftReport
+	}
+
+	tests := []struct {
+		name        string
+		seen        []seen
+		wantTickers map[string][]string
+	}{
+		{
+			name: "extra drift on one ticker keeps the shared signature",
+			seen: []seen{
+				{"BBCA", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{removed}}}},
+				{"BBRI", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{added, removed}}}},
+			},
+			wantTickers: map[string][]string{
+				schemaDriftSignature("equities", removed): {"BBCA", "BBRI"},
+				schemaDriftSignature("equities", added):   {"BBRI"},
+			},
+		},
+		{
+			name: "same path on another endpoint is a separate signature",
+			seen: []seen{
+				{"BBCA", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{removed}}, {Endpoint: "earnings", Drifts: []SchemaDrift{removed}}}},
+			},
+			wantTickers: map[string][]string{
+				schemaDriftSignature("equities", removed): {"BBCA"},
+				schemaDriftSignature("earnings", removed): {"BBCA"},
+			},
+		},
+		{
+			name: "actual type does not split a signature",
+			seen: []seen{
+				{"BBCA", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{changed}}}},
+				{"BBRI", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{changedAgain}}}},
+				{"BBRI", []*payloadDriftReport{{Endpoint: "equities", Drifts: []SchemaDrift{changed}}}},
+			},
+			wantTickers: map[string][]string{
+				schemaDriftSignature("equities", changed): {"BBCA", "BBRI"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := newSchemaDriftCollector()
+			for _, s := range tt.seen {
+				collector.add(s.ticker, s.reports)
+			}
+
+			if len(collector.order) != len(tt.wantTickers) {
+				t.Fatalf("got %d signatures, want %d", len(collector.order), len(tt.wantTickers))
+			}
+			for signature, wantTickers := range tt.wantTickers {
+				collected, ok := collector.bySignature[signature]
+				if !ok {
+					t.Fatalf("missing signature %s", signature)
+				}
+				if len(collected.report.Drifts) != 1 {
+					t.Fatalf("got %d drifts under signature %s, want 1", len(collected.report.Drifts), signature)
+				}
+				if !reflect.DeepEqual(collected.tickers, wantTickers) {
+					t.Fatalf("got tickers %v for signature %s, want %v", collected.tickers, signature, wantTickers)
+				}
+			}
+		})
+	}
+}
@@ -21,7 +21,11 @@ type EarningsData struct {
 	Currency               string             `json:"Currency"`
 	MarketCap              *float64           `json:"MarketCap"`
 	LastActualFiscalPeriod string             `json:"LastActualFiscalPeriod"`
+	FiscalPeriodEndDate    *string            `json:"FiscalPeriodEndDate"`
+	CiqFiscalPeriodType    *string            `json:"CiqFiscalPeriodType"`
 	ExpectedReportDate     *string            `json:"ExpectedReportDate"`
+	EpsForecast            *float64           `json:"EpsForecast"`
+	RevenueForecast        *float64           `json:"RevenueForecast"`
 	TimeLastUpdated        *string            `json:"TimeLastUpdated"`
 	LastActual             EarningsLastActual `json:"LastActual"`
 	History                EarningsHistory    `json:"History"`
@@ -136,3 +140,33 @@ func (e EarningsResponse) ToOverviewMetricsRecord() (*models.StockOverviewMetric
 
 	return record, nil
 }
+
+// ToEarningCalendarRecord returns the upcoming release for the current fiscal period,
+// or nil when the datasource does not announce one.
+func (e EarningsResponse) ToEarningCalendarRecord() (*models.StockEarningCalendarRecord, error) {
+	expectedReportDate, err := helper.ParseRFC3339Pointer(e.Data.ExpectedReportDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected report date: %w", err)
+	}
+
+	fiscalPeriodEndDate, err := helper.ParseRFC3339Pointer(e.Data.FiscalPeriodEndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fiscal period end date: %w", err)
+	}
+
+	if expectedReportDate == nil || fiscalPeriodEndDate == nil {
+		return nil, nil
+	}
+
+	record := &models.StockEarningCalendarRecord{
+		Symbol:              e.Symbol,
+		PeriodCode:          fiscalPeriodEndDate.Format("200601"),
+		CiqFiscalPeriodType: e.Data.CiqFiscalPeriodType,
+		FiscalPeriodEndDate: *fiscalPeriodEndDate,
+		ExpectedReportDate:  *expectedReportDate,
+		EpsForecast:         e.Data.EpsForecast,
+		RevenueForecast:     e.Data.RevenueForecast,
+	}
+
+	return record, nil
+}
@@ -0,0 +1,214 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"golang.org/x/time/rate"
+)
+
+const (
+	IngestStatusSucceeded = models.IngestionItemStatusSucceeded
+	IngestStatusFailed    = models.IngestionItemStatusFailed
+	IngestStatusSkipped   = models.IngestionItemStatusSkipped
+)
+
+const (
+	ingestSkipCanceled      = "canceled"
+	ingestSkipMissingAPIKey = "missing_api_key"
+	ingestSkipCircuitOpen   = "circuit_open"
+)
+
+// stockProcessResult captures the outcome of processing a single ticker.
+type stockProcessResult struct {
+	Ticker        string
+	StartedAt     time.Time
+	Duration      time.Duration
+	FailedActions []string
+	LastErr       error
+	SkipReason    string
+	Drifts        []*payloadDriftReport
+}
+
+// fail records a failed step, keyed by the same action name used for Sentry tags.
+func (r *stockProcessResult) fail(action string, err error) {
+	r.FailedActions = append(r.FailedActions, action)
+	r.LastErr = err
+}
+
+// Status reports the ticker outcome for the run summary.
+func (r *stockProcessResult) Status() string {
+	if r.SkipReason != "" {
+		return IngestStatusSkipped
+	}
+	if len(r.FailedActions) > 0 {
+		return IngestStatusFailed
+	}
+	return IngestStatusSucceeded
+}
+
+// IngestionSummary reports how a stock ingestion run went.
+type IngestionSummary struct {
+	RunID      *int64            `json:"run_id"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Total      int               `json:"total"`
+	Succeeded  []string          `json:"succeeded"`
+	Failed     map[string]string `json:"failed"`
+	Skipped    map[string]string `json:"skipped"`
+	Canceled   bool              `json:"canceled"`
+}
+
+func newIngestionSummary(total int) *IngestionSummary {
+	return &IngestionSummary{
+		StartedAt: utime.Utime.Now().ToTime(),
+		Total:     total,
+		Succeeded: []string{},
+		Failed:    map[string]string{},
+		Skipped:   map[string]string{},
+	}
+}
+
+func (s *IngestionSummary) add(result *stockProcessResult) {
+	switch result.Status() {
+	case IngestStatusSkipped:
+		s.Skipped[result.Ticker] = result.SkipReason
+	case IngestStatusFailed:
+		errMessage := ""
+		if result.LastErr != nil {
+			errMessage = result.LastErr.Error()
+		}
+		s.Failed[result.Ticker] = errMessage
+	default:
+		s.Succeeded = append(s.Succeeded, result.Ticker)
+	}
+}
+
+// ingestStocks processes tickers with a bounded worker pool. Tickers are grouped
+// by API key and each key is paced by its own token bucket, so keys with few
+// tickers are not held back by keys with many. onResult, when set, is called
+// from a single goroutine as each ticker finishes.
+func (r *Runner) ingestStocks(ctx context.Context, stockRepo models.StockRepository, stocks []models.StockInformation, onResult func(*stockProcessResult)) *IngestionSummary {
+	cfg := config.Get().Cron
+	workers := cfg.IngestWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	keyInterval := cfg.IngestKeyInterval
+	keyBurst := cfg.IngestKeyBurst
+	if keyBurst <= 0 {
+		keyBurst = 1
+	}
+
+	summary := newIngestionSummary(len(stocks))
+	results := make(chan *stockProcessResult)
+
+	// Group tickers per API key, preserving the original order within each key
+	stocksByKey := make(map[string][]models.StockInformation)
+	keyOrder := []string{}
+	for _, stock := range stocks {
+		if stock.ApiKey == "" {
+			continue
+		}
+		if _, ok := stocksByKey[stock.ApiKey]; !ok {
+			keyOrder = append(keyOrder, stock.ApiKey)
+		}
+		stocksByKey[stock.ApiKey] = append(stocksByKey[stock.ApiKey], stock)
+	}
+
+	jobs := make(chan models.StockInformation)
+
+	var dispatchers sync.WaitGroup
+	for _, apiKey := range keyOrder {
+		limit := rate.Inf
+		if keyInterval > 0 {
+			limit = rate.Every(keyInterval)
+		}
+		limiter := rate.NewLimiter(limit, keyBurst)
+		keyStocks := stocksByKey[apiKey]
+
+		dispatchers.Add(1)
+		go func() {
+			defer dispatchers.Done()
+			for index, stock := range keyStocks {
+				if err := limiter.Wait(ctx); err != nil {
+					for _, remaining := range keyStocks[index:] {
+						results <- &stockProcessResult{Ticker: remaining.Ticker, SkipReason: ingestSkipCanceled}
+					}
+					return
+				}
+				select {
+				case jobs <- stock:
+				case <-ctx.Done():
+					for _, remaining := range keyStocks[index:] {
+						results <- &stockProcessResult{Ticker: remaining.Ticker, SkipReason: ingestSkipCanceled}
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	var pool sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		pool.Add(1)
+		go func() {
+			defer pool.Done()
+			for stock := range jobs {
+				results <- r.processStockWhenAvailable(ctx, stockRepo, stock)
+			}
+		}()
+	}
+
+	go func() {
+		for _, stock := range stocks {
+			if stock.ApiKey == "" {
+				results <- &stockProcessResult{Ticker: stock.Ticker, SkipReason: ingestSkipMissingAPIKey}
+			}
+		}
+		dispatchers.Wait()
+		close(jobs)
+		pool.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		summary.add(result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	summary.FinishedAt = utime.Utime.Now().ToTime()
+	summary.Canceled = ctx.Err() != nil
+	return summary
+}
+
+// processStockWhenAvailable waits out an open provider circuit before processing
+// a ticker, and retries it once if the circuit opened while it was in flight.
+func (r *Runner) processStockWhenAvailable(ctx context.Context, stockRepo models.StockRepository, stock models.StockInformation) *stockProcessResult {
+	waiter, canWait := r.provider.(availabilityWaiter)
+
+	var result *stockProcessResult
+	for attempt := 0; attempt < 2; attempt++ {
+		if canWait {
+			if err := waiter.WaitAvailable(ctx); err != nil {
+				return &stockProcessResult{Ticker: stock.Ticker, SkipReason: ingestSkipCanceled}
+			}
+		}
+
+		result = r.processStock(ctx, stockRepo, stock)
+		if result.SkipReason != ingestSkipCircuitOpen || !canWait {
+			return result
+		}
+		r.logger.Info().
+			Str("job", "upsertStockInformation").
+			Str("ticker", stock.Ticker).
+			Msg("Datasource circuit open; ticker will resume once the provider recovers")
+	}
+	return result
+}
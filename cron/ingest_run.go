@@ -0,0 +1,263 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const (
+	jobUpsertStockInformation = "upsertStockInformation"
+	jobRetryFailedStocks      = "retryFailedStocks"
+	jobIngestTicker           = "ingestTicker"
+)
+
+var (
+	// ErrStockNotTracked is returned by IngestTicker for an unknown ticker.
+	ErrStockNotTracked = errors.New("stock is not tracked")
+	// ErrStockMissingApiKey is returned by IngestTicker when no API key is assigned.
+	ErrStockMissingApiKey = errors.New("stock has no API key")
+)
+
+// runIngestion ingests stocks and records the run and each ticker outcome in
+// ingestion_runs / ingestion_run_items. History is best effort: a failure to
+// record never stops ingestion itself. User alerts are evaluated once the run
+// completes.
+func (r *Runner) runIngestion(ctx context.Context, job, trigger string, parentRunID *int64, stocks []models.StockInformation) *IngestionSummary {
+	run := r.startRun(job, trigger, parentRunID, len(stocks))
+	return r.ingestRun(ctx, job, run, stocks)
+}
+
+// startRun records a new ingestion run. It returns nil when the run could not
+// be recorded.
+func (r *Runner) startRun(job, trigger string, parentRunID *int64, total int) *models.IngestionRun {
+	run, err := models.NewIngestionRunRepository().CreateRun(&models.IngestionRun{
+		Job:         job,
+		Trigger:     trigger,
+		ParentRunID: parentRunID,
+		Provider:    r.provider.Name(),
+		TotalCount:  total,
+		StartedAt:   utime.Utime.Now().ToTime(),
+	})
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    job,
+			"action": "create_ingestion_run",
+		}, nil)
+		r.logger.Error().Err(err).Str("job", job).Msg("Failed to record ingestion run; continuing without history")
+		return nil
+	}
+	return run
+}
+
+// ingestRun ingests stocks under a run created by startRun; run may be nil.
+func (r *Runner) ingestRun(ctx context.Context, job string, run *models.IngestionRun, stocks []models.StockInformation) *IngestionSummary {
+	stockRepo := models.NewStockRepository()
+	runRepo := models.NewIngestionRunRepository()
+
+	drifts := newSchemaDriftCollector()
+	onResult := func(result *stockProcessResult) {
+		drifts.add(result.Ticker, result.Drifts)
+		if run == nil {
+			return
+		}
+		item := result.toRunItem(run.ID)
+		if err := runRepo.InsertRunItem(item); err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    job,
+				"action": "record_ingestion_item",
+			}, map[string]interface{}{
+				"ticker": result.Ticker,
+				"run_id": run.ID,
+			})
+			r.logger.Error().Err(err).Str("job", job).Str("ticker", result.Ticker).Int64("runId", run.ID).Msg("Failed to record ingestion item")
+		}
+	}
+
+	summary := r.ingestStocks(ctx, stockRepo, stocks, onResult)
+
+	var runID *int64
+	if run != nil {
+		runID = &run.ID
+	}
+	r.reportSchemaDrifts(job, runID, drifts)
+
+	if run != nil {
+		status := models.IngestionRunStatusCompleted
+		if summary.Canceled {
+			status = models.IngestionRunStatusCanceled
+		}
+		summary.RunID = &run.ID
+		if err := runRepo.FinishRun(run.ID, status, len(summary.Succeeded), len(summary.Failed), len(summary.Skipped), summary.FinishedAt); err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    job,
+				"action": "finish_ingestion_run",
+			}, map[string]interface{}{
+				"run_id": run.ID,
+			})
+			r.logger.Error().Err(err).Str("job", job).Int64("runId", run.ID).Msg("Failed to finish ingestion run")
+		}
+	}
+
+	logEvent := r.logger.Info()
+	if summary.Canceled {
+		logEvent = r.logger.Warn()
+	}
+	if summary.RunID != nil {
+		logEvent = logEvent.Int64("runId", *summary.RunID)
+	}
+	logEvent.
+		Str("job", job).
+		Int("total", summary.Total).
+		Int("succeeded", len(summary.Succeeded)).
+		Int("failed", len(summary.Failed)).
+		Int("skipped", len(summary.Skipped)).
+		Interface("failedTickers", summary.Failed).
+		Interface("skippedTickers", summary.Skipped).
+		Bool("canceled", summary.Canceled).
+		Dur("duration", summary.FinishedAt.Sub(summary.StartedAt)).
+		Msg("Cron job execution completed")
+
+	if !summary.Canceled {
+		r.EvaluateAlerts(ctx)
+	}
+
+	return summary
+}
+
+// RetryFailedStocks re-ingests only the tickers that failed in the latest
+// run that has not been retried yet.
+func (r *Runner) RetryFailedStocks(ctx context.Context) *IngestionSummary {
+	r.logger.Info().Str("job", jobRetryFailedStocks).Msg("Cron job execution started")
+
+	runRepo := models.NewIngestionRunRepository()
+	parentRun, err := runRepo.GetLatestRunPendingRetry(jobUpsertStockInformation)
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobRetryFailedStocks,
+			"action": "get_run_pending_retry",
+		}, nil)
+		r.logger.Error().Err(err).Str("job", jobRetryFailedStocks).Msg("Failed to find ingestion run to retry")
+		return nil
+	}
+	if parentRun == nil {
+		r.logger.Info().Str("job", jobRetryFailedStocks).Msg("No failed tickers to retry")
+		return nil
+	}
+
+	stocks, err := runRepo.GetFailedStocksForRun(parentRun.ID)
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobRetryFailedStocks,
+			"action": "get_failed_stocks",
+		}, map[string]interface{}{
+			"run_id": parentRun.ID,
+		})
+		r.logger.Error().Err(err).Str("job", jobRetryFailedStocks).Int64("runId", parentRun.ID).Msg("Failed to get failed tickers")
+		return nil
+	}
+
+	// Still record an (empty) retry run so the parent is not picked up again
+	r.logger.Info().
+		Str("job", jobRetryFailedStocks).
+		Int64("parentRunId", parentRun.ID).
+		Int("tickers", len(stocks)).
+		Msg("Retrying failed tickers")
+
+	return r.runIngestion(ctx, jobRetryFailedStocks, models.IngestionTriggerRetryFailed, &parentRun.ID, stocks)
+}
+
+// IngestTicker starts ingesting a single ticker on demand, regardless of its
+// enabled flag or when it was last updated. The run is recorded with the manual
+// trigger and runs in the background under the runner's own context, so it
+// outlives the request; the returned run ID is nil when the run could not be
+// recorded.
+func (r *Runner) IngestTicker(ticker string) (*int64, error) {
+	stock, err := models.NewStockRepository().GetStockInformation(ticker)
+	if err != nil {
+		return nil, err
+	}
+	if stock == nil {
+		return nil, ErrStockNotTracked
+	}
+	if stock.ApiKey == "" {
+		return nil, ErrStockMissingApiKey
+	}
+
+	run := r.startRun(jobIngestTicker, models.IngestionTriggerManual, nil, 1)
+	var runID *int64
+	if run != nil {
+		runID = &run.ID
+	}
+
+	r.logger.Info().Str("job", jobIngestTicker).Str("ticker", ticker).Msg("Manual ingestion started")
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+		r.ingestRun(r.ctx, jobIngestTicker, run, []models.StockInformation{*stock})
+	}()
+	return runID, nil
+}
+
+// encryptLegacyApiKeys migrates plaintext API keys once an encryption key is configured.
+func (r *Runner) encryptLegacyApiKeys() {
+	if config.Get().MarketData.APIKeyEncryptionKey == "" {
+		r.logger.Warn().Msg("MARKET_DATA_API_KEY_ENCRYPTION_KEY is not set; stock API keys cannot be encrypted")
+		return
+	}
+
+	migrated, err := models.NewStockRepository().EncryptLegacyApiKeys()
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"action": "encrypt_legacy_api_keys",
+		}, nil)
+		r.logger.Error().Err(err).Int("migrated", migrated).Msg("Failed to encrypt plaintext stock API keys")
+		return
+	}
+	if migrated > 0 {
+		r.logger.Info().Int("migrated", migrated).Msg("Encrypted plaintext stock API keys")
+	}
+}
+
+// toRunItem converts a ticker outcome to its persisted form.
+func (r *stockProcessResult) toRunItem(runID int64) *models.IngestionRunItem {
+	startedAt := r.StartedAt
+	if startedAt.IsZero() {
+		startedAt = utime.Utime.Now().ToTime()
+	}
+
+	item := &models.IngestionRunItem{
+		RunID:      runID,
+		Ticker:     r.Ticker,
+		Status:     r.Status(),
+		StartedAt:  startedAt,
+		DurationMs: r.Duration.Milliseconds(),
+	}
+
+	if len(r.FailedActions) > 0 {
+		errorAction := r.FailedActions[0]
+		failedActions := strings.Join(r.FailedActions, ",")
+		item.ErrorAction = &errorAction
+		item.FailedActions = &failedActions
+	}
+	if r.LastErr != nil {
+		errMessage := r.LastErr.Error()
+		item.ErrorMessage = &errMessage
+	}
+	if r.SkipReason != "" {
+		skipReason := r.SkipReason
+		item.SkipReason = &skipReason
+	}
+
+	return item
+}
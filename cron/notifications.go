@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const (
+	jobDispatchNotifications  = "dispatchNotifications"
+	jobSendPortfolioReminders = "sendPortfolioReminders"
+)
+
+var (
+	// depositMaturityReminderDays are the days before maturity a deposit reminder is sent
+	depositMaturityReminderDays = []int{7, 1}
+	// bondCouponReminderDays are the days before payment a coupon reminder is sent
+	bondCouponReminderDays = []int{1, 0}
+)
+
+// DispatchNotifications delivers due entries of the notification outbox.
+func (r *Runner) DispatchNotifications(ctx context.Context) *notification.DispatchSummary {
+	summary, err := r.notifier.Dispatch(ctx)
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobDispatchNotifications,
+			"action": "dispatch",
+		}, nil)
+		r.logger.Error().Err(err).Str("job", jobDispatchNotifications).Msg("Failed to dispatch notifications")
+	}
+	if summary.Sent+summary.Retrying+summary.Failed > 0 {
+		r.logger.Info().
+			Str("job", jobDispatchNotifications).
+			Int("sent", summary.Sent).
+			Int("retrying", summary.Retrying).
+			Int("failed", summary.Failed).
+			Msg("Notifications dispatched")
+	}
+	return summary
+}
+
+// SendPortfolioReminders notifies users of deposits approaching maturity and upcoming
+// bond coupon payments. Dedupe keys include the date and offset, so re-running the job
+// on the same day sends nothing new.
+func (r *Runner) SendPortfolioReminders(ctx context.Context) {
+	now := utime.Utime.Now().ToTime()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	enqueued := 0
+
+	deposits, err := models.NewPortfolioCashRepository().FindMaturingBetween(today, today.AddDate(0, 0, maxDays(depositMaturityReminderDays)))
+	if err != nil {
+		r.reportReminderError(err, "find_maturing_deposits", nil)
+	}
+	for _, deposit := range deposits {
+		if ctx.Err() != nil {
+			return
+		}
+		daysLeft, ok := reminderOffset(today, deposit.MaturityDate, depositMaturityReminderDays)
+		if !ok {
+			continue
+		}
+		maturityDate := deposit.MaturityDate.Format(models.SQLDateFormat)
+		count, err := r.notifier.Notify(ctx, notification.Notification{
+			UserID: deposit.UserID,
+			Event:  notification.EventDepositMaturity,
+			Data: map[string]interface{}{
+				"Bank":         deposit.Bank,
+				"Account":      deposit.Account,
+				"Amount":       deposit.Amount,
+				"MaturityDate": maturityDate,
+				"DaysLeft":     daysLeft,
+			},
+			DedupeKey: fmt.Sprintf("deposit_maturity:%d:%s:%d", deposit.ID, maturityDate, daysLeft),
+			URL:       "/portfolio/cash",
+		})
+		if err != nil {
+			r.reportReminderError(err, "notify_deposit_maturity", map[string]interface{}{"portfolio_cash_id": deposit.ID})
+			continue
+		}
+		enqueued += count
+	}
+
+	bonds, err := models.NewPortfolioBondRepository().FindCouponsDueBetween(today, today.AddDate(0, 0, maxDays(bondCouponReminderDays)))
+	if err != nil {
+		r.reportReminderError(err, "find_coupons_due", nil)
+	}
+	for _, bond := range bonds {
+		if ctx.Err() != nil {
+			return
+		}
+		daysLeft, ok := reminderOffset(today, bond.NextCouponDate, bondCouponReminderDays)
+		if !ok {
+			continue
+		}
+		paymentDate := bond.NextCouponDate.Format(models.SQLDateFormat)
+		count, err := r.notifier.Notify(ctx, notification.Notification{
+			UserID: bond.UserID,
+			Event:  notification.EventBondCoupon,
+			Data: map[string]interface{}{
+				"BondName":    bond.Name,
+				"CouponRate":  bond.CouponRate,
+				"PaymentDate": paymentDate,
+				"DaysLeft":    daysLeft,
+			},
+			DedupeKey: fmt.Sprintf("bond_coupon:%d:%s:%d", bond.ID, paymentDate, daysLeft),
+			URL:       "/portfolio/bond",
+		})
+		if err != nil {
+			r.reportReminderError(err, "notify_bond_coupon", map[string]interface{}{"portfolio_bond_id": bond.ID})
+			continue
+		}
+		enqueued += count
+	}
+
+	r.logger.Info().
+		Str("job", jobSendPortfolioReminders).
+		Int("deposits", len(deposits)).
+		Int("bonds", len(bonds)).
+		Int("enqueued", enqueued).
+		Msg("Portfolio reminders completed")
+}
+
+func (r *Runner) reportReminderError(err error, action string, extra map[string]interface{}) {
+	r.captureException(err, map[string]string{
+		"module": "cron",
+		"job":    jobSendPortfolioReminders,
+		"action": action,
+	}, extra)
+	r.logger.Error().Err(err).Str("job", jobSendPortfolioReminders).Str("action", action).Msg("Portfolio reminder failed")
+}
+
+// reminderOffset returns the days between today and date when it is one of offsets.
+func reminderOffset(today time.Time, date *time.Time, offsets []int) (int, bool) {
+	if date == nil {
+		return 0, false
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	daysLeft := int(day.Sub(today).Hours() / 24)
+	for _, offset := range offsets {
+		if daysLeft == offset {
+			return daysLeft, true
+		}
+	}
+	return 0, false
+}
+
+func maxDays(offsets []int) int {
+	max := 0
+	for _, offset := range offsets {
+		if offset > max {
+			max = offset
+		}
+	}
+	return max
+}
@@ -0,0 +1,282 @@
+package cron
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const (
+	PayloadArchiveFilesystem = "filesystem"
+	PayloadArchiveDB         = "db"
+	PayloadArchiveNone       = "none"
+)
+
+const (
+	payloadEndpointEarnings = "earnings"
+	payloadEndpointEquities = "equities"
+)
+
+// ErrPayloadNotArchived is returned when no payload exists for a key.
+var ErrPayloadNotArchived = errors.New("payload not archived")
+
+// PayloadKey identifies an archived payload by ticker, endpoint and day.
+type PayloadKey struct {
+	Ticker   string
+	Endpoint string
+	Date     time.Time
+}
+
+// PayloadArchive stores raw datasource bodies so parsing can be replayed later.
+// Bodies are passed uncompressed; implementations compress them at rest.
+type PayloadArchive interface {
+	Name() string
+	Store(ctx context.Context, key PayloadKey, body []byte) error
+	Load(ctx context.Context, key PayloadKey) ([]byte, error)
+	// List returns keys between from and to (inclusive), ordered by ticker then date.
+	List(ctx context.Context, from, to time.Time, tickers []string) ([]PayloadKey, error)
+}
+
+// NewPayloadArchive builds the archive selected in configuration, or nil when disabled.
+func NewPayloadArchive(cfg config.PayloadArchiveConfig) (PayloadArchive, error) {
+	switch cfg.Backend {
+	case PayloadArchiveNone:
+		return nil, nil
+	case "", PayloadArchiveFilesystem:
+		return NewFilesystemPayloadArchive(cfg.Dir), nil
+	case PayloadArchiveDB:
+		return NewDBPayloadArchive(models.NewRawPayloadRepository()), nil
+	default:
+		return nil, fmt.Errorf("unknown payload archive backend %q", cfg.Backend)
+	}
+}
+
+func gzipBytes(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, fmt.Errorf("error compressing payload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("error compressing payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing payload: %w", err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing payload: %w", err)
+	}
+	return body, nil
+}
+
+func payloadDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func tickerFilter(tickers []string) map[string]bool {
+	if len(tickers) == 0 {
+		return nil
+	}
+	filter := make(map[string]bool, len(tickers))
+	for _, ticker := range tickers {
+		filter[strings.ToUpper(ticker)] = true
+	}
+	return filter
+}
+
+// FilesystemPayloadArchive keeps payloads at <dir>/<YYYY-MM-DD>/<TICKER>/<endpoint>.json.gz.
+type FilesystemPayloadArchive struct {
+	dir string
+}
+
+// NewFilesystemPayloadArchive creates an archive rooted at dir.
+func NewFilesystemPayloadArchive(dir string) *FilesystemPayloadArchive {
+	return &FilesystemPayloadArchive{dir: dir}
+}
+
+func (a *FilesystemPayloadArchive) Name() string {
+	return PayloadArchiveFilesystem
+}
+
+func (a *FilesystemPayloadArchive) path(key PayloadKey) string {
+	return filepath.Join(a.dir, key.Date.Format(models.SQLDateFormat), strings.ToUpper(key.Ticker), key.Endpoint+".json.gz")
+}
+
+func (a *FilesystemPayloadArchive) Store(ctx context.Context, key PayloadKey, body []byte) error {
+	compressed, err := gzipBytes(body)
+	if err != nil {
+		return err
+	}
+
+	path := a.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated payload
+	tmp, err := os.CreateTemp(filepath.Dir(path), key.Endpoint+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating archive file: %w", err)
+	}
+	if _, err := tmp.Write(compressed); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing archive file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing archive file: %w", err)
+	}
+	return nil
+}
+
+func (a *FilesystemPayloadArchive) Load(ctx context.Context, key PayloadKey) ([]byte, error) {
+	compressed, err := os.ReadFile(a.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrPayloadNotArchived
+		}
+		return nil, fmt.Errorf("error reading archive file: %w", err)
+	}
+	return gunzipBytes(compressed)
+}
+
+func (a *FilesystemPayloadArchive) List(ctx context.Context, from, to time.Time, tickers []string) ([]PayloadKey, error) {
+	dateDirs, err := os.ReadDir(a.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []PayloadKey{}, nil
+		}
+		return nil, fmt.Errorf("error reading archive directory: %w", err)
+	}
+
+	from, to = payloadDay(from), payloadDay(to)
+	filter := tickerFilter(tickers)
+	keys := []PayloadKey{}
+
+	for _, dateDir := range dateDirs {
+		if !dateDir.IsDir() {
+			continue
+		}
+		date, err := time.Parse(models.SQLDateFormat, dateDir.Name())
+		if err != nil || date.Before(from) || date.After(to) {
+			continue
+		}
+
+		tickerDirs, err := os.ReadDir(filepath.Join(a.dir, dateDir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive directory: %w", err)
+		}
+		for _, tickerDir := range tickerDirs {
+			if !tickerDir.IsDir() || (filter != nil && !filter[tickerDir.Name()]) {
+				continue
+			}
+			files, err := os.ReadDir(filepath.Join(a.dir, dateDir.Name(), tickerDir.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("error reading archive directory: %w", err)
+			}
+			for _, file := range files {
+				endpoint, ok := strings.CutSuffix(file.Name(), ".json.gz")
+				if file.IsDir() || !ok {
+					continue
+				}
+				keys = append(keys, PayloadKey{Ticker: tickerDir.Name(), Endpoint: endpoint, Date: date})
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Ticker != keys[j].Ticker {
+			return keys[i].Ticker < keys[j].Ticker
+		}
+		if !keys[i].Date.Equal(keys[j].Date) {
+			return keys[i].Date.Before(keys[j].Date)
+		}
+		return keys[i].Endpoint < keys[j].Endpoint
+	})
+	return keys, nil
+}
+
+// DBPayloadArchive keeps payloads in the stock_raw_payloads table.
+type DBPayloadArchive struct {
+	repo models.RawPayloadRepository
+}
+
+// NewDBPayloadArchive creates an archive backed by repo.
+func NewDBPayloadArchive(repo models.RawPayloadRepository) *DBPayloadArchive {
+	return &DBPayloadArchive{repo: repo}
+}
+
+func (a *DBPayloadArchive) Name() string {
+	return PayloadArchiveDB
+}
+
+func (a *DBPayloadArchive) Store(ctx context.Context, key PayloadKey, body []byte) error {
+	compressed, err := gzipBytes(body)
+	if err != nil {
+		return err
+	}
+
+	return a.repo.UpsertRawPayload(&models.RawPayloadRecord{
+		Ticker:          strings.ToUpper(key.Ticker),
+		Endpoint:        key.Endpoint,
+		PayloadDate:     key.Date,
+		ContentEncoding: "gzip",
+		Body:            compressed,
+		BodySize:        len(body),
+		FetchedAt:       utime.Utime.Now().ToTime(),
+	})
+}
+
+func (a *DBPayloadArchive) Load(ctx context.Context, key PayloadKey) ([]byte, error) {
+	record, err := a.repo.GetRawPayload(strings.ToUpper(key.Ticker), key.Endpoint, key.Date)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrPayloadNotArchived
+	}
+	return gunzipBytes(record.Body)
+}
+
+func (a *DBPayloadArchive) List(ctx context.Context, from, to time.Time, tickers []string) ([]PayloadKey, error) {
+	upper := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		upper = append(upper, strings.ToUpper(ticker))
+	}
+
+	rows, err := a.repo.ListRawPayloadKeys(from, to, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]PayloadKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, PayloadKey{Ticker: row.Ticker, Endpoint: row.Endpoint, Date: payloadDay(row.PayloadDate)})
+	}
+	return keys, nil
+}
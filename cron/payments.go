@@ -0,0 +1,28 @@
+package cron
+
+import (
+	"context"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const jobExpirePendingPayments = "expirePendingPayments"
+
+// ExpirePendingPayments moves gateway checkouts that were not paid within
+// PAYMENT_CHECKOUT_TTL to expired. A late paid webhook still completes them.
+func (r *Runner) ExpirePendingPayments(ctx context.Context) {
+	expired, err := models.NewPaymentRepository().ExpirePendingPayments(utime.Utime.Now().ToTime())
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobExpirePendingPayments,
+			"action": "expire",
+		}, nil)
+		r.logger.Error().Err(err).Str("job", jobExpirePendingPayments).Msg("Failed to expire pending payments")
+		return
+	}
+	if expired > 0 {
+		r.logger.Info().Str("job", jobExpirePendingPayments).Int64("expired", expired).Msg("Pending payments expired")
+	}
+}
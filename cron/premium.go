@@ -0,0 +1,90 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const jobSendPremiumExpiryReminders = "sendPremiumExpiryReminders"
+
+// SendPremiumExpiryReminders notifies premium users whose subscription ends within the
+// configured reminder offsets (PREMIUM_REMINDER_DAYS). Sent reminders are recorded per
+// subscription period so each offset is sent once; renewing starts a new period.
+func (r *Runner) SendPremiumExpiryReminders(ctx context.Context) {
+	offsets := config.Get().Premium.ReminderDays
+	if len(offsets) == 0 {
+		return
+	}
+
+	now := utime.Utime.Now().ToTime()
+	reminderRepo := models.NewPremiumReminderRepository()
+	candidates, err := reminderRepo.ListPremiumExpiryCandidates(now, now.AddDate(0, 0, maxDays(offsets)+1))
+	if err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    jobSendPremiumExpiryReminders,
+			"action": "list_candidates",
+		}, nil)
+		r.logger.Error().Err(err).Str("job", jobSendPremiumExpiryReminders).Msg("Failed to load premium expiry candidates")
+		return
+	}
+
+	sent := 0
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+
+		daysLeft := models.DaysUntil(now, candidate.PremiumExpiresAt)
+		offset, due := models.DuePremiumReminder(daysLeft, offsets, candidate.LastReminderDays)
+		if !due {
+			continue
+		}
+
+		// Notify is idempotent on the dedupe key, so a failed record below is safe to retry
+		_, err := r.notifier.Notify(ctx, notification.Notification{
+			UserID: candidate.UserID,
+			Event:  notification.EventPremiumExpiry,
+			Data: map[string]interface{}{
+				"Level":     string(candidate.UserLevel),
+				"ExpiresAt": candidate.PremiumExpiresAt.In(now.Location()).Format(models.SQLDateFormat),
+				"DaysLeft":  daysLeft,
+			},
+			DedupeKey: fmt.Sprintf("premium_expiry:%d:%d", candidate.PremiumExpiresAt.Unix(), offset),
+			URL:       "/pricing",
+		})
+		if err == nil {
+			_, err = reminderRepo.RecordPremiumExpiryReminder(&models.PremiumExpiryReminder{
+				UserID:     candidate.UserID,
+				ExpiresAt:  candidate.PremiumExpiresAt,
+				DaysBefore: offset,
+				DaysLeft:   daysLeft,
+				SentAt:     now,
+			})
+		}
+		if err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    jobSendPremiumExpiryReminders,
+				"action": "send_reminder",
+			}, map[string]interface{}{
+				"user_id": candidate.UserID,
+				"offset":  offset,
+			})
+			r.logger.Error().Err(err).Str("job", jobSendPremiumExpiryReminders).Int("userId", candidate.UserID).Msg("Failed to send premium expiry reminder")
+			continue
+		}
+		sent++
+	}
+
+	r.logger.Info().
+		Str("job", jobSendPremiumExpiryReminders).
+		Int("candidates", len(candidates)).
+		Int("sent", sent).
+		Msg("Premium expiry reminders completed")
+}
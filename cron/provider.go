@@ -0,0 +1,59 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+)
+
+const (
+	MarketDataProviderDatasectors = "datasectors"
+	MarketDataProviderFixture     = "fixture"
+)
+
+// ErrMarketDataUnsupported is returned when a provider does not offer a dataset.
+var ErrMarketDataUnsupported = errors.New("market data not supported by provider")
+
+// MarketDataProvider supplies raw datasource payloads for a single ticker.
+// Responses keep the datasectors JSON shape so EarningsResponse and
+// EquitiesResponse can decode them regardless of the provider.
+type MarketDataProvider interface {
+	Name() string
+	FetchEarnings(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error)
+	FetchEquities(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error)
+	FetchPrices(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error)
+}
+
+// availabilityWaiter is implemented by providers that can report the upstream
+// as temporarily unavailable (e.g. an open circuit breaker).
+type availabilityWaiter interface {
+	WaitAvailable(ctx context.Context) error
+}
+
+// ProviderEvents receives retry and circuit breaker notifications from live providers.
+type ProviderEvents struct {
+	OnRetry       func(endpoint string, stock models.StockInformation, attempt int, delay time.Duration, err error)
+	OnCircuitOpen func(host string, endpoint string, stock models.StockInformation, err error)
+}
+
+// NewMarketDataProvider builds the provider selected in configuration.
+func NewMarketDataProvider(cfg config.MarketDataConfig, httpClient *http.Client, events ProviderEvents) (MarketDataProvider, error) {
+	switch cfg.Provider {
+	case "", MarketDataProviderDatasectors:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = STOCK_DATASOURCE
+		}
+		return NewDatasectorsProvider(baseURL, httpClient, cfg, events)
+	case MarketDataProviderFixture:
+		return NewFixtureProvider(cfg.FixtureDir), nil
+	default:
+		return nil, fmt.Errorf("unknown market data provider %q", cfg.Provider)
+	}
+}
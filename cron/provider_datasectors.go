@@ -0,0 +1,119 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+)
+
+// halfOpenPollInterval is how often WaitAvailable re-checks while a probe request is in flight.
+const halfOpenPollInterval = time.Second
+
+// DatasectorsProvider fetches market data from the api.datasectors.com stocks API.
+type DatasectorsProvider struct {
+	baseURL    string
+	host       string
+	httpClient *http.Client
+	retry      helper.RetryPolicy
+	breakers   *helper.CircuitBreakerRegistry
+	events     ProviderEvents
+}
+
+// NewDatasectorsProvider creates a provider for the datasectors API rooted at baseURL.
+func NewDatasectorsProvider(baseURL string, httpClient *http.Client, cfg config.MarketDataConfig, events ProviderEvents) (*DatasectorsProvider, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid market data base URL %q", baseURL)
+	}
+
+	return &DatasectorsProvider{
+		baseURL:    baseURL,
+		host:       parsed.Host,
+		httpClient: httpClient,
+		retry: helper.RetryPolicy{
+			MaxAttempts:    cfg.RetryMaxAttempts,
+			BaseDelay:      cfg.RetryBaseDelay,
+			MaxDelay:       cfg.RetryMaxDelay,
+			AttemptTimeout: cfg.RequestTimeout,
+		},
+		breakers: helper.NewCircuitBreakerRegistry(cfg.BreakerFailureThreshold, cfg.BreakerOpenTimeout),
+		events:   events,
+	}, nil
+}
+
+func (p *DatasectorsProvider) Name() string {
+	return MarketDataProviderDatasectors
+}
+
+func (p *DatasectorsProvider) FetchEarnings(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	return p.get(ctx, "earnings", stock)
+}
+
+func (p *DatasectorsProvider) FetchEquities(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	return p.get(ctx, "equities", stock)
+}
+
+// FetchPrices is not wired up yet; the datasectors price endpoint is not integrated.
+func (p *DatasectorsProvider) FetchPrices(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	return nil, ErrMarketDataUnsupported
+}
+
+// WaitAvailable blocks while the circuit for the datasectors host is open.
+func (p *DatasectorsProvider) WaitAvailable(ctx context.Context) error {
+	breaker := p.breakers.Get(p.host)
+	for {
+		var wait time.Duration
+		switch breaker.State() {
+		case helper.CircuitClosed:
+			return nil
+		case helper.CircuitOpen:
+			wait = time.Until(breaker.OpenUntil())
+			if wait <= 0 {
+				return nil
+			}
+		default:
+			wait = halfOpenPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *DatasectorsProvider) get(ctx context.Context, endpoint string, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	policy := p.retry
+	policy.Breaker = p.breakers.Get(p.host)
+	policy.OnRetry = func(attempt int, delay time.Duration, err error) {
+		if p.events.OnRetry != nil {
+			p.events.OnRetry(endpoint, stock, attempt, delay, err)
+		}
+	}
+	policy.OnCircuitOpen = func(err error) {
+		if p.events.OnCircuitOpen != nil {
+			p.events.OnCircuitOpen(p.host, endpoint, stock, err)
+		}
+	}
+
+	return helper.DoExternalJSONRequestWithRetry(
+		ctx,
+		p.httpClient,
+		http.MethodGet,
+		p.baseURL+endpoint,
+		helper.ExternalJSONRequestOptions{
+			Headers: map[string]string{"X-API-Key": stock.ApiKey},
+			Query:   map[string]string{"symbol": stock.Ticker, "market": "id-id"},
+		},
+		policy,
+	)
+}
@@ -0,0 +1,77 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+)
+
+// FixtureProvider serves market data from JSON files on disk so the ingestion
+// pipeline can run offline. For each dataset it looks for
+// <dir>/<TICKER>/<file> first and falls back to the shared <dir>/<file>
+// (the bundled datasource/earning.json and equities.json fixtures).
+type FixtureProvider struct {
+	dir string
+}
+
+// NewFixtureProvider creates a provider reading fixtures from dir.
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{dir: dir}
+}
+
+func (p *FixtureProvider) Name() string {
+	return MarketDataProviderFixture
+}
+
+func (p *FixtureProvider) FetchEarnings(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	return p.read(ctx, "earning.json", stock)
+}
+
+func (p *FixtureProvider) FetchEquities(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	return p.read(ctx, "equities.json", stock)
+}
+
+func (p *FixtureProvider) FetchPrices(ctx context.Context, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	response, err := p.read(ctx, "prices.json", stock)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMarketDataUnsupported
+	}
+	return response, err
+}
+
+func (p *FixtureProvider) read(ctx context.Context, fileName string, stock models.StockInformation) (*helper.ExternalResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	candidates := []string{
+		filepath.Join(p.dir, strings.ToUpper(stock.Ticker), fileName),
+		filepath.Join(p.dir, fileName),
+	}
+
+	for _, path := range candidates {
+		body, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixture %s: %w", path, err)
+		}
+
+		return &helper.ExternalResponse{
+			StatusCode: http.StatusOK,
+			Body:       body,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Success:    helper.ParseSuccessEnvelope(body),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("fixture %s not found for ticker %s: %w", fileName, stock.Ticker, os.ErrNotExist)
+}
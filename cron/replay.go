@@ -0,0 +1,136 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+const jobReplayPayloads = "replayPayloads"
+
+// ErrPayloadArchiveDisabled is returned by ReplayArchivedPayloads when no archive is configured.
+var ErrPayloadArchiveDisabled = errors.New("payload archive is disabled")
+
+// ReplayOptions selects which archived payloads to replay.
+type ReplayOptions struct {
+	From    time.Time
+	To      time.Time
+	Tickers []string
+}
+
+// ReplaySummary reports how a replay went. Entries are keyed by "TICKER@YYYY-MM-DD".
+type ReplaySummary struct {
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Replayed   []string          `json:"replayed"`
+	Failed     map[string]string `json:"failed"`
+	Incomplete []string          `json:"incomplete"`
+	Canceled   bool              `json:"canceled"`
+}
+
+type replayDay struct {
+	ticker    string
+	date      time.Time
+	endpoints map[string]bool
+}
+
+// ReplayArchivedPayloads re-runs parsing and upserts over archived payloads
+// without calling the provider. Overview history is recorded under each
+// payload's day, current overview metrics are only replaced by a payload at
+// least as recent as the data they hold, and stock.last_update is left alone.
+// Days missing either the earnings or the equities payload are reported as
+// incomplete and skipped, since a partial overview would overwrite good data.
+func (r *Runner) ReplayArchivedPayloads(ctx context.Context, opts ReplayOptions) (*ReplaySummary, error) {
+	if r.archive == nil {
+		return nil, ErrPayloadArchiveDisabled
+	}
+
+	keys, err := r.archive.List(ctx, opts.From, opts.To, opts.Tickers)
+	if err != nil {
+		return nil, fmt.Errorf("error listing archived payloads: %w", err)
+	}
+
+	// Keys are ordered by ticker then date, so consecutive keys form a day
+	days := []*replayDay{}
+	for _, key := range keys {
+		last := len(days) - 1
+		if last < 0 || days[last].ticker != key.Ticker || !days[last].date.Equal(key.Date) {
+			days = append(days, &replayDay{ticker: key.Ticker, date: key.Date, endpoints: map[string]bool{}})
+			last++
+		}
+		days[last].endpoints[key.Endpoint] = true
+	}
+
+	summary := &ReplaySummary{
+		StartedAt:  utime.Utime.Now().ToTime(),
+		Replayed:   []string{},
+		Failed:     map[string]string{},
+		Incomplete: []string{},
+	}
+	stockRepo := models.NewStockRepository()
+
+	r.logger.Info().
+		Str("job", jobReplayPayloads).
+		Str("archive", r.archive.Name()).
+		Int("days", len(days)).
+		Msg("Replaying archived payloads")
+
+	for _, day := range days {
+		if ctx.Err() != nil {
+			summary.Canceled = true
+			break
+		}
+
+		label := day.ticker + "@" + day.date.Format(models.SQLDateFormat)
+		if !day.endpoints[payloadEndpointEarnings] || !day.endpoints[payloadEndpointEquities] {
+			summary.Incomplete = append(summary.Incomplete, label)
+			continue
+		}
+
+		earningsResp, earningsErr := r.loadArchivedResponse(ctx, day, payloadEndpointEarnings)
+		equitiesResp, equitiesErr := r.loadArchivedResponse(ctx, day, payloadEndpointEquities)
+		if earningsErr != nil || equitiesErr != nil {
+			summary.Failed[label] = errors.Join(earningsErr, equitiesErr).Error()
+			continue
+		}
+
+		result := &stockProcessResult{Ticker: day.ticker, StartedAt: utime.Utime.Now().ToTime()}
+		r.applyStockPayloads(stockRepo, models.StockInformation{Ticker: day.ticker}, result, earningsResp, equitiesResp, nil, nil, models.StockUpsertOptions{AsOf: day.date, Replay: true})
+		if result.Status() == IngestStatusFailed {
+			summary.Failed[label] = result.LastErr.Error()
+			continue
+		}
+		summary.Replayed = append(summary.Replayed, label)
+	}
+
+	summary.FinishedAt = utime.Utime.Now().ToTime()
+	r.logger.Info().
+		Str("job", jobReplayPayloads).
+		Int("replayed", len(summary.Replayed)).
+		Int("failed", len(summary.Failed)).
+		Int("incomplete", len(summary.Incomplete)).
+		Interface("failedDays", summary.Failed).
+		Bool("canceled", summary.Canceled).
+		Dur("duration", summary.FinishedAt.Sub(summary.StartedAt)).
+		Msg("Replay completed")
+
+	return summary, nil
+}
+
+func (r *Runner) loadArchivedResponse(ctx context.Context, day *replayDay, endpoint string) (*helper.ExternalResponse, error) {
+	body, err := r.archive.Load(ctx, PayloadKey{Ticker: day.ticker, Endpoint: endpoint, Date: day.date})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", endpoint, err)
+	}
+	return &helper.ExternalResponse{
+		StatusCode: http.StatusOK,
+		Body:       body,
+		Success:    helper.ParseSuccessEnvelope(body),
+	}, nil
+}
@@ -2,11 +2,15 @@ package cron
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
 	"github.com/WahyuSiddarta/be_saham_go/models"
-	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
 	"github.com/getsentry/sentry-go"
 	robfigcron "github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
@@ -14,22 +18,117 @@ import (
 
 const STOCK_DATASOURCE = "https://api.datasectors.com/api/stocks/v2/"
 
+const (
+	upsertStockInformationSchedule = "0 14 * * *"
+	// retryFailedStocksSchedule runs after the daily ingestion to re-process only failed tickers
+	retryFailedStocksSchedule = "0 16 * * *"
+	// dispatchNotificationsSchedule delivers the notification outbox every minute
+	dispatchNotificationsSchedule      = "* * * * *"
+	sendPortfolioRemindersSchedule     = "0 7 * * *"
+	sendPremiumExpiryRemindersSchedule = "0 9 * * *"
+	expirePendingPaymentsSchedule      = "*/5 * * * *"
+)
+
 type Runner struct {
 	logger     *zerolog.Logger
 	httpClient *http.Client
+	provider   MarketDataProvider
+	archive    PayloadArchive
+	notifier   *notification.Service
+
+	// ctx outlives requests and is canceled when Start returns; background
+	// tracks the on-demand ingestions running under it
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+
+	// alertedDriftSignatures backs up the schema_drift_signatures table when it is unavailable
+	alertedDriftSignatures sync.Map
 }
 
-func NewRunner(logger *zerolog.Logger) *Runner {
-	return &Runner{
-		logger: logger,
-		httpClient: &http.Client{
-			Transport: &http.Transport{
-				MaxIdleConns:        20,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
+func NewRunner(logger *zerolog.Logger) (*Runner, error) {
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:        20,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
 		},
 	}
+
+	if key := config.Get().MarketData.APIKeyEncryptionKey; key != "" {
+		if _, err := helper.ParseEncryptionKey(key); err != nil {
+			return nil, fmt.Errorf("invalid MARKET_DATA_API_KEY_ENCRYPTION_KEY: %w", err)
+		}
+	}
+
+	r := &Runner{
+		logger:     logger,
+		httpClient: httpClient,
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+
+	provider, err := NewMarketDataProvider(config.Get().MarketData, httpClient, ProviderEvents{
+		OnRetry:       r.onProviderRetry,
+		OnCircuitOpen: r.onProviderCircuitOpen,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.provider = provider
+	logger.Info().Str("provider", provider.Name()).Msg("Market data provider configured")
+
+	archive, err := NewPayloadArchive(config.Get().PayloadArchive)
+	if err != nil {
+		return nil, err
+	}
+	r.archive = archive
+	if archive != nil {
+		logger.Info().Str("backend", archive.Name()).Msg("Raw payload archive configured")
+	}
+
+	notifier, err := notification.NewService(config.Get().Notification, &http.Client{Timeout: 30 * time.Second}, logger)
+	if err != nil {
+		return nil, err
+	}
+	r.notifier = notifier
+	logger.Info().Str("delivery", config.Get().Notification.Delivery).Msg("Notification service configured")
+
+	return r, nil
+}
+
+// onProviderRetry logs a transient datasource failure that is about to be retried.
+func (r *Runner) onProviderRetry(endpoint string, stock models.StockInformation, attempt int, delay time.Duration, err error) {
+	r.logger.Warn().
+		Err(err).
+		Str("job", "upsertStockInformation").
+		Str("provider", r.provider.Name()).
+		Str("endpoint", endpoint).
+		Str("ticker", stock.Ticker).
+		Int("attempt", attempt).
+		Dur("retryIn", delay).
+		Msg("Datasource request failed; retrying")
+}
+
+// onProviderCircuitOpen reports that the datasource host has been paused.
+// It fires once per open transition, not once per ticker.
+func (r *Runner) onProviderCircuitOpen(host string, endpoint string, stock models.StockInformation, err error) {
+	r.captureException(err, map[string]string{
+		"module": "cron",
+		"job":    "upsertStockInformation",
+		"action": "circuit_open",
+		"host":   host,
+	}, map[string]interface{}{
+		"endpoint": endpoint,
+		"ticker":   stock.Ticker,
+	})
+	r.logger.Error().
+		Err(err).
+		Str("job", "upsertStockInformation").
+		Str("host", host).
+		Str("endpoint", endpoint).
+		Str("ticker", stock.Ticker).
+		Dur("pause", config.Get().MarketData.BreakerOpenTimeout).
+		Msg("Datasource circuit opened; pausing ingestion")
 }
 
 func (r *Runner) Start(ctx context.Context) {
@@ -37,76 +136,65 @@ func (r *Runner) Start(ctx context.Context) {
 
 	scheduler := robfigcron.New()
 
+	r.encryptLegacyApiKeys()
 	r.UpsertStockInformation(ctx)
-	if _, err := scheduler.AddFunc("0 14 * * *", func() {
-		r.UpsertStockInformation(ctx)
-	}); err != nil {
-		r.captureException(err, map[string]string{
-			"module": "cron",
-			"job":    "upsertStockInformation",
-			"action": "register_schedule",
-		}, nil)
-		r.logger.Fatal().Err(err).Msg("Failed to register cron schedule")
-		return
+
+	jobs := []struct {
+		name     string
+		schedule string
+		run      func()
+	}{
+		{jobUpsertStockInformation, upsertStockInformationSchedule, func() { r.UpsertStockInformation(ctx) }},
+		{jobRetryFailedStocks, retryFailedStocksSchedule, func() { r.RetryFailedStocks(ctx) }},
+		{jobDispatchNotifications, dispatchNotificationsSchedule, func() { r.DispatchNotifications(ctx) }},
+		{jobSendPortfolioReminders, sendPortfolioRemindersSchedule, func() { r.SendPortfolioReminders(ctx) }},
+		{jobSendPremiumExpiryReminders, sendPremiumExpiryRemindersSchedule, func() { r.SendPremiumExpiryReminders(ctx) }},
+		{jobExpirePendingPayments, expirePendingPaymentsSchedule, func() { r.ExpirePendingPayments(ctx) }},
+	}
+	for _, job := range jobs {
+		if _, err := scheduler.AddFunc(job.schedule, job.run); err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    job.name,
+				"action": "register_schedule",
+			}, nil)
+			r.logger.Fatal().Err(err).Str("job", job.name).Msg("Failed to register cron schedule")
+			return
+		}
+		r.logger.Info().Str("job", job.name).Str("schedule", job.schedule).Msg("Cron job registered")
 	}
-	r.logger.Info().Str("job", "upsertStockInformation").Str("schedule", "0 14 * * *").Msg("Cron job registered")
 
 	scheduler.Start()
 
 	<-ctx.Done()
 	stopCtx := scheduler.Stop()
 	<-stopCtx.Done()
+	r.cancel()
+	r.background.Wait()
 	r.logger.Info().Msg("Cron runner stopped")
 }
 
-func (r *Runner) UpsertStockInformation(ctx context.Context) {
-	startTime := utime.Utime.Now().ToTime()
-	r.logger.Info().Str("job", "upsertStockInformation").Msg("Cron job execution started")
-
-	const processStockInterval = 20 * time.Second
+func (r *Runner) UpsertStockInformation(ctx context.Context) *IngestionSummary {
+	r.logger.Info().Str("job", jobUpsertStockInformation).Msg("Cron job execution started")
 
 	stockRepo := models.NewStockRepository()
 	targetStocks, err := stockRepo.GetStockApiKey()
 	if err != nil {
 		r.captureException(err, map[string]string{
 			"module": "cron",
-			"job":    "upsertStockInformation",
+			"job":    jobUpsertStockInformation,
 			"action": "get_stock_api_key",
 		}, nil)
-		r.logger.Error().Err(err).Str("job", "upsertStockInformation").Msg("Failed to get stock API key")
-		return
+		r.logger.Error().Err(err).Str("job", jobUpsertStockInformation).Msg("Failed to get stock API key")
+		return nil
 	}
 
 	if len(targetStocks) == 0 {
-		r.logger.Warn().Str("job", "upsertStockInformation").Msg("No target stocks configured")
-		return
-	}
-
-	for index, stock := range targetStocks {
-		select {
-		case <-ctx.Done():
-			r.logger.Info().Str("job", "upsertStockInformation").Msg("Cron job canceled")
-			return
-		default:
-		}
-
-		processStartTime := utime.Utime.Now().ToTime()
-		r.processStock(ctx, stockRepo, stock)
-
-		if index < len(targetStocks)-1 {
-			remainingWait := processStockInterval - utime.Utime.Now().ToTime().Sub(processStartTime)
-			if remainingWait > 0 {
-				select {
-				case <-ctx.Done():
-					r.logger.Info().Str("job", "upsertStockInformation").Msg("Cron job canceled while waiting for rate limit")
-					return
-				case <-time.After(remainingWait):
-				}
-			}
-		}
+		r.logger.Warn().Str("job", jobUpsertStockInformation).Msg("No target stocks configured")
+		return nil
 	}
 
-	r.logger.Info().Str("job", "upsertStockInformation").Dur("duration", utime.Utime.Now().ToTime().Sub(startTime)).Msg("Cron job execution completed")
+	return r.runIngestion(ctx, jobUpsertStockInformation, models.IngestionTriggerSchedule, nil, targetStocks)
 }
 
 func (r *Runner) captureException(err error, tags map[string]string, extra map[string]interface{}) {
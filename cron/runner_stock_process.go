@@ -2,70 +2,89 @@ package cron
 
 import (
 	"context"
-	"net/http"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/WahyuSiddarta/be_saham_go/helper"
 	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
 	"github.com/bytedance/sonic"
 )
 
+// fetchStockTimeout bounds all attempts of a single fetch, including retry backoff.
+const fetchStockTimeout = 3 * time.Minute
+
 // fetchStockData fetches earnings and equities data concurrently for a single stock.
 func (r *Runner) fetchStockData(ctx context.Context, stock models.StockInformation) (earningsResp *helper.ExternalResponse, equitiesResp *helper.ExternalResponse, earningsErr error, equitiesErr error) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		ctxEarning, cancel := context.WithTimeout(ctx, 30*time.Second)
+		ctxEarning, cancel := context.WithTimeout(ctx, fetchStockTimeout)
 		defer cancel()
-
-		earningsResp, earningsErr = helper.DoExternalJSONRequest(
-			ctxEarning,
-			r.httpClient,
-			http.MethodGet,
-			STOCK_DATASOURCE+"earnings",
-			helper.ExternalJSONRequestOptions{
-				Headers: map[string]string{"X-API-Key": stock.ApiKey},
-				Query:   map[string]string{"symbol": stock.Ticker, "market": "id-id"},
-			},
-		)
+		earningsResp, earningsErr = r.provider.FetchEarnings(ctxEarning, stock)
 	}()
 
 	go func() {
 		defer wg.Done()
-		ctxEquities, cancel := context.WithTimeout(ctx, 30*time.Second)
+		ctxEquities, cancel := context.WithTimeout(ctx, fetchStockTimeout)
 		defer cancel()
-		equitiesResp, equitiesErr = helper.DoExternalJSONRequest(
-			ctxEquities,
-			r.httpClient,
-			http.MethodGet,
-			STOCK_DATASOURCE+"equities",
-			helper.ExternalJSONRequestOptions{
-				Headers: map[string]string{"X-API-Key": stock.ApiKey},
-				Query:   map[string]string{"symbol": stock.Ticker, "market": "id-id"},
-			},
-		)
+		equitiesResp, equitiesErr = r.provider.FetchEquities(ctxEquities, stock)
 	}()
 
 	wg.Wait()
 	return
 }
 
-func (r *Runner) processStock(ctx context.Context, stockRepo models.StockRepository, stock models.StockInformation) {
+func (r *Runner) processStock(ctx context.Context, stockRepo models.StockRepository, stock models.StockInformation) *stockProcessResult {
+	result := &stockProcessResult{Ticker: stock.Ticker, StartedAt: utime.Utime.Now().ToTime()}
+	defer func() {
+		result.Duration = utime.Utime.Now().ToTime().Sub(result.StartedAt)
+	}()
+
 	earningsResp, equitiesResp, earningsErr, equitiesErr := r.fetchStockData(ctx, stock)
 
+	// The provider is paused; leave the ticker to the pool instead of reporting it per ticker
+	if earningsErr != nil && equitiesErr != nil &&
+		(errors.Is(earningsErr, helper.ErrCircuitOpen) || errors.Is(equitiesErr, helper.ErrCircuitOpen)) {
+		result.SkipReason = ingestSkipCircuitOpen
+		return result
+	}
+
+	if earningsErr == nil {
+		r.archivePayload(ctx, stock, payloadEndpointEarnings, earningsResp)
+	}
+	if equitiesErr == nil {
+		r.archivePayload(ctx, stock, payloadEndpointEquities, equitiesResp)
+	}
+
+	earningsErr = r.checkPayloadDrift(result, stock, payloadEndpointEarnings, earningsResp, earningsErr)
+	equitiesErr = r.checkPayloadDrift(result, stock, payloadEndpointEquities, equitiesResp, equitiesErr)
+
+	r.applyStockPayloads(stockRepo, stock, result, earningsResp, equitiesResp, earningsErr, equitiesErr, models.StockUpsertOptions{AsOf: result.StartedAt})
+	return result
+}
+
+// applyStockPayloads decodes fetched (or archived) payloads and upserts the
+// derived records as of the payload day in opts. Failed steps are recorded on result.
+func (r *Runner) applyStockPayloads(stockRepo models.StockRepository, stock models.StockInformation, result *stockProcessResult, earningsResp *helper.ExternalResponse, equitiesResp *helper.ExternalResponse, earningsErr error, equitiesErr error, opts models.StockUpsertOptions) {
 	var overviewRecord *models.StockOverviewMetricsRecord
 	isOverviewRecordFromEarnings := false
+	isOverviewRecordFromEquities := false
 
 	if earningsErr != nil {
-		r.captureException(earningsErr, map[string]string{
-			"module": "cron",
-			"job":    "upsertStockInformation",
-			"action": "fetch_earnings",
-		}, map[string]interface{}{
-			"ticker": stock.Ticker,
-		})
+		// Envelope failures are reported once per drift signature, not per ticker
+		if !errors.Is(earningsErr, ErrEnvelopeFailure) {
+			result.fail("fetch_earnings", earningsErr)
+			r.captureException(earningsErr, map[string]string{
+				"module": "cron",
+				"job":    "upsertStockInformation",
+				"action": "fetch_earnings",
+			}, map[string]interface{}{
+				"ticker": stock.Ticker,
+			})
+		}
 		r.logger.Warn().
 			Err(earningsErr).
 			Str("job", "upsertStockInformation").
@@ -74,6 +93,7 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 	} else {
 		var earnings EarningsResponse
 		if err := sonic.Unmarshal(earningsResp.Body, &earnings); err != nil {
+			result.fail("decode_earnings", err)
 			r.captureException(err, map[string]string{
 				"module": "cron",
 				"job":    "upsertStockInformation",
@@ -89,6 +109,7 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 		} else {
 			quarterlyRecords, err := earnings.ToQuarterlyHistoryRecords()
 			if err != nil {
+				result.fail("parse_quarterly_history", err)
 				r.captureException(err, map[string]string{
 					"module": "cron",
 					"job":    "upsertStockInformation",
@@ -101,7 +122,8 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 					Str("job", "upsertStockInformation").
 					Str("ticker", stock.Ticker).
 					Msg("Failed to parse quarterly history records")
-			} else if err := stockRepo.UpsertStockEarningQuarterlyHistory(quarterlyRecords); err != nil {
+			} else if err := stockRepo.UpsertStockEarningQuarterlyHistory(quarterlyRecords, opts); err != nil {
+				result.fail("upsert_quarterly_history", err)
 				r.captureException(err, map[string]string{
 					"module": "cron",
 					"job":    "upsertStockInformation",
@@ -124,8 +146,40 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 					Msg("Quarterly history upserted")
 			}
 
+			calendarRecord, err := earnings.ToEarningCalendarRecord()
+			if err != nil {
+				result.fail("parse_earning_calendar", err)
+				r.captureException(err, map[string]string{
+					"module": "cron",
+					"job":    "upsertStockInformation",
+					"action": "parse_earning_calendar",
+				}, map[string]interface{}{
+					"ticker": stock.Ticker,
+				})
+				r.logger.Error().
+					Err(err).
+					Str("job", "upsertStockInformation").
+					Str("ticker", stock.Ticker).
+					Msg("Failed to parse earning calendar record")
+			} else if err := stockRepo.UpsertStockEarningCalendar(calendarRecord); err != nil {
+				result.fail("upsert_earning_calendar", err)
+				r.captureException(err, map[string]string{
+					"module": "cron",
+					"job":    "upsertStockInformation",
+					"action": "upsert_earning_calendar",
+				}, map[string]interface{}{
+					"ticker": stock.Ticker,
+				})
+				r.logger.Error().
+					Err(err).
+					Str("job", "upsertStockInformation").
+					Str("ticker", stock.Ticker).
+					Msg("Failed to upsert earning calendar record")
+			}
+
 			overviewRecord, err = earnings.ToOverviewMetricsRecord()
 			if err != nil {
+				result.fail("parse_overview_from_earnings", err)
 				r.captureException(err, map[string]string{
 					"module": "cron",
 					"job":    "upsertStockInformation",
@@ -149,13 +203,17 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 	}
 
 	if equitiesErr != nil {
-		r.captureException(equitiesErr, map[string]string{
-			"module": "cron",
-			"job":    "upsertStockInformation",
-			"action": "fetch_equities",
-		}, map[string]interface{}{
-			"ticker": stock.Ticker,
-		})
+		// Envelope failures are reported once per drift signature, not per ticker
+		if !errors.Is(equitiesErr, ErrEnvelopeFailure) {
+			result.fail("fetch_equities", equitiesErr)
+			r.captureException(equitiesErr, map[string]string{
+				"module": "cron",
+				"job":    "upsertStockInformation",
+				"action": "fetch_equities",
+			}, map[string]interface{}{
+				"ticker": stock.Ticker,
+			})
+		}
 		r.logger.Error().
 			Err(equitiesErr).
 			Str("job", "upsertStockInformation").
@@ -164,6 +222,7 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 	} else {
 		var equities EquitiesResponse
 		if err := sonic.Unmarshal(equitiesResp.Body, &equities); err != nil {
+			result.fail("decode_equities", err)
 			r.captureException(err, map[string]string{
 				"module": "cron",
 				"job":    "upsertStockInformation",
@@ -178,6 +237,7 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 				Msg("Failed to decode overview data")
 		} else {
 			if err := equities.MergeIntoOverviewMetricsRecord(overviewRecord); err != nil {
+				result.fail("merge_overview_from_equities", err)
 				r.captureException(err, map[string]string{
 					"module": "cron",
 					"job":    "upsertStockInformation",
@@ -190,11 +250,23 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 					Str("job", "upsertStockInformation").
 					Str("ticker", stock.Ticker).
 					Msg("Failed to merge overview metrics from equities data")
+			} else {
+				isOverviewRecordFromEquities = true
 			}
 		}
 	}
 
-	if err := stockRepo.UpsertStockOverviewMetrics(overviewRecord); err != nil {
+	// An empty record would blank the current metrics and add a NULL gap to every history series
+	if !isOverviewRecordFromEarnings && !isOverviewRecordFromEquities {
+		r.logger.Warn().
+			Str("job", "upsertStockInformation").
+			Str("ticker", stock.Ticker).
+			Msg("No overview metrics parsed; overview upsert skipped")
+		return
+	}
+
+	if err := stockRepo.UpsertStockOverviewMetrics(overviewRecord, opts); err != nil {
+		result.fail("upsert_overview_metrics", err)
 		r.captureException(err, map[string]string{
 			"module": "cron",
 			"job":    "upsertStockInformation",
@@ -216,5 +288,61 @@ func (r *Runner) processStock(ctx context.Context, stockRepo models.StockReposit
 		Str("ticker", stock.Ticker).
 		Str("symbol", overviewRecord.Symbol).
 		Bool("overviewFromEarnings", isOverviewRecordFromEarnings).
+		Bool("overviewFromEquities", isOverviewRecordFromEquities).
 		Msg("Overview metrics upserted")
 }
+
+// archivePayload keeps the raw body for later replay. Archiving is best effort
+// and never fails the ticker.
+func (r *Runner) archivePayload(ctx context.Context, stock models.StockInformation, endpoint string, resp *helper.ExternalResponse) {
+	if r.archive == nil || resp == nil {
+		return
+	}
+
+	key := PayloadKey{Ticker: stock.Ticker, Endpoint: endpoint, Date: payloadDay(utime.Utime.Now().ToTime())}
+	if err := r.archive.Store(ctx, key, resp.Body); err != nil {
+		r.captureException(err, map[string]string{
+			"module": "cron",
+			"job":    "upsertStockInformation",
+			"action": "archive_" + endpoint,
+		}, map[string]interface{}{
+			"ticker": stock.Ticker,
+		})
+		r.logger.Warn().
+			Err(err).
+			Str("job", "upsertStockInformation").
+			Str("ticker", stock.Ticker).
+			Str("endpoint", endpoint).
+			Msg("Failed to archive raw payload")
+	}
+}
+
+// checkPayloadDrift compares a fetched payload with the expected schema and
+// records any drift on result. It returns ErrEnvelopeFailure for a success=false
+// envelope so the payload is not upserted.
+func (r *Runner) checkPayloadDrift(result *stockProcessResult, stock models.StockInformation, endpoint string, resp *helper.ExternalResponse, fetchErr error) error {
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	report, err := detectPayloadDrift(endpoint, resp)
+	if err != nil {
+		r.logger.Warn().
+			Err(err).
+			Str("job", "upsertStockInformation").
+			Str("ticker", stock.Ticker).
+			Str("endpoint", endpoint).
+			Msg("Failed to check payload schema")
+		return nil
+	}
+	if report == nil {
+		return nil
+	}
+
+	result.Drifts = append(result.Drifts, report)
+	if report.Drifts[0].Kind == SchemaDriftEnvelopeFailure {
+		result.fail("envelope_"+endpoint, ErrEnvelopeFailure)
+		return ErrEnvelopeFailure
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/bytedance/sonic"
+)
+
+const (
+	SchemaDriftAddedKey        = "added_key"
+	SchemaDriftRemovedKey      = "removed_key"
+	SchemaDriftTypeChanged     = "type_changed"
+	SchemaDriftEnvelopeFailure = "envelope_failure"
+)
+
+// ErrEnvelopeFailure is recorded when a 2xx response carries "success": false.
+var ErrEnvelopeFailure = errors.New("datasource returned success=false")
+
+// schemaBaselines holds the expected payload schemas, generated from known-good
+// payloads with `go run . schema-baseline`.
+//
+//go:embed schemas/*.json
+var schemaBaselines embed.FS
+
+var (
+	expectedSchemasOnce sync.Once
+	expectedSchemas     map[string]PayloadSchema
+	expectedSchemasErr  error
+)
+
+// dynamicKeyPattern matches object keys that are data rather than field names
+// (years, period codes), which are collapsed to "*".
+var dynamicKeyPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// PayloadSchema maps a JSON path (e.g. "data.History.quarterly.*.EpsActual")
+// to the sorted set of JSON types observed there.
+type PayloadSchema map[string][]string
+
+// SchemaDrift is a single structural difference from the expected schema.
+type SchemaDrift struct {
+	Kind     string `json:"kind"`
+	Path     string `json:"path"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// payloadDriftReport is the drift found in one payload.
+type payloadDriftReport struct {
+	Endpoint string
+	Drifts   []SchemaDrift
+}
+
+// BuildPayloadSchema derives the structural schema of a JSON body.
+func BuildPayloadSchema(body []byte) (PayloadSchema, error) {
+	var root interface{}
+	if err := sonic.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("error decoding payload: %w", err)
+	}
+
+	observed := map[string]map[string]bool{}
+	walkPayload(root, "", observed)
+
+	schema := make(PayloadSchema, len(observed))
+	for path, types := range observed {
+		list := make([]string, 0, len(types))
+		for jsonType := range types {
+			list = append(list, jsonType)
+		}
+		sort.Strings(list)
+		schema[path] = list
+	}
+	return schema, nil
+}
+
+func walkPayload(value interface{}, path string, observed map[string]map[string]bool) {
+	if path != "" {
+		if observed[path] == nil {
+			observed[path] = map[string]bool{}
+		}
+		observed[path][jsonTypeOf(value)] = true
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			segment := key
+			if dynamicKeyPattern.MatchString(key) {
+				segment = "*"
+			}
+			childPath := segment
+			if path != "" {
+				childPath = path + "." + segment
+			}
+			walkPayload(child, childPath, observed)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			walkPayload(child, path+"[]", observed)
+		}
+	}
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return "number"
+	}
+}
+
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "[]") {
+		return strings.TrimSuffix(path, "[]")
+	}
+	if index := strings.LastIndex(path, "."); index >= 0 {
+		return path[:index]
+	}
+	return ""
+}
+
+// isOptionalElementPath reports whether path is an array element or dynamic
+// map entry, which may legitimately be absent when the container is empty.
+func isOptionalElementPath(path string) bool {
+	return strings.HasSuffix(path, "[]") || strings.HasSuffix(path, ".*") || path == "*"
+}
+
+// DetectSchemaDrift compares a payload schema with the expected one. Only the
+// top-most added or removed path is reported, and null is compatible with any type.
+func DetectSchemaDrift(expected, actual PayloadSchema) []SchemaDrift {
+	drifts := []SchemaDrift{}
+
+	for path, actualTypes := range actual {
+		expectedTypes, known := expected[path]
+		if !known {
+			parent := parentPath(path)
+			if _, parentKnown := expected[parent]; parent == "" || parentKnown {
+				drifts = append(drifts, SchemaDrift{Kind: SchemaDriftAddedKey, Path: path, Actual: strings.Join(actualTypes, "|")})
+			}
+			continue
+		}
+
+		expectedNonNull := withoutNull(expectedTypes)
+		if len(expectedNonNull) == 0 {
+			continue
+		}
+		for _, actualType := range withoutNull(actualTypes) {
+			if !containsString(expectedNonNull, actualType) {
+				drifts = append(drifts, SchemaDrift{
+					Kind:     SchemaDriftTypeChanged,
+					Path:     path,
+					Expected: strings.Join(expectedNonNull, "|"),
+					Actual:   strings.Join(withoutNull(actualTypes), "|"),
+				})
+				break
+			}
+		}
+	}
+
+	for path, expectedTypes := range expected {
+		if _, present := actual[path]; present || isOptionalElementPath(path) {
+			continue
+		}
+		parent := parentPath(path)
+		if _, parentPresent := actual[parent]; parent == "" || parentPresent {
+			drifts = append(drifts, SchemaDrift{Kind: SchemaDriftRemovedKey, Path: path, Expected: strings.Join(expectedTypes, "|")})
+		}
+	}
+
+	sortSchemaDrifts(drifts)
+	return drifts
+}
+
+func sortSchemaDrifts(drifts []SchemaDrift) {
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Path != drifts[j].Path {
+			return drifts[i].Path < drifts[j].Path
+		}
+		return drifts[i].Kind < drifts[j].Kind
+	})
+}
+
+func withoutNull(types []string) []string {
+	result := make([]string, 0, len(types))
+	for _, jsonType := range types {
+		if jsonType != "null" {
+			result = append(result, jsonType)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaDriftSignature identifies a single drift by endpoint, kind and path,
+// independently of the ticker it was seen on or the other drifts beside it.
+func schemaDriftSignature(endpoint string, drift SchemaDrift) string {
+	hash := sha256.Sum256([]byte(endpoint + "|" + drift.Kind + "|" + drift.Path))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+func loadExpectedSchemas() (map[string]PayloadSchema, error) {
+	expectedSchemasOnce.Do(func() {
+		expectedSchemas = map[string]PayloadSchema{}
+		for _, endpoint := range []string{payloadEndpointEarnings, payloadEndpointEquities} {
+			raw, err := schemaBaselines.ReadFile("schemas/" + endpoint + ".json")
+			if err != nil {
+				expectedSchemasErr = fmt.Errorf("error reading %s schema baseline: %w", endpoint, err)
+				return
+			}
+			var schema PayloadSchema
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				expectedSchemasErr = fmt.Errorf("error decoding %s schema baseline: %w", endpoint, err)
+				return
+			}
+			expectedSchemas[endpoint] = schema
+		}
+	})
+	return expectedSchemas, expectedSchemasErr
+}
+
+// detectPayloadDrift checks a fetched payload against the expected schema and
+// returns nil when it matches. A success=false envelope is reported on its own,
+// since its body is not expected to follow the data schema.
+func detectPayloadDrift(endpoint string, resp *helper.ExternalResponse) (*payloadDriftReport, error) {
+	if resp == nil {
+		return nil, nil
+	}
+
+	var drifts []SchemaDrift
+	if resp.Success != nil && !*resp.Success {
+		drifts = []SchemaDrift{{Kind: SchemaDriftEnvelopeFailure, Path: "success", Expected: "true", Actual: "false"}}
+	} else {
+		schemas, err := loadExpectedSchemas()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := BuildPayloadSchema(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		drifts = DetectSchemaDrift(schemas[endpoint], actual)
+	}
+
+	if len(drifts) == 0 {
+		return nil, nil
+	}
+	return &payloadDriftReport{
+		Endpoint: endpoint,
+		Drifts:   drifts,
+	}, nil
+}
+
+// WritePayloadSchemaBaselines regenerates the expected schemas from known-good
+// payloads (earning.json / equities.json in fixtureDir) into outDir.
+func WritePayloadSchemaBaselines(fixtureDir, outDir string) error {
+	files := map[string]string{
+		payloadEndpointEarnings: "earning.json",
+		payloadEndpointEquities: "equities.json",
+	}
+
+	for endpoint, file := range files {
+		body, err := os.ReadFile(filepath.Join(fixtureDir, file))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", file, err)
+		}
+		schema, err := BuildPayloadSchema(body)
+		if err != nil {
+			return fmt.Errorf("error building %s schema: %w", endpoint, err)
+		}
+		encoded, err := encodePayloadSchema(schema)
+		if err != nil {
+			return fmt.Errorf("error encoding %s schema: %w", endpoint, err)
+		}
+		if err := os.WriteFile(filepath.Join(outDir, endpoint+".json"), encoded, 0o644); err != nil {
+			return fmt.Errorf("error writing %s schema: %w", endpoint, err)
+		}
+	}
+	return nil
+}
+
+// encodePayloadSchema writes one path per line so baseline diffs stay readable.
+func encodePayloadSchema(schema PayloadSchema) ([]byte, error) {
+	paths := make([]string, 0, len(schema))
+	for path := range schema {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	for index, path := range paths {
+		key, err := json.Marshal(path)
+		if err != nil {
+			return nil, err
+		}
+		types, err := json.Marshal(schema[path])
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString("  " + string(key) + ": " + string(types))
+		if index < len(paths)-1 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("}\n")
+	return []byte(builder.String()), nil
+}
@@ -0,0 +1,134 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+// maxDriftSampleTickers bounds how many tickers are stored per drift signature and run.
+const maxDriftSampleTickers = 10
+
+type collectedDrift struct {
+	report  *payloadDriftReport
+	tickers []string
+}
+
+// schemaDriftCollector splits the drift reports of a run into single drifts
+// and groups them by signature, so one ticker with extra drifts does not
+// create a new signature for drifts already seen elsewhere.
+type schemaDriftCollector struct {
+	bySignature map[string]*collectedDrift
+	order       []string
+}
+
+func newSchemaDriftCollector() *schemaDriftCollector {
+	return &schemaDriftCollector{bySignature: map[string]*collectedDrift{}}
+}
+
+func (c *schemaDriftCollector) add(ticker string, reports []*payloadDriftReport) {
+	for _, report := range reports {
+		for _, drift := range report.Drifts {
+			signature := schemaDriftSignature(report.Endpoint, drift)
+			collected, exists := c.bySignature[signature]
+			if !exists {
+				collected = &collectedDrift{report: &payloadDriftReport{
+					Endpoint: report.Endpoint,
+					Drifts:   []SchemaDrift{drift},
+				}}
+				c.bySignature[signature] = collected
+				c.order = append(c.order, signature)
+			}
+			if !containsString(collected.tickers, ticker) {
+				collected.tickers = append(collected.tickers, ticker)
+			}
+		}
+	}
+}
+
+// reportSchemaDrifts records the drift signatures seen in a run and alerts
+// Sentry only for signatures that have never been seen before.
+func (r *Runner) reportSchemaDrifts(job string, runID *int64, collector *schemaDriftCollector) {
+	runRepo := models.NewIngestionRunRepository()
+
+	for _, signature := range collector.order {
+		collected := collector.bySignature[signature]
+		report := collected.report
+
+		sample := collected.tickers
+		if len(sample) > maxDriftSampleTickers {
+			sample = sample[:maxDriftSampleTickers]
+		}
+		sampleTickers := strings.Join(sample, ",")
+
+		drifts, err := json.Marshal(report.Drifts)
+		if err != nil {
+			r.logger.Error().Err(err).Str("job", job).Str("signature", signature).Msg("Failed to encode schema drift")
+			continue
+		}
+
+		isNew, err := runRepo.RecordSchemaDriftSignature(signature, report.Endpoint, drifts, utime.Utime.Now().ToTime())
+		if err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    job,
+				"action": "record_schema_drift",
+			}, map[string]interface{}{
+				"signature": signature,
+			})
+			r.logger.Error().Err(err).Str("job", job).Str("signature", signature).Msg("Failed to record schema drift signature")
+			// Without the signature table, alert at most once per process
+			_, alerted := r.alertedDriftSignatures.LoadOrStore(signature, true)
+			isNew = !alerted
+		}
+
+		r.logger.Warn().
+			Str("job", job).
+			Str("endpoint", report.Endpoint).
+			Str("signature", signature).
+			Int("tickerCount", len(collected.tickers)).
+			Str("sampleTickers", sampleTickers).
+			Interface("drifts", report.Drifts).
+			Bool("newSignature", isNew).
+			Msg("Datasource schema drift detected")
+
+		if isNew {
+			r.captureException(fmt.Errorf("schema drift on %s endpoint (%s): %d change(s)", report.Endpoint, signature, len(report.Drifts)), map[string]string{
+				"module":    "cron",
+				"job":       job,
+				"action":    "schema_drift",
+				"endpoint":  report.Endpoint,
+				"signature": signature,
+			}, map[string]interface{}{
+				"drifts":         report.Drifts,
+				"ticker_count":   len(collected.tickers),
+				"sample_tickers": sampleTickers,
+			})
+		}
+
+		if runID == nil {
+			continue
+		}
+		if err := runRepo.InsertRunSchemaDrift(&models.IngestionRunSchemaDrift{
+			RunID:         *runID,
+			Endpoint:      report.Endpoint,
+			Signature:     signature,
+			Drifts:        drifts,
+			TickerCount:   len(collected.tickers),
+			SampleTickers: sampleTickers,
+		}); err != nil {
+			r.captureException(err, map[string]string{
+				"module": "cron",
+				"job":    job,
+				"action": "record_schema_drift",
+			}, map[string]interface{}{
+				"signature": signature,
+				"run_id":    *runID,
+			})
+			r.logger.Error().Err(err).Str("job", job).Str("signature", signature).Int64("runId", *runID).Msg("Failed to record run schema drift")
+		}
+	}
+}
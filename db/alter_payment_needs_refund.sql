@@ -0,0 +1,7 @@
+-- Payments needing a refund
+-- A gateway payment that captured money but must not grant a subscription (e.g. the paid
+-- amount differs from the charge) is moved to 'needs_refund' with the reason in
+-- failure_reason, instead of 'failed', so staff can find and refund it.
+-- Run once on existing databases.
+
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'needs_refund';
@@ -0,0 +1,7 @@
+-- Adds the payload day stock_overview_metrics rows were built from.
+-- Replaying archived payloads only replaces a row with data from the same or a later
+-- day. Rows written before this column existed are replaced by any payload.
+-- Run once on existing databases.
+
+ALTER TABLE stock_overview_metrics
+    ADD COLUMN IF NOT EXISTS metrics_as_of DATE NULL;
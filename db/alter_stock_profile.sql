@@ -0,0 +1,12 @@
+-- Adds stock master attributes to public.stock alongside name/sector/industry.
+-- board is the IDX listing board; sub_industry is free text below the stock_industry enum.
+-- Run once on existing databases.
+
+ALTER TABLE public.stock
+    ADD COLUMN IF NOT EXISTS sub_industry varchar NULL,
+    ADD COLUMN IF NOT EXISTS board varchar(16) NULL;
+
+ALTER TABLE public.stock
+    ADD CONSTRAINT chk_stock_board CHECK (board IN ('main', 'development', 'acceleration'));
+
+CREATE INDEX IF NOT EXISTS idx_stock_sector ON public.stock(sector);
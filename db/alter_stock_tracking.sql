@@ -0,0 +1,10 @@
+-- Adds admin-managed tracking flags to public.stock.
+-- api_key holds AES-256-GCM ciphertext ("enc:v1:..."); legacy plaintext keys are
+-- encrypted by the cron runner at startup once MARKET_DATA_API_KEY_ENCRYPTION_KEY is set.
+-- Run once on existing databases.
+
+ALTER TABLE public.stock
+    ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS api_key_last4 VARCHAR(4) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_stock_enabled_last_update ON public.stock(enabled, last_update);
@@ -0,0 +1,18 @@
+-- Email verification
+-- New accounts start 'unverified' and are activated by a single-use token emailed on
+-- sign-up. Only the SHA-256 hash of a token is stored. Issued tokens are kept after use
+-- or expiry so resends can be throttled per user.
+
+ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'unverified';
+
+CREATE TABLE email_verification_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_email_verification_tokens_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_email_verification_tokens_user ON email_verification_tokens(user_id, created_at DESC);
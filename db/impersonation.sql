@@ -0,0 +1,20 @@
+-- Admin impersonation
+-- Support staff holding users:impersonate can obtain a short-lived read-only token to act
+-- as a user; users:impersonate_write (superadmin only) allows tokens that may write. Every
+-- request made with it is audited with the admin as actor and the user in
+-- impersonated_user_id.
+
+ALTER TABLE audit_logs ADD COLUMN impersonated_user_id INTEGER;
+
+CREATE INDEX idx_audit_logs_impersonated_user ON audit_logs(impersonated_user_id, created_at DESC)
+    WHERE impersonated_user_id IS NOT NULL;
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:impersonate', 'Act as a user with a short-lived impersonation token'),
+    ('users:impersonate_write', 'Make changes while impersonating a user');
+
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, 'users:impersonate' FROM roles r WHERE r.name IN ('superadmin', 'support');
+
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, 'users:impersonate_write' FROM roles r WHERE r.name = 'superadmin';
@@ -0,0 +1,85 @@
+-- Stock ingestion run history
+-- One row per cron execution in ingestion_runs, one row per ticker in ingestion_run_items.
+-- Retry runs point at the run whose failed tickers they re-process via parent_run_id.
+
+-- ============================================================================
+-- INGESTION RUNS
+-- ============================================================================
+
+CREATE TABLE ingestion_runs (
+    id BIGSERIAL PRIMARY KEY,
+    job VARCHAR(64) NOT NULL,
+    trigger VARCHAR(32) NOT NULL CHECK (trigger IN ('schedule', 'retry_failed', 'manual')),
+    parent_run_id BIGINT REFERENCES ingestion_runs(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('running', 'completed', 'canceled')),
+    provider VARCHAR(32) NOT NULL,
+    total_count INTEGER NOT NULL DEFAULT 0,
+    succeeded_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ingestion_runs_started_at ON ingestion_runs(started_at DESC);
+CREATE INDEX idx_ingestion_runs_job_status ON ingestion_runs(job, status);
+CREATE INDEX idx_ingestion_runs_parent ON ingestion_runs(parent_run_id);
+
+-- ============================================================================
+-- INGESTION RUN ITEMS
+-- error_action is the first failing step (fetch_earnings, decode_equities, ...);
+-- failed_actions lists every failing step, comma separated.
+-- ============================================================================
+
+CREATE TABLE ingestion_run_items (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES ingestion_runs(id) ON DELETE CASCADE,
+    ticker VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('succeeded', 'failed', 'skipped')),
+    error_action VARCHAR(64),
+    failed_actions TEXT,
+    error_message TEXT,
+    skip_reason VARCHAR(64),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE ingestion_run_items
+    ADD CONSTRAINT uq_ingestion_run_items_run_ticker UNIQUE (run_id, ticker);
+
+CREATE INDEX idx_ingestion_run_items_run_status ON ingestion_run_items(run_id, status);
+CREATE INDEX idx_ingestion_run_items_ticker ON ingestion_run_items(ticker, created_at DESC);
+
+-- ============================================================================
+-- SCHEMA DRIFT
+-- Structural differences between datasource payloads and the expected schema
+-- (cron/schemas). A signature identifies one set of differences on one endpoint;
+-- it is alerted once when first seen and counted on every later run.
+-- ============================================================================
+
+CREATE TABLE schema_drift_signatures (
+    signature VARCHAR(32) PRIMARY KEY,
+    endpoint VARCHAR(32) NOT NULL,
+    drifts JSONB NOT NULL,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE ingestion_run_schema_drifts (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES ingestion_runs(id) ON DELETE CASCADE,
+    endpoint VARCHAR(32) NOT NULL,
+    signature VARCHAR(32) NOT NULL REFERENCES schema_drift_signatures(signature),
+    drifts JSONB NOT NULL,
+    ticker_count INTEGER NOT NULL,
+    sample_tickers TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE ingestion_run_schema_drifts
+    ADD CONSTRAINT uq_ingestion_run_schema_drifts_run_signature UNIQUE (run_id, signature);
+
+CREATE INDEX idx_ingestion_run_schema_drifts_signature ON ingestion_run_schema_drifts(signature);
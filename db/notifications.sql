@@ -0,0 +1,71 @@
+-- Notifications
+-- Per-user channel preferences, web push subscriptions and the outbox the cron
+-- dispatcher delivers from. Every notification is rendered at enqueue time into
+-- one outbox row per channel; failed deliveries are retried with backoff until
+-- NOTIFICATION_MAX_ATTEMPTS is reached.
+
+-- ============================================================================
+-- USER NOTIFICATION PREFERENCES
+-- ============================================================================
+
+CREATE TABLE user_notification_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    locale VARCHAR(2) NOT NULL DEFAULT 'id' CHECK (locale IN ('id', 'en')),
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    telegram_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    telegram_chat_id VARCHAR(64),
+    web_push_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    in_app_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================================================
+-- WEB PUSH SUBSCRIPTIONS
+-- ============================================================================
+
+CREATE TABLE user_web_push_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL,
+    p256dh VARCHAR(255) NOT NULL,
+    auth VARCHAR(255) NOT NULL,
+    user_agent VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE user_web_push_subscriptions
+    ADD CONSTRAINT uq_user_web_push_subscriptions_endpoint UNIQUE (endpoint);
+
+CREATE INDEX idx_user_web_push_subscriptions_user ON user_web_push_subscriptions(user_id);
+
+-- ============================================================================
+-- NOTIFICATION OUTBOX
+-- ============================================================================
+
+CREATE TABLE notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(16) NOT NULL CHECK (channel IN ('email', 'telegram', 'web_push', 'in_app')),
+    event VARCHAR(64) NOT NULL,
+    -- dedupe_key makes enqueueing idempotent per user and channel; NULL disables it
+    dedupe_key VARCHAR(255),
+    locale VARCHAR(2) NOT NULL,
+    recipient VARCHAR(255),
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    url VARCHAR(500),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE notification_outbox
+    ADD CONSTRAINT uq_notification_outbox_dedupe UNIQUE (user_id, channel, dedupe_key);
+
+CREATE INDEX idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_notification_outbox_user ON notification_outbox(user_id, created_at DESC);
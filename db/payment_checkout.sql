@@ -0,0 +1,48 @@
+-- Payment gateway checkout
+-- A checkout creates a pending payment_records row that is paid through a payment
+-- gateway. Gateway webhooks are stored in payment_gateway_events, unique per gateway
+-- event id, so a redelivered webhook never completes a payment or extends premium twice.
+-- Checkouts left unpaid past checkout_expires_at are moved to 'expired' by a cron job.
+
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'expired';
+
+-- ============================================================================
+-- PAYMENT RECORDS CHECKOUT COLUMNS
+-- ============================================================================
+
+ALTER TABLE payment_records
+    ADD COLUMN gateway VARCHAR(30),
+    ADD COLUMN gateway_reference VARCHAR(100),
+    ADD COLUMN checkout_url TEXT,
+    ADD COLUMN checkout_expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN failure_reason TEXT;
+
+ALTER TABLE payment_records
+    ADD CONSTRAINT uq_payment_records_gateway_reference UNIQUE (gateway, gateway_reference);
+
+CREATE INDEX idx_payment_records_pending_checkout ON payment_records(checkout_expires_at)
+    WHERE payment_status = 'pending';
+
+-- ============================================================================
+-- PAYMENT GATEWAY EVENTS
+-- ============================================================================
+
+CREATE TABLE payment_gateway_events (
+    id BIGSERIAL PRIMARY KEY,
+    gateway VARCHAR(30) NOT NULL,
+    event_id VARCHAR(100) NOT NULL,
+    payment_record_id INTEGER REFERENCES payment_records(id) ON DELETE SET NULL,
+    event_status VARCHAR(20) NOT NULL CHECK (event_status IN ('paid', 'failed', 'expired')),
+    amount NUMERIC(12, 2),
+    payment_method VARCHAR(50),
+    -- applied is false when the event arrived after the payment had already settled
+    applied BOOLEAN NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE payment_gateway_events
+    ADD CONSTRAINT uq_payment_gateway_events_gateway_event UNIQUE (gateway, event_id);
+
+CREATE INDEX idx_payment_gateway_events_payment ON payment_gateway_events(payment_record_id);
@@ -0,0 +1,35 @@
+-- Payment invoices
+-- Every completed payment gets one invoice with a sequential number per year
+-- (INV/<year>/<sequence>). Numbers are allocated from payment_invoice_counters in the
+-- transaction that completes the payment, so they are gapless. Payments completed
+-- before this table existed are issued a number the first time their invoice is opened.
+
+-- ============================================================================
+-- PAYMENT INVOICES
+-- ============================================================================
+
+CREATE TABLE payment_invoice_counters (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL CHECK (last_number > 0)
+);
+
+CREATE TABLE payment_invoices (
+    id BIGSERIAL PRIMARY KEY,
+    payment_record_id INTEGER NOT NULL REFERENCES payment_records(id) ON DELETE RESTRICT,
+    invoice_number VARCHAR(30) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE payment_invoices
+    ADD CONSTRAINT uq_payment_invoices_payment_record UNIQUE (payment_record_id);
+
+ALTER TABLE payment_invoices
+    ADD CONSTRAINT uq_payment_invoices_invoice_number UNIQUE (invoice_number);
+
+-- ============================================================================
+-- PAYMENT LISTING INDEXES
+-- ============================================================================
+
+CREATE INDEX idx_payment_records_payment_method ON payment_records(payment_method);
+CREATE INDEX idx_payment_records_processed_by_admin ON payment_records(processed_by_admin_id)
+    WHERE processed_by_admin_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Premium expiry reminders
+-- History of renewal reminders sent before premium_expires_at, one row per user,
+-- subscription period (expires_at) and reminder offset, so each is sent only once.
+-- Renewing moves premium_expires_at and starts a fresh set of reminders.
+
+-- ============================================================================
+-- PREMIUM EXPIRY REMINDERS
+-- ============================================================================
+
+CREATE TABLE premium_expiry_reminders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    days_before INTEGER NOT NULL CHECK (days_before >= 0),
+    days_left INTEGER NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE premium_expiry_reminders
+    ADD CONSTRAINT uq_premium_expiry_reminders_user_period_offset UNIQUE (user_id, expires_at, days_before);
+
+CREATE INDEX idx_premium_expiry_reminders_user ON premium_expiry_reminders(user_id, sent_at DESC);
@@ -0,0 +1,54 @@
+-- Promo codes
+-- Admin-created discount codes applied at checkout or when an admin records a payment.
+-- Each use is recorded in promo_code_redemptions against its payment; only pending and
+-- completed payments count towards max_redemptions and per_user_limit, so an expired or
+-- failed checkout gives its use back. Codes are stored upper case.
+
+-- ============================================================================
+-- PROMO CODES
+-- ============================================================================
+
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    description VARCHAR(255),
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_value NUMERIC(15, 2) NOT NULL CHECK (discount_value > 0),
+    max_discount_amount NUMERIC(15, 2) CHECK (max_discount_amount > 0),
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    valid_until TIMESTAMP WITH TIME ZONE,
+    max_redemptions INTEGER CHECK (max_redemptions > 0),
+    per_user_limit INTEGER NOT NULL DEFAULT 1 CHECK (per_user_limit > 0),
+    -- Comma-separated user levels; empty means every paid level
+    eligible_levels VARCHAR(100) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by_admin_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (discount_type <> 'percentage' OR discount_value <= 100),
+    CHECK (valid_until IS NULL OR valid_until > valid_from)
+);
+
+ALTER TABLE promo_codes
+    ADD CONSTRAINT uq_promo_codes_code UNIQUE (code);
+
+-- ============================================================================
+-- REDEMPTIONS
+-- ============================================================================
+
+ALTER TABLE payment_records
+    ADD COLUMN promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE RESTRICT;
+
+CREATE TABLE promo_code_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payment_record_id INTEGER NOT NULL REFERENCES payment_records(id) ON DELETE CASCADE,
+    discount_amount NUMERIC(15, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE promo_code_redemptions
+    ADD CONSTRAINT uq_promo_code_redemptions_payment_record UNIQUE (payment_record_id);
+
+CREATE INDEX idx_promo_code_redemptions_code_user ON promo_code_redemptions(promo_code_id, user_id);
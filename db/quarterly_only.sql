@@ -20,8 +20,12 @@ CREATE TABLE public.stock (
 	phone varchar NULL,
 	industry public.stock_industry NULL,
 	sector public.stock_sector NULL,
+	sub_industry varchar NULL,
+	board varchar(16) NULL CHECK (board IN ('main', 'development', 'acceleration')),
 	last_update timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
   api_key varchar NULL,
+	api_key_last4 varchar(4) NULL,
+	enabled bool DEFAULT true NOT NULL,
 	CONSTRAINT stock_pk PRIMARY KEY (ticker)
 );
 
@@ -173,3 +177,29 @@ ALTER TABLE stock_overview_metrics
 
 CREATE INDEX idx_stock_overview_symbol ON stock_overview_metrics(symbol);
 CREATE INDEX idx_stock_overview_source_time ON stock_overview_metrics(source_time_last_updated);
+
+-- ============================================================================
+-- EARNING CALENDAR (next expected release per fiscal period)
+-- Source paths:
+-- - earning.json: data.ExpectedReportDate, data.FiscalPeriodEndDate,
+--   data.CiqFiscalPeriodType, data.EpsForecast, data.RevenueForecast
+-- ============================================================================
+
+CREATE TABLE stock_earning_calendar (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(32) NOT NULL,
+    period_code CHAR(6) NOT NULL CHECK (period_code ~ '^[0-9]{6}$'),
+    ciq_fiscal_period_type VARCHAR(32),
+    fiscal_period_end_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    expected_report_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    eps_forecast NUMERIC(20, 6),
+    revenue_forecast NUMERIC(20, 2),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE stock_earning_calendar
+    ADD CONSTRAINT uq_stock_earning_calendar_symbol_period UNIQUE (symbol, period_code);
+
+CREATE INDEX idx_stock_earning_calendar_expected_date ON stock_earning_calendar(expected_report_date);
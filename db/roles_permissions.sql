@@ -0,0 +1,96 @@
+-- Roles and permissions
+-- Admin access moves from the 'admin' user level (and a hard-coded email list) to roles
+-- granting named permissions. Permission codes are referenced by the API routes, so they
+-- are seeded here and not created at runtime; roles and their grants are managed by admins.
+
+-- ============================================================================
+-- PERMISSIONS AND ROLES
+-- ============================================================================
+
+CREATE TABLE permissions (
+    code VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_roles_name UNIQUE (name)
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_code VARCHAR(50) NOT NULL REFERENCES permissions(code),
+    PRIMARY KEY (role_id, permission_code)
+);
+
+CREATE TABLE user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    granted_by_admin_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles(role_id);
+
+-- ============================================================================
+-- SEED DATA
+-- ============================================================================
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:read', 'View users and expired subscriptions'),
+    ('users:write', 'Change user account status'),
+    ('payments:read', 'View payment history and invoices'),
+    ('payments:write', 'Change subscription levels and record manual payments'),
+    ('plans:read', 'View subscription plans'),
+    ('plans:write', 'Create and edit subscription plans'),
+    ('promo_codes:read', 'View promo codes and their redemptions'),
+    ('promo_codes:write', 'Create and edit promo codes'),
+    ('reports:read', 'View subscription and revenue reports'),
+    ('stocks:read', 'View tracked stocks and ingestion runs'),
+    ('stocks:write', 'Manage tracked stocks, profiles and ingestion'),
+    ('audit:read', 'Search the audit log'),
+    ('roles:read', 'View roles and user role assignments'),
+    ('roles:write', 'Manage roles and assign them to users');
+
+INSERT INTO roles (name, description) VALUES
+    ('superadmin', 'Full administrative access'),
+    ('support', 'Look up users and their payments'),
+    ('finance', 'Subscriptions, payments, pricing and reports'),
+    ('operations', 'Stock tracking and market data ingestion');
+
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, p.code FROM roles r CROSS JOIN permissions p WHERE r.name = 'superadmin';
+
+INSERT INTO role_permissions (role_id, permission_code)
+SELECT r.id, p.code
+FROM roles r
+JOIN (VALUES
+    ('support', 'users:read'),
+    ('support', 'payments:read'),
+    ('support', 'plans:read'),
+    ('support', 'promo_codes:read'),
+    ('finance', 'users:read'),
+    ('finance', 'payments:read'),
+    ('finance', 'payments:write'),
+    ('finance', 'plans:read'),
+    ('finance', 'plans:write'),
+    ('finance', 'promo_codes:read'),
+    ('finance', 'promo_codes:write'),
+    ('finance', 'reports:read'),
+    ('operations', 'stocks:read'),
+    ('operations', 'stocks:write')
+) AS p(role_name, code) ON p.role_name = r.name;
+
+-- Existing admins keep full access: users on the 'admin' level and the accounts of the
+-- former hard-coded admin email list
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id
+FROM users u
+JOIN roles r ON r.name = 'superadmin'
+WHERE u.user_level = 'admin' OR u.email IN ('admin@example.com', 'superadmin@example.com')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,67 @@
+-- Dated snapshots of stock_overview_metrics
+-- Written in the same transaction as UpsertStockOverviewMetrics, only when one of the
+-- tracked ratios differs from the symbol's latest snapshot. At most one row per day.
+
+-- ============================================================================
+-- STOCK OVERVIEW METRICS HISTORY
+-- ============================================================================
+
+CREATE TABLE stock_overview_metrics_history (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(32) NOT NULL,
+    recorded_on DATE NOT NULL,
+
+    eps NUMERIC(30, 6),
+    book_value_per_share NUMERIC(30, 6),
+    latest_revenue NUMERIC(30, 2),
+    latest_income NUMERIC(30, 2),
+    debt_to_equity_ratio NUMERIC(30, 6),
+    current_ratio NUMERIC(30, 6),
+    quick_ratio NUMERIC(30, 6),
+    debt_asset_ratio NUMERIC(30, 6),
+    interest_coverage NUMERIC(30, 6),
+    return_on_assets NUMERIC(30, 6),
+    return_on_equity NUMERIC(30, 6),
+    return_on_capital NUMERIC(30, 6),
+    gross_margin NUMERIC(30, 6),
+    operating_margin NUMERIC(30, 6),
+    pretax_margin NUMERIC(30, 6),
+    net_profit_margin NUMERIC(30, 6),
+    payout_ratio NUMERIC(30, 6),
+    price_to_book_ratio NUMERIC(30, 6),
+    price_to_sales_ratio NUMERIC(30, 6),
+    forward_price_to_eps NUMERIC(30, 6),
+    dividend_yield NUMERIC(30, 6),
+    trailing_annual_dividend_yield NUMERIC(30, 6),
+    market_cap NUMERIC(30, 2),
+    enterprise_value NUMERIC(30, 2),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE stock_overview_metrics_history
+    ADD CONSTRAINT uq_stock_overview_metrics_history_symbol_date UNIQUE (symbol, recorded_on);
+
+CREATE INDEX idx_stock_overview_metrics_history_symbol_date ON stock_overview_metrics_history(symbol, recorded_on DESC);
+
+-- Seed one snapshot per symbol from the current overview rows (existing databases).
+INSERT INTO stock_overview_metrics_history (
+    symbol, recorded_on,
+    eps, book_value_per_share, latest_revenue, latest_income, debt_to_equity_ratio,
+    current_ratio, quick_ratio, debt_asset_ratio, interest_coverage, return_on_assets,
+    return_on_equity, return_on_capital, gross_margin, operating_margin, pretax_margin,
+    net_profit_margin, payout_ratio, price_to_book_ratio, price_to_sales_ratio,
+    forward_price_to_eps, dividend_yield, trailing_annual_dividend_yield, market_cap,
+    enterprise_value
+)
+SELECT
+    symbol, COALESCE(source_time_last_updated::date, CURRENT_DATE),
+    eps, book_value_per_share, latest_revenue, latest_income, debt_to_equity_ratio,
+    current_ratio, quick_ratio, debt_asset_ratio, interest_coverage, return_on_assets,
+    return_on_equity, return_on_capital, gross_margin, operating_margin, pretax_margin,
+    net_profit_margin, payout_ratio, price_to_book_ratio, price_to_sales_ratio,
+    forward_price_to_eps, dividend_yield, trailing_annual_dividend_yield, market_cap,
+    enterprise_value
+FROM stock_overview_metrics
+ON CONFLICT (symbol, recorded_on) DO NOTHING;
@@ -0,0 +1,24 @@
+-- Raw datasource payload archive (PAYLOAD_ARCHIVE_BACKEND=db)
+-- Gzipped response bodies of the earnings/equities endpoints, one row per
+-- ticker, endpoint and day. Used to replay parsing without hitting the network.
+
+-- ============================================================================
+-- STOCK RAW PAYLOADS
+-- ============================================================================
+
+CREATE TABLE stock_raw_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    ticker VARCHAR(32) NOT NULL,
+    endpoint VARCHAR(32) NOT NULL,
+    payload_date DATE NOT NULL,
+    content_encoding VARCHAR(16) NOT NULL DEFAULT 'gzip',
+    body BYTEA NOT NULL,
+    body_size INTEGER NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE stock_raw_payloads
+    ADD CONSTRAINT uq_stock_raw_payloads_ticker_endpoint_date UNIQUE (ticker, endpoint, payload_date);
+
+CREATE INDEX idx_stock_raw_payloads_date ON stock_raw_payloads(payload_date);
@@ -0,0 +1,37 @@
+-- Subscription plans
+-- Purchasable premium plans (tier, duration in months, price in IDR). Checkouts and admin
+-- upgrades reference a plan; a plan's tier and duration never change, so a price change is
+-- an update and past payments keep their recorded prices. Moving from premium to premium+
+-- mid-period credits the unused premium time (proration_credit) and starts the premium+
+-- period at payment.
+
+-- ============================================================================
+-- SUBSCRIPTION PLANS
+-- ============================================================================
+
+CREATE TABLE subscription_plans (
+    id SERIAL PRIMARY KEY,
+    user_level user_level NOT NULL CHECK (user_level IN ('premium', 'premium+')),
+    name VARCHAR(100) NOT NULL,
+    duration_months INTEGER NOT NULL CHECK (duration_months > 0),
+    price NUMERIC(15, 2) NOT NULL CHECK (price >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE subscription_plans
+    ADD CONSTRAINT uq_subscription_plans_level_duration UNIQUE (user_level, duration_months);
+
+-- The durations and prices that were hardcoded before plans existed
+INSERT INTO subscription_plans (user_level, name, duration_months, price) VALUES
+    ('premium', 'Premium Bulanan', 1, 49000),
+    ('premium+', 'Premium+ Tahunan', 12, 499000);
+
+-- ============================================================================
+-- PAYMENT RECORDS
+-- ============================================================================
+
+ALTER TABLE payment_records
+    ADD COLUMN subscription_plan_id INTEGER REFERENCES subscription_plans(id) ON DELETE RESTRICT,
+    ADD COLUMN proration_credit NUMERIC(15, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,55 @@
+-- User alerts
+-- Alert rules defined by users (price/dividend yield thresholds, upcoming earnings,
+-- bond coupons and deposit maturities) and the inbox of triggered alerts. The cron
+-- evaluator keeps condition_met/last_trigger_key so each alert fires once per crossing.
+
+-- ============================================================================
+-- USER ALERTS
+-- ============================================================================
+
+CREATE TABLE user_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    alert_type VARCHAR(32) NOT NULL CHECK (alert_type IN (
+        'price_above', 'price_below', 'dividend_yield_above',
+        'earnings_within_days', 'bond_coupon_due', 'deposit_maturing'
+    )),
+    ticker VARCHAR(32),
+    -- No foreign key: the application table is portfolio_bond while schema.sql names it portfolio_bonds
+    portfolio_bond_id INTEGER,
+    portfolio_cash_id INTEGER REFERENCES portfolio_cash(id) ON DELETE CASCADE,
+    threshold NUMERIC(30, 6),
+    days INTEGER CHECK (days BETWEEN 0 AND 365),
+    note TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    condition_met BOOLEAN NOT NULL DEFAULT FALSE,
+    last_trigger_key VARCHAR(64),
+    last_triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_alerts_user ON user_alerts(user_id);
+CREATE INDEX idx_user_alerts_active ON user_alerts(id) WHERE active;
+
+-- ============================================================================
+-- ALERT INBOX
+-- ============================================================================
+-- Also receives in-app notifications (alert_id NULL, alert_type holds the notification event)
+
+CREATE TABLE user_alert_inbox (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    alert_id BIGINT REFERENCES user_alerts(id) ON DELETE SET NULL,
+    alert_type VARCHAR(32) NOT NULL,
+    ticker VARCHAR(32),
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    observed_value NUMERIC(30, 6),
+    event_date DATE,
+    triggered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_user_alert_inbox_user_triggered ON user_alert_inbox(user_id, triggered_at DESC);
+CREATE INDEX idx_user_alert_inbox_user_unread ON user_alert_inbox(user_id) WHERE read_at IS NULL;
@@ -0,0 +1,39 @@
+-- User watchlists
+-- Named, ordered lists of tickers per user; the number of lists and tickers per list
+-- is capped by the user's plan (models.WatchlistLimits).
+
+-- ============================================================================
+-- WATCHLISTS
+-- ============================================================================
+
+CREATE TABLE watchlists (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uq_watchlists_user_name ON watchlists(user_id, LOWER(name));
+CREATE INDEX idx_watchlists_user_position ON watchlists(user_id, position);
+
+-- ============================================================================
+-- WATCHLIST ITEMS
+-- ============================================================================
+
+CREATE TABLE watchlist_items (
+    id BIGSERIAL PRIMARY KEY,
+    watchlist_id BIGINT NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+    ticker VARCHAR(32) NOT NULL,
+    note TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE watchlist_items
+    ADD CONSTRAINT uq_watchlist_items_watchlist_ticker UNIQUE (watchlist_id, ticker);
+
+CREATE INDEX idx_watchlist_items_watchlist_position ON watchlist_items(watchlist_id, position);
+CREATE INDEX idx_watchlist_items_ticker ON watchlist_items(ticker);
@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected because the host's circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState represents the state of a circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreaker stops calls to a failing host after consecutive failures and
+// lets a single probe request through once the open timeout has elapsed.
+type CircuitBreaker struct {
+	mu                  sync.Mutex
+	failureThreshold    int
+	openTimeout         time.Duration
+	consecutiveFailures int
+	state               CircuitState
+	openedAt            time.Time
+	probeInFlight       bool
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		state:            CircuitClosed,
+	}
+}
+
+// Allow reports whether a request may be sent now.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.probeInFlight = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probeInFlight {
+			return ErrCircuitOpen
+		}
+		b.probeInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess closes the circuit and resets the failure count.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures = 0
+	b.probeInFlight = false
+	b.state = CircuitClosed
+}
+
+// ReleaseProbe ends a half-open probe that got no answer, e.g. because the caller
+// canceled it, so the next request can probe again. The state and failure count are kept.
+func (b *CircuitBreaker) ReleaseProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// RecordFailure counts a failure and returns true when this call opened the circuit.
+func (b *CircuitBreaker) RecordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.probeInFlight = false
+
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.consecutiveFailures >= b.failureThreshold) {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// State returns the current circuit state.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// OpenUntil returns when an open circuit will allow a probe; zero if not open.
+func (b *CircuitBreaker) OpenUntil() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != CircuitOpen {
+		return time.Time{}
+	}
+	return b.openedAt.Add(b.openTimeout)
+}
+
+// CircuitBreakerRegistry keeps one circuit breaker per host.
+type CircuitBreakerRegistry struct {
+	mu               sync.Mutex
+	breakers         map[string]*CircuitBreaker
+	failureThreshold int
+	openTimeout      time.Duration
+}
+
+// NewCircuitBreakerRegistry creates a registry whose breakers share the given settings.
+func NewCircuitBreakerRegistry(failureThreshold int, openTimeout time.Duration) *CircuitBreakerRegistry {
+	return &CircuitBreakerRegistry{
+		breakers:         make(map[string]*CircuitBreaker),
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// Get returns the circuit breaker for host, creating it on first use.
+func (r *CircuitBreakerRegistry) Get(host string) *CircuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breaker, exists := r.breakers[host]
+	if !exists {
+		breaker = NewCircuitBreaker(r.failureThreshold, r.openTimeout)
+		r.breakers[host] = breaker
+	}
+	return breaker
+}
@@ -0,0 +1,103 @@
+package helper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedSecretPrefix marks values produced by EncryptSecret, so legacy
+// plaintext values can still be read while they are being migrated.
+const encryptedSecretPrefix = "enc:v1:"
+
+// ErrEncryptionKeyMissing is returned when a secret must be encrypted but no key is configured.
+var ErrEncryptionKeyMissing = errors.New("encryption key is not configured")
+
+// ParseEncryptionKey decodes a 32-byte AES-256 key given as base64 or hex.
+func ParseEncryptionKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, ErrEncryptionKeyMissing
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 bytes encoded as base64 or hex")
+}
+
+// IsEncryptedSecret reports whether value was produced by EncryptSecret.
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+// EncryptSecret encrypts plaintext with AES-256-GCM.
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	if len(key) == 0 {
+		return "", ErrEncryptionKeyMissing
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret. Values without the encrypted prefix
+// are returned unchanged.
+func DecryptSecret(key []byte, value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	if len(key) == 0 {
+		return "", ErrEncryptionKeyMissing
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedSecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("error decoding encrypted secret: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted secret is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -1,6 +1,9 @@
 package helper
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 func ParseRFC3339Pointer(raw *string) (*time.Time, error) {
 	if raw == nil || *raw == "" {
@@ -22,3 +25,23 @@ func StringPointerOrNil(value string) *string {
 	valueCopy := value
 	return &valueCopy
 }
+
+// FormatThousands formats an amount without decimals using dots as thousand separators.
+func FormatThousands(value float64) string {
+	negative := value < 0
+	if negative {
+		value = -value
+	}
+	digits := fmt.Sprintf("%.0f", value)
+	var out []byte
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, '.')
+		}
+		out = append(out, digits[i])
+	}
+	if negative {
+		return "-" + string(out)
+	}
+	return string(out)
+}
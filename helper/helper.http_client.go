@@ -122,13 +122,7 @@ func DoExternalJSONRequest(ctx context.Context, client *http.Client, method, end
 		}
 	}
 
-	var envelope struct {
-		Success bool `json:"success"`
-	}
-	if len(bodyBytes) > 0 && sonic.Unmarshal(bodyBytes, &envelope) == nil {
-		success := envelope.Success
-		result.Success = &success
-	}
+	result.Success = ParseSuccessEnvelope(bodyBytes)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return result, &ExternalJSONResponseError{
@@ -139,3 +133,14 @@ func DoExternalJSONRequest(ctx context.Context, client *http.Client, method, end
 
 	return result, nil
 }
+
+// ParseSuccessEnvelope reads the top-level "success" flag from a JSON body, if present.
+func ParseSuccessEnvelope(body []byte) *bool {
+	var envelope struct {
+		Success *bool `json:"success"`
+	}
+	if len(body) == 0 || sonic.Unmarshal(body, &envelope) != nil {
+		return nil
+	}
+	return envelope.Success
+}
@@ -0,0 +1,195 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures DoExternalJSONRequestWithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// AttemptTimeout bounds each individual attempt; zero means no extra timeout
+	AttemptTimeout time.Duration
+	// Breaker, when set, guards every attempt and records its outcome
+	Breaker *CircuitBreaker
+	// OnRetry is called before sleeping ahead of the next attempt
+	OnRetry func(attempt int, delay time.Duration, err error)
+	// OnCircuitOpen is called when a failed attempt opens the breaker
+	OnCircuitOpen func(err error)
+}
+
+// RetryAfterTooLongError is returned when a 429 asks to wait longer than MaxDelay.
+type RetryAfterTooLongError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RetryAfterTooLongError) Error() string {
+	return fmt.Sprintf("retry-after %s exceeds retry policy: %v", e.RetryAfter, e.Err)
+}
+
+func (e *RetryAfterTooLongError) Unwrap() error {
+	return e.Err
+}
+
+// DoExternalJSONRequestWithRetry performs DoExternalJSONRequest with exponential
+// backoff and jitter. Only idempotent methods without a streaming body are retried.
+// Network errors and 5xx responses are retried and counted by the breaker; 429
+// responses are retried after Retry-After but do not trip the breaker.
+func DoExternalJSONRequestWithRetry(ctx context.Context, client *http.Client, method, endpoint string, options ExternalJSONRequestOptions, policy RetryPolicy) (*ExternalResponse, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 || !isIdempotentMethod(method) || options.Body != nil {
+		maxAttempts = 1
+	}
+
+	var (
+		response *ExternalResponse
+		err      error
+	)
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if policy.Breaker != nil {
+			if breakerErr := policy.Breaker.Allow(); breakerErr != nil {
+				if err != nil {
+					return response, fmt.Errorf("%w (last error: %v)", breakerErr, err)
+				}
+				return nil, breakerErr
+			}
+		}
+
+		response, err = doAttempt(ctx, client, method, endpoint, options, policy.AttemptTimeout)
+		if err == nil {
+			if policy.Breaker != nil {
+				policy.Breaker.RecordSuccess()
+			}
+			return response, nil
+		}
+
+		// The caller gave up; do not blame the host, but free a half-open probe
+		if ctx.Err() != nil {
+			if policy.Breaker != nil {
+				policy.Breaker.ReleaseProbe()
+			}
+			return response, err
+		}
+
+		retryable, countsAsFailure := classifyExternalError(response, err)
+		if policy.Breaker != nil {
+			if countsAsFailure {
+				if policy.Breaker.RecordFailure() && policy.OnCircuitOpen != nil {
+					policy.OnCircuitOpen(err)
+				}
+			} else {
+				// The host answered; release a half-open probe without tripping it
+				policy.Breaker.RecordSuccess()
+			}
+		}
+
+		if !retryable || attempt == maxAttempts {
+			return response, err
+		}
+
+		delay := backoffDelay(policy, attempt)
+		if response != nil && response.StatusCode == http.StatusTooManyRequests {
+			if retryAfter, ok := parseRetryAfter(response.Headers["Retry-After"]); ok {
+				if policy.MaxDelay > 0 && retryAfter > policy.MaxDelay {
+					return response, &RetryAfterTooLongError{RetryAfter: retryAfter, Err: err}
+				}
+				delay = retryAfter
+			}
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return response, err
+}
+
+func doAttempt(ctx context.Context, client *http.Client, method, endpoint string, options ExternalJSONRequestOptions, timeout time.Duration) (*ExternalResponse, error) {
+	if timeout <= 0 {
+		return DoExternalJSONRequest(ctx, client, method, endpoint, options)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return DoExternalJSONRequest(attemptCtx, client, method, endpoint, options)
+}
+
+// classifyExternalError reports whether err is worth retrying and whether it
+// indicates the host is unhealthy.
+func classifyExternalError(response *ExternalResponse, err error) (retryable bool, countsAsFailure bool) {
+	var responseErr *ExternalJSONResponseError
+	if !errors.As(err, &responseErr) {
+		// Transport-level error (timeout, connection refused, reset, ...)
+		return true, true
+	}
+
+	switch {
+	case responseErr.StatusCode == http.StatusTooManyRequests:
+		return true, false
+	case responseErr.StatusCode >= 500:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+func backoffDelay(policy RetryPolicy, attempt int) time.Duration {
+	base := policy.BaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+
+	delay := base << (attempt - 1)
+	if policy.MaxDelay > 0 && (delay > policy.MaxDelay || delay <= 0) {
+		delay = policy.MaxDelay
+	}
+
+	// Equal jitter: keep half of the delay, randomise the other half
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter supports both delta-seconds and HTTP-date forms.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,262 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{"first attempt", RetryPolicy{BaseDelay: 100 * time.Millisecond}, 1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"doubles per attempt", RetryPolicy{BaseDelay: 100 * time.Millisecond}, 3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped at max delay", RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}, 5, 150 * time.Millisecond, 300 * time.Millisecond},
+		{"overflow falls back to max delay", RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}, 80, 30 * time.Second, time.Minute},
+		{"default base delay", RetryPolicy{}, 1, 250 * time.Millisecond, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				delay := backoffDelay(tt.policy, tt.attempt)
+				if delay < tt.wantMin || delay > tt.wantMax {
+					t.Fatalf("got delay %s, want between %s and %s", delay, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantOK  bool
+		approx  bool
+		wantMin time.Duration
+	}{
+		{name: "empty", value: "", wantOK: false},
+		{name: "seconds", value: "7", want: 7 * time.Second, wantOK: true},
+		{name: "zero seconds", value: "0", want: 0, wantOK: true},
+		{name: "negative seconds", value: "-3", wantOK: false},
+		{name: "garbage", value: "soon", wantOK: false},
+		{name: "past http date", value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+		{name: "future http date", value: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), wantOK: true, approx: true, wantMin: 58 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if tt.approx {
+				if got < tt.wantMin || got > time.Hour {
+					t.Fatalf("got %s, want between %s and 1h", got, tt.wantMin)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const openTimeout = 20 * time.Millisecond
+
+	type step struct {
+		action    string // allow, success, failure, release, wait
+		wantErr   bool
+		wantOpens bool
+		wantState CircuitState
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after consecutive failures",
+			steps: []step{
+				{action: "failure", wantState: CircuitClosed},
+				{action: "failure", wantOpens: true, wantState: CircuitOpen},
+				{action: "allow", wantErr: true, wantState: CircuitOpen},
+			},
+		},
+		{
+			name: "success resets the failure count",
+			steps: []step{
+				{action: "failure", wantState: CircuitClosed},
+				{action: "success", wantState: CircuitClosed},
+				{action: "failure", wantState: CircuitClosed},
+			},
+		},
+		{
+			name: "half-open probe success closes",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure", wantOpens: true, wantState: CircuitOpen},
+				{action: "wait"},
+				{action: "allow", wantState: CircuitHalfOpen},
+				{action: "allow", wantErr: true, wantState: CircuitHalfOpen},
+				{action: "success", wantState: CircuitClosed},
+				{action: "allow", wantState: CircuitClosed},
+			},
+		},
+		{
+			name: "half-open probe failure reopens",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure", wantOpens: true},
+				{action: "wait"},
+				{action: "allow", wantState: CircuitHalfOpen},
+				{action: "failure", wantOpens: true, wantState: CircuitOpen},
+				{action: "allow", wantErr: true, wantState: CircuitOpen},
+			},
+		},
+		{
+			name: "released probe lets the next request probe",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure", wantOpens: true},
+				{action: "wait"},
+				{action: "allow", wantState: CircuitHalfOpen},
+				{action: "release", wantState: CircuitHalfOpen},
+				{action: "allow", wantState: CircuitHalfOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(2, openTimeout)
+			for i, s := range tt.steps {
+				switch s.action {
+				case "allow":
+					err := breaker.Allow()
+					if (err != nil) != s.wantErr || (err != nil && !errors.Is(err, ErrCircuitOpen)) {
+						t.Fatalf("step %d: got Allow error %v, want error %v", i, err, s.wantErr)
+					}
+				case "success":
+					breaker.RecordSuccess()
+				case "failure":
+					if opened := breaker.RecordFailure(); opened != s.wantOpens {
+						t.Fatalf("step %d: got opened %v, want %v", i, opened, s.wantOpens)
+					}
+				case "release":
+					breaker.ReleaseProbe()
+				case "wait":
+					time.Sleep(openTimeout + 5*time.Millisecond)
+				}
+				if s.wantState != "" && breaker.State() != s.wantState {
+					t.Fatalf("step %d (%s): got state %s, want %s", i, s.action, breaker.State(), s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestDoExternalJSONRequestWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retryAfter   string
+		wantAttempts int32
+		wantErr      bool
+		wantTooLong  bool
+		wantState    CircuitState
+	}{
+		{name: "retries 5xx until success", method: http.MethodGet, statuses: []int{503, 502, 200}, wantAttempts: 3, wantState: CircuitClosed},
+		{name: "gives up after max attempts", method: http.MethodGet, statuses: []int{500, 500, 500}, wantAttempts: 3, wantErr: true, wantState: CircuitOpen},
+		{name: "4xx is not retried", method: http.MethodGet, statuses: []int{404}, wantAttempts: 1, wantErr: true, wantState: CircuitClosed},
+		{name: "non-idempotent method is not retried", method: http.MethodPost, statuses: []int{503}, wantAttempts: 1, wantErr: true, wantState: CircuitClosed},
+		{name: "429 honours retry-after without tripping", method: http.MethodGet, statuses: []int{429, 429, 200}, retryAfter: "0", wantAttempts: 3, wantState: CircuitClosed},
+		{name: "429 retry-after beyond max delay", method: http.MethodGet, statuses: []int{429}, retryAfter: "60", wantAttempts: 1, wantErr: true, wantTooLong: true, wantState: CircuitClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+				w.Write([]byte(`{"success":true}`))
+			}))
+			defer server.Close()
+
+			breaker := NewCircuitBreaker(3, time.Minute)
+			_, err := DoExternalJSONRequestWithRetry(context.Background(), server.Client(), tt.method, server.URL, ExternalJSONRequestOptions{}, RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    10 * time.Millisecond,
+				Breaker:     breaker,
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			var tooLong *RetryAfterTooLongError
+			if errors.As(err, &tooLong) != tt.wantTooLong {
+				t.Fatalf("got error %v, want RetryAfterTooLongError %v", err, tt.wantTooLong)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if breaker.State() != tt.wantState {
+				t.Fatalf("got breaker state %s, want %s", breaker.State(), tt.wantState)
+			}
+		})
+	}
+}
+
+func TestParseSuccessEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *bool
+	}{
+		{name: "empty body", body: "", want: nil},
+		{name: "invalid json", body: "<html>", want: nil},
+		{name: "no success key", body: `{"data":[]}`, want: nil},
+		{name: "success null", body: `{"success":null}`, want: nil},
+		{name: "success true", body: `{"success":true,"data":[]}`, want: boolPtr(true)},
+		{name: "success false", body: `{"success":false,"message":"limit"}`, want: boolPtr(false)},
+		{name: "array body", body: `[{"success":true}]`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSuccessEnvelope([]byte(tt.body))
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("got %v, want %v", formatBoolPtr(got), formatBoolPtr(tt.want))
+			}
+		})
+	}
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func formatBoolPtr(v *bool) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return strconv.FormatBool(*v)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -17,7 +19,9 @@ import (
 	exLogger "github.com/WahyuSiddarta/be_saham_go/logger"
 	"github.com/WahyuSiddarta/be_saham_go/middleware"
 	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
 	"github.com/WahyuSiddarta/be_saham_go/router"
+	"github.com/WahyuSiddarta/be_saham_go/verification"
 
 	"github.com/rs/zerolog"
 
@@ -155,20 +159,122 @@ func initializeAPISystem() *api.API {
 }
 
 func initializeCronSystem() *cron.Runner {
-	cronRunner := cron.NewRunner(Logger)
+	cronRunner, err := cron.NewRunner(Logger)
+	if err != nil {
+		handleCriticalError(Logger, "cron runner initialization", err)
+	}
 	Logger.Info().Msg("Cron initialization completed")
 	return cronRunner
 }
 
+func initializePaymentSystem() *payment.Service {
+	paymentService, err := payment.NewService(config.Get().Payment, config.Get().Env, Logger)
+	if errors.Is(err, payment.ErrPaymentsDisabled) {
+		// The rest of the API runs; checkout and webhook routes answer 503
+		Logger.Warn().Err(err).Msg("Payment initialization skipped")
+		return nil
+	}
+	if err != nil {
+		handleCriticalError(Logger, "payment service initialization", err)
+	}
+	Logger.Info().Str("gateway", paymentService.Gateway().Name()).Msg("Payment initialization completed")
+	return paymentService
+}
+
+func initializeEmailVerificationSystem() *verification.Service {
+	verificationService, err := verification.NewService(config.Get().EmailVerification, config.Get().Notification, Logger)
+	if err != nil {
+		handleCriticalError(Logger, "email verification service initialization", err)
+	}
+	Logger.Info().Str("sender", verificationService.Sender().Name()).Msg("Email verification initialization completed")
+	return verificationService
+}
+
+// runReplayCommand re-processes archived datasource payloads without starting
+// the API server or scheduler, e.g. `go run . replay -from 2026-01-01 -ticker TLKM`.
+func runReplayCommand(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	fromFlag := flags.String("from", "", "first archived day to replay (YYYY-MM-DD, default: -to)")
+	toFlag := flags.String("to", "", "last archived day to replay (YYYY-MM-DD, default: today)")
+	tickerFlag := flags.String("ticker", "", "comma-separated tickers to replay (default: all)")
+	_ = flags.Parse(args)
+
+	initializeCoreSystem()
+	cronRunner := initializeCronSystem()
+
+	now := time.Now().UTC()
+	opts := cron.ReplayOptions{To: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+	if *toFlag != "" {
+		to, err := time.Parse(models.SQLDateFormat, *toFlag)
+		if err != nil {
+			handleCriticalError(Logger, "parsing -to", err)
+		}
+		opts.To = to
+	}
+	opts.From = opts.To
+	if *fromFlag != "" {
+		from, err := time.Parse(models.SQLDateFormat, *fromFlag)
+		if err != nil {
+			handleCriticalError(Logger, "parsing -from", err)
+		}
+		opts.From = from
+	}
+	for _, ticker := range strings.Split(*tickerFlag, ",") {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != "" {
+			opts.Tickers = append(opts.Tickers, ticker)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	summary, err := cronRunner.ReplayArchivedPayloads(ctx, opts)
+	if err != nil {
+		handleCriticalError(Logger, "replaying archived payloads", err)
+	}
+
+	middleware.FlushSentry(5)
+	if len(summary.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runSchemaBaselineCommand regenerates the expected datasource payload schemas
+// used for drift detection from known-good payloads.
+func runSchemaBaselineCommand(args []string) {
+	flags := flag.NewFlagSet("schema-baseline", flag.ExitOnError)
+	fixtureDir := flags.String("fixtures", "./datasource", "directory containing earning.json and equities.json")
+	outDir := flags.String("out", "./cron/schemas", "directory to write the schema baselines to")
+	_ = flags.Parse(args)
+
+	if err := cron.WritePayloadSchemaBaselines(*fixtureDir, *outDir); err != nil {
+		handleCriticalError(nil, "writing schema baselines", err)
+	}
+	fmt.Println("Schema baselines written to", *outDir)
+}
+
 func main() {
 	runtime.GOMAXPROCS(2 * runtime.NumCPU())
 	fmt.Println("VCPU Proc :", runtime.NumCPU())
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplayCommand(os.Args[2:])
+			return
+		case "schema-baseline":
+			runSchemaBaselineCommand(os.Args[2:])
+			return
+		}
+	}
+
 	initializeCoreSystem()
 	apiInstance := initializeAPISystem()
 	cronRunner := initializeCronSystem()
+	paymentService := initializePaymentSystem()
+	verificationService := initializeEmailVerificationSystem()
 
-	r := router.New(apiInstance, Logger)
+	r := router.New(apiInstance, Logger, cronRunner, paymentService, verificationService)
 	go func() {
 		if err := r.SetupRoutes(); err != nil {
 			Logger.Fatal().Err(err).Msg("Failed to start server")
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/bytedance/sonic"
+	"github.com/labstack/echo/v4"
+)
+
+// auditRepo records audit events for all handlers
+var auditRepo = models.NewAuditRepository()
+
+// Column sizes of audit_logs (db/audit_logs.sql); longer values are cut so the entry is
+// still recorded
+const (
+	maxAuditEmailLength     = 255
+	maxAuditIPLength        = 64
+	maxAuditRequestIDLength = 100
+)
+
+// AuditEvent describes one audited action. Before and After are stored as JSON and must
+// not carry secrets.
+type AuditEvent struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     interface{}
+	After      interface{}
+	// ActorID and ActorEmail identify the actor when no user is authenticated, e.g. on login
+	ActorID    *int
+	ActorEmail string
+}
+
+// RecordAudit appends event to the audit trail with the authenticated user, client IP and
+// request id of c. Recording is best-effort: failures are logged and reported to Sentry
+// without failing the request.
+func RecordAudit(c echo.Context, event AuditEvent) {
+	entry := &models.AuditLog{
+		Action:     event.Action,
+		ActorID:    event.ActorID,
+		TargetType: optionalString(event.TargetType),
+		TargetID:   optionalString(event.TargetID),
+		IPAddress:  optionalString(truncateString(c.RealIP(), maxAuditIPLength)),
+		RequestID:  optionalString(truncateString(c.Response().Header().Get(echo.HeaderXRequestID), maxAuditRequestIDLength)),
+	}
+	if event.ActorEmail != "" {
+		entry.ActorEmail = optionalString(truncateString(event.ActorEmail, maxAuditEmailLength))
+	}
+	if authUser, err := GetAuthUser(c); err == nil {
+		entry.ActorID = &authUser.ID
+		entry.ActorEmail = &authUser.Email
+		// An impersonating admin is the actor of everything done as the user
+		if impersonation := GetImpersonation(c); impersonation != nil {
+			entry.ActorID = &impersonation.AdminID
+			entry.ActorEmail = &impersonation.AdminEmail
+			entry.ImpersonatedUserID = &authUser.ID
+		}
+	}
+
+	var err error
+	if entry.BeforeData, err = auditJSON(event.Before); err == nil {
+		entry.AfterData, err = auditJSON(event.After)
+	}
+	if err == nil {
+		err = auditRepo.RecordAudit(entry)
+	}
+	if err != nil {
+		Logger.Error().Err(err).Str("action", event.Action).Msg("[RecordAudit] Gagal mencatat audit log")
+		CaptureError(c, err, map[string]string{"action": "record_audit", "audit_action": event.Action}, nil)
+	}
+}
+
+// auditJSON marshals an audit snapshot, nil when there is none.
+func auditJSON(data interface{}) (models.JSONRaw, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := sonic.Marshal(data)
+	if err != nil || string(raw) == "null" {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func truncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -17,9 +17,23 @@ import (
 // JWTClaims represents the claims stored in JWT token
 type JWTClaims struct {
 	UserID int `json:"user_id"`
+	// Impersonation marks a token an admin obtained to act as the user
+	Impersonation *ImpersonationClaims `json:"impersonation,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ImpersonationClaims identifies the admin behind an impersonation token
+type ImpersonationClaims struct {
+	AdminID  int  `json:"admin_id"`
+	ReadOnly bool `json:"read_only"`
+}
+
+// Response headers set when a lapsed premium user is served during the grace period
+const (
+	PremiumGraceHeader     = "X-Premium-Grace"
+	PremiumGraceEndsHeader = "X-Premium-Grace-Ends-At"
+)
+
 // AuthUser represents authenticated user data stored in context
 type AuthUser struct {
 	ID               int               `json:"id"`
@@ -49,11 +63,43 @@ func GenerateToken(userID int) (string, error) {
 		},
 	}
 
+	return signToken(claims)
+}
+
+// GenerateImpersonationToken generates a short-lived token letting adminID act as userID,
+// read-only unless readOnly is false. It returns the token and its expiry.
+func GenerateImpersonationToken(userID, adminID int, readOnly bool) (string, time.Time, error) {
+	now := utime.Utime.Now().ToTime()
+	expiresAt := now.Add(config.Get().JWT.ImpersonationTTL)
+
+	claims := &JWTClaims{
+		UserID: userID,
+		Impersonation: &ImpersonationClaims{
+			AdminID:  adminID,
+			ReadOnly: readOnly,
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "impersonation",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := signToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// signToken signs claims with the JWT secret
+func signToken(claims *JWTClaims) (string, error) {
 	// Create token with claims
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// Sign token with secret
-	tokenString, err := token.SignedString([]byte(cfg.JWT.Secret))
+	tokenString, err := token.SignedString([]byte(config.Get().JWT.Secret))
 	if err != nil {
 		return "", fmt.Errorf("error signing token: %w", err)
 	}
@@ -144,8 +190,8 @@ func AuthMiddleware() echo.MiddlewareFunc {
 				return helper.ErrorResponse(c, http.StatusUnauthorized, "Pengguna tidak ditemukan", nil)
 			}
 
-			// Check if user is active
-			if user.Status != models.UserStatusActive {
+			// Check if user may sign in; unverified users are limited by RequireVerified
+			if !user.Status.CanSignIn() {
 				return helper.ErrorResponse(c, http.StatusForbidden, "Account access denied", nil)
 			}
 
@@ -164,6 +210,10 @@ func AuthMiddleware() echo.MiddlewareFunc {
 			// Set user context in Sentry for error tracking
 			SetUserContext(c, user.ID, user.Email)
 
+			if claims.Impersonation != nil {
+				return serveImpersonated(c, next, claims.Impersonation)
+			}
+
 			return next(c)
 		}
 	}
@@ -174,6 +224,26 @@ func RequireAuth() echo.MiddlewareFunc {
 	return AuthMiddleware()
 }
 
+// RequireVerified returns a middleware that rejects users who have not confirmed their
+// email yet; they keep access to their profile and to resending the verification email
+func RequireVerified() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authUser, ok := c.Get("user").(*AuthUser)
+			if !ok {
+				Logger.Warn().Msg("[RequireVerified] Missing authenticated user in context")
+				return helper.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
+			}
+
+			if authUser.Status == models.UserStatusUnverified {
+				return helper.ErrorResponse(c, http.StatusForbidden, "Verifikasi email diperlukan", map[string]string{"status": string(authUser.Status)})
+			}
+
+			return next(c)
+		}
+	}
+}
+
 // RequirePremium returns a middleware that requires premium subscription
 func RequirePremium() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -191,7 +261,7 @@ func RequirePremium() echo.MiddlewareFunc {
 			}
 
 			// Check if premium subscription has expired
-			if authUser.PremiumExpiresAt != nil && authUser.PremiumExpiresAt.Before(utime.Utime.Now().ToTime()) {
+			if !premiumAccessActive(c, authUser.PremiumExpiresAt) {
 				return helper.ErrorResponse(c, http.StatusForbidden, "Premium subscription expired", nil)
 			}
 
@@ -218,7 +288,7 @@ func RequirePremiumPlus() echo.MiddlewareFunc {
 			}
 
 			// Check if premium+ subscription has expired
-			if authUser.PremiumExpiresAt != nil && authUser.PremiumExpiresAt.Before(utime.Utime.Now().ToTime()) {
+			if !premiumAccessActive(c, authUser.PremiumExpiresAt) {
 				Logger.Warn().Str("user_level", string(authUser.UserLevel)).Msg("[RequirePremiumPlus] Premium+ subscription expired")
 
 				return helper.ErrorResponse(c, http.StatusForbidden, "Premium+ subscription expired", nil)
@@ -229,6 +299,25 @@ func RequirePremiumPlus() echo.MiddlewareFunc {
 	}
 }
 
+// premiumAccessActive reports whether a subscription expiring at expiresAt still grants
+// access. Within PREMIUM_GRACE_PERIOD after expiry access is kept and the response is
+// flagged with the premium grace headers.
+func premiumAccessActive(c echo.Context, expiresAt *time.Time) bool {
+	now := utime.Utime.Now().ToTime()
+	if expiresAt == nil || !expiresAt.Before(now) {
+		return true
+	}
+
+	graceEndsAt := expiresAt.Add(config.Get().Premium.GracePeriod)
+	if !graceEndsAt.After(now) {
+		return false
+	}
+
+	c.Response().Header().Set(PremiumGraceHeader, "true")
+	c.Response().Header().Set(PremiumGraceEndsHeader, graceEndsAt.UTC().Format(time.RFC3339))
+	return true
+}
+
 // GetAuthUser retrieves authenticated user from context
 func GetAuthUser(c echo.Context) (*AuthUser, error) {
 	user, ok := c.Get("user").(*AuthUser)
@@ -271,6 +360,11 @@ func OptionalAuth() echo.MiddlewareFunc {
 				return next(c)
 			}
 
+			// Impersonation is only served by AuthMiddleware, which audits it
+			if claims.Impersonation != nil {
+				return next(c)
+			}
+
 			// Get user from database
 			userRepo := models.NewUserRepository()
 			user, err := userRepo.FindByID(claims.UserID)
@@ -279,8 +373,8 @@ func OptionalAuth() echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			// Check if user is active
-			if user.Status != models.UserStatusActive {
+			// Check if user may sign in
+			if !user.Status.CanSignIn() {
 				// User not active, continue without setting user context
 				return next(c)
 			}
@@ -301,39 +395,3 @@ func OptionalAuth() echo.MiddlewareFunc {
 		}
 	}
 }
-
-// AdminRequired middleware that requires admin privileges
-func AdminRequired() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// For now, this is a placeholder. In a real implementation,
-			// you would check for admin role in the user model or JWT claims
-
-			authUser, err := GetAuthUser(c)
-			if err != nil {
-				Logger.Warn().Err(err).Msg("[AdminRequired] Authentication required")
-				return helper.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
-			}
-
-			// TODO: Implement proper admin role checking
-			// For now, we'll use a simple check based on user ID or email
-			// In production, you should have a proper role system
-
-			adminEmails := []string{"admin@example.com", "superadmin@example.com"}
-			isAdmin := false
-			for _, adminEmail := range adminEmails {
-				if authUser.Email == adminEmail {
-					isAdmin = true
-					break
-				}
-			}
-
-			if !isAdmin {
-				Logger.Warn().Str("email", authUser.Email).Msg("[AdminRequired] Non-admin user attempted admin route")
-				return helper.ErrorResponse(c, http.StatusForbidden, "Admin access required", nil)
-			}
-
-			return next(c)
-		}
-	}
-}
@@ -22,6 +22,7 @@ func ConfigureCORS() echo.MiddlewareFunc {
 		AllowMethods:     []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
 		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
 		AllowCredentials: true,
+		ExposeHeaders:    []string{PremiumGraceHeader, PremiumGraceEndsHeader},
 	})
 }
 
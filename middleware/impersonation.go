@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/labstack/echo/v4"
+)
+
+// ImpersonatedByHeader is set on responses served to an impersonation token, carrying the admin id
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// Impersonation describes an admin acting as the authenticated user
+type Impersonation struct {
+	AdminID    int    `json:"admin_id"`
+	AdminEmail string `json:"admin_email"`
+	ReadOnly   bool   `json:"read_only"`
+}
+
+// GetImpersonation returns the impersonation behind the current request, or nil when the
+// user is acting for themselves.
+func GetImpersonation(c echo.Context) *Impersonation {
+	impersonation, _ := c.Get("impersonation").(*Impersonation)
+	return impersonation
+}
+
+// serveImpersonated serves a request made with an impersonation token. The admin must
+// still be active and allowed to impersonate, read-only sessions may only read, and
+// every request is written to the audit log.
+func serveImpersonated(c echo.Context, next echo.HandlerFunc, claims *ImpersonationClaims) error {
+	admin, err := models.NewUserRepository().FindByID(claims.AdminID)
+	if err != nil {
+		Logger.Error().Err(err).Int("admin_id", claims.AdminID).Msg("[AuthMiddleware] Gagal mengambil data admin impersonasi")
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data pengguna", nil)
+	}
+	if admin == nil || admin.Status != models.UserStatusActive {
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Token tidak valid", nil)
+	}
+
+	permissions, err := roleRepo.GetUserPermissions(admin.ID)
+	if err != nil {
+		Logger.Error().Err(err).Int("admin_id", admin.ID).Msg("[AuthMiddleware] Gagal mengambil izin admin impersonasi")
+		return helper.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data pengguna", nil)
+	}
+	allowed, allowedWrite := false, false
+	for _, permission := range permissions {
+		switch permission {
+		case models.PermissionUsersImpersonate:
+			allowed = true
+		case models.PermissionUsersImpersonateWrite:
+			allowedWrite = true
+		}
+	}
+	if !allowed || (!claims.ReadOnly && !allowedWrite) {
+		Logger.Warn().Int("admin_id", admin.ID).Msg("[AuthMiddleware] Impersonation token of an admin no longer allowed to impersonate")
+		return helper.ErrorResponse(c, http.StatusUnauthorized, "Token tidak valid", nil)
+	}
+
+	c.Set("impersonation", &Impersonation{
+		AdminID:    admin.ID,
+		AdminEmail: admin.Email,
+		ReadOnly:   claims.ReadOnly,
+	})
+	c.Response().Header().Set(ImpersonatedByHeader, strconv.Itoa(admin.ID))
+
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		err = next(c)
+	default:
+		if claims.ReadOnly {
+			err = helper.ErrorResponse(c, http.StatusForbidden, "Sesi impersonasi hanya dapat membaca data", nil)
+		} else {
+			err = next(c)
+		}
+	}
+
+	userID, _ := GetUserID(c)
+	RecordAudit(c, AuditEvent{
+		Action:     models.AuditActionImpersonatedCall,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		After: map[string]interface{}{
+			"method": c.Request().Method,
+			"path":   c.Request().URL.Path,
+			"status": handledStatus(c, err),
+		},
+	})
+
+	return err
+}
+
+// handledStatus returns the status a request is answered with. An error returned before
+// the response was written is only turned into a response later by Echo's error handler,
+// so the status is taken from the error the same way.
+func handledStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// BlockImpersonation returns a middleware that rejects impersonated requests, for
+// sensitive actions only the user themselves may take
+func BlockImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if GetImpersonation(c) != nil {
+				return helper.ErrorResponse(c, http.StatusForbidden, "Tindakan ini tidak tersedia selama impersonasi", nil)
+			}
+			return next(c)
+		}
+	}
+}
@@ -26,6 +26,7 @@ func RequestLogger() echo.MiddlewareFunc {
 						Str("method", c.Request().Method).
 						Str("path", c.Request().URL.Path).
 						Str("remote_ip", c.RealIP()).
+						Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
 						Int("status", c.Response().Status).
 						Dur("duration", duration).
 						Str("user_agent", c.Request().UserAgent()).
@@ -36,6 +37,7 @@ func RequestLogger() echo.MiddlewareFunc {
 						Str("method", c.Request().Method).
 						Str("path", c.Request().URL.Path).
 						Str("remote_ip", c.RealIP()).
+						Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
 						Dur("duration", duration).
 						Err(err).
 						Msg("Request failed")
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/labstack/echo/v4"
+)
+
+// roleRepo resolves the permissions granted by user roles
+var roleRepo = models.NewRoleRepository()
+
+// GetPermissions returns the permissions granted to the authenticated user by their
+// roles, loaded once per request.
+func GetPermissions(c echo.Context) (map[string]bool, error) {
+	if permissions, ok := c.Get("permissions").(map[string]bool); ok {
+		return permissions, nil
+	}
+
+	authUser, err := GetAuthUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := roleRepo.GetUserPermissions(authUser.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading permissions: %w", err)
+	}
+	permissions := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		permissions[code] = true
+	}
+
+	c.Set("permissions", permissions)
+	return permissions, nil
+}
+
+// AdminRequired middleware that requires a staff user, i.e. one holding any permission.
+// Routes behind it check the specific permission with RequirePermission.
+func AdminRequired() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authUser, err := GetAuthUser(c)
+			if err != nil {
+				Logger.Warn().Err(err).Msg("[AdminRequired] Authentication required")
+				return helper.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
+			}
+
+			// Impersonation sessions never reach admin routes, even as an admin
+			if GetImpersonation(c) != nil {
+				return helper.ErrorResponse(c, http.StatusForbidden, "Admin access required", nil)
+			}
+
+			permissions, err := GetPermissions(c)
+			if err != nil {
+				Logger.Error().Err(err).Int("user_id", authUser.ID).Msg("[AdminRequired] Error loading permissions")
+				CaptureError(c, err, map[string]string{"middleware": "AdminRequired"}, nil)
+				return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+			}
+
+			if len(permissions) == 0 {
+				Logger.Warn().Str("email", authUser.Email).Msg("[AdminRequired] Non-admin user attempted admin route")
+				return helper.ErrorResponse(c, http.StatusForbidden, "Admin access required", nil)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequirePermission returns a middleware that requires the authenticated user to hold
+// permission through one of their roles.
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authUser, err := GetAuthUser(c)
+			if err != nil {
+				Logger.Warn().Err(err).Msg("[RequirePermission] Authentication required")
+				return helper.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
+			}
+
+			permissions, err := GetPermissions(c)
+			if err != nil {
+				Logger.Error().Err(err).Int("user_id", authUser.ID).Msg("[RequirePermission] Error loading permissions")
+				CaptureError(c, err, map[string]string{"middleware": "RequirePermission"}, nil)
+				return helper.ErrorResponse(c, http.StatusInternalServerError, "Terjadi kesalahan pada server", nil)
+			}
+
+			if !permissions[permission] {
+				Logger.Warn().Str("email", authUser.Email).Str("permission", permission).Msg("[RequirePermission] Missing permission")
+				return helper.ErrorResponse(c, http.StatusForbidden, "Permission required: "+permission, nil)
+			}
+
+			return next(c)
+		}
+	}
+}
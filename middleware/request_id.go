@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+// validRequestID matches the incoming request ids kept as-is, e.g. set by a load balancer
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID returns a middleware that sets X-Request-Id on the request and response. An
+// incoming id is only kept when it is a short token; anything else is replaced with a
+// generated id so clients cannot inject arbitrary values into logs and the audit trail.
+func RequestID() echo.MiddlewareFunc {
+	requestID := echomw.RequestID()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := requestID(next)
+		return func(c echo.Context) error {
+			header := c.Request().Header
+			if id := header.Get(echo.HeaderXRequestID); id != "" && !validRequestID.MatchString(id) {
+				header.Del(echo.HeaderXRequestID)
+			}
+			return h(c)
+		}
+	}
+}
+
+// clientIPExtractor returns how c.RealIP() finds the client IP. X-Forwarded-For is only
+// honoured from the trusted proxies (IPs or CIDRs); without any, the connection address
+// is used so clients cannot pick their own IP.
+func clientIPExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			Logger.Warn().Err(err).Str("proxy", proxy).Msg("Ignoring invalid trusted proxy")
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"github.com/WahyuSiddarta/be_saham_go/config"
 	sentryecho "github.com/getsentry/sentry-go/echo"
 	"github.com/labstack/echo/v4"
 )
 
 // SetupGlobalMiddleware configures all global middleware for the Echo instance
 func SetupGlobalMiddleware(e *echo.Echo) {
+	// Only trust X-Forwarded-For from the configured proxies for c.RealIP()
+	e.IPExtractor = clientIPExtractor(config.Get().TrustedProxies)
+
 	// Add panic recovery middleware (should be first for safety)
 	e.Use(Recover())
 
@@ -15,6 +19,9 @@ func SetupGlobalMiddleware(e *echo.Echo) {
 		Repanic: false, // Already handled by custom Recover() middleware
 	}))
 
+	// Add request id middleware, echoed in X-Request-Id and recorded in the audit log
+	e.Use(RequestID())
+
 	// Add request logging middleware
 	e.Use(RequestLogger())
 
@@ -24,7 +31,7 @@ func SetupGlobalMiddleware(e *echo.Echo) {
 	// Log middleware setup status
 	LogCORSStatus()
 
-	Logger.Info().Msg("Global middleware configured: Panic Recovery, Sentry, Request Logging, CORS")
+	Logger.Info().Msg("Global middleware configured: Panic Recovery, Sentry, Request ID, Request Logging, CORS")
 }
 
 // SetupAPIMiddleware configures middleware specifically for API routes
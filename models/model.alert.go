@@ -0,0 +1,593 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AlertType is the condition a user alert watches.
+type AlertType string
+
+const (
+	AlertTypePriceAbove         AlertType = "price_above"
+	AlertTypePriceBelow         AlertType = "price_below"
+	AlertTypeDividendYieldAbove AlertType = "dividend_yield_above"
+	AlertTypeEarningsWithinDays AlertType = "earnings_within_days"
+	AlertTypeBondCouponDue      AlertType = "bond_coupon_due"
+	AlertTypeDepositMaturing    AlertType = "deposit_maturing"
+)
+
+// AlertTypes lists every supported alert type.
+var AlertTypes = []AlertType{
+	AlertTypePriceAbove,
+	AlertTypePriceBelow,
+	AlertTypeDividendYieldAbove,
+	AlertTypeEarningsWithinDays,
+	AlertTypeBondCouponDue,
+	AlertTypeDepositMaturing,
+}
+
+// UserAlert is a user-defined alert rule. ConditionMet and LastTriggerKey hold the
+// evaluator state so an alert fires once per crossing rather than on every run.
+type UserAlert struct {
+	ID              int64      `json:"id" db:"id"`
+	UserID          int        `json:"user_id" db:"user_id"`
+	AlertType       AlertType  `json:"alert_type" db:"alert_type"`
+	Ticker          *string    `json:"ticker" db:"ticker"`
+	PortfolioBondID *int       `json:"portfolio_bond_id" db:"portfolio_bond_id"`
+	PortfolioCashID *int       `json:"portfolio_cash_id" db:"portfolio_cash_id"`
+	Threshold       *float64   `json:"threshold" db:"threshold"`
+	Days            *int       `json:"days" db:"days"`
+	Note            *string    `json:"note" db:"note"`
+	Active          bool       `json:"active" db:"active"`
+	ConditionMet    bool       `json:"condition_met" db:"condition_met"`
+	LastTriggerKey  *string    `json:"-" db:"last_trigger_key"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at" db:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// AlertInboxEntry is a triggered alert delivered to the user's inbox.
+type AlertInboxEntry struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        int        `json:"user_id" db:"user_id"`
+	AlertID       *int64     `json:"alert_id" db:"alert_id"`
+	AlertType     AlertType  `json:"alert_type" db:"alert_type"`
+	Ticker        *string    `json:"ticker" db:"ticker"`
+	Title         string     `json:"title" db:"title"`
+	Message       string     `json:"message" db:"message"`
+	ObservedValue *float64   `json:"observed_value" db:"observed_value"`
+	EventDate     *time.Time `json:"event_date" db:"event_date"`
+	TriggeredAt   time.Time  `json:"triggered_at" db:"triggered_at"`
+	ReadAt        *time.Time `json:"read_at" db:"read_at"`
+}
+
+// AlertInboxResponse is a paginated page of the alert inbox.
+type AlertInboxResponse struct {
+	Alerts      []AlertInboxEntry `json:"alerts"`
+	UnreadCount int               `json:"unread_count"`
+	Pagination  *PaginationInfo   `json:"pagination"`
+}
+
+// AlertEvaluationInput is an active alert joined with the values it watches.
+type AlertEvaluationInput struct {
+	UserAlert
+	Price          *float64   `db:"price"`
+	DividendYield  *float64   `db:"dividend_yield"`
+	NextReportDate *time.Time `db:"next_report_date"`
+	NextCouponDate *time.Time `db:"next_coupon_date"`
+	MaturityDate   *time.Time `db:"maturity_date"`
+	TargetLabel    *string    `db:"target_label"`
+}
+
+// AlertEvaluation is the outcome of evaluating one alert.
+type AlertEvaluation struct {
+	AlertID int64
+	// Previous state, used to guard against concurrent evaluators
+	WasMet  bool
+	WasKey  *string
+	Met     bool
+	Key     *string
+	Fire    bool
+	Trigger *AlertInboxEntry
+}
+
+// Changed reports whether the alert state needs to be written.
+func (e AlertEvaluation) Changed() bool {
+	return e.Fire || e.Met != e.WasMet || !equalStringPointers(e.Key, e.WasKey)
+}
+
+func equalStringPointers(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+var (
+	// ErrAlertInvalid is returned when an alert definition lacks the fields its type needs.
+	ErrAlertInvalid = errors.New("invalid alert definition")
+	// ErrAlertTargetNotFound is returned when the ticker or portfolio an alert watches does not exist.
+	ErrAlertTargetNotFound = errors.New("alert target not found")
+)
+
+// ValidateAlert checks that an alert carries the target and parameter its type needs.
+func ValidateAlert(alert *UserAlert) error {
+	switch alert.AlertType {
+	case AlertTypePriceAbove, AlertTypePriceBelow, AlertTypeDividendYieldAbove:
+		if alert.Ticker == nil || alert.Threshold == nil {
+			return fmt.Errorf("%w: %s needs ticker and threshold", ErrAlertInvalid, alert.AlertType)
+		}
+	case AlertTypeEarningsWithinDays:
+		if alert.Ticker == nil || alert.Days == nil {
+			return fmt.Errorf("%w: %s needs ticker and days", ErrAlertInvalid, alert.AlertType)
+		}
+	case AlertTypeBondCouponDue:
+		if alert.PortfolioBondID == nil || alert.Days == nil {
+			return fmt.Errorf("%w: %s needs portfolio_bond_id and days", ErrAlertInvalid, alert.AlertType)
+		}
+	case AlertTypeDepositMaturing:
+		if alert.PortfolioCashID == nil || alert.Days == nil {
+			return fmt.Errorf("%w: %s needs portfolio_cash_id and days", ErrAlertInvalid, alert.AlertType)
+		}
+	default:
+		return fmt.Errorf("%w: unknown alert type %s", ErrAlertInvalid, alert.AlertType)
+	}
+	return nil
+}
+
+// EvaluateAlert decides whether an alert's condition holds at today and whether it
+// should fire. Threshold alerts fire when the condition turns true and re-arm once it
+// turns false; date alerts fire once per event date entering the window.
+func EvaluateAlert(input AlertEvaluationInput, today time.Time) AlertEvaluation {
+	evaluation := AlertEvaluation{
+		AlertID: input.ID,
+		WasMet:  input.ConditionMet,
+		WasKey:  input.LastTriggerKey,
+	}
+
+	var value *float64
+	var eventDate *time.Time
+	switch input.AlertType {
+	case AlertTypePriceAbove:
+		value = input.Price
+		evaluation.Met = value != nil && input.Threshold != nil && *value >= *input.Threshold
+	case AlertTypePriceBelow:
+		value = input.Price
+		evaluation.Met = value != nil && input.Threshold != nil && *value <= *input.Threshold
+	case AlertTypeDividendYieldAbove:
+		value = input.DividendYield
+		evaluation.Met = value != nil && input.Threshold != nil && *value >= *input.Threshold
+	case AlertTypeEarningsWithinDays:
+		eventDate = input.NextReportDate
+	case AlertTypeBondCouponDue:
+		eventDate = input.NextCouponDate
+	case AlertTypeDepositMaturing:
+		eventDate = input.MaturityDate
+	}
+
+	if eventDate != nil && input.Days != nil {
+		day := truncateToDay(*eventDate)
+		start := truncateToDay(today)
+		evaluation.Met = !day.Before(start) && !day.After(start.AddDate(0, 0, *input.Days))
+		if evaluation.Met {
+			key := day.Format(SQLDateFormat)
+			evaluation.Key = &key
+		}
+	}
+
+	if !evaluation.Met {
+		// Keep the last event key so the same date does not fire twice if it re-enters the window
+		evaluation.Key = input.LastTriggerKey
+		return evaluation
+	}
+
+	if eventDate != nil {
+		evaluation.Fire = !equalStringPointers(evaluation.Key, input.LastTriggerKey)
+	} else {
+		evaluation.Fire = !input.ConditionMet
+	}
+
+	if evaluation.Fire {
+		title, message := alertMessage(input, value, eventDate)
+		evaluation.Trigger = &AlertInboxEntry{
+			UserID:        input.UserID,
+			AlertID:       &input.ID,
+			AlertType:     input.AlertType,
+			Ticker:        input.Ticker,
+			Title:         title,
+			Message:       message,
+			ObservedValue: value,
+			EventDate:     eventDate,
+			TriggeredAt:   today,
+		}
+	}
+	return evaluation
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// alertMessage builds the inbox title and message of a triggered alert.
+func alertMessage(input AlertEvaluationInput, value *float64, eventDate *time.Time) (string, string) {
+	ticker := ""
+	if input.Ticker != nil {
+		ticker = *input.Ticker
+	}
+	label := ticker
+	if input.TargetLabel != nil {
+		label = *input.TargetLabel
+	}
+	date := ""
+	if eventDate != nil {
+		date = eventDate.Format(SQLDateFormat)
+	}
+	threshold, observed := 0.0, 0.0
+	if input.Threshold != nil {
+		threshold = *input.Threshold
+	}
+	if value != nil {
+		observed = *value
+	}
+
+	switch input.AlertType {
+	case AlertTypePriceAbove:
+		return fmt.Sprintf("%s di atas %.2f", ticker, threshold),
+			fmt.Sprintf("Harga %s mencapai %.2f, melewati batas atas %.2f.", ticker, observed, threshold)
+	case AlertTypePriceBelow:
+		return fmt.Sprintf("%s di bawah %.2f", ticker, threshold),
+			fmt.Sprintf("Harga %s turun ke %.2f, melewati batas bawah %.2f.", ticker, observed, threshold)
+	case AlertTypeDividendYieldAbove:
+		return fmt.Sprintf("Dividend yield %s di atas %.2f%%", ticker, threshold),
+			fmt.Sprintf("Dividend yield %s mencapai %.2f%%.", ticker, observed)
+	case AlertTypeEarningsWithinDays:
+		return fmt.Sprintf("Laporan keuangan %s segera rilis", ticker),
+			fmt.Sprintf("%s dijadwalkan merilis laporan keuangan pada %s.", ticker, date)
+	case AlertTypeBondCouponDue:
+		return fmt.Sprintf("Kupon %s segera dibayar", label),
+			fmt.Sprintf("Kupon obligasi %s jatuh tempo pada %s.", label, date)
+	case AlertTypeDepositMaturing:
+		return fmt.Sprintf("Deposito %s segera jatuh tempo", label),
+			fmt.Sprintf("Deposito %s jatuh tempo pada %s.", label, date)
+	}
+	return string(input.AlertType), string(input.AlertType)
+}
+
+// AlertRepository defines operations for user alerts and the alert inbox.
+type AlertRepository interface {
+	ListAlerts(userID int) ([]UserAlert, error)
+	CreateAlert(alert *UserAlert) (*UserAlert, error)
+	UpdateAlert(id int64, userID int, threshold *float64, days *int, note *string, active *bool) (*UserAlert, error)
+	DeleteAlert(id int64, userID int) (bool, error)
+
+	ListInbox(userID, page, limit int, unreadOnly bool) (*AlertInboxResponse, error)
+	MarkInboxRead(id int64, userID int) (bool, error)
+	MarkAllInboxRead(userID int) (int64, error)
+	CreateInboxEntry(entry *AlertInboxEntry) error
+
+	// Evaluator
+	ListAlertsForEvaluation(afterID int64, limit int) ([]AlertEvaluationInput, error)
+	ApplyAlertEvaluations(evaluations []AlertEvaluation, evaluatedAt time.Time) ([]AlertInboxEntry, error)
+}
+
+type alertRepository struct{}
+
+// NewAlertRepository creates a new alert repository.
+func NewAlertRepository() AlertRepository {
+	return &alertRepository{}
+}
+
+func (r *alertRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *alertRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+const userAlertColumns = `id, user_id, alert_type, ticker, portfolio_bond_id, portfolio_cash_id, threshold, days,
+	note, active, condition_met, last_trigger_key, last_triggered_at, created_at, updated_at`
+
+func (r *alertRepository) ListAlerts(userID int) ([]UserAlert, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []UserAlert{}
+	if err := db.Select(&alerts, `SELECT `+userAlertColumns+` FROM user_alerts WHERE user_id = $1 ORDER BY created_at DESC`, userID); err != nil {
+		return nil, fmt.Errorf("error fetching alerts for user %d: %w", userID, err)
+	}
+	return alerts, nil
+}
+
+// CreateAlert stores an alert after checking that its ticker or portfolio exists and
+// belongs to the user.
+func (r *alertRepository) CreateAlert(alert *UserAlert) (*UserAlert, error) {
+	if err := ValidateAlert(alert); err != nil {
+		return nil, err
+	}
+
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	switch {
+	case alert.PortfolioBondID != nil:
+		err = db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM portfolio_bond WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`,
+			*alert.PortfolioBondID, alert.UserID)
+	case alert.PortfolioCashID != nil:
+		err = db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM portfolio_cash WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND has_maturity)`,
+			*alert.PortfolioCashID, alert.UserID)
+	default:
+		err = db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM stock WHERE ticker = $1)`, *alert.Ticker)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking alert target: %w", err)
+	}
+	if !exists {
+		return nil, ErrAlertTargetNotFound
+	}
+
+	query := `INSERT INTO user_alerts (user_id, alert_type, ticker, portfolio_bond_id, portfolio_cash_id, threshold, days, note, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE)
+		RETURNING ` + userAlertColumns
+
+	var created UserAlert
+	if err := db.QueryRowx(query, alert.UserID, alert.AlertType, alert.Ticker, alert.PortfolioBondID, alert.PortfolioCashID,
+		alert.Threshold, alert.Days, alert.Note).StructScan(&created); err != nil {
+		return nil, fmt.Errorf("error creating alert for user %d: %w", alert.UserID, err)
+	}
+	return &created, nil
+}
+
+// UpdateAlert changes an alert's parameters. Changing the threshold or window re-arms it.
+func (r *alertRepository) UpdateAlert(id int64, userID int, threshold *float64, days *int, note *string, active *bool) (*UserAlert, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE user_alerts SET
+			threshold = CASE WHEN threshold IS NULL THEN NULL ELSE COALESCE($3, threshold) END,
+			days = CASE WHEN days IS NULL THEN NULL ELSE COALESCE($4, days) END,
+			note = COALESCE($5, note),
+			active = COALESCE($6, active),
+			condition_met = CASE WHEN $3::numeric IS NOT NULL OR $4::integer IS NOT NULL THEN FALSE ELSE condition_met END,
+			last_trigger_key = CASE WHEN $3::numeric IS NOT NULL OR $4::integer IS NOT NULL THEN NULL ELSE last_trigger_key END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + userAlertColumns
+
+	var alert UserAlert
+	if err := db.QueryRowx(query, id, userID, threshold, days, note, active).StructScan(&alert); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error updating alert %d: %w", id, err)
+	}
+	return &alert, nil
+}
+
+func (r *alertRepository) DeleteAlert(id int64, userID int) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`DELETE FROM user_alerts WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting alert %d: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting alert %d: %w", id, err)
+	}
+	return affected > 0, nil
+}
+
+func (r *alertRepository) ListInbox(userID, page, limit int, unreadOnly bool) (*AlertInboxResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * limit
+	query := `SELECT id, user_id, alert_id, alert_type, ticker, title, message, observed_value, event_date, triggered_at, read_at
+		FROM user_alert_inbox WHERE user_id = $1`
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY triggered_at DESC, id DESC LIMIT $2 OFFSET $3`
+
+	entries := []AlertInboxEntry{}
+	if err := db.Select(&entries, query, userID, limit+1, offset); err != nil { // Fetch one extra to check if there's more data
+		return nil, fmt.Errorf("error fetching alert inbox for user %d: %w", userID, err)
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	var unread int
+	if err := db.Get(&unread, `SELECT COUNT(*) FROM user_alert_inbox WHERE user_id = $1 AND read_at IS NULL`, userID); err != nil {
+		return nil, fmt.Errorf("error counting unread alerts for user %d: %w", userID, err)
+	}
+
+	return &AlertInboxResponse{
+		Alerts:      entries,
+		UnreadCount: unread,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+func (r *alertRepository) MarkInboxRead(id int64, userID int) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`UPDATE user_alert_inbox SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("error marking alert %d as read: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error marking alert %d as read: %w", id, err)
+	}
+	return affected > 0, nil
+}
+
+func (r *alertRepository) MarkAllInboxRead(userID int) (int64, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`UPDATE user_alert_inbox SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error marking alerts as read for user %d: %w", userID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error marking alerts as read for user %d: %w", userID, err)
+	}
+	return affected, nil
+}
+
+// CreateInboxEntry adds an entry to the user's inbox outside of alert evaluation, e.g.
+// an in-app notification.
+func (r *alertRepository) CreateInboxEntry(entry *AlertInboxEntry) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	if err := db.QueryRowx(alertInboxInsertQuery+` RETURNING id`, entry.UserID, entry.AlertID, entry.AlertType, entry.Ticker, entry.Title,
+		entry.Message, entry.ObservedValue, entry.EventDate, entry.TriggeredAt).Scan(&entry.ID); err != nil {
+		return fmt.Errorf("error creating inbox entry for user %d: %w", entry.UserID, err)
+	}
+	return nil
+}
+
+const alertInboxInsertQuery = `
+	INSERT INTO user_alert_inbox (user_id, alert_id, alert_type, ticker, title, message, observed_value, event_date, triggered_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+// ListAlertsForEvaluation returns a batch of active alerts with id > afterID, each
+// joined with the latest price, dividend yield, report date or portfolio date it watches.
+// Price is derived from market cap and shares outstanding.
+func (r *alertRepository) ListAlertsForEvaluation(afterID int64, limit int) ([]AlertEvaluationInput, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + prefixColumns("a", userAlertColumns) + `,
+			m.market_cap / NULLIF(m.shares_outstanding, 0) AS price,
+			m.dividend_yield,
+			m.next_expected_report_date::date AS next_report_date,
+			b.next_coupon_date,
+			c.maturity_date,
+			COALESCE(b.name, c.bank || ' ' || c.account) AS target_label
+		FROM user_alerts a
+		LEFT JOIN stock_overview_metrics m ON m.symbol = a.ticker
+		LEFT JOIN portfolio_bond b ON b.id = a.portfolio_bond_id AND b.deleted_at IS NULL AND b.status = 'active'
+		LEFT JOIN portfolio_cash c ON c.id = a.portfolio_cash_id AND c.deleted_at IS NULL AND c.status = 'active'
+		WHERE a.active = TRUE AND a.id > $1
+		ORDER BY a.id ASC
+		LIMIT $2`
+
+	var inputs []AlertEvaluationInput
+	if err := db.Select(&inputs, query, afterID, limit); err != nil {
+		return nil, fmt.Errorf("error fetching alerts for evaluation: %w", err)
+	}
+	return inputs, nil
+}
+
+// prefixColumns qualifies a comma separated column list with a table alias.
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ApplyAlertEvaluations writes changed alert states and inbox entries in one transaction
+// and returns the entries of the alerts that fired. Each update is guarded by the state
+// the evaluation was based on, so an alert evaluated concurrently twice fires only once.
+func (r *alertRepository) ApplyAlertEvaluations(evaluations []AlertEvaluation, evaluatedAt time.Time) ([]AlertInboxEntry, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for alert evaluation: %w", err)
+	}
+	defer tx.Rollback()
+
+	const updateQuery = `
+		UPDATE user_alerts SET
+			condition_met = $4,
+			last_trigger_key = $5,
+			last_triggered_at = CASE WHEN $6 THEN $7 ELSE last_triggered_at END
+		WHERE id = $1 AND condition_met = $2 AND last_trigger_key IS NOT DISTINCT FROM $3`
+
+	var fired []AlertInboxEntry
+	for _, evaluation := range evaluations {
+		if !evaluation.Changed() {
+			continue
+		}
+
+		result, err := tx.Exec(updateQuery, evaluation.AlertID, evaluation.WasMet, evaluation.WasKey,
+			evaluation.Met, evaluation.Key, evaluation.Fire, evaluatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error updating alert %d state: %w", evaluation.AlertID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("error updating alert %d state: %w", evaluation.AlertID, err)
+		}
+		if affected == 0 || !evaluation.Fire || evaluation.Trigger == nil {
+			continue
+		}
+
+		trigger := *evaluation.Trigger
+		trigger.TriggeredAt = evaluatedAt
+		if err := tx.QueryRowx(alertInboxInsertQuery+` RETURNING id`, trigger.UserID, trigger.AlertID, trigger.AlertType, trigger.Ticker,
+			trigger.Title, trigger.Message, trigger.ObservedValue, trigger.EventDate, trigger.TriggeredAt).Scan(&trigger.ID); err != nil {
+			return nil, fmt.Errorf("error recording triggered alert %d: %w", evaluation.AlertID, err)
+		}
+		fired = append(fired, trigger)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing alert evaluation: %w", err)
+	}
+	return fired, nil
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Audit actions
+const (
+	AuditActionLogin            = "auth.login"
+	AuditActionLoginFailed      = "auth.login_failed"
+	AuditActionPasswordChange   = "user.password_change"
+	AuditActionEmailVerified    = "user.email_verified"
+	AuditActionUserLevelUpdate  = "admin.user_level_update"
+	AuditActionUserStatusUpdate = "admin.user_status_update"
+	AuditActionPromoCodeCreate  = "admin.promo_code_create"
+	AuditActionPromoCodeUpdate  = "admin.promo_code_update"
+	AuditActionPlanCreate       = "admin.subscription_plan_create"
+	AuditActionPlanUpdate       = "admin.subscription_plan_update"
+	AuditActionStockCreate      = "admin.stock_create"
+	AuditActionStockStatus      = "admin.stock_status_update"
+	AuditActionStockApiKey      = "admin.stock_api_key_update"
+	AuditActionStockProfile     = "admin.stock_profile_update"
+	AuditActionStockDelete      = "admin.stock_delete"
+	AuditActionStockIngest      = "admin.stock_ingest"
+	AuditActionStockImport      = "admin.stock_profile_import"
+	AuditActionRoleCreate       = "admin.role_create"
+	AuditActionRoleUpdate       = "admin.role_update"
+	AuditActionUserRolesUpdate  = "admin.user_roles_update"
+	AuditActionImpersonateStart = "admin.impersonation_start"
+	AuditActionImpersonatedCall = "impersonation.request"
+	AuditActionCashPortfolioDel = "portfolio.cash_delete"
+	AuditActionBondPortfolioDel = "portfolio.bond_delete"
+)
+
+// Audit target types
+const (
+	AuditTargetUser             = "user"
+	AuditTargetPromoCode        = "promo_code"
+	AuditTargetSubscriptionPlan = "subscription_plan"
+	AuditTargetStock            = "stock"
+	AuditTargetRole             = "role"
+	AuditTargetPortfolioCash    = "portfolio_cash"
+	AuditTargetPortfolioBond    = "portfolio_bond"
+)
+
+// AuditLog is one entry of the append-only audit trail.
+type AuditLog struct {
+	ID         int64   `json:"id" db:"id"`
+	ActorID    *int    `json:"actor_id,omitempty" db:"actor_id"`
+	ActorEmail *string `json:"actor_email,omitempty" db:"actor_email"`
+	Action     string  `json:"action" db:"action"`
+	TargetType *string `json:"target_type,omitempty" db:"target_type"`
+	TargetID   *string `json:"target_id,omitempty" db:"target_id"`
+	// ImpersonatedUserID is the user the actor was acting as, if any
+	ImpersonatedUserID *int      `json:"impersonated_user_id,omitempty" db:"impersonated_user_id"`
+	BeforeData         JSONRaw   `json:"before,omitempty" db:"before_data"`
+	AfterData          JSONRaw   `json:"after,omitempty" db:"after_data"`
+	IPAddress          *string   `json:"ip_address,omitempty" db:"ip_address"`
+	RequestID          *string   `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows audit log listings; nil fields are ignored.
+type AuditFilter struct {
+	ActorID            *int
+	ImpersonatedUserID *int
+	Action             *string
+	TargetType         *string
+	TargetID           *string
+	RequestID          *string
+	// From and To bound created_at, To exclusive
+	From *time.Time
+	To   *time.Time
+}
+
+// AuditLogsResponse represents a page of audit log entries.
+type AuditLogsResponse struct {
+	Entries    []*AuditLog     `json:"entries"`
+	Pagination *PaginationInfo `json:"pagination"`
+}
+
+// AuditRepository defines operations on the audit trail. Entries can only be added.
+type AuditRepository interface {
+	RecordAudit(entry *AuditLog) error
+	ListAuditLogs(filter AuditFilter, page, limit int) (*AuditLogsResponse, error)
+}
+
+type auditRepository struct{}
+
+// NewAuditRepository creates a new audit repository.
+func NewAuditRepository() AuditRepository {
+	return &auditRepository{}
+}
+
+func (r *auditRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *auditRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// RecordAudit appends an entry to the audit trail.
+func (r *auditRepository) RecordAudit(entry *AuditLog) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_logs
+			(actor_id, actor_email, action, target_type, target_id, impersonated_user_id, before_data, after_data, ip_address, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = db.Exec(query, entry.ActorID, entry.ActorEmail, entry.Action, entry.TargetType, entry.TargetID,
+		entry.ImpersonatedUserID, nullableJSON(entry.BeforeData), nullableJSON(entry.AfterData), entry.IPAddress, entry.RequestID)
+	if err != nil {
+		return fmt.Errorf("error recording audit log %s: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// nullableJSON returns data as a JSONB parameter, NULL when empty.
+func nullableJSON(data JSONRaw) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// ListAuditLogs returns a page of audit entries matching filter, newest first.
+func (r *auditRepository) ListAuditLogs(filter AuditFilter, page, limit int) (*AuditLogsResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != nil {
+		add("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.ImpersonatedUserID != nil {
+		add("impersonated_user_id = $%d", *filter.ImpersonatedUserID)
+	}
+	if filter.Action != nil {
+		add("action = $%d", *filter.Action)
+	}
+	if filter.TargetType != nil {
+		add("target_type = $%d", *filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		add("target_id = $%d", *filter.TargetID)
+	}
+	if filter.RequestID != nil {
+		add("request_id = $%d", *filter.RequestID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit+1, (page-1)*limit)
+	query := fmt.Sprintf(`
+		SELECT * FROM audit_logs
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	var entries []*AuditLog
+	if err := db.Select(&entries, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching audit logs: %w", err)
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		entries = []*AuditLog{}
+	}
+
+	return &AuditLogsResponse{
+		Entries: entries,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrVerificationTokenInvalid is returned for unknown or already used verification tokens.
+	ErrVerificationTokenInvalid = errors.New("invalid verification token")
+	// ErrVerificationTokenExpired is returned for verification tokens past their expiry.
+	ErrVerificationTokenExpired = errors.New("verification token expired")
+)
+
+// EmailVerificationStats summarizes the verification emails sent to a user, for throttling resends.
+type EmailVerificationStats struct {
+	LastSentAt *time.Time `db:"last_sent_at"`
+	// SentCount and FirstSentAt cover the tokens issued since the requested time
+	SentCount   int        `db:"sent_count"`
+	FirstSentAt *time.Time `db:"first_sent_at"`
+}
+
+type emailVerificationToken struct {
+	ID        int64      `db:"id"`
+	UserID    int        `db:"user_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// EmailVerificationRepository defines operations on email verification tokens.
+type EmailVerificationRepository interface {
+	CreateVerificationToken(userID int, tokenHash string, expiresAt time.Time) error
+	GetVerificationStats(userID int, since time.Time) (*EmailVerificationStats, error)
+	VerifyEmail(tokenHash string, now time.Time) (*User, error)
+}
+
+type emailVerificationRepository struct{}
+
+// NewEmailVerificationRepository creates a new email verification repository.
+func NewEmailVerificationRepository() EmailVerificationRepository {
+	return &emailVerificationRepository{}
+}
+
+func (r *emailVerificationRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// CreateVerificationToken stores the hash of a newly issued verification token.
+func (r *emailVerificationRepository) CreateVerificationToken(userID int, tokenHash string, expiresAt time.Time) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO email_verification_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := db.Exec(query, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("error creating verification token: %w", err)
+	}
+	return nil
+}
+
+// GetVerificationStats returns when the last verification email was issued to the user,
+// and how many were issued since since and when the first of those was. It reads the
+// primary so a resend right after sign-up sees the token just created.
+func (r *emailVerificationRepository) GetVerificationStats(userID int, since time.Time) (*EmailVerificationStats, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats EmailVerificationStats
+	query := `SELECT MAX(created_at) AS last_sent_at,
+				COUNT(*) FILTER (WHERE created_at >= $2) AS sent_count,
+				MIN(created_at) FILTER (WHERE created_at >= $2) AS first_sent_at
+			  FROM email_verification_tokens
+			  WHERE user_id = $1`
+	if err := db.Get(&stats, query, userID, since); err != nil {
+		return nil, fmt.Errorf("error fetching verification stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// VerifyEmail consumes a verification token and activates its unverified user. Every
+// outstanding token of the user is used up with it. Users in another status (e.g.
+// suspended by an admin meanwhile) keep that status.
+func (r *emailVerificationRepository) VerifyEmail(tokenHash string, now time.Time) (*User, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var token emailVerificationToken
+	err = tx.Get(&token, `SELECT id, user_id, expires_at, used_at
+						  FROM email_verification_tokens
+						  WHERE token_hash = $1
+						  FOR UPDATE`, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, fmt.Errorf("error fetching verification token: %w", err)
+	}
+	if token.UsedAt != nil {
+		return nil, ErrVerificationTokenInvalid
+	}
+	if !token.ExpiresAt.After(now) {
+		return nil, ErrVerificationTokenExpired
+	}
+
+	if _, err := tx.Exec(`UPDATE email_verification_tokens SET used_at = $2
+						  WHERE user_id = $1 AND used_at IS NULL`, token.UserID, now); err != nil {
+		return nil, fmt.Errorf("error using verification tokens: %w", err)
+	}
+
+	var user User
+	query := `UPDATE users
+			  SET status = CASE WHEN status = $2 THEN $3::user_status ELSE status END,
+				  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1
+			  RETURNING id, email, status, user_level, premium_expires_at, created_at, updated_at`
+	if err := tx.Get(&user, query, token.UserID, UserStatusUnverified, UserStatusActive); err != nil {
+		return nil, fmt.Errorf("error activating user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	IngestionRunStatusRunning   = "running"
+	IngestionRunStatusCompleted = "completed"
+	IngestionRunStatusCanceled  = "canceled"
+)
+
+const (
+	IngestionTriggerSchedule    = "schedule"
+	IngestionTriggerRetryFailed = "retry_failed"
+	IngestionTriggerManual      = "manual"
+)
+
+const (
+	IngestionItemStatusSucceeded = "succeeded"
+	IngestionItemStatusFailed    = "failed"
+	IngestionItemStatusSkipped   = "skipped"
+)
+
+// IngestionRun is one execution of a stock ingestion job.
+type IngestionRun struct {
+	ID             int64      `json:"id" db:"id"`
+	Job            string     `json:"job" db:"job"`
+	Trigger        string     `json:"trigger" db:"trigger"`
+	ParentRunID    *int64     `json:"parent_run_id" db:"parent_run_id"`
+	Status         string     `json:"status" db:"status"`
+	Provider       string     `json:"provider" db:"provider"`
+	TotalCount     int        `json:"total_count" db:"total_count"`
+	SucceededCount int        `json:"succeeded_count" db:"succeeded_count"`
+	FailedCount    int        `json:"failed_count" db:"failed_count"`
+	SkippedCount   int        `json:"skipped_count" db:"skipped_count"`
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at" db:"finished_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IngestionRunItem is the outcome for a single ticker within a run.
+type IngestionRunItem struct {
+	ID            int64     `json:"id" db:"id"`
+	RunID         int64     `json:"run_id" db:"run_id"`
+	Ticker        string    `json:"ticker" db:"ticker"`
+	Status        string    `json:"status" db:"status"`
+	ErrorAction   *string   `json:"error_action" db:"error_action"`
+	FailedActions *string   `json:"failed_actions" db:"failed_actions"`
+	ErrorMessage  *string   `json:"error_message" db:"error_message"`
+	SkipReason    *string   `json:"skip_reason" db:"skip_reason"`
+	StartedAt     time.Time `json:"started_at" db:"started_at"`
+	DurationMs    int64     `json:"duration_ms" db:"duration_ms"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// IngestionRunDetail is a run together with its per-ticker items.
+type IngestionRunDetail struct {
+	Run          *IngestionRun             `json:"run"`
+	Items        []IngestionRunItem        `json:"items"`
+	SchemaDrifts []IngestionRunSchemaDrift `json:"schema_drifts"`
+}
+
+// IngestionRunsResponse is a paginated list of runs.
+type IngestionRunsResponse struct {
+	Runs       []IngestionRun  `json:"runs"`
+	Pagination *PaginationInfo `json:"pagination"`
+}
+
+// IngestionRunRepository persists ingestion run history.
+type IngestionRunRepository interface {
+	CreateRun(run *IngestionRun) (*IngestionRun, error)
+	FinishRun(id int64, status string, succeeded, failed, skipped int, finishedAt time.Time) error
+	InsertRunItem(item *IngestionRunItem) error
+	GetRuns(page, limit int, status, trigger *string) (*IngestionRunsResponse, error)
+	GetRunByID(id int64) (*IngestionRun, error)
+	GetRunItems(runID int64, status *string) ([]IngestionRunItem, error)
+	GetLatestRunPendingRetry(job string) (*IngestionRun, error)
+	GetFailedStocksForRun(runID int64) ([]StockInformation, error)
+
+	// Schema drift detected while ingesting
+	RecordSchemaDriftSignature(signature, endpoint string, drifts []byte, seenAt time.Time) (bool, error)
+	InsertRunSchemaDrift(drift *IngestionRunSchemaDrift) error
+	GetRunSchemaDrifts(runID int64) ([]IngestionRunSchemaDrift, error)
+}
+
+type ingestionRunRepository struct{}
+
+func NewIngestionRunRepository() IngestionRunRepository {
+	return &ingestionRunRepository{}
+}
+
+func (r *ingestionRunRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *ingestionRunRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+const ingestionRunColumns = `id, job, trigger, parent_run_id, status, provider, total_count, succeeded_count,
+	failed_count, skipped_count, started_at, finished_at, created_at`
+
+func (r *ingestionRunRepository) CreateRun(run *IngestionRun) (*IngestionRun, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO ingestion_runs (job, trigger, parent_run_id, status, provider, total_count, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + ingestionRunColumns
+
+	var created IngestionRun
+	err = db.QueryRowx(query, run.Job, run.Trigger, run.ParentRunID, IngestionRunStatusRunning, run.Provider, run.TotalCount, run.StartedAt).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ingestion run: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *ingestionRunRepository) FinishRun(id int64, status string, succeeded, failed, skipped int, finishedAt time.Time) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	const query = `UPDATE ingestion_runs
+		SET status = $2, succeeded_count = $3, failed_count = $4, skipped_count = $5, finished_at = $6
+		WHERE id = $1`
+
+	if _, err := db.Exec(query, id, status, succeeded, failed, skipped, finishedAt); err != nil {
+		return fmt.Errorf("error finishing ingestion run %d: %w", id, err)
+	}
+	return nil
+}
+
+func (r *ingestionRunRepository) InsertRunItem(item *IngestionRunItem) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	const query = `INSERT INTO ingestion_run_items
+		(run_id, ticker, status, error_action, failed_actions, error_message, skip_reason, started_at, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = db.Exec(query, item.RunID, item.Ticker, item.Status, item.ErrorAction, item.FailedActions,
+		item.ErrorMessage, item.SkipReason, item.StartedAt, item.DurationMs)
+	if err != nil {
+		return fmt.Errorf("error inserting ingestion run item for %s: %w", item.Ticker, err)
+	}
+	return nil
+}
+
+func (r *ingestionRunRepository) GetRuns(page, limit int, status, trigger *string) (*IngestionRunsResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * limit
+	args := []interface{}{}
+	argCount := 0
+
+	query := `SELECT ` + ingestionRunColumns + ` FROM ingestion_runs WHERE 1=1`
+
+	if status != nil {
+		argCount++
+		query += fmt.Sprintf(" AND status = $%d", argCount)
+		args = append(args, *status)
+	}
+
+	if trigger != nil {
+		argCount++
+		query += fmt.Sprintf(" AND trigger = $%d", argCount)
+		args = append(args, *trigger)
+	}
+
+	argCount++
+	args = append(args, limit+1) // Fetch one extra to check if there's more data
+	argCount++
+	args = append(args, offset)
+	query += fmt.Sprintf(" ORDER BY started_at DESC, id DESC LIMIT $%d OFFSET $%d", argCount-1, argCount)
+
+	runs := []IngestionRun{}
+	if err := db.Select(&runs, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching ingestion runs: %w", err)
+	}
+
+	hasMore := len(runs) > limit
+	if hasMore {
+		runs = runs[:limit]
+	}
+
+	return &IngestionRunsResponse{
+		Runs: runs,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+func (r *ingestionRunRepository) GetRunByID(id int64) (*IngestionRun, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var run IngestionRun
+	err = db.Get(&run, `SELECT `+ingestionRunColumns+` FROM ingestion_runs WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching ingestion run %d: %w", id, err)
+	}
+	return &run, nil
+}
+
+func (r *ingestionRunRepository) GetRunItems(runID int64, status *string) ([]IngestionRunItem, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{runID}
+	query := `SELECT id, run_id, ticker, status, error_action, failed_actions, error_message, skip_reason,
+		started_at, duration_ms, created_at
+		FROM ingestion_run_items WHERE run_id = $1`
+	if status != nil {
+		query += " AND status = $2"
+		args = append(args, *status)
+	}
+	query += " ORDER BY ticker ASC"
+
+	items := []IngestionRunItem{}
+	if err := db.Select(&items, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching ingestion run items for run %d: %w", runID, err)
+	}
+	return items, nil
+}
+
+// GetLatestRunPendingRetry returns the most recent finished scheduled run of job that
+// had failed tickers and has not been retried yet, or nil when there is none. Retry and
+// manual runs are never retried themselves.
+func (r *ingestionRunRepository) GetLatestRunPendingRetry(job string) (*IngestionRun, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + ingestionRunColumns + ` FROM ingestion_runs r
+		WHERE r.job = $1
+		  AND r.trigger = $3
+		  AND r.status <> $2
+		  AND r.failed_count > 0
+		  AND NOT EXISTS (SELECT 1 FROM ingestion_runs c WHERE c.parent_run_id = r.id)
+		ORDER BY r.started_at DESC
+		LIMIT 1`
+
+	var run IngestionRun
+	err = db.Get(&run, query, job, IngestionRunStatusRunning, IngestionTriggerSchedule)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching ingestion run pending retry: %w", err)
+	}
+	return &run, nil
+}
+
+// GetFailedStocksForRun returns the failed tickers of a run with their current decrypted
+// API key; keys that cannot be decrypted are returned empty.
+func (r *ingestionRunRepository) GetFailedStocksForRun(runID int64) ([]StockInformation, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `SELECT s.ticker, s.api_key
+		FROM ingestion_run_items i
+		JOIN stock s ON s.ticker = i.ticker
+		WHERE i.run_id = $1 AND i.status = $2 AND s.api_key IS NOT NULL
+		ORDER BY s.ticker ASC`
+
+	stocks := []StockInformation{}
+	if err := db.Select(&stocks, query, runID, IngestionItemStatusFailed); err != nil {
+		return nil, fmt.Errorf("error fetching failed stocks for run %d: %w", runID, err)
+	}
+	decryptStockApiKeys(stocks, "IngestionRun.GetFailedStocksForRun")
+	return stocks, nil
+}
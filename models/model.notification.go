@@ -0,0 +1,420 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NotificationChannel is a delivery channel of the notification service.
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail    NotificationChannel = "email"
+	NotificationChannelTelegram NotificationChannel = "telegram"
+	NotificationChannelWebPush  NotificationChannel = "web_push"
+	NotificationChannelInApp    NotificationChannel = "in_app"
+)
+
+// NotificationChannels lists every delivery channel in delivery order.
+var NotificationChannels = []NotificationChannel{
+	NotificationChannelInApp,
+	NotificationChannelEmail,
+	NotificationChannelTelegram,
+	NotificationChannelWebPush,
+}
+
+// Notification locales.
+const (
+	NotificationLocaleID = "id"
+	NotificationLocaleEN = "en"
+)
+
+// Notification outbox statuses.
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// NotificationPreferences are a user's locale and enabled channels. Users without a
+// stored row get the table defaults: Indonesian, in-app and email.
+type NotificationPreferences struct {
+	UserID          int     `json:"user_id" db:"user_id"`
+	Email           string  `json:"email" db:"email"`
+	Locale          string  `json:"locale" db:"locale"`
+	EmailEnabled    bool    `json:"email_enabled" db:"email_enabled"`
+	TelegramEnabled bool    `json:"telegram_enabled" db:"telegram_enabled"`
+	TelegramChatID  *string `json:"telegram_chat_id" db:"telegram_chat_id"`
+	WebPushEnabled  bool    `json:"web_push_enabled" db:"web_push_enabled"`
+	InAppEnabled    bool    `json:"in_app_enabled" db:"in_app_enabled"`
+}
+
+// Enabled reports whether the user wants notifications on channel and has the
+// contact details it needs.
+func (p NotificationPreferences) Enabled(channel NotificationChannel) bool {
+	switch channel {
+	case NotificationChannelEmail:
+		return p.EmailEnabled && p.Email != ""
+	case NotificationChannelTelegram:
+		return p.TelegramEnabled && p.TelegramChatID != nil && *p.TelegramChatID != ""
+	case NotificationChannelWebPush:
+		return p.WebPushEnabled
+	case NotificationChannelInApp:
+		return p.InAppEnabled
+	}
+	return false
+}
+
+// Recipient returns the channel address stored on the outbox row, if the channel has one.
+func (p NotificationPreferences) Recipient(channel NotificationChannel) *string {
+	switch channel {
+	case NotificationChannelEmail:
+		return &p.Email
+	case NotificationChannelTelegram:
+		return p.TelegramChatID
+	}
+	return nil
+}
+
+// WebPushServiceHosts are the browser push services subscriptions may point at; an
+// entry starting with a dot matches its subdomains.
+var WebPushServiceHosts = []string{
+	"fcm.googleapis.com",
+	"android.googleapis.com",
+	"updates.push.services.mozilla.com",
+	".notify.windows.com",
+	".push.apple.com",
+}
+
+// IsWebPushServiceEndpoint reports whether endpoint is an https URL on a known push
+// service, so pushes are never sent to arbitrary hosts.
+func IsWebPushServiceEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil || (u.Port() != "" && u.Port() != "443") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range WebPushServiceHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebPushSubscription is a browser push subscription of a user.
+type WebPushSubscription struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Endpoint  string    `json:"endpoint" db:"endpoint"`
+	P256dh    string    `json:"p256dh" db:"p256dh"`
+	Auth      string    `json:"auth" db:"auth"`
+	UserAgent *string   `json:"user_agent" db:"user_agent"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NotificationOutboxEntry is a rendered notification waiting for, or done with, delivery
+// on one channel.
+type NotificationOutboxEntry struct {
+	ID            int64               `json:"id" db:"id"`
+	UserID        int                 `json:"user_id" db:"user_id"`
+	Channel       NotificationChannel `json:"channel" db:"channel"`
+	Event         string              `json:"event" db:"event"`
+	DedupeKey     *string             `json:"dedupe_key" db:"dedupe_key"`
+	Locale        string              `json:"locale" db:"locale"`
+	Recipient     *string             `json:"recipient" db:"recipient"`
+	Subject       string              `json:"subject" db:"subject"`
+	Body          string              `json:"body" db:"body"`
+	URL           *string             `json:"url" db:"url"`
+	Status        string              `json:"status" db:"status"`
+	Attempts      int                 `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time           `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string             `json:"last_error" db:"last_error"`
+	SentAt        *time.Time          `json:"sent_at" db:"sent_at"`
+	CreatedAt     time.Time           `json:"created_at" db:"created_at"`
+}
+
+// NotificationRepository defines operations for notification preferences, web push
+// subscriptions and the outbox.
+type NotificationRepository interface {
+	GetPreferences(userID int) (*NotificationPreferences, error)
+	UpsertPreferences(prefs *NotificationPreferences) (*NotificationPreferences, error)
+
+	ListWebPushSubscriptions(userID int) ([]WebPushSubscription, error)
+	SaveWebPushSubscription(sub *WebPushSubscription) (*WebPushSubscription, error)
+	DeleteWebPushSubscription(userID int, endpoint string) (bool, error)
+	DeleteWebPushSubscriptionByEndpoint(endpoint string) error
+
+	// Outbox
+	EnqueueNotifications(entries []NotificationOutboxEntry) (int, error)
+	ClaimDueNotifications(now, leaseUntil time.Time, limit int) ([]NotificationOutboxEntry, error)
+	MarkNotificationSent(id int64, sentAt time.Time) error
+	MarkNotificationFailed(id int64, lastError string, retryAt *time.Time) error
+}
+
+type notificationRepository struct{}
+
+// NewNotificationRepository creates a new notification repository.
+func NewNotificationRepository() NotificationRepository {
+	return &notificationRepository{}
+}
+
+func (r *notificationRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *notificationRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+const notificationPreferencesQuery = `
+	SELECT u.id AS user_id, u.email,
+		COALESCE(p.locale, 'id') AS locale,
+		COALESCE(p.email_enabled, TRUE) AS email_enabled,
+		COALESCE(p.telegram_enabled, FALSE) AS telegram_enabled,
+		p.telegram_chat_id,
+		COALESCE(p.web_push_enabled, FALSE) AS web_push_enabled,
+		COALESCE(p.in_app_enabled, TRUE) AS in_app_enabled
+	FROM users u
+	LEFT JOIN user_notification_preferences p ON p.user_id = u.id
+	WHERE u.id = $1`
+
+// GetPreferences returns the user's preferences, falling back to the defaults when
+// none are stored, or nil when the user does not exist.
+func (r *notificationRepository) GetPreferences(userID int) (*NotificationPreferences, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs NotificationPreferences
+	if err := db.Get(&prefs, notificationPreferencesQuery, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching notification preferences for user %d: %w", userID, err)
+	}
+	return &prefs, nil
+}
+
+func (r *notificationRepository) UpsertPreferences(prefs *NotificationPreferences) (*NotificationPreferences, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO user_notification_preferences (user_id, locale, email_enabled, telegram_enabled, telegram_chat_id, web_push_enabled, in_app_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			locale = EXCLUDED.locale,
+			email_enabled = EXCLUDED.email_enabled,
+			telegram_enabled = EXCLUDED.telegram_enabled,
+			telegram_chat_id = EXCLUDED.telegram_chat_id,
+			web_push_enabled = EXCLUDED.web_push_enabled,
+			in_app_enabled = EXCLUDED.in_app_enabled,
+			updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := db.Exec(query, prefs.UserID, prefs.Locale, prefs.EmailEnabled, prefs.TelegramEnabled, prefs.TelegramChatID,
+		prefs.WebPushEnabled, prefs.InAppEnabled); err != nil {
+		return nil, fmt.Errorf("error saving notification preferences for user %d: %w", prefs.UserID, err)
+	}
+
+	var saved NotificationPreferences
+	if err := db.Get(&saved, notificationPreferencesQuery, prefs.UserID); err != nil {
+		return nil, fmt.Errorf("error fetching notification preferences for user %d: %w", prefs.UserID, err)
+	}
+	return &saved, nil
+}
+
+func (r *notificationRepository) ListWebPushSubscriptions(userID int) ([]WebPushSubscription, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	subs := []WebPushSubscription{}
+	query := `SELECT id, user_id, endpoint, p256dh, auth, user_agent, created_at
+		FROM user_web_push_subscriptions WHERE user_id = $1 ORDER BY created_at ASC`
+	if err := db.Select(&subs, query, userID); err != nil {
+		return nil, fmt.Errorf("error fetching web push subscriptions for user %d: %w", userID, err)
+	}
+	return subs, nil
+}
+
+// SaveWebPushSubscription stores a subscription; an endpoint that is already known is
+// taken over by the given user with the new keys, since a browser holds one endpoint
+// for whoever is signed in.
+func (r *notificationRepository) SaveWebPushSubscription(sub *WebPushSubscription) (*WebPushSubscription, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO user_web_push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING id, user_id, endpoint, p256dh, auth, user_agent, created_at`
+
+	var saved WebPushSubscription
+	if err := db.QueryRowx(query, sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth, sub.UserAgent).StructScan(&saved); err != nil {
+		return nil, fmt.Errorf("error saving web push subscription for user %d: %w", sub.UserID, err)
+	}
+	return &saved, nil
+}
+
+func (r *notificationRepository) DeleteWebPushSubscription(userID int, endpoint string) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`DELETE FROM user_web_push_subscriptions WHERE user_id = $1 AND endpoint = $2`, userID, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("error deleting web push subscription for user %d: %w", userID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting web push subscription for user %d: %w", userID, err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteWebPushSubscriptionByEndpoint removes a subscription the push service reported as gone.
+func (r *notificationRepository) DeleteWebPushSubscriptionByEndpoint(endpoint string) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM user_web_push_subscriptions WHERE endpoint = $1`, endpoint); err != nil {
+		return fmt.Errorf("error deleting web push subscription: %w", err)
+	}
+	return nil
+}
+
+// EnqueueNotifications inserts outbox entries and returns how many were new; entries
+// whose dedupe key was already enqueued for the user and channel are skipped.
+func (r *notificationRepository) EnqueueNotifications(entries []NotificationOutboxEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	db, err := r.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction for notification outbox: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO notification_outbox (user_id, channel, event, dedupe_key, locale, recipient, subject, body, url, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, channel, dedupe_key) DO NOTHING`
+
+	inserted := 0
+	for _, entry := range entries {
+		result, err := tx.Exec(query, entry.UserID, entry.Channel, entry.Event, entry.DedupeKey, entry.Locale, entry.Recipient,
+			entry.Subject, entry.Body, entry.URL, entry.NextAttemptAt)
+		if err != nil {
+			return 0, fmt.Errorf("error enqueueing %s notification for user %d: %w", entry.Channel, entry.UserID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error enqueueing %s notification for user %d: %w", entry.Channel, entry.UserID, err)
+		}
+		inserted += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing notification outbox: %w", err)
+	}
+	return inserted, nil
+}
+
+// ClaimDueNotifications picks pending entries due at now, counts the attempt and leases
+// them until leaseUntil so a crashed dispatcher's entries are retried after the lease.
+// Concurrent dispatchers never claim the same entry.
+func (r *notificationRepository) ClaimDueNotifications(now, leaseUntil time.Time, limit int) ([]NotificationOutboxEntry, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE notification_outbox SET
+			attempts = attempts + 1,
+			next_attempt_at = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, channel, event, dedupe_key, locale, recipient, subject, body, url,
+			status, attempts, next_attempt_at, last_error, sent_at, created_at`
+
+	var entries []NotificationOutboxEntry
+	if err := db.Select(&entries, query, now, leaseUntil, limit); err != nil {
+		return nil, fmt.Errorf("error claiming due notifications: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *notificationRepository) MarkNotificationSent(id int64, sentAt time.Time) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE notification_outbox SET status = 'sent', sent_at = $2, last_error = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := db.Exec(query, id, sentAt); err != nil {
+		return fmt.Errorf("error marking notification %d as sent: %w", id, err)
+	}
+	return nil
+}
+
+// MarkNotificationFailed records a delivery error and schedules the next attempt at
+// retryAt, or gives up on the entry when retryAt is nil.
+func (r *notificationRepository) MarkNotificationFailed(id int64, lastError string, retryAt *time.Time) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE notification_outbox SET
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_error = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+	if _, err := db.Exec(query, id, lastError, retryAt); err != nil {
+		return fmt.Errorf("error marking notification %d as failed: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,492 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/jmoiron/sqlx"
+)
+
+// Payment statuses
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusCompleted = "completed"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+	PaymentStatusExpired   = "expired"
+	// PaymentStatusNeedsRefund marks a payment the gateway captured money for without it
+	// granting a subscription; staff must refund it
+	PaymentStatusNeedsRefund = "needs_refund"
+)
+
+// Gateway event statuses
+const (
+	PaymentEventPaid    = "paid"
+	PaymentEventFailed  = "failed"
+	PaymentEventExpired = "expired"
+)
+
+// paymentOrderPrefix prefixes payment record ids in the order id sent to gateways
+const paymentOrderPrefix = "PAY-"
+
+var (
+	ErrPaymentNotFound        = errors.New("payment not found")
+	ErrPaymentGatewayMismatch = errors.New("payment belongs to another gateway")
+)
+
+// PaymentOrderID returns the order id a payment record is charged under.
+func PaymentOrderID(paymentID int) string {
+	return paymentOrderPrefix + strconv.Itoa(paymentID)
+}
+
+// ParsePaymentOrderID returns the payment record id of an order id.
+func ParsePaymentOrderID(orderID string) (int, bool) {
+	if !strings.HasPrefix(orderID, paymentOrderPrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(orderID, paymentOrderPrefix))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// PaymentGatewayEvent is a verified webhook notification about a checkout.
+type PaymentGatewayEvent struct {
+	ID              int64     `json:"id" db:"id"`
+	Gateway         string    `json:"gateway" db:"gateway"`
+	EventID         string    `json:"event_id" db:"event_id"`
+	PaymentRecordID *int      `json:"payment_record_id,omitempty" db:"payment_record_id"`
+	EventStatus     string    `json:"event_status" db:"event_status"`
+	Amount          *float64  `json:"amount,omitempty" db:"amount"`
+	PaymentMethod   *string   `json:"payment_method,omitempty" db:"payment_method"`
+	Applied         bool      `json:"applied" db:"applied"`
+	Payload         []byte    `json:"-" db:"payload"`
+	OccurredAt      time.Time `json:"occurred_at" db:"occurred_at"`
+	ReceivedAt      time.Time `json:"received_at" db:"received_at"`
+}
+
+// PaymentEventResult reports what applying a gateway event did.
+type PaymentEventResult struct {
+	Payment *PaymentRecord `json:"payment"`
+	// Duplicate is true when the event id was already processed
+	Duplicate bool `json:"duplicate"`
+	// Applied is true when the event changed the payment status
+	Applied bool `json:"applied"`
+}
+
+// PaymentRepository defines operations for gateway checkouts.
+type PaymentRepository interface {
+	FindPendingCheckout(userID, planID int, promoCode *string, now time.Time) (*PaymentRecord, error)
+	FindUserPayment(userID, paymentID int) (*PaymentRecord, error)
+	CreateCheckoutPayment(payment *PaymentRecord, promoCode *string, now time.Time) (*PaymentRecord, error)
+	AttachCheckout(paymentID int, gateway, reference, checkoutURL string) (*PaymentRecord, error)
+	FailCheckout(paymentID int, reason string) error
+	ApplyGatewayEvent(event *PaymentGatewayEvent) (*PaymentEventResult, error)
+	ExpirePendingPayments(now time.Time) (int64, error)
+}
+
+type paymentRepository struct{}
+
+// NewPaymentRepository creates a new payment repository.
+func NewPaymentRepository() PaymentRepository {
+	return &paymentRepository{}
+}
+
+func (r *paymentRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// FindPendingCheckout returns the user's unexpired pending checkout for the plan and promo
+// code, if any, so repeated checkout requests reuse it instead of opening another charge.
+func (r *paymentRepository) FindPendingCheckout(userID, planID int, promoCode *string, now time.Time) (*PaymentRecord, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var code *string
+	if promoCode != nil {
+		normalized := NormalizePromoCode(*promoCode)
+		code = &normalized
+	}
+
+	query := `
+		SELECT p.* FROM payment_records p
+		LEFT JOIN promo_codes pc ON pc.id = p.promo_code_id
+		WHERE p.user_id = $1 AND p.subscription_plan_id = $2 AND p.payment_status = 'pending'
+		  AND p.checkout_url IS NOT NULL AND p.checkout_expires_at > $3
+		  AND pc.code IS NOT DISTINCT FROM $4::TEXT
+		ORDER BY p.created_at DESC
+		LIMIT 1`
+
+	var payment PaymentRecord
+	if err := db.Get(&payment, query, userID, planID, now, code); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching pending checkout: %w", err)
+	}
+	return &payment, nil
+}
+
+// FindUserPayment returns one payment of the user, or nil when it does not exist.
+func (r *paymentRepository) FindUserPayment(userID, paymentID int) (*PaymentRecord, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var payment PaymentRecord
+	query := `
+		SELECT ` + paymentSelectColumns + `
+		FROM payment_records p
+		LEFT JOIN payment_invoices i ON i.payment_record_id = p.id
+		WHERE p.id = $1 AND p.user_id = $2`
+	if err := db.Get(&payment, query, paymentID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching payment: %w", err)
+	}
+	return &payment, nil
+}
+
+// CreateCheckoutPayment inserts a pending checkout payment for its subscription plan,
+// discounted by promoCode when set and by the upgrade proration credit. The promo code is
+// redeemed in the same transaction. A payment that ends up free is completed right away,
+// as no gateway charge is needed.
+func (r *paymentRepository) CreateCheckoutPayment(payment *PaymentRecord, promoCode *string, now time.Time) (*PaymentRecord, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if payment.SubscriptionPlanID == nil {
+		return nil, ErrSubscriptionPlanNotFound
+	}
+	plan, err := purchasablePlan(tx, *payment.SubscriptionPlanID, payment.SubscriptionType)
+	if err != nil {
+		return nil, err
+	}
+	payment.OriginalPrice = plan.Price
+	payment.PaidPrice = plan.Price
+
+	// Locked so a checkout completed right away prices and applies the credit on the same row
+	var user User
+	if err := tx.Get(&user, `SELECT id, user_level, premium_expires_at FROM users WHERE id = $1 FOR UPDATE`, payment.UserID); err != nil {
+		return nil, fmt.Errorf("error locking user %d: %w", payment.UserID, err)
+	}
+	if premiumLevelRank(plan.UserLevel) < premiumLevelRank(activePremiumLevel(&user, now)) {
+		return nil, ErrSubscriptionPlanDowngrade
+	}
+
+	if promoCode != nil {
+		if err := applyPromoCode(tx, payment, *promoCode, now); err != nil {
+			return nil, err
+		}
+	}
+	credit, err := upgradeProrationCredit(tx, &user, plan.UserLevel, now)
+	if err != nil {
+		return nil, err
+	}
+	applyProrationCredit(payment, credit)
+	// Projected expiry; recomputed from the subscription state when the payment completes
+	payment.ExpiresAt = ExtendPremiumExpiry(&user, plan.DurationMonths, credit > 0, now)
+
+	query := `
+		INSERT INTO payment_records
+			(user_id, subscription_type, original_price, paid_price, discount_amount,
+			 discount_reason, payment_method, payment_status, payment_date, expires_at,
+			 notes, gateway, checkout_expires_at, promo_code_id, subscription_plan_id, proration_credit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending', $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING *`
+
+	var created PaymentRecord
+	err = tx.Get(&created, query, payment.UserID, payment.SubscriptionType, payment.OriginalPrice,
+		payment.PaidPrice, payment.DiscountAmount, payment.DiscountReason, payment.PaymentMethod,
+		payment.PaymentDate, payment.ExpiresAt, payment.Notes, payment.Gateway, payment.CheckoutExpiresAt,
+		payment.PromoCodeID, payment.SubscriptionPlanID, payment.ProrationCredit)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pending payment: %w", err)
+	}
+	if err := recordPromoRedemption(tx, &created); err != nil {
+		return nil, err
+	}
+
+	if created.PaidPrice <= 0 {
+		paymentMethod := "proration"
+		if created.PromoCodeID != nil {
+			paymentMethod = "promo"
+		}
+		if err := completeGatewayPayment(tx, &created, &PaymentGatewayEvent{OccurredAt: now, PaymentMethod: &paymentMethod}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &created, nil
+}
+
+// AttachCheckout stores the gateway charge opened for a pending payment.
+func (r *paymentRepository) AttachCheckout(paymentID int, gateway, reference, checkoutURL string) (*PaymentRecord, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE payment_records
+		SET gateway = $2, gateway_reference = $3, checkout_url = $4
+		WHERE id = $1
+		RETURNING *`
+
+	var payment PaymentRecord
+	if err := db.Get(&payment, query, paymentID, gateway, reference, checkoutURL); err != nil {
+		return nil, fmt.Errorf("error attaching checkout to payment %d: %w", paymentID, err)
+	}
+	return &payment, nil
+}
+
+// FailCheckout marks a pending payment whose charge could not be opened as failed.
+func (r *paymentRepository) FailCheckout(paymentID int, reason string) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE payment_records
+		SET payment_status = 'failed', failure_reason = $2
+		WHERE id = $1 AND payment_status = 'pending'`
+
+	if _, err := db.Exec(query, paymentID, reason); err != nil {
+		return fmt.Errorf("error failing payment %d: %w", paymentID, err)
+	}
+	return nil
+}
+
+// paymentEventApplies reports whether a gateway event changes a payment in status
+// paymentStatus. A paid event may still revive an expired checkout; any event on a
+// closed payment is only recorded.
+func paymentEventApplies(eventStatus, paymentStatus string) (bool, error) {
+	switch eventStatus {
+	case PaymentEventPaid:
+		return paymentStatus == PaymentStatusPending || paymentStatus == PaymentStatusExpired, nil
+	case PaymentEventFailed, PaymentEventExpired:
+		return paymentStatus == PaymentStatusPending, nil
+	default:
+		return false, fmt.Errorf("unknown payment event status %q", eventStatus)
+	}
+}
+
+// ApplyGatewayEvent records a webhook event and applies it to its payment in one
+// transaction. Events are unique per gateway and event id, so a redelivered event is
+// reported as a duplicate without side effects. A paid event completes a pending (or
+// locally expired) payment and extends the user's premium subscription; a payment that
+// already settled is never extended again.
+func (r *paymentRepository) ApplyGatewayEvent(event *PaymentGatewayEvent) (*PaymentEventResult, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+	if event.PaymentRecordID == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var payment PaymentRecord
+	if err := tx.Get(&payment, `SELECT * FROM payment_records WHERE id = $1 FOR UPDATE`, *event.PaymentRecordID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("error locking payment %d: %w", *event.PaymentRecordID, err)
+	}
+	if payment.Gateway == nil || *payment.Gateway != event.Gateway {
+		return nil, ErrPaymentGatewayMismatch
+	}
+
+	applied, err := paymentEventApplies(event.EventStatus, payment.PaymentStatus)
+	if err != nil {
+		return nil, err
+	}
+	result := &PaymentEventResult{Payment: &payment, Applied: applied}
+
+	insertQuery := `
+		INSERT INTO payment_gateway_events
+			(gateway, event_id, payment_record_id, event_status, amount, payment_method, applied, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (gateway, event_id) DO NOTHING
+		RETURNING id`
+
+	var eventID int64
+	err = tx.Get(&eventID, insertQuery, event.Gateway, event.EventID, payment.ID, event.EventStatus,
+		event.Amount, event.PaymentMethod, result.Applied, string(event.Payload), event.OccurredAt)
+	if err == sql.ErrNoRows {
+		return &PaymentEventResult{Payment: &payment, Duplicate: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error recording payment event %s: %w", event.EventID, err)
+	}
+
+	if result.Applied {
+		switch {
+		case event.EventStatus == PaymentEventPaid && event.Amount != nil && math.Abs(*event.Amount-payment.PaidPrice) >= 0.01:
+			// Never grant a subscription for an amount other than the one charged; the
+			// captured money is handed to staff to refund
+			err = failGatewayPayment(tx, &payment, PaymentStatusNeedsRefund,
+				fmt.Sprintf("paid amount %.2f does not match %.2f", *event.Amount, payment.PaidPrice))
+		case event.EventStatus == PaymentEventPaid:
+			err = completeGatewayPayment(tx, &payment, event)
+		case event.EventStatus == PaymentEventExpired:
+			err = failGatewayPayment(tx, &payment, PaymentStatusExpired, "gateway reported expired")
+		default:
+			err = failGatewayPayment(tx, &payment, PaymentStatusFailed, "gateway reported failed")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return result, nil
+}
+
+func failGatewayPayment(tx *sqlx.Tx, payment *PaymentRecord, status, reason string) error {
+	query := `
+		UPDATE payment_records
+		SET payment_status = $2, failure_reason = $3
+		WHERE id = $1
+		RETURNING *`
+	if err := tx.Get(payment, query, payment.ID, status, reason); err != nil {
+		return fmt.Errorf("error updating payment %d to %s: %w", payment.ID, status, err)
+	}
+	return nil
+}
+
+// completeGatewayPayment marks payment completed and extends the user's premium
+// subscription by the payment's plan from the locked user row. The captured payment
+// grants nothing and is moved to needs_refund instead when the user's active subscription
+// is now of a higher tier than the payment (a lower-tier checkout paid after upgrading),
+// when the subscription no longer backs the proration credit priced at checkout (e.g.
+// another upgrade already used it up or it lapsed), or when an expired payment paid late
+// no longer fits the limits of its promo code.
+func completeGatewayPayment(tx *sqlx.Tx, payment *PaymentRecord, event *PaymentGatewayEvent) error {
+	var user User
+	if err := tx.Get(&user, `SELECT id, user_level, premium_expires_at FROM users WHERE id = $1 FOR UPDATE`, payment.UserID); err != nil {
+		return fmt.Errorf("error locking user %d: %w", payment.UserID, err)
+	}
+
+	if current := activePremiumLevel(&user, event.OccurredAt); premiumLevelRank(current) > premiumLevelRank(payment.SubscriptionType) {
+		return failGatewayPayment(tx, payment, PaymentStatusNeedsRefund,
+			fmt.Sprintf("user already holds an active %s subscription", current))
+	}
+
+	if payment.PaymentStatus == PaymentStatusExpired && payment.PromoCodeID != nil {
+		err := checkRevivedPromoRedemption(tx, payment)
+		if errors.Is(err, ErrPromoCodeExhausted) || errors.Is(err, ErrPromoCodeUserLimit) {
+			return failGatewayPayment(tx, payment, PaymentStatusNeedsRefund,
+				fmt.Sprintf("promo code limit reached while the payment was expired: %s", err))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if payment.ProrationCredit > 0 {
+		credit, err := checkoutProrationCredit(tx, &user, payment, event.OccurredAt)
+		if err != nil {
+			return err
+		}
+		if payment.ProrationCredit-credit >= 0.01 {
+			return failGatewayPayment(tx, payment, PaymentStatusNeedsRefund,
+				fmt.Sprintf("proration credit %.2f exceeds the %.2f still available", payment.ProrationCredit, credit))
+		}
+	}
+
+	months, err := paymentPlanDurationMonths(tx, payment)
+	if err != nil {
+		return err
+	}
+	premiumExpiresAt := ExtendPremiumExpiry(&user, months, payment.ProrationCredit > 0, event.OccurredAt)
+	if _, err := tx.Exec(`UPDATE users SET user_level = $1, premium_expires_at = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
+		payment.SubscriptionType, premiumExpiresAt, user.ID); err != nil {
+		return fmt.Errorf("error extending premium for user %d: %w", user.ID, err)
+	}
+
+	paymentMethod := payment.PaymentMethod
+	if event.PaymentMethod != nil && *event.PaymentMethod != "" {
+		paymentMethod = *event.PaymentMethod
+	}
+
+	query := `
+		UPDATE payment_records
+		SET payment_status = 'completed', payment_date = $2, expires_at = $3, payment_method = $4, failure_reason = NULL
+		WHERE id = $1
+		RETURNING *`
+	if err := tx.Get(payment, query, payment.ID, event.OccurredAt, premiumExpiresAt, paymentMethod); err != nil {
+		return fmt.Errorf("error completing payment %d: %w", payment.ID, err)
+	}
+
+	invoiceNumber, err := issuePaymentInvoice(tx, payment.ID, utime.Utime.Now().ToTime())
+	if err != nil {
+		return err
+	}
+	payment.InvoiceNumber = invoiceNumber
+	return nil
+}
+
+// checkoutProrationCredit returns the proration credit payment may still use. The credit
+// is valued at the checkout time, which payment_date holds until the payment completes, so
+// the time the user spent paying does not lower it; the premium subscription must still be
+// active at completion though.
+func checkoutProrationCredit(tx *sqlx.Tx, user *User, payment *PaymentRecord, now time.Time) (float64, error) {
+	if user.PremiumExpiresAt == nil || !user.PremiumExpiresAt.After(now) {
+		return 0, nil
+	}
+	return upgradeProrationCredit(tx, user, payment.SubscriptionType, payment.PaymentDate)
+}
+
+// ExpirePendingPayments moves pending checkouts past their checkout expiry to expired.
+func (r *paymentRepository) ExpirePendingPayments(now time.Time) (int64, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE payment_records
+		SET payment_status = 'expired', failure_reason = 'checkout expired'
+		WHERE payment_status = 'pending' AND checkout_expires_at <= $1`
+
+	result, err := db.Exec(query, now)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring pending payments: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,290 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvoiceUnavailable is returned for payments that have not been paid.
+var ErrInvoiceUnavailable = errors.New("invoice is only available for paid payments")
+
+// paymentSelectColumns selects a payment record with its invoice number, joined as i.
+const paymentSelectColumns = `p.*, i.invoice_number`
+
+// PaymentFilter narrows payment listings; nil fields are ignored.
+type PaymentFilter struct {
+	UserID             *int
+	Status             *string
+	PaymentMethod      *string
+	ProcessedByAdminID *int
+	// From and To bound payment_date, To exclusive
+	From *time.Time
+	To   *time.Time
+}
+
+// AdminPaymentRecord is a payment with the emails of its user and processing admin.
+type AdminPaymentRecord struct {
+	PaymentRecord
+	UserEmail             string  `json:"user_email" db:"user_email"`
+	ProcessedByAdminEmail *string `json:"processed_by_admin_email,omitempty" db:"processed_by_admin_email"`
+}
+
+// PaymentsResponse represents a page of the user's payments.
+type PaymentsResponse struct {
+	Payments   []*PaymentRecord `json:"payments"`
+	Pagination *PaginationInfo  `json:"pagination"`
+}
+
+// AdminPaymentsResponse represents a page of payments across users.
+type AdminPaymentsResponse struct {
+	Payments   []*AdminPaymentRecord `json:"payments"`
+	Pagination *PaginationInfo       `json:"pagination"`
+}
+
+// PaymentInvoice is the invoice issued for a paid payment.
+type PaymentInvoice struct {
+	InvoiceNumber string         `json:"invoice_number" db:"invoice_number"`
+	IssuedAt      time.Time      `json:"issued_at" db:"issued_at"`
+	UserEmail     string         `json:"user_email" db:"user_email"`
+	Payment       *PaymentRecord `json:"payment" db:"-"`
+}
+
+// PaymentHistoryRepository defines read operations on payment records and invoices.
+type PaymentHistoryRepository interface {
+	ListUserPayments(userID, page, limit int, status *string) (*PaymentsResponse, error)
+	ListPayments(filter PaymentFilter, page, limit int) (*AdminPaymentsResponse, error)
+	GetPaymentInvoice(paymentID int, userID *int) (*PaymentInvoice, error)
+}
+
+type paymentHistoryRepository struct{}
+
+// NewPaymentHistoryRepository creates a new payment history repository.
+func NewPaymentHistoryRepository() PaymentHistoryRepository {
+	return &paymentHistoryRepository{}
+}
+
+func (r *paymentHistoryRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *paymentHistoryRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// paymentFilterClause builds the WHERE conditions of filter on payment_records p.
+func paymentFilterClause(filter PaymentFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != nil {
+		add("p.user_id = $%d", *filter.UserID)
+	}
+	if filter.Status != nil {
+		add("p.payment_status = $%d", *filter.Status)
+	}
+	if filter.PaymentMethod != nil {
+		add("p.payment_method = $%d", *filter.PaymentMethod)
+	}
+	if filter.ProcessedByAdminID != nil {
+		add("p.processed_by_admin_id = $%d", *filter.ProcessedByAdminID)
+	}
+	if filter.From != nil {
+		add("p.payment_date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("p.payment_date < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListUserPayments returns a page of the user's payments, newest first.
+func (r *paymentHistoryRepository) ListUserPayments(userID, page, limit int, status *string) (*PaymentsResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := paymentFilterClause(PaymentFilter{UserID: &userID, Status: status})
+	args = append(args, limit+1, (page-1)*limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM payment_records p
+		LEFT JOIN payment_invoices i ON i.payment_record_id = p.id
+		%s
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $%d OFFSET $%d`, paymentSelectColumns, where, len(args)-1, len(args))
+
+	var payments []*PaymentRecord
+	if err := db.Select(&payments, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching user payments: %w", err)
+	}
+
+	hasMore := len(payments) > limit
+	if hasMore {
+		payments = payments[:limit]
+	}
+	if payments == nil {
+		payments = []*PaymentRecord{}
+	}
+
+	return &PaymentsResponse{
+		Payments: payments,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+// ListPayments returns a page of payments across users matching filter, newest payment first.
+func (r *paymentHistoryRepository) ListPayments(filter PaymentFilter, page, limit int) (*AdminPaymentsResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := paymentFilterClause(filter)
+	args = append(args, limit+1, (page-1)*limit)
+	query := fmt.Sprintf(`
+		SELECT %s, u.email AS user_email, a.email AS processed_by_admin_email
+		FROM payment_records p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN users a ON a.id = p.processed_by_admin_id
+		LEFT JOIN payment_invoices i ON i.payment_record_id = p.id
+		%s
+		ORDER BY p.payment_date DESC, p.id DESC
+		LIMIT $%d OFFSET $%d`, paymentSelectColumns, where, len(args)-1, len(args))
+
+	var payments []*AdminPaymentRecord
+	if err := db.Select(&payments, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching payments: %w", err)
+	}
+
+	hasMore := len(payments) > limit
+	if hasMore {
+		payments = payments[:limit]
+	}
+	if payments == nil {
+		payments = []*AdminPaymentRecord{}
+	}
+
+	return &AdminPaymentsResponse{
+		Payments: payments,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+// GetPaymentInvoice returns the invoice of a paid payment, restricted to userID when
+// set. Payments completed before invoicing existed are issued a number on first access.
+// It returns nil when the payment does not exist.
+func (r *paymentHistoryRepository) GetPaymentInvoice(paymentID int, userID *int) (*PaymentInvoice, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var payment PaymentRecord
+	query := `SELECT * FROM payment_records WHERE id = $1 AND ($2::INTEGER IS NULL OR user_id = $2) FOR UPDATE`
+	if err := tx.Get(&payment, query, paymentID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching payment %d: %w", paymentID, err)
+	}
+	if payment.PaymentStatus != PaymentStatusCompleted && payment.PaymentStatus != PaymentStatusRefunded {
+		return nil, ErrInvoiceUnavailable
+	}
+
+	invoiceNumber, err := issuePaymentInvoice(tx, payment.ID, utime.Utime.Now().ToTime())
+	if err != nil {
+		return nil, err
+	}
+	payment.InvoiceNumber = invoiceNumber
+
+	invoice := PaymentInvoice{Payment: &payment}
+	invoiceQuery := `
+		SELECT i.invoice_number, i.issued_at, u.email AS user_email
+		FROM payment_invoices i
+		JOIN users u ON u.id = $2
+		WHERE i.payment_record_id = $1`
+	if err := tx.Get(&invoice, invoiceQuery, payment.ID, payment.UserID); err != nil {
+		return nil, fmt.Errorf("error fetching invoice of payment %d: %w", payment.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &invoice, nil
+}
+
+// issuePaymentInvoice assigns the next invoice number of the issue year (WIB) to a paid
+// payment and returns it; a payment that already has one keeps it. Numbers come from a
+// per-year counter row updated in the caller's transaction, so they have no gaps. The
+// caller must hold the payment row lock.
+func issuePaymentInvoice(tx *sqlx.Tx, paymentID int, issuedAt time.Time) (*string, error) {
+	var existing string
+	err := tx.Get(&existing, `SELECT invoice_number FROM payment_invoices WHERE payment_record_id = $1`, paymentID)
+	if err == nil {
+		return &existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error fetching invoice of payment %d: %w", paymentID, err)
+	}
+
+	local, _ := helper.TimeInWIB(issuedAt)
+	var sequence int
+	counterQuery := `
+		INSERT INTO payment_invoice_counters (year, last_number)
+		VALUES ($1, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = payment_invoice_counters.last_number + 1
+		RETURNING last_number`
+	if err := tx.Get(&sequence, counterQuery, local.Year()); err != nil {
+		return nil, fmt.Errorf("error allocating invoice number: %w", err)
+	}
+
+	invoiceNumber := FormatInvoiceNumber(local.Year(), sequence)
+	if _, err := tx.Exec(`INSERT INTO payment_invoices (payment_record_id, invoice_number, issued_at) VALUES ($1, $2, $3)`,
+		paymentID, invoiceNumber, issuedAt); err != nil {
+		return nil, fmt.Errorf("error issuing invoice for payment %d: %w", paymentID, err)
+	}
+	return &invoiceNumber, nil
+}
+
+// FormatInvoiceNumber formats the sequence-th invoice of year, e.g. INV/2026/000042.
+func FormatInvoiceNumber(year, sequence int) string {
+	return fmt.Sprintf("INV/%d/%06d", year, sequence)
+}
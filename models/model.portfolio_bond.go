@@ -146,6 +146,7 @@ type PortfolioBondRepository interface {
 
 	UpdateMarketPriceOverride(id int, userID int, marketPrice float64) (*PortfolioBond, error)
 	FindByUserIDWithPotentialGain(userID int) ([]*PortfolioBondWithPotentialGain, error)
+	FindCouponsDueBetween(from, to time.Time) ([]*PortfolioBond, error)
 }
 
 // PortfolioBondCouponRepository defines operations for bond coupons.
@@ -252,6 +253,33 @@ func (r *portfolioBondRepository) FindByUserID(userID int) ([]*PortfolioBond, er
 	return bonds, nil
 }
 
+// FindCouponsDueBetween returns active bonds of all users whose next coupon falls between from and to (inclusive).
+func (r *portfolioBondRepository) FindCouponsDueBetween(from, to time.Time) ([]*PortfolioBond, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+	SELECT 
+		a.id, a.bond_id, a.user_id, a.name, a.purchase_price,
+		a.coupon_rate, a.coupon_frequency, a.next_coupon_date, a.maturity_date, a.quantity, 
+		a.status, a.note, b.market_price, a.market_price_override, a.market_price_override_date,
+		a.created_at, a.updated_at, a.deleted_at, a.secondary_market 
+	FROM portfolio_bond a LEFT JOIN bond_tracker b ON a.bond_id = b.bond_id 
+	WHERE a.status = 'active' AND a.deleted_at IS NULL AND a.next_coupon_date BETWEEN $1 AND $2
+	ORDER BY a.next_coupon_date ASC, a.id ASC`
+
+	var bonds []*PortfolioBond
+	err = db.Select(&bonds, query, from.Format(SQLDateFormat), to.Format(SQLDateFormat))
+	if err != nil {
+		Logger.Error().Err(err).Msg("[PortfolioBond.FindCouponsDueBetween] Error querying bonds")
+		return nil, fmt.Errorf("kesalahan mengambil kupon obligasi: %w", err)
+	}
+
+	return bonds, nil
+}
+
 func (r *portfolioBondRepository) FindByID(id int, userID int) (*PortfolioBond, error) {
 	db, err := r.getDB()
 	if err != nil {
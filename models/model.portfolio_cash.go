@@ -86,6 +86,8 @@ type PortfolioCashRepository interface {
 	UpdatePnlEntry(id, userID int, amount *float64, realizedAt *time.Time) (*PortfolioPnlRealizedCash, error)
 	DeletePnlEntry(id, userID int) error
 	GetPnlSummary(userID int) (*PnlSummary, error)
+
+	FindMaturingBetween(from, to time.Time) ([]*PortfolioCash, error)
 }
 
 type portfolioCashRepository struct{}
@@ -192,6 +194,33 @@ func (r *portfolioCashRepository) FindByUserID(userID int) ([]*PortfolioCash, er
 	return portfolios, nil
 }
 
+// FindMaturingBetween retrieves active cash portfolios of all users maturing between from and to (inclusive)
+func (r *portfolioCashRepository) FindMaturingBetween(from, to time.Time) ([]*PortfolioCash, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, user_id, account, bank, amount, yield_rate, yield_period,
+		       yield_frequency_type, yield_frequency_value, yield_payment_type,
+		       has_maturity, maturity_date, note, status, category, created_at, updated_at, deleted_at
+		FROM portfolio_cash
+		WHERE has_maturity = TRUE AND status = 'active' AND deleted_at IS NULL
+		  AND maturity_date BETWEEN $1 AND $2
+		ORDER BY maturity_date ASC, id ASC
+	`
+
+	var portfolios []*PortfolioCash
+	err = db.Select(&portfolios, query, from.Format(SQLDateFormat), to.Format(SQLDateFormat))
+	if err != nil {
+		Logger.Error().Err(err).Msg("Error finding maturing cash portfolios")
+		return nil, fmt.Errorf("kesalahan mengambil portfolio kas jatuh tempo: %w", err)
+	}
+
+	return portfolios, nil
+}
+
 // FindByID retrieves a specific cash portfolio entry
 func (r *portfolioCashRepository) FindByID(id int, userID int) (*PortfolioCash, error) {
 	db, err := r.getDB()
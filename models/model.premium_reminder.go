@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PremiumExpiryCandidate is a premium user whose subscription ends within the reminder window.
+type PremiumExpiryCandidate struct {
+	UserID           int       `db:"user_id"`
+	UserLevel        UserLevel `db:"user_level"`
+	PremiumExpiresAt time.Time `db:"premium_expires_at"`
+	// LastReminderDays is the smallest offset already sent for this subscription period
+	LastReminderDays *int `db:"last_reminder_days"`
+}
+
+// PremiumExpiryReminder is a reminder recorded as sent.
+type PremiumExpiryReminder struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	DaysBefore int       `json:"days_before" db:"days_before"`
+	DaysLeft   int       `json:"days_left" db:"days_left"`
+	SentAt     time.Time `json:"sent_at" db:"sent_at"`
+}
+
+// DaysUntil returns the number of calendar days from now until t, in now's location.
+func DaysUntil(now, t time.Time) int {
+	t = t.In(now.Location())
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// DuePremiumReminder picks the reminder offset to send for a subscription daysLeft days
+// from expiry: the smallest configured offset not below daysLeft. A missed run therefore
+// sends one catch-up reminder rather than several. It reports false when that offset, or
+// a closer one, was already sent.
+func DuePremiumReminder(daysLeft int, offsets []int, lastSent *int) (int, bool) {
+	sorted := append([]int(nil), offsets...)
+	sort.Ints(sorted)
+	for _, offset := range sorted {
+		if offset < daysLeft {
+			continue
+		}
+		if lastSent != nil && *lastSent <= offset {
+			return 0, false
+		}
+		return offset, true
+	}
+	return 0, false
+}
+
+// PremiumReminderRepository defines operations for premium expiry reminders.
+type PremiumReminderRepository interface {
+	ListPremiumExpiryCandidates(now, until time.Time) ([]PremiumExpiryCandidate, error)
+	RecordPremiumExpiryReminder(reminder *PremiumExpiryReminder) (bool, error)
+}
+
+type premiumReminderRepository struct{}
+
+// NewPremiumReminderRepository creates a new premium reminder repository.
+func NewPremiumReminderRepository() PremiumReminderRepository {
+	return &premiumReminderRepository{}
+}
+
+func (r *premiumReminderRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// ListPremiumExpiryCandidates returns active premium users expiring after now and up to
+// until, with the closest reminder already sent for their current period.
+func (r *premiumReminderRepository) ListPremiumExpiryCandidates(now, until time.Time) ([]PremiumExpiryCandidate, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT u.id AS user_id, u.user_level, u.premium_expires_at, MIN(r.days_before) AS last_reminder_days
+		FROM users u
+		LEFT JOIN premium_expiry_reminders r ON r.user_id = u.id AND r.expires_at = u.premium_expires_at
+		WHERE u.user_level IN ('premium', 'premium+')
+		  AND u.status = 'active'
+		  AND u.premium_expires_at > $1
+		  AND u.premium_expires_at <= $2
+		GROUP BY u.id, u.user_level, u.premium_expires_at
+		ORDER BY u.premium_expires_at ASC`
+
+	var candidates []PremiumExpiryCandidate
+	if err := db.Select(&candidates, query, now, until); err != nil {
+		return nil, fmt.Errorf("error fetching premium expiry candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// RecordPremiumExpiryReminder stores a sent reminder; it returns false when the same
+// reminder was already recorded.
+func (r *premiumReminderRepository) RecordPremiumExpiryReminder(reminder *PremiumExpiryReminder) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO premium_expiry_reminders (user_id, expires_at, days_before, days_left, sent_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, expires_at, days_before) DO NOTHING`
+
+	result, err := db.Exec(query, reminder.UserID, reminder.ExpiresAt, reminder.DaysBefore, reminder.DaysLeft, reminder.SentAt)
+	if err != nil {
+		return false, fmt.Errorf("error recording premium expiry reminder for user %d: %w", reminder.UserID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error recording premium expiry reminder for user %d: %w", reminder.UserID, err)
+	}
+	return affected > 0, nil
+}
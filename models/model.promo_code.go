@@ -0,0 +1,471 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Promo code discount types
+const (
+	PromoDiscountPercentage = "percentage"
+	PromoDiscountFixed      = "fixed"
+)
+
+var (
+	// ErrPromoCodeNotFound is returned for unknown codes.
+	ErrPromoCodeNotFound = errors.New("promo code not found")
+	// ErrPromoCodeExists is returned when creating a code that is already taken.
+	ErrPromoCodeExists = errors.New("promo code already exists")
+	// ErrPromoCodeInactive is returned for disabled codes or outside the validity window.
+	ErrPromoCodeInactive = errors.New("promo code is not active")
+	// ErrPromoCodeNotEligible is returned when the code does not apply to the subscription level.
+	ErrPromoCodeNotEligible = errors.New("promo code is not valid for this subscription")
+	// ErrPromoCodeExhausted is returned when the code reached its maximum redemptions.
+	ErrPromoCodeExhausted = errors.New("promo code has been fully redeemed")
+	// ErrPromoCodeUserLimit is returned when the user already used the code the allowed number of times.
+	ErrPromoCodeUserLimit = errors.New("promo code already used")
+	// ErrPromoCodeWithManualDiscount is returned when a payment has both a promo code and a manual discount.
+	ErrPromoCodeWithManualDiscount = errors.New("promo code cannot be combined with a manual discount")
+)
+
+// UserLevelList is a set of subscription levels stored as a comma-separated string.
+type UserLevelList []UserLevel
+
+// Scan implements the sql.Scanner interface
+func (l *UserLevelList) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into UserLevelList", value)
+	}
+
+	*l = UserLevelList{}
+	for _, level := range strings.Split(str, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			*l = append(*l, UserLevel(level))
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (l UserLevelList) Value() (driver.Value, error) {
+	levels := make([]string, len(l))
+	for i, level := range l {
+		levels[i] = string(level)
+	}
+	return strings.Join(levels, ","), nil
+}
+
+// PromoCode is an admin-created discount code for subscription payments.
+type PromoCode struct {
+	ID           int     `json:"id" db:"id"`
+	Code         string  `json:"code" db:"code"`
+	Description  *string `json:"description,omitempty" db:"description"`
+	DiscountType string  `json:"discount_type" db:"discount_type"`
+	// DiscountValue is a percentage (0-100] or a fixed IDR amount
+	DiscountValue float64 `json:"discount_value" db:"discount_value"`
+	// MaxDiscountAmount caps a percentage discount; optional
+	MaxDiscountAmount *float64   `json:"max_discount_amount,omitempty" db:"max_discount_amount"`
+	ValidFrom         time.Time  `json:"valid_from" db:"valid_from"`
+	ValidUntil        *time.Time `json:"valid_until,omitempty" db:"valid_until"`
+	// MaxRedemptions limits redemptions across all users; nil is unlimited
+	MaxRedemptions *int `json:"max_redemptions,omitempty" db:"max_redemptions"`
+	PerUserLimit   int  `json:"per_user_limit" db:"per_user_limit"`
+	// EligibleLevels restricts the subscription levels; empty means every paid level
+	EligibleLevels   UserLevelList `json:"eligible_levels" db:"eligible_levels"`
+	Active           bool          `json:"active" db:"active"`
+	CreatedByAdminID *int          `json:"created_by_admin_id,omitempty" db:"created_by_admin_id"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+
+	// RedemptionCount counts pending and completed payments using the code
+	RedemptionCount int `json:"redemption_count" db:"redemption_count"`
+}
+
+// PromoCodeRedemption is a payment that used a promo code.
+type PromoCodeRedemption struct {
+	ID              int64     `json:"id" db:"id"`
+	PromoCodeID     int       `json:"promo_code_id" db:"promo_code_id"`
+	UserID          int       `json:"user_id" db:"user_id"`
+	UserEmail       string    `json:"user_email" db:"user_email"`
+	PaymentRecordID int       `json:"payment_record_id" db:"payment_record_id"`
+	PaymentStatus   string    `json:"payment_status" db:"payment_status"`
+	DiscountAmount  float64   `json:"discount_amount" db:"discount_amount"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// PromoCodesResponse represents a page of promo codes.
+type PromoCodesResponse struct {
+	PromoCodes []*PromoCode    `json:"promo_codes"`
+	Pagination *PaginationInfo `json:"pagination"`
+}
+
+// PromoCodeRedemptionsResponse represents a page of promo code redemptions.
+type PromoCodeRedemptionsResponse struct {
+	Redemptions []*PromoCodeRedemption `json:"redemptions"`
+	Pagination  *PaginationInfo        `json:"pagination"`
+}
+
+// PromoQuote is the price of a subscription level after a promo code.
+type PromoQuote struct {
+	Code           string    `json:"code"`
+	UserLevel      UserLevel `json:"user_level"`
+	OriginalPrice  float64   `json:"original_price"`
+	DiscountAmount float64   `json:"discount_amount"`
+	PaidPrice      float64   `json:"paid_price"`
+}
+
+// NormalizePromoCode returns the canonical (trimmed, upper case) form of a code.
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Eligible reports whether the code applies to level.
+func (p *PromoCode) Eligible(level UserLevel) bool {
+	if level != UserLevelPremium && level != UserLevelPremiumPlus {
+		return false
+	}
+	if len(p.EligibleLevels) == 0 {
+		return true
+	}
+	for _, eligible := range p.EligibleLevels {
+		if eligible == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount returns the discount on price, rounded to whole rupiah and never above price.
+func (p *PromoCode) Discount(price float64) float64 {
+	discount := p.DiscountValue
+	if p.DiscountType == PromoDiscountPercentage {
+		discount = price * p.DiscountValue / 100
+		if p.MaxDiscountAmount != nil && discount > *p.MaxDiscountAmount {
+			discount = *p.MaxDiscountAmount
+		}
+	}
+	discount = math.Round(discount)
+	if discount > price {
+		return price
+	}
+	if discount < 0 {
+		return 0
+	}
+	return discount
+}
+
+// PromoCodeRepository defines operations for promo codes and their redemptions.
+type PromoCodeRepository interface {
+	ListPromoCodes(page, limit int, active *bool) (*PromoCodesResponse, error)
+	FindPromoCode(id int) (*PromoCode, error)
+	CreatePromoCode(promo *PromoCode) (*PromoCode, error)
+	UpdatePromoCode(promo *PromoCode) (*PromoCode, error)
+	ListPromoCodeRedemptions(promoCodeID, page, limit int) (*PromoCodeRedemptionsResponse, error)
+	QuotePromoCode(code string, userID int, level UserLevel, price float64, now time.Time) (*PromoQuote, error)
+}
+
+type promoCodeRepository struct{}
+
+// NewPromoCodeRepository creates a new promo code repository.
+func NewPromoCodeRepository() PromoCodeRepository {
+	return &promoCodeRepository{}
+}
+
+func (r *promoCodeRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// promoRedemptionCountColumn counts the redemptions that hold a use of the code: those of
+// pending or completed payments. Failed and expired checkouts give the use back.
+const promoRedemptionCountColumn = `(
+	SELECT COUNT(*) FROM promo_code_redemptions r
+	JOIN payment_records p ON p.id = r.payment_record_id
+	WHERE r.promo_code_id = pc.id AND p.payment_status IN ('pending', 'completed')
+) AS redemption_count`
+
+// ListPromoCodes returns a page of promo codes, newest first.
+func (r *promoCodeRepository) ListPromoCodes(page, limit int, active *bool) (*PromoCodesResponse, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT pc.*, ` + promoRedemptionCountColumn + `
+		FROM promo_codes pc
+		WHERE ($1::BOOLEAN IS NULL OR pc.active = $1)
+		ORDER BY pc.created_at DESC, pc.id DESC
+		LIMIT $2 OFFSET $3`
+
+	var promos []*PromoCode
+	if err := db.Select(&promos, query, active, limit+1, (page-1)*limit); err != nil {
+		return nil, fmt.Errorf("error fetching promo codes: %w", err)
+	}
+
+	hasMore := len(promos) > limit
+	if hasMore {
+		promos = promos[:limit]
+	}
+	if promos == nil {
+		promos = []*PromoCode{}
+	}
+
+	return &PromoCodesResponse{
+		PromoCodes: promos,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+// FindPromoCode returns a promo code with its redemption count, or nil when it does not exist.
+func (r *promoCodeRepository) FindPromoCode(id int) (*PromoCode, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT pc.*, ` + promoRedemptionCountColumn + ` FROM promo_codes pc WHERE pc.id = $1`
+
+	var promo PromoCode
+	if err := db.Get(&promo, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching promo code %d: %w", id, err)
+	}
+	return &promo, nil
+}
+
+// CreatePromoCode inserts a promo code; the code is stored normalized.
+func (r *promoCodeRepository) CreatePromoCode(promo *PromoCode) (*PromoCode, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO promo_codes
+			(code, description, discount_type, discount_value, max_discount_amount, valid_from,
+			 valid_until, max_redemptions, per_user_limit, eligible_levels, active, created_by_admin_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING *`
+
+	var created PromoCode
+	err = db.Get(&created, query, NormalizePromoCode(promo.Code), promo.Description, promo.DiscountType,
+		promo.DiscountValue, promo.MaxDiscountAmount, promo.ValidFrom, promo.ValidUntil, promo.MaxRedemptions,
+		promo.PerUserLimit, promo.EligibleLevels, promo.Active, promo.CreatedByAdminID)
+	if err == sql.ErrNoRows {
+		return nil, ErrPromoCodeExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating promo code: %w", err)
+	}
+	return &created, nil
+}
+
+// UpdatePromoCode saves the mutable fields of a promo code. The code and discount are
+// fixed once created so past redemptions stay consistent with the code's terms.
+func (r *promoCodeRepository) UpdatePromoCode(promo *PromoCode) (*PromoCode, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE promo_codes
+		SET description = $2, max_discount_amount = $3, valid_from = $4, valid_until = $5,
+		    max_redemptions = $6, per_user_limit = $7, eligible_levels = $8, active = $9,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING *`
+
+	var updated PromoCode
+	err = db.Get(&updated, query, promo.ID, promo.Description, promo.MaxDiscountAmount, promo.ValidFrom,
+		promo.ValidUntil, promo.MaxRedemptions, promo.PerUserLimit, promo.EligibleLevels, promo.Active)
+	if err == sql.ErrNoRows {
+		return nil, ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error updating promo code %d: %w", promo.ID, err)
+	}
+	updated.RedemptionCount = promo.RedemptionCount
+	return &updated, nil
+}
+
+// ListPromoCodeRedemptions returns a page of the code's redemptions, newest first.
+func (r *promoCodeRepository) ListPromoCodeRedemptions(promoCodeID, page, limit int) (*PromoCodeRedemptionsResponse, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT r.id, r.promo_code_id, r.user_id, u.email AS user_email, r.payment_record_id,
+		       p.payment_status, r.discount_amount, r.created_at
+		FROM promo_code_redemptions r
+		JOIN users u ON u.id = r.user_id
+		JOIN payment_records p ON p.id = r.payment_record_id
+		WHERE r.promo_code_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT $2 OFFSET $3`
+
+	var redemptions []*PromoCodeRedemption
+	if err := db.Select(&redemptions, query, promoCodeID, limit+1, (page-1)*limit); err != nil {
+		return nil, fmt.Errorf("error fetching promo code redemptions: %w", err)
+	}
+
+	hasMore := len(redemptions) > limit
+	if hasMore {
+		redemptions = redemptions[:limit]
+	}
+	if redemptions == nil {
+		redemptions = []*PromoCodeRedemption{}
+	}
+
+	return &PromoCodeRedemptionsResponse{
+		Redemptions: redemptions,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+// QuotePromoCode checks whether userID can use code for level now and returns the
+// discounted price, without redeeming it.
+func (r *promoCodeRepository) QuotePromoCode(code string, userID int, level UserLevel, price float64, now time.Time) (*PromoQuote, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	promo, err := checkPromoCode(db, code, "", userID, level, now)
+	if err != nil {
+		return nil, err
+	}
+
+	discount := promo.Discount(price)
+	return &PromoQuote{
+		Code:           promo.Code,
+		UserLevel:      level,
+		OriginalPrice:  price,
+		DiscountAmount: discount,
+		PaidPrice:      price - discount,
+	}, nil
+}
+
+// checkPromoCode loads code and verifies userID may redeem it for level at now. lock is
+// appended to the lookup query, e.g. "FOR UPDATE" to serialize concurrent redemptions.
+func checkPromoCode(q sqlx.Queryer, code, lock string, userID int, level UserLevel, now time.Time) (*PromoCode, error) {
+	var promo PromoCode
+	if err := sqlx.Get(q, &promo, `SELECT * FROM promo_codes WHERE code = $1 `+lock, NormalizePromoCode(code)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, fmt.Errorf("error fetching promo code: %w", err)
+	}
+
+	if !promo.Active || now.Before(promo.ValidFrom) || (promo.ValidUntil != nil && !now.Before(*promo.ValidUntil)) {
+		return nil, ErrPromoCodeInactive
+	}
+	if !promo.Eligible(level) {
+		return nil, ErrPromoCodeNotEligible
+	}
+	if err := checkPromoCodeLimits(q, &promo, userID); err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+// checkPromoCodeLimits returns ErrPromoCodeExhausted or ErrPromoCodeUserLimit when promo
+// has no redemption left for userID, and sets its RedemptionCount. Redemptions of pending
+// and completed payments count.
+func checkPromoCodeLimits(q sqlx.Queryer, promo *PromoCode, userID int) error {
+	var usage struct {
+		Total int `db:"total"`
+		User  int `db:"user_total"`
+	}
+	usageQuery := `
+		SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE r.user_id = $2) AS user_total
+		FROM promo_code_redemptions r
+		JOIN payment_records p ON p.id = r.payment_record_id
+		WHERE r.promo_code_id = $1 AND p.payment_status IN ('pending', 'completed')`
+	if err := sqlx.Get(q, &usage, usageQuery, promo.ID, userID); err != nil {
+		return fmt.Errorf("error counting promo code redemptions: %w", err)
+	}
+	if promo.MaxRedemptions != nil && usage.Total >= *promo.MaxRedemptions {
+		return ErrPromoCodeExhausted
+	}
+	if usage.User >= promo.PerUserLimit {
+		return ErrPromoCodeUserLimit
+	}
+
+	promo.RedemptionCount = usage.Total
+	return nil
+}
+
+// checkRevivedPromoRedemption checks that an expired payment paid late still fits the
+// limits of its promo code. Expiring the payment freed its redemption, which another
+// checkout may have taken since. The code row stays locked until tx ends.
+func checkRevivedPromoRedemption(tx *sqlx.Tx, payment *PaymentRecord) error {
+	var promo PromoCode
+	if err := tx.Get(&promo, `SELECT * FROM promo_codes WHERE id = $1 FOR UPDATE`, *payment.PromoCodeID); err != nil {
+		return fmt.Errorf("error locking promo code %d: %w", *payment.PromoCodeID, err)
+	}
+	return checkPromoCodeLimits(tx, &promo, payment.UserID)
+}
+
+// applyPromoCode validates code for the payment's user and level and fills the payment's
+// discount fields from it. The code row stays locked until tx ends, so limits hold
+// under concurrent checkouts; recordPromoRedemption must follow the payment insert.
+func applyPromoCode(tx *sqlx.Tx, payment *PaymentRecord, code string, now time.Time) error {
+	promo, err := checkPromoCode(tx, code, "FOR UPDATE", payment.UserID, payment.SubscriptionType, now)
+	if err != nil {
+		return err
+	}
+
+	reason := "Promo " + promo.Code
+	payment.PromoCodeID = &promo.ID
+	payment.DiscountAmount = promo.Discount(payment.OriginalPrice)
+	payment.PaidPrice = payment.OriginalPrice - payment.DiscountAmount
+	payment.DiscountReason = &reason
+	return nil
+}
+
+// recordPromoRedemption records the promo code use of an inserted payment, if any.
+func recordPromoRedemption(tx *sqlx.Tx, payment *PaymentRecord) error {
+	if payment.PromoCodeID == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO promo_code_redemptions (promo_code_id, user_id, payment_record_id, discount_amount)
+		VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(query, *payment.PromoCodeID, payment.UserID, payment.ID, payment.DiscountAmount); err != nil {
+		return fmt.Errorf("error recording promo code redemption for payment %d: %w", payment.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RawPayloadRecord is a compressed datasource response body for one ticker,
+// endpoint and day.
+type RawPayloadRecord struct {
+	ID              int64     `json:"id" db:"id"`
+	Ticker          string    `json:"ticker" db:"ticker"`
+	Endpoint        string    `json:"endpoint" db:"endpoint"`
+	PayloadDate     time.Time `json:"payload_date" db:"payload_date"`
+	ContentEncoding string    `json:"content_encoding" db:"content_encoding"`
+	Body            []byte    `json:"-" db:"body"`
+	BodySize        int       `json:"body_size" db:"body_size"`
+	FetchedAt       time.Time `json:"fetched_at" db:"fetched_at"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// RawPayloadKey identifies an archived payload.
+type RawPayloadKey struct {
+	Ticker      string    `db:"ticker"`
+	Endpoint    string    `db:"endpoint"`
+	PayloadDate time.Time `db:"payload_date"`
+}
+
+// RawPayloadRepository stores archived datasource payloads in the database.
+type RawPayloadRepository interface {
+	UpsertRawPayload(record *RawPayloadRecord) error
+	GetRawPayload(ticker, endpoint string, date time.Time) (*RawPayloadRecord, error)
+	ListRawPayloadKeys(from, to time.Time, tickers []string) ([]RawPayloadKey, error)
+}
+
+type rawPayloadRepository struct{}
+
+func NewRawPayloadRepository() RawPayloadRepository {
+	return &rawPayloadRepository{}
+}
+
+func (r *rawPayloadRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// UpsertRawPayload stores a payload; a later fetch on the same day replaces it.
+func (r *rawPayloadRepository) UpsertRawPayload(record *RawPayloadRecord) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO stock_raw_payloads (ticker, endpoint, payload_date, content_encoding, body, body_size, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (ticker, endpoint, payload_date) DO UPDATE SET
+			content_encoding = EXCLUDED.content_encoding,
+			body = EXCLUDED.body,
+			body_size = EXCLUDED.body_size,
+			fetched_at = EXCLUDED.fetched_at`
+
+	_, err = db.Exec(query, record.Ticker, record.Endpoint, record.PayloadDate.Format(SQLDateFormat),
+		record.ContentEncoding, record.Body, record.BodySize, record.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("error upserting raw payload %s/%s: %w", record.Ticker, record.Endpoint, err)
+	}
+	return nil
+}
+
+func (r *rawPayloadRepository) GetRawPayload(ticker, endpoint string, date time.Time) (*RawPayloadRecord, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT id, ticker, endpoint, payload_date, content_encoding, body, body_size, fetched_at, created_at
+		FROM stock_raw_payloads
+		WHERE ticker = $1 AND endpoint = $2 AND payload_date = $3`
+
+	var record RawPayloadRecord
+	err = db.Get(&record, query, ticker, endpoint, date.Format(SQLDateFormat))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching raw payload %s/%s: %w", ticker, endpoint, err)
+	}
+	return &record, nil
+}
+
+// ListRawPayloadKeys lists archived payloads between from and to (inclusive),
+// optionally limited to tickers, ordered by ticker and date.
+func (r *rawPayloadRepository) ListRawPayloadKeys(from, to time.Time, tickers []string) ([]RawPayloadKey, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{from.Format(SQLDateFormat), to.Format(SQLDateFormat)}
+	query := `SELECT ticker, endpoint, payload_date FROM stock_raw_payloads WHERE payload_date BETWEEN $1 AND $2`
+	if len(tickers) > 0 {
+		placeholders := make([]string, 0, len(tickers))
+		for _, ticker := range tickers {
+			args = append(args, ticker)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		query += " AND ticker IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	query += ` ORDER BY ticker ASC, payload_date ASC, endpoint ASC`
+
+	keys := []RawPayloadKey{}
+	if err := db.Select(&keys, query, args...); err != nil {
+		return nil, fmt.Errorf("error listing raw payloads: %w", err)
+	}
+	return keys, nil
+}
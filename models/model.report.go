@@ -0,0 +1,279 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// reportTimeZone is the time zone report dates and months are counted in
+const reportTimeZone = "Asia/Jakarta"
+
+// SubscriptionTierMetrics is the recurring revenue of one premium tier.
+type SubscriptionTierMetrics struct {
+	UserLevel   UserLevel `json:"user_level" db:"user_level"`
+	Subscribers int       `json:"subscribers" db:"subscribers"`
+	MRR         float64   `json:"mrr" db:"mrr"`
+}
+
+// SubscriptionMetrics is a snapshot of active subscriptions and recurring revenue.
+type SubscriptionMetrics struct {
+	AsOf              time.Time                  `json:"as_of"`
+	ActiveSubscribers int                        `json:"active_subscribers"`
+	MRR               float64                    `json:"mrr"`
+	ARR               float64                    `json:"arr"`
+	ByTier            []*SubscriptionTierMetrics `json:"by_tier"`
+}
+
+// MonthlySubscriptionStats summarizes the payments and subscription changes of one month.
+type MonthlySubscriptionStats struct {
+	Month            string  `json:"month" db:"month"`
+	Revenue          float64 `json:"revenue" db:"revenue"`
+	Payments         int     `json:"payments" db:"payments"`
+	NewSubscriptions int     `json:"new_subscriptions" db:"new_subscriptions"`
+	Renewals         int     `json:"renewals" db:"renewals"`
+	ActiveAtStart    int     `json:"active_at_start" db:"active_at_start"`
+	Churned          int     `json:"churned" db:"churned"`
+	// ChurnRate is Churned over ActiveAtStart
+	ChurnRate float64 `json:"churn_rate" db:"-"`
+}
+
+// PaymentMethodRevenue is the completed revenue collected through one payment method.
+type PaymentMethodRevenue struct {
+	PaymentMethod string  `json:"payment_method" db:"payment_method"`
+	Payments      int     `json:"payments" db:"payments"`
+	Revenue       float64 `json:"revenue" db:"revenue"`
+}
+
+// PromoCodeDiscount is the discount granted through one promo code.
+type PromoCodeDiscount struct {
+	Code           string  `json:"code" db:"code"`
+	Payments       int     `json:"payments" db:"payments"`
+	DiscountAmount float64 `json:"discount_amount" db:"discount_amount"`
+}
+
+// DiscountTotals sums the discounts and proration credits of completed payments.
+type DiscountTotals struct {
+	DiscountedPayments int                  `json:"discounted_payments" db:"discounted_payments"`
+	DiscountAmount     float64              `json:"discount_amount" db:"discount_amount"`
+	ProrationCredit    float64              `json:"proration_credit" db:"proration_credit"`
+	ByPromoCode        []*PromoCodeDiscount `json:"by_promo_code" db:"-"`
+}
+
+// RefundsDue sums the payments the gateway captured money for without granting a
+// subscription, which still have to be refunded.
+type RefundsDue struct {
+	Payments int     `json:"payments" db:"payments"`
+	Amount   float64 `json:"amount" db:"amount"`
+}
+
+// RevenueReport breaks down completed revenue between two dates.
+type RevenueReport struct {
+	From            string                  `json:"from"`
+	To              string                  `json:"to"`
+	Payments        int                     `json:"payments"`
+	Revenue         float64                 `json:"revenue"`
+	ByPaymentMethod []*PaymentMethodRevenue `json:"by_payment_method"`
+	Discounts       *DiscountTotals         `json:"discounts"`
+	RefundsDue      *RefundsDue             `json:"refunds_due"`
+}
+
+// ReportRepository computes subscription and revenue reports from payment records and users.
+// Dates are SQLDateFormat strings in WIB, to inclusive.
+type ReportRepository interface {
+	GetSubscriptionMetrics(now time.Time) (*SubscriptionMetrics, error)
+	GetMonthlySubscriptionStats(from, to string, now time.Time) ([]*MonthlySubscriptionStats, error)
+	GetRevenueReport(from, to string) (*RevenueReport, error)
+}
+
+type reportRepository struct{}
+
+// NewReportRepository creates a new report repository.
+func NewReportRepository() ReportRepository {
+	return &reportRepository{}
+}
+
+func (r *reportRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// GetSubscriptionMetrics returns active subscribers and recurring revenue at now. A
+// subscriber's MRR is the price of their latest completed payment, after discounts but
+// before the one-off proration credit, spread over the months it bought. Subscribers
+// granted premium without payment count with zero MRR.
+func (r *reportRepository) GetSubscriptionMetrics(now time.Time) (*SubscriptionMetrics, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH current_periods AS (
+			SELECT DISTINCT ON (p.user_id) p.user_id,
+			       (p.original_price - p.discount_amount)
+			         / COALESCE(sp.duration_months, CASE WHEN p.subscription_type = 'premium' THEN 1 ELSE 12 END) AS monthly_value
+			FROM payment_records p
+			LEFT JOIN subscription_plans sp ON sp.id = p.subscription_plan_id
+			WHERE p.payment_status = 'completed'
+			ORDER BY p.user_id, p.expires_at DESC, p.id DESC
+		)
+		SELECT u.user_level, COUNT(*) AS subscribers, COALESCE(SUM(cp.monthly_value), 0) AS mrr
+		FROM users u
+		LEFT JOIN current_periods cp ON cp.user_id = u.id
+		WHERE u.user_level IN ('premium', 'premium+') AND u.premium_expires_at > $1
+		GROUP BY u.user_level
+		ORDER BY u.user_level`
+
+	var tiers []*SubscriptionTierMetrics
+	if err := db.Select(&tiers, query, now); err != nil {
+		return nil, fmt.Errorf("error computing subscription metrics: %w", err)
+	}
+
+	metrics := &SubscriptionMetrics{AsOf: now, ByTier: []*SubscriptionTierMetrics{}}
+	for _, tier := range tiers {
+		metrics.ActiveSubscribers += tier.Subscribers
+		metrics.MRR += tier.MRR
+		metrics.ByTier = append(metrics.ByTier, tier)
+	}
+	metrics.ARR = metrics.MRR * 12
+	return metrics, nil
+}
+
+// GetMonthlySubscriptionStats returns one row per calendar month from the month of from
+// to the month of to. A completed payment is a renewal when the user was still subscribed
+// through an earlier payment when paying, otherwise a new subscription. A subscription
+// churns in the month its period ended, before now, without a payment extending it.
+func (r *reportRepository) GetMonthlySubscriptionStats(from, to string, now time.Time) ([]*MonthlySubscriptionStats, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH months AS (
+			SELECT month_start,
+			       (month_start AT TIME ZONE '` + reportTimeZone + `') AS start_at,
+			       ((month_start + INTERVAL '1 month') AT TIME ZONE '` + reportTimeZone + `') AS end_at
+			FROM generate_series(date_trunc('month', $1::DATE::TIMESTAMP), date_trunc('month', $2::DATE::TIMESTAMP), INTERVAL '1 month') AS month_start
+		),
+		completed AS (
+			SELECT p.id, p.user_id, p.paid_price, p.payment_date, p.expires_at,
+			       EXISTS (
+			           SELECT 1 FROM payment_records prev
+			           WHERE prev.user_id = p.user_id AND prev.payment_status = 'completed' AND prev.id <> p.id
+			             AND prev.payment_date < p.payment_date AND prev.expires_at >= p.payment_date
+			       ) AS renewal,
+			       NOT EXISTS (
+			           SELECT 1 FROM payment_records later
+			           WHERE later.user_id = p.user_id AND later.payment_status = 'completed' AND later.id <> p.id
+			             AND later.payment_date <= p.expires_at AND later.expires_at > p.expires_at
+			       ) AS lapsed
+			FROM payment_records p
+			WHERE p.payment_status = 'completed'
+		)
+		SELECT to_char(m.month_start, 'YYYY-MM') AS month,
+		       COALESCE((SELECT SUM(c.paid_price) FROM completed c WHERE c.payment_date >= m.start_at AND c.payment_date < m.end_at), 0) AS revenue,
+		       (SELECT COUNT(*) FROM completed c WHERE c.payment_date >= m.start_at AND c.payment_date < m.end_at) AS payments,
+		       (SELECT COUNT(*) FROM completed c WHERE c.payment_date >= m.start_at AND c.payment_date < m.end_at AND NOT c.renewal) AS new_subscriptions,
+		       (SELECT COUNT(*) FROM completed c WHERE c.payment_date >= m.start_at AND c.payment_date < m.end_at AND c.renewal) AS renewals,
+		       (SELECT COUNT(DISTINCT c.user_id) FROM completed c WHERE c.payment_date <= m.start_at AND c.expires_at > m.start_at) AS active_at_start,
+		       (SELECT COUNT(*) FROM completed c WHERE c.lapsed AND c.expires_at >= m.start_at AND c.expires_at < m.end_at AND c.expires_at <= $3) AS churned
+		FROM months m
+		ORDER BY m.month_start`
+
+	var stats []*MonthlySubscriptionStats
+	if err := db.Select(&stats, query, from, to, now); err != nil {
+		return nil, fmt.Errorf("error computing monthly subscription stats: %w", err)
+	}
+	if stats == nil {
+		stats = []*MonthlySubscriptionStats{}
+	}
+	for _, month := range stats {
+		if month.ActiveAtStart > 0 {
+			month.ChurnRate = float64(month.Churned) / float64(month.ActiveAtStart)
+		}
+	}
+	return stats, nil
+}
+
+// GetRevenueReport returns completed revenue by payment method, the discounts granted and
+// the captured payments waiting for a refund between from and to.
+func (r *reportRepository) GetRevenueReport(from, to string) (*RevenueReport, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	dateClause := `p.payment_date >= ($1::DATE::TIMESTAMP AT TIME ZONE '` + reportTimeZone + `')
+		  AND p.payment_date < (($2::DATE + 1)::TIMESTAMP AT TIME ZONE '` + reportTimeZone + `')`
+	rangeClause := `p.payment_status = 'completed' AND ` + dateClause
+
+	report := &RevenueReport{From: from, To: to}
+
+	methodQuery := `
+		SELECT p.payment_method, COUNT(*) AS payments, SUM(p.paid_price) AS revenue
+		FROM payment_records p
+		WHERE ` + rangeClause + `
+		GROUP BY p.payment_method
+		ORDER BY revenue DESC, p.payment_method`
+	if err := db.Select(&report.ByPaymentMethod, methodQuery, from, to); err != nil {
+		return nil, fmt.Errorf("error computing revenue by payment method: %w", err)
+	}
+	if report.ByPaymentMethod == nil {
+		report.ByPaymentMethod = []*PaymentMethodRevenue{}
+	}
+	for _, method := range report.ByPaymentMethod {
+		report.Payments += method.Payments
+		report.Revenue += method.Revenue
+	}
+
+	var discounts DiscountTotals
+	discountQuery := `
+		SELECT COUNT(*) FILTER (WHERE p.discount_amount > 0 OR p.proration_credit > 0) AS discounted_payments,
+		       COALESCE(SUM(p.discount_amount), 0) AS discount_amount,
+		       COALESCE(SUM(p.proration_credit), 0) AS proration_credit
+		FROM payment_records p
+		WHERE ` + rangeClause
+	if err := db.Get(&discounts, discountQuery, from, to); err != nil {
+		return nil, fmt.Errorf("error computing discount totals: %w", err)
+	}
+
+	promoQuery := `
+		SELECT pc.code, COUNT(*) AS payments, SUM(p.discount_amount) AS discount_amount
+		FROM payment_records p
+		JOIN promo_codes pc ON pc.id = p.promo_code_id
+		WHERE ` + rangeClause + `
+		GROUP BY pc.code
+		ORDER BY discount_amount DESC, pc.code`
+	if err := db.Select(&discounts.ByPromoCode, promoQuery, from, to); err != nil {
+		return nil, fmt.Errorf("error computing discounts by promo code: %w", err)
+	}
+	if discounts.ByPromoCode == nil {
+		discounts.ByPromoCode = []*PromoCodeDiscount{}
+	}
+	report.Discounts = &discounts
+
+	// The captured amount is the one of the paid event, which may differ from the price
+	var refunds RefundsDue
+	refundQuery := `
+		SELECT COUNT(*) AS payments,
+		       COALESCE(SUM(COALESCE((
+		           SELECT e.amount FROM payment_gateway_events e
+		           WHERE e.payment_record_id = p.id AND e.event_status = 'paid' AND e.applied
+		           ORDER BY e.id DESC
+		           LIMIT 1
+		       ), p.paid_price)), 0) AS amount
+		FROM payment_records p
+		WHERE p.payment_status = 'needs_refund' AND ` + dateClause
+	if err := db.Get(&refunds, refundQuery, from, to); err != nil {
+		return nil, fmt.Errorf("error computing refunds due: %w", err)
+	}
+	report.RefundsDue = &refunds
+
+	return report, nil
+}
@@ -0,0 +1,410 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Permission codes checked by the admin routes, seeded in db/roles_permissions.sql
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionPaymentsRead     = "payments:read"
+	PermissionPaymentsWrite    = "payments:write"
+	PermissionPlansRead        = "plans:read"
+	PermissionPlansWrite       = "plans:write"
+	PermissionPromoCodesRead   = "promo_codes:read"
+	PermissionPromoCodesWrite  = "promo_codes:write"
+	PermissionReportsRead      = "reports:read"
+	PermissionStocksRead       = "stocks:read"
+	PermissionStocksWrite      = "stocks:write"
+	PermissionAuditRead        = "audit:read"
+	PermissionRolesRead        = "roles:read"
+	PermissionRolesWrite       = "roles:write"
+
+	// PermissionUsersImpersonateWrite additionally allows impersonation tokens that may write
+	PermissionUsersImpersonateWrite = "users:impersonate_write"
+)
+
+var (
+	// ErrRoleNotFound is returned for unknown role ids.
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrRoleExists is returned when creating or renaming a role to a taken name.
+	ErrRoleExists = errors.New("role already exists")
+	// ErrUnknownPermission is returned when granting a permission code that does not exist.
+	ErrUnknownPermission = errors.New("unknown permission")
+	// ErrUserNotFound is returned when assigning roles to an unknown user.
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// Permission is a named capability granted through roles.
+type Permission struct {
+	Code        string `json:"code" db:"code"`
+	Description string `json:"description" db:"description"`
+}
+
+// Role groups permissions that can be assigned to users.
+type Role struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Permissions []string  `json:"permissions" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RoleRepository defines operations on roles, their permissions and user assignments.
+type RoleRepository interface {
+	ListPermissions() ([]*Permission, error)
+	ListRoles() ([]*Role, error)
+	FindRole(id int) (*Role, error)
+	CreateRole(role *Role) (*Role, error)
+	UpdateRole(role *Role) (*Role, error)
+	GetUserRoles(userID int) ([]*Role, error)
+	SetUserRoles(userID int, roleIDs []int, adminID int) ([]*Role, error)
+	GetUserPermissions(userID int) ([]string, error)
+}
+
+type roleRepository struct{}
+
+// NewRoleRepository creates a new role repository.
+func NewRoleRepository() RoleRepository {
+	return &roleRepository{}
+}
+
+func (r *roleRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *roleRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// ListPermissions returns every permission code.
+func (r *roleRepository) ListPermissions() ([]*Permission, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []*Permission
+	if err := db.Select(&permissions, `SELECT code, description FROM permissions ORDER BY code`); err != nil {
+		return nil, fmt.Errorf("error fetching permissions: %w", err)
+	}
+	if permissions == nil {
+		permissions = []*Permission{}
+	}
+	return permissions, nil
+}
+
+// ListRoles returns all roles with their permissions.
+func (r *roleRepository) ListRoles() ([]*Role, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*Role
+	if err := db.Select(&roles, `SELECT * FROM roles ORDER BY name`); err != nil {
+		return nil, fmt.Errorf("error fetching roles: %w", err)
+	}
+	if err := attachRolePermissions(db, roles); err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []*Role{}
+	}
+	return roles, nil
+}
+
+// FindRole returns a role with its permissions, or nil when it does not exist.
+func (r *roleRepository) FindRole(id int) (*Role, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+	return findRole(db, id)
+}
+
+// CreateRole creates a role granting role.Permissions.
+func (r *roleRepository) CreateRole(role *Role) (*Role, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id`
+
+	var id int
+	err = tx.Get(&id, query, role.Name, role.Description)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating role: %w", err)
+	}
+	if err := replaceRolePermissions(tx, id, role.Permissions); err != nil {
+		return nil, err
+	}
+
+	created, err := findRole(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateRole replaces a role's name, description and permissions.
+func (r *roleRepository) UpdateRole(role *Role) (*Role, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var taken bool
+	if err := tx.Get(&taken, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1 AND id <> $2)`, role.Name, role.ID); err != nil {
+		return nil, fmt.Errorf("error checking role name: %w", err)
+	}
+	if taken {
+		return nil, ErrRoleExists
+	}
+
+	result, err := tx.Exec(`
+		UPDATE roles SET name = $2, description = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, role.ID, role.Name, role.Description)
+	if err != nil {
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrRoleNotFound
+	}
+	if err := replaceRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return nil, err
+	}
+
+	updated, err := findRole(tx, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return updated, nil
+}
+
+// GetUserRoles returns the roles assigned to a user.
+func (r *roleRepository) GetUserRoles(userID int) ([]*Role, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+	return userRoles(db, userID)
+}
+
+// SetUserRoles replaces the roles of a user with roleIDs. Roles the user already holds
+// keep their original grant; new ones are recorded as granted by adminID.
+func (r *roleRepository) SetUserRoles(userID int, roleIDs []int, adminID int) ([]*Role, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID); err != nil {
+		return nil, fmt.Errorf("error checking user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	keep := make(map[int]bool, len(roleIDs))
+	for _, roleID := range roleIDs {
+		keep[roleID] = true
+		result, err := tx.Exec(`
+			INSERT INTO user_roles (user_id, role_id, granted_by_admin_id)
+			SELECT $1, id, $3 FROM roles WHERE id = $2
+			ON CONFLICT (user_id, role_id) DO NOTHING`, userID, roleID, adminID)
+		if err != nil {
+			return nil, fmt.Errorf("error assigning role %d: %w", roleID, err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			var known bool
+			if err := tx.Get(&known, `SELECT EXISTS (SELECT 1 FROM roles WHERE id = $1)`, roleID); err != nil {
+				return nil, fmt.Errorf("error checking role %d: %w", roleID, err)
+			}
+			if !known {
+				return nil, ErrRoleNotFound
+			}
+		}
+	}
+
+	var current []int
+	if err := tx.Select(&current, `SELECT role_id FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("error fetching user roles: %w", err)
+	}
+	for _, roleID := range current {
+		if keep[roleID] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID); err != nil {
+			return nil, fmt.Errorf("error removing role %d: %w", roleID, err)
+		}
+	}
+
+	roles, err := userRoles(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return roles, nil
+}
+
+// GetUserPermissions returns the distinct permission codes granted to a user by their roles.
+func (r *roleRepository) GetUserPermissions(userID int) ([]string, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT rp.permission_code
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY rp.permission_code`
+
+	var permissions []string
+	if err := db.Select(&permissions, query, userID); err != nil {
+		return nil, fmt.Errorf("error fetching user permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+func findRole(q sqlx.Queryer, id int) (*Role, error) {
+	var role Role
+	err := sqlx.Get(q, &role, `SELECT * FROM roles WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching role: %w", err)
+	}
+	if err := attachRolePermissions(q, []*Role{&role}); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func userRoles(q sqlx.Queryer, userID int) ([]*Role, error) {
+	query := `
+		SELECT r.* FROM roles r
+		JOIN user_roles ur ON ur.role_id = r.id
+		WHERE ur.user_id = $1
+		ORDER BY r.name`
+
+	var roles []*Role
+	if err := sqlx.Select(q, &roles, query, userID); err != nil {
+		return nil, fmt.Errorf("error fetching user roles: %w", err)
+	}
+	if err := attachRolePermissions(q, roles); err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []*Role{}
+	}
+	return roles, nil
+}
+
+// attachRolePermissions fills the Permissions of roles.
+func attachRolePermissions(q sqlx.Queryer, roles []*Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	var grants []struct {
+		RoleID         int    `db:"role_id"`
+		PermissionCode string `db:"permission_code"`
+	}
+	if err := sqlx.Select(q, &grants, `SELECT role_id, permission_code FROM role_permissions ORDER BY permission_code`); err != nil {
+		return fmt.Errorf("error fetching role permissions: %w", err)
+	}
+
+	byRole := make(map[int]*Role, len(roles))
+	for _, role := range roles {
+		role.Permissions = []string{}
+		byRole[role.ID] = role
+	}
+	for _, grant := range grants {
+		if role, ok := byRole[grant.RoleID]; ok {
+			role.Permissions = append(role.Permissions, grant.PermissionCode)
+		}
+	}
+	return nil
+}
+
+// replaceRolePermissions sets the permissions of a role to codes, rejecting unknown codes.
+func replaceRolePermissions(tx *sqlx.Tx, roleID int, codes []string) error {
+	var known []string
+	if err := tx.Select(&known, `SELECT code FROM permissions`); err != nil {
+		return fmt.Errorf("error fetching permissions: %w", err)
+	}
+	valid := make(map[string]bool, len(known))
+	for _, code := range known {
+		valid[code] = true
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("error clearing role permissions: %w", err)
+	}
+	for _, code := range codes {
+		if !valid[code] {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, code)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission_code) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, roleID, code); err != nil {
+			return fmt.Errorf("error granting permission %s: %w", code, err)
+		}
+	}
+	return nil
+}
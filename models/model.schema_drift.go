@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// IngestionRunSchemaDrift is a drift signature observed during an ingestion run.
+type IngestionRunSchemaDrift struct {
+	ID            int64     `json:"id" db:"id"`
+	RunID         int64     `json:"run_id" db:"run_id"`
+	Endpoint      string    `json:"endpoint" db:"endpoint"`
+	Signature     string    `json:"signature" db:"signature"`
+	Drifts        JSONRaw   `json:"drifts" db:"drifts"`
+	TickerCount   int       `json:"ticker_count" db:"ticker_count"`
+	SampleTickers string    `json:"sample_tickers" db:"sample_tickers"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// JSONRaw is a JSON document stored as-is and emitted without re-encoding.
+type JSONRaw []byte
+
+// MarshalJSON returns the stored document, or null when empty.
+func (j JSONRaw) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// Scan implements the sql.Scanner interface
+func (j *JSONRaw) Scan(value interface{}) error {
+	switch typed := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], typed...)
+	case string:
+		*j = JSONRaw(typed)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONRaw", value)
+	}
+	return nil
+}
+
+// RecordSchemaDriftSignature upserts a drift signature and reports whether it
+// was seen for the first time, which is when it should be alerted.
+func (r *ingestionRunRepository) RecordSchemaDriftSignature(signature, endpoint string, drifts []byte, seenAt time.Time) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	const query = `
+		INSERT INTO schema_drift_signatures (signature, endpoint, drifts, first_seen_at, last_seen_at, occurrences)
+		VALUES ($1, $2, $3, $4, $4, 1)
+		ON CONFLICT (signature) DO UPDATE SET
+			last_seen_at = EXCLUDED.last_seen_at,
+			occurrences = schema_drift_signatures.occurrences + 1
+		RETURNING (xmax = 0) AS inserted`
+
+	var inserted bool
+	if err := db.QueryRowx(query, signature, endpoint, string(drifts), seenAt).Scan(&inserted); err != nil {
+		return false, fmt.Errorf("error recording schema drift signature %s: %w", signature, err)
+	}
+	return inserted, nil
+}
+
+func (r *ingestionRunRepository) InsertRunSchemaDrift(drift *IngestionRunSchemaDrift) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO ingestion_run_schema_drifts (run_id, endpoint, signature, drifts, ticker_count, sample_tickers)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (run_id, signature) DO UPDATE SET
+			ticker_count = EXCLUDED.ticker_count,
+			sample_tickers = EXCLUDED.sample_tickers`
+
+	_, err = db.Exec(query, drift.RunID, drift.Endpoint, drift.Signature, string(drift.Drifts), drift.TickerCount, drift.SampleTickers)
+	if err != nil {
+		return fmt.Errorf("error inserting schema drift for run %d: %w", drift.RunID, err)
+	}
+	return nil
+}
+
+func (r *ingestionRunRepository) GetRunSchemaDrifts(runID int64) ([]IngestionRunSchemaDrift, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT id, run_id, endpoint, signature, drifts, ticker_count, sample_tickers, created_at
+		FROM ingestion_run_schema_drifts
+		WHERE run_id = $1
+		ORDER BY ticker_count DESC, endpoint ASC`
+
+	drifts := []IngestionRunSchemaDrift{}
+	if err := db.Select(&drifts, query, runID); err != nil {
+		return nil, fmt.Errorf("error fetching schema drifts for run %d: %w", runID, err)
+	}
+	return drifts, nil
+}
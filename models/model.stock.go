@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -116,11 +118,49 @@ type StockOverviewMetricsRecord struct {
 	SourceTimeLastUpdated         *time.Time
 }
 
+// StockUpsertOptions describe the payload day ingested stock data is upserted for.
+type StockUpsertOptions struct {
+	// AsOf is the day the payloads were fetched. Overview history is dated with it and
+	// current overview metrics are only replaced by data as of the same or a later day.
+	AsOf time.Time
+	// Replay is set when archived payloads are re-applied. stock.last_update is left
+	// alone so the next scheduled ingestion of the ticker is not delayed.
+	Replay bool
+}
+
 // StockRepository defines operations for stocks.
 type StockRepository interface {
 	GetStockApiKey() ([]StockInformation, error)
-	UpsertStockEarningQuarterlyHistory(records []StockEarningQuarterlyHistoryRecord) error
-	UpsertStockOverviewMetrics(record *StockOverviewMetricsRecord) error
+	UpsertStockEarningQuarterlyHistory(records []StockEarningQuarterlyHistoryRecord, opts StockUpsertOptions) error
+	UpsertStockOverviewMetrics(record *StockOverviewMetricsRecord, opts StockUpsertOptions) error
+
+	// Earnings calendar and surprise analytics
+	UpsertStockEarningCalendar(record *StockEarningCalendarRecord) error
+	GetEarningsCalendar(from, to time.Time) ([]EarningsCalendarEntry, error)
+	GetStockEarningQuarterlyHistory(symbol string) ([]StockEarningQuarterlyHistoryRecord, error)
+	GetNextExpectedReportDate(symbol string) (*time.Time, error)
+
+	// Overview metric history
+	GetStockOverviewMetricHistory(symbol, metric string, from, to *time.Time) ([]OverviewMetricPoint, error)
+
+	// Stock master attributes and sector comparison
+	GetStockClassificationOptions() (*StockClassificationOptions, error)
+	GetStockProfile(ticker string) (*StockProfile, error)
+	UpdateStockProfile(profile StockProfile) (*StockProfile, error)
+	ImportStockProfiles(profiles []StockProfile) (*StockProfileImportResult, error)
+	GetStockKeyMetrics(symbol string) (*StockKeyMetrics, error)
+	GetSectorAverages(sector *string) ([]StockSectorAverage, error)
+	GetSectorPeerMetrics(sector string) ([]StockPeerMetrics, error)
+
+	// Admin management of tracked tickers
+	ListTrackedStocks(page, limit int, enabled *bool, tickerFilter *string) (*TrackedStocksResponse, error)
+	GetTrackedStock(ticker string) (*TrackedStock, error)
+	CreateTrackedStock(ticker string, name *string, apiKey *string, enabled bool) (*TrackedStock, error)
+	UpdateTrackedStockEnabled(ticker string, enabled bool) (*TrackedStock, error)
+	UpdateTrackedStockApiKey(ticker string, apiKey *string) (*TrackedStock, error)
+	DeleteTrackedStock(ticker string) (bool, error)
+	GetStockInformation(ticker string) (*StockInformation, error)
+	EncryptLegacyApiKeys() (int, error)
 }
 
 type stockRepository struct{}
@@ -137,13 +177,16 @@ func (r *stockRepository) getDB() (*sqlx.DB, error) {
 	return db, nil
 }
 
+// GetStockApiKey returns enabled tickers due for ingestion with their decrypted
+// API keys. A key that cannot be decrypted is returned empty so the ticker is
+// skipped instead of failing the whole run.
 func (r *stockRepository) GetStockApiKey() ([]StockInformation, error) {
 	db, err := r.getDB()
 	if err != nil {
 		return nil, err
 	}
 
-	const query = `SELECT a.ticker, a.api_key FROM stock a WHERE a.last_update < NOW() - INTERVAL '14 days' AND a.api_key IS NOT NULL`
+	const query = `SELECT a.ticker, a.api_key FROM stock a WHERE a.enabled = TRUE AND a.last_update < NOW() - INTERVAL '14 days' AND a.api_key IS NOT NULL`
 
 	var stocks []StockInformation
 	err = db.Select(&stocks, query)
@@ -151,10 +194,31 @@ func (r *stockRepository) GetStockApiKey() ([]StockInformation, error) {
 		return nil, fmt.Errorf("error fetching stock API keys: %w", err)
 	}
 
+	decryptStockApiKeys(stocks, "Stock.GetStockApiKey")
 	return stocks, nil
 }
 
-func (r *stockRepository) UpsertStockEarningQuarterlyHistory(records []StockEarningQuarterlyHistoryRecord) error {
+// decryptStockApiKeys decrypts the API keys of stocks in place. A key that cannot be
+// decrypted is cleared, so ingestion skips the ticker as missing its API key.
+func decryptStockApiKeys(stocks []StockInformation, caller string) {
+	key, keyErr := stockApiKeyEncryptionKey()
+	for i := range stocks {
+		if !helper.IsEncryptedSecret(stocks[i].ApiKey) {
+			continue
+		}
+		decrypted := ""
+		err := keyErr
+		if keyErr == nil {
+			decrypted, err = helper.DecryptSecret(key, stocks[i].ApiKey)
+		}
+		if err != nil {
+			Logger.Error().Err(err).Str("ticker", stocks[i].Ticker).Msgf("[%s] Error decrypting API key", caller)
+		}
+		stocks[i].ApiKey = decrypted
+	}
+}
+
+func (r *stockRepository) UpsertStockEarningQuarterlyHistory(records []StockEarningQuarterlyHistoryRecord, opts StockUpsertOptions) error {
 	if len(records) == 0 {
 		return nil
 	}
@@ -251,9 +315,11 @@ func (r *stockRepository) UpsertStockEarningQuarterlyHistory(records []StockEarn
 	defer stmtUpdate.Close()
 
 	for _, record := range records {
-		if _, err := stmtUpdate.Exec(record.Symbol); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error updating last update timestamp for symbol %s: %w", record.Symbol, err)
+		if !opts.Replay {
+			if _, err := stmtUpdate.Exec(record.Symbol); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error updating last update timestamp for symbol %s: %w", record.Symbol, err)
+			}
 		}
 
 		if _, err := stmt.Exec(
@@ -289,7 +355,10 @@ func (r *stockRepository) UpsertStockEarningQuarterlyHistory(records []StockEarn
 	return nil
 }
 
-func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetricsRecord) error {
+// UpsertStockOverviewMetrics stores the current overview metrics of a symbol and snapshots
+// them into the history as of opts.AsOf. The current row is kept when it was built from a
+// later day than opts.AsOf, which happens when older archived payloads are replayed.
+func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetricsRecord, opts StockUpsertOptions) error {
 	if record == nil {
 		return nil
 	}
@@ -377,7 +446,8 @@ func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetric
 			last_actual_quarter_eps,
 			last_actual_quarter_revenue,
 			next_expected_report_date,
-			source_time_last_updated
+			source_time_last_updated,
+			metrics_as_of
 		)
 		VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
@@ -387,7 +457,7 @@ func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetric
 			$41, $42, $43, $44, $45, $46, $47, $48, $49, $50,
 			$51, $52, $53, $54, $55, $56, $57, $58, $59, $60,
 			$61, $62, $63, $64, $65, $66, $67, $68, $69, $70,
-			$71, $72, $73, $74, $75, $76
+			$71, $72, $73, $74, $75, $76, $77
 		)
 		ON CONFLICT (symbol) 
 		DO UPDATE SET 
@@ -465,7 +535,10 @@ func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetric
 			last_actual_quarter_eps = EXCLUDED.last_actual_quarter_eps,
 			last_actual_quarter_revenue = EXCLUDED.last_actual_quarter_revenue,
 			next_expected_report_date = EXCLUDED.next_expected_report_date,
-			source_time_last_updated = EXCLUDED.source_time_last_updated
+			source_time_last_updated = EXCLUDED.source_time_last_updated,
+			metrics_as_of = EXCLUDED.metrics_as_of
+		WHERE stock_overview_metrics.metrics_as_of IS NULL
+		   OR stock_overview_metrics.metrics_as_of <= EXCLUDED.metrics_as_of
 	`
 
 	tx, err := db.Beginx()
@@ -564,15 +637,23 @@ func (r *stockRepository) UpsertStockOverviewMetrics(record *StockOverviewMetric
 		&record.LastActualQuarterRevenue,
 		&record.NextExpectedReportDate,
 		&record.SourceTimeLastUpdated,
+		opts.AsOf.Format(SQLDateFormat),
 	); err != nil {
 		tx.Rollback()
 		Logger.Error().Err(err).Msgf("Error upserting stock overview metrics : %+v", record)
 		return fmt.Errorf("error upserting stock overview metrics for symbol %s: %w", record.Symbol, err)
 	}
 
-	if _, err := stmtUpdate.Exec(record.Symbol); err != nil {
+	if err := recordOverviewMetricsHistory(tx, record, opts.AsOf); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("error updating last update timestamp for symbol %s: %w", record.Symbol, err)
+		return err
+	}
+
+	if !opts.Replay {
+		if _, err := stmtUpdate.Exec(record.Symbol); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error updating last update timestamp for symbol %s: %w", record.Symbol, err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+)
+
+// TrackedStock is the admin view of a ticker in the stock table. The API key
+// itself is never returned; only whether one is set and its last characters.
+type TrackedStock struct {
+	Ticker     string    `json:"ticker" db:"ticker"`
+	Name       *string   `json:"name" db:"name"`
+	Sector     *string   `json:"sector" db:"sector"`
+	Board      *string   `json:"board" db:"board"`
+	Enabled    bool      `json:"enabled" db:"enabled"`
+	HasApiKey  bool      `json:"has_api_key" db:"has_api_key"`
+	ApiKeyHint *string   `json:"api_key_hint" db:"api_key_last4"`
+	LastUpdate time.Time `json:"last_update" db:"last_update"`
+}
+
+// TrackedStocksResponse is a paginated list of tracked stocks.
+type TrackedStocksResponse struct {
+	Stocks     []TrackedStock  `json:"stocks"`
+	Pagination *PaginationInfo `json:"pagination"`
+}
+
+// ErrTrackedStockExists is returned when adding a ticker that is already tracked.
+var ErrTrackedStockExists = errors.New("ticker already tracked")
+
+const trackedStockColumns = `ticker, name, sector::text AS sector, board, enabled, api_key IS NOT NULL AS has_api_key, api_key_last4, last_update`
+
+// stockApiKeyEncryptionKey returns the configured key, or nil when none is set.
+func stockApiKeyEncryptionKey() ([]byte, error) {
+	value := config.Get().MarketData.APIKeyEncryptionKey
+	if value == "" {
+		return nil, nil
+	}
+	return helper.ParseEncryptionKey(value)
+}
+
+// encryptStockApiKey encrypts apiKey and returns it with its display hint.
+func encryptStockApiKey(apiKey string) (string, string, error) {
+	key, err := stockApiKeyEncryptionKey()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := helper.EncryptSecret(key, apiKey)
+	if err != nil {
+		return "", "", err
+	}
+	return encrypted, apiKeyHint(apiKey), nil
+}
+
+func apiKeyHint(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return apiKey
+	}
+	return apiKey[len(apiKey)-4:]
+}
+
+func (r *stockRepository) ListTrackedStocks(page, limit int, enabled *bool, tickerFilter *string) (*TrackedStocksResponse, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * limit
+	args := []interface{}{}
+	argCount := 0
+
+	query := `SELECT ` + trackedStockColumns + ` FROM stock WHERE 1=1`
+
+	if enabled != nil {
+		argCount++
+		query += fmt.Sprintf(" AND enabled = $%d", argCount)
+		args = append(args, *enabled)
+	}
+
+	if tickerFilter != nil {
+		argCount++
+		query += fmt.Sprintf(" AND ticker ILIKE $%d", argCount)
+		args = append(args, *tickerFilter+"%")
+	}
+
+	argCount++
+	args = append(args, limit+1) // Fetch one extra to check if there's more data
+	argCount++
+	args = append(args, offset)
+	query += fmt.Sprintf(" ORDER BY ticker ASC LIMIT $%d OFFSET $%d", argCount-1, argCount)
+
+	stocks := []TrackedStock{}
+	if err := db.Select(&stocks, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching tracked stocks: %w", err)
+	}
+
+	hasMore := len(stocks) > limit
+	if hasMore {
+		stocks = stocks[:limit]
+	}
+
+	return &TrackedStocksResponse{
+		Stocks: stocks,
+		Pagination: &PaginationInfo{
+			CurrentPage: page,
+			HasMore:     hasMore,
+			Limit:       limit,
+		},
+	}, nil
+}
+
+func (r *stockRepository) GetTrackedStock(ticker string) (*TrackedStock, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var stock TrackedStock
+	err = db.Get(&stock, `SELECT `+trackedStockColumns+` FROM stock WHERE ticker = $1`, ticker)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching tracked stock %s: %w", ticker, err)
+	}
+	return &stock, nil
+}
+
+// CreateTrackedStock adds a ticker. last_update starts at the epoch so the next
+// scheduled ingestion picks it up immediately.
+func (r *stockRepository) CreateTrackedStock(ticker string, name *string, apiKey *string, enabled bool) (*TrackedStock, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptedKey, hint *string
+	if apiKey != nil {
+		encrypted, last4, err := encryptStockApiKey(*apiKey)
+		if err != nil {
+			return nil, err
+		}
+		encryptedKey, hint = &encrypted, &last4
+	}
+
+	query := `INSERT INTO stock (ticker, name, api_key, api_key_last4, enabled, last_update)
+		VALUES ($1, $2, $3, $4, $5, TIMESTAMPTZ 'epoch')
+		ON CONFLICT (ticker) DO NOTHING
+		RETURNING ` + trackedStockColumns
+
+	var stock TrackedStock
+	err = db.QueryRowx(query, ticker, name, encryptedKey, hint, enabled).StructScan(&stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTrackedStockExists
+		}
+		return nil, fmt.Errorf("error creating tracked stock %s: %w", ticker, err)
+	}
+	return &stock, nil
+}
+
+func (r *stockRepository) UpdateTrackedStockEnabled(ticker string, enabled bool) (*TrackedStock, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE stock SET enabled = $2 WHERE ticker = $1 RETURNING ` + trackedStockColumns
+
+	var stock TrackedStock
+	err = db.QueryRowx(query, ticker, enabled).StructScan(&stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error updating tracked stock %s: %w", ticker, err)
+	}
+	return &stock, nil
+}
+
+// UpdateTrackedStockApiKey assigns (or clears, when apiKey is nil) the ticker's API key.
+func (r *stockRepository) UpdateTrackedStockApiKey(ticker string, apiKey *string) (*TrackedStock, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptedKey, hint *string
+	if apiKey != nil {
+		encrypted, last4, err := encryptStockApiKey(*apiKey)
+		if err != nil {
+			return nil, err
+		}
+		encryptedKey, hint = &encrypted, &last4
+	}
+
+	query := `UPDATE stock SET api_key = $2, api_key_last4 = $3 WHERE ticker = $1 RETURNING ` + trackedStockColumns
+
+	var stock TrackedStock
+	err = db.QueryRowx(query, ticker, encryptedKey, hint).StructScan(&stock)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error updating API key for %s: %w", ticker, err)
+	}
+	return &stock, nil
+}
+
+func (r *stockRepository) DeleteTrackedStock(ticker string) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`DELETE FROM stock WHERE ticker = $1`, ticker)
+	if err != nil {
+		return false, fmt.Errorf("error deleting tracked stock %s: %w", ticker, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting tracked stock %s: %w", ticker, err)
+	}
+	return affected > 0, nil
+}
+
+// GetStockInformation returns a single ticker with its decrypted API key,
+// regardless of its enabled flag or last update.
+func (r *stockRepository) GetStockInformation(ticker string) (*StockInformation, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var stock StockInformation
+	err = db.Get(&stock, `SELECT ticker, COALESCE(api_key, '') AS api_key FROM stock WHERE ticker = $1`, ticker)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching stock %s: %w", ticker, err)
+	}
+
+	key, err := stockApiKeyEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	if stock.ApiKey, err = helper.DecryptSecret(key, stock.ApiKey); err != nil {
+		return nil, fmt.Errorf("error decrypting API key for %s: %w", ticker, err)
+	}
+	return &stock, nil
+}
+
+// EncryptLegacyApiKeys encrypts API keys still stored in plaintext and returns
+// how many were migrated.
+func (r *stockRepository) EncryptLegacyApiKeys() (int, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var legacy []StockInformation
+	if err := db.Select(&legacy, `SELECT ticker, api_key FROM stock WHERE api_key IS NOT NULL AND api_key NOT LIKE 'enc:%'`); err != nil {
+		return 0, fmt.Errorf("error fetching plaintext API keys: %w", err)
+	}
+
+	migrated := 0
+	for _, stock := range legacy {
+		encrypted, hint, err := encryptStockApiKey(stock.ApiKey)
+		if err != nil {
+			return migrated, err
+		}
+		// Guard on the old value so a concurrent admin update is not overwritten
+		if _, err := db.Exec(`UPDATE stock SET api_key = $2, api_key_last4 = $3 WHERE ticker = $1 AND api_key = $4`,
+			stock.Ticker, encrypted, hint, stock.ApiKey); err != nil {
+			return migrated, fmt.Errorf("error encrypting API key for %s: %w", stock.Ticker, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,376 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	EarningSurpriseBeat   = "beat"
+	EarningSurpriseMiss   = "miss"
+	EarningSurpriseInLine = "in_line"
+
+	EarningCalendarStatusExpected = "expected"
+	EarningCalendarStatusReleased = "released"
+)
+
+// StockEarningCalendarRecord stores the next expected earnings release for a symbol.
+type StockEarningCalendarRecord struct {
+	ID                  int64     `json:"id" db:"id"`
+	Symbol              string    `json:"symbol" db:"symbol"`
+	PeriodCode          string    `json:"period_code" db:"period_code"`
+	CiqFiscalPeriodType *string   `json:"ciq_fiscal_period_type" db:"ciq_fiscal_period_type"`
+	FiscalPeriodEndDate time.Time `json:"fiscal_period_end_date" db:"fiscal_period_end_date"`
+	ExpectedReportDate  time.Time `json:"expected_report_date" db:"expected_report_date"`
+	EpsForecast         *float64  `json:"eps_forecast" db:"eps_forecast"`
+	RevenueForecast     *float64  `json:"revenue_forecast" db:"revenue_forecast"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EarningsCalendarEntry is a single row of the market-wide earnings calendar.
+type EarningsCalendarEntry struct {
+	Symbol                 string    `json:"symbol" db:"symbol"`
+	PeriodCode             string    `json:"period_code" db:"period_code"`
+	ReportDate             time.Time `json:"report_date" db:"report_date"`
+	Status                 string    `json:"status" db:"status"`
+	EpsForecast            *float64  `json:"eps_forecast" db:"eps_forecast"`
+	EpsActual              *float64  `json:"eps_actual" db:"eps_actual"`
+	EpsSurprisePercent     *float64  `json:"eps_surprise_percent" db:"eps_surprise_percent"`
+	RevenueForecast        *float64  `json:"revenue_forecast" db:"revenue_forecast"`
+	RevenueActual          *float64  `json:"revenue_actual" db:"revenue_actual"`
+	RevenueSurprisePercent *float64  `json:"revenue_surprise_percent" db:"revenue_surprise_percent"`
+	Name                   *string   `json:"name" db:"name"`
+	Sector                 *string   `json:"sector" db:"sector"`
+}
+
+// EarningStreak describes a run of consecutive quarters with the same surprise outcome.
+type EarningStreak struct {
+	Outcome string `json:"outcome"`
+	Length  int    `json:"length"`
+}
+
+// EarningSurpriseSummary counts beats and misses for one measure (EPS or revenue).
+type EarningSurpriseSummary struct {
+	Beats                  int            `json:"beats"`
+	Misses                 int            `json:"misses"`
+	InLine                 int            `json:"in_line"`
+	BeatRate               *float64       `json:"beat_rate"`
+	AverageSurprisePercent *float64       `json:"average_surprise_percent"`
+	CurrentStreak          *EarningStreak `json:"current_streak"`
+	LongestBeatStreak      int            `json:"longest_beat_streak"`
+	LongestMissStreak      int            `json:"longest_miss_streak"`
+}
+
+// EarningSurpriseStats summarises a symbol's earnings surprise history.
+type EarningSurpriseStats struct {
+	Symbol            string                 `json:"symbol"`
+	QuartersAnalyzed  int                    `json:"quarters_analyzed"`
+	FirstPeriodCode   *string                `json:"first_period_code"`
+	LastPeriodCode    *string                `json:"last_period_code"`
+	LastReleaseDate   *time.Time             `json:"last_release_date"`
+	NextExpectedDate  *time.Time             `json:"next_expected_report_date"`
+	Eps               EarningSurpriseSummary `json:"eps"`
+	Revenue           EarningSurpriseSummary `json:"revenue"`
+	QuarterlyOutcomes []QuarterSurpriseItem  `json:"quarterly_outcomes"`
+}
+
+// QuarterSurpriseItem is the per-quarter outcome used to build the streaks.
+type QuarterSurpriseItem struct {
+	PeriodCode             string     `json:"period_code"`
+	EarningReleaseDate     *time.Time `json:"earning_release_date"`
+	EpsSurprisePercent     *float64   `json:"eps_surprise_percent"`
+	EpsOutcome             *string    `json:"eps_outcome"`
+	RevenueSurprisePercent *float64   `json:"revenue_surprise_percent"`
+	RevenueOutcome         *string    `json:"revenue_outcome"`
+}
+
+// ClassifyEarningSurprise maps a surprise percentage to beat, miss or in-line.
+func ClassifyEarningSurprise(surprisePercent *float64) *string {
+	if surprisePercent == nil {
+		return nil
+	}
+
+	outcome := EarningSurpriseInLine
+	if *surprisePercent > 0 {
+		outcome = EarningSurpriseBeat
+	} else if *surprisePercent < 0 {
+		outcome = EarningSurpriseMiss
+	}
+	return &outcome
+}
+
+// ComputeEarningSurpriseStats builds beat/miss statistics from quarterly history sorted by period code ascending.
+func ComputeEarningSurpriseStats(symbol string, records []StockEarningQuarterlyHistoryRecord) *EarningSurpriseStats {
+	stats := &EarningSurpriseStats{
+		Symbol:            symbol,
+		QuarterlyOutcomes: []QuarterSurpriseItem{},
+	}
+
+	var epsOutcomes, revenueOutcomes []string
+	var epsSurprises, revenueSurprises []float64
+
+	for _, record := range records {
+		// Quarters without an actual figure have not been reported yet
+		if record.EpsActual == nil && record.RevenueActual == nil {
+			continue
+		}
+
+		item := QuarterSurpriseItem{
+			PeriodCode:             record.PeriodCode,
+			EarningReleaseDate:     record.EarningReleaseDate,
+			EpsSurprisePercent:     record.EpsSurprisePercent,
+			EpsOutcome:             ClassifyEarningSurprise(record.EpsSurprisePercent),
+			RevenueSurprisePercent: record.RevenueSurprisePercent,
+			RevenueOutcome:         ClassifyEarningSurprise(record.RevenueSurprisePercent),
+		}
+
+		if item.EpsOutcome != nil {
+			epsOutcomes = append(epsOutcomes, *item.EpsOutcome)
+			epsSurprises = append(epsSurprises, *record.EpsSurprisePercent)
+		}
+		if item.RevenueOutcome != nil {
+			revenueOutcomes = append(revenueOutcomes, *item.RevenueOutcome)
+			revenueSurprises = append(revenueSurprises, *record.RevenueSurprisePercent)
+		}
+
+		if stats.FirstPeriodCode == nil {
+			periodCode := record.PeriodCode
+			stats.FirstPeriodCode = &periodCode
+		}
+		periodCode := record.PeriodCode
+		stats.LastPeriodCode = &periodCode
+		if record.EarningReleaseDate != nil {
+			stats.LastReleaseDate = record.EarningReleaseDate
+		}
+
+		stats.QuarterlyOutcomes = append(stats.QuarterlyOutcomes, item)
+	}
+
+	stats.QuartersAnalyzed = len(stats.QuarterlyOutcomes)
+	stats.Eps = summarizeEarningSurprises(epsOutcomes, epsSurprises)
+	stats.Revenue = summarizeEarningSurprises(revenueOutcomes, revenueSurprises)
+
+	return stats
+}
+
+// summarizeEarningSurprises aggregates outcomes ordered from oldest to newest quarter.
+func summarizeEarningSurprises(outcomes []string, surprises []float64) EarningSurpriseSummary {
+	summary := EarningSurpriseSummary{}
+	if len(outcomes) == 0 {
+		return summary
+	}
+
+	runOutcome := ""
+	runLength := 0
+	for _, outcome := range outcomes {
+		switch outcome {
+		case EarningSurpriseBeat:
+			summary.Beats++
+		case EarningSurpriseMiss:
+			summary.Misses++
+		default:
+			summary.InLine++
+		}
+
+		if outcome == runOutcome {
+			runLength++
+		} else {
+			runOutcome = outcome
+			runLength = 1
+		}
+
+		if runOutcome == EarningSurpriseBeat && runLength > summary.LongestBeatStreak {
+			summary.LongestBeatStreak = runLength
+		}
+		if runOutcome == EarningSurpriseMiss && runLength > summary.LongestMissStreak {
+			summary.LongestMissStreak = runLength
+		}
+	}
+
+	summary.CurrentStreak = &EarningStreak{Outcome: runOutcome, Length: runLength}
+
+	beatRate := float64(summary.Beats) / float64(len(outcomes)) * 100
+	summary.BeatRate = &beatRate
+
+	total := 0.0
+	for _, surprise := range surprises {
+		total += surprise
+	}
+	average := total / float64(len(surprises))
+	summary.AverageSurprisePercent = &average
+
+	return summary
+}
+
+func (r *stockRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *stockRepository) UpsertStockEarningCalendar(record *StockEarningCalendarRecord) error {
+	if record == nil {
+		return nil
+	}
+
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO stock_earning_calendar (
+			symbol,
+			period_code,
+			ciq_fiscal_period_type,
+			fiscal_period_end_date,
+			expected_report_date,
+			eps_forecast,
+			revenue_forecast
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (symbol, period_code)
+		DO UPDATE SET
+			ciq_fiscal_period_type = EXCLUDED.ciq_fiscal_period_type,
+			fiscal_period_end_date = EXCLUDED.fiscal_period_end_date,
+			expected_report_date = EXCLUDED.expected_report_date,
+			eps_forecast = EXCLUDED.eps_forecast,
+			revenue_forecast = EXCLUDED.revenue_forecast,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := db.Exec(query,
+		record.Symbol,
+		record.PeriodCode,
+		record.CiqFiscalPeriodType,
+		record.FiscalPeriodEndDate,
+		record.ExpectedReportDate,
+		record.EpsForecast,
+		record.RevenueForecast,
+	); err != nil {
+		return fmt.Errorf("error upserting stock earning calendar for symbol %s period %s: %w", record.Symbol, record.PeriodCode, err)
+	}
+
+	return nil
+}
+
+func (r *stockRepository) GetEarningsCalendar(from, to time.Time) ([]EarningsCalendarEntry, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	// Released quarters come from the history table; expected releases are only
+	// listed while the matching quarter has not been reported yet.
+	const query = `
+		SELECT e.*, s.name, s.sector::text AS sector
+		FROM (
+		SELECT
+			h.symbol,
+			h.period_code,
+			h.earning_release_date AS report_date,
+			'released' AS status,
+			h.eps_forecast,
+			h.eps_actual,
+			h.eps_surprise_percent,
+			h.revenue_forecast,
+			h.revenue_actual,
+			h.revenue_surprise_percent
+		FROM stock_earning_quarterly_history h
+		WHERE h.earning_release_date >= $1 AND h.earning_release_date < $2
+		  AND (h.eps_actual IS NOT NULL OR h.revenue_actual IS NOT NULL)
+		UNION ALL
+		SELECT
+			c.symbol,
+			c.period_code,
+			c.expected_report_date AS report_date,
+			'expected' AS status,
+			c.eps_forecast,
+			NULL AS eps_actual,
+			NULL AS eps_surprise_percent,
+			c.revenue_forecast,
+			NULL AS revenue_actual,
+			NULL AS revenue_surprise_percent
+		FROM stock_earning_calendar c
+		WHERE c.expected_report_date >= $1 AND c.expected_report_date < $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM stock_earning_quarterly_history h
+			WHERE h.symbol = c.symbol
+			  AND h.period_code = c.period_code
+			  AND (h.eps_actual IS NOT NULL OR h.revenue_actual IS NOT NULL)
+		  )
+		) e
+		LEFT JOIN stock s ON s.ticker = e.symbol
+		ORDER BY e.report_date ASC, e.symbol ASC
+	`
+
+	var entries []EarningsCalendarEntry
+	if err := db.Select(&entries, query, from, to); err != nil {
+		return nil, fmt.Errorf("error fetching earnings calendar: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *stockRepository) GetStockEarningQuarterlyHistory(symbol string) ([]StockEarningQuarterlyHistoryRecord, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT id, symbol, period_code, eps_actual, eps_surprise, eps_surprise_percent,
+			revenue_actual, revenue_surprise, revenue_surprise_percent, forecast_source,
+			eps_forecast, revenue_forecast, earning_release_date, eps_gaap_consensus_median,
+			eps_normalized_consensus_median, ciq_fiscal_period_type, calendar_period_type,
+			calendar_period_start_date, calendar_period_end_date, primary_eps,
+			created_at, updated_at
+		FROM stock_earning_quarterly_history
+		WHERE symbol = $1
+		ORDER BY period_code ASC
+	`
+
+	var records []StockEarningQuarterlyHistoryRecord
+	if err := db.Select(&records, query, symbol); err != nil {
+		return nil, fmt.Errorf("error fetching stock earning quarterly history for symbol %s: %w", symbol, err)
+	}
+
+	return records, nil
+}
+
+func (r *stockRepository) GetNextExpectedReportDate(symbol string) (*time.Time, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT c.expected_report_date
+		FROM stock_earning_calendar c
+		WHERE c.symbol = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM stock_earning_quarterly_history h
+			WHERE h.symbol = c.symbol
+			  AND h.period_code = c.period_code
+			  AND (h.eps_actual IS NOT NULL OR h.revenue_actual IS NOT NULL)
+		  )
+		ORDER BY c.expected_report_date ASC
+		LIMIT 1
+	`
+
+	var expected time.Time
+	if err := db.Get(&expected, query, symbol); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching next expected report date for symbol %s: %w", symbol, err)
+	}
+
+	return &expected, nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// overviewMetricHistoryColumns are the stock_overview_metrics columns snapshotted into
+// stock_overview_metrics_history. A new row is written only when one of them changes.
+var overviewMetricHistoryColumns = []string{
+	"eps",
+	"book_value_per_share",
+	"latest_revenue",
+	"latest_income",
+	"debt_to_equity_ratio",
+	"current_ratio",
+	"quick_ratio",
+	"debt_asset_ratio",
+	"interest_coverage",
+	"return_on_assets",
+	"return_on_equity",
+	"return_on_capital",
+	"gross_margin",
+	"operating_margin",
+	"pretax_margin",
+	"net_profit_margin",
+	"payout_ratio",
+	"price_to_book_ratio",
+	"price_to_sales_ratio",
+	"forward_price_to_eps",
+	"dividend_yield",
+	"trailing_annual_dividend_yield",
+	"market_cap",
+	"enterprise_value",
+}
+
+// overviewMetricAliases maps the short names used by the API to history columns.
+var overviewMetricAliases = map[string]string{
+	"roe": "return_on_equity",
+	"roa": "return_on_assets",
+	"roc": "return_on_capital",
+	"der": "debt_to_equity_ratio",
+	"npm": "net_profit_margin",
+	"gpm": "gross_margin",
+	"opm": "operating_margin",
+	"pbv": "price_to_book_ratio",
+	"psr": "price_to_sales_ratio",
+}
+
+// OverviewMetricPoint is one dated value of an overview metric.
+type OverviewMetricPoint struct {
+	RecordedOn time.Time `db:"recorded_on" json:"recorded_on"`
+	Value      *float64  `db:"value" json:"value"`
+}
+
+// StockOverviewMetricHistory is the time series of a single overview metric for a symbol.
+type StockOverviewMetricHistory struct {
+	Symbol string                `json:"symbol"`
+	Metric string                `json:"metric"`
+	Points []OverviewMetricPoint `json:"points"`
+}
+
+// ResolveOverviewMetricColumn returns the history column for a metric name or alias.
+func ResolveOverviewMetricColumn(metric string) (string, bool) {
+	metric = strings.ToLower(strings.TrimSpace(metric))
+	if column, ok := overviewMetricAliases[metric]; ok {
+		return column, true
+	}
+	for _, column := range overviewMetricHistoryColumns {
+		if column == metric {
+			return column, true
+		}
+	}
+	return "", false
+}
+
+// OverviewMetricNames lists the metric names accepted by ResolveOverviewMetricColumn, sorted.
+func OverviewMetricNames() []string {
+	names := make([]string, 0, len(overviewMetricHistoryColumns)+len(overviewMetricAliases))
+	for alias := range overviewMetricAliases {
+		names = append(names, alias)
+	}
+	names = append(names, overviewMetricHistoryColumns...)
+	sort.Strings(names)
+	return names
+}
+
+// recordOverviewMetricsHistoryQuery writes a symbol's overview metrics as the snapshot of
+// a day; a second change on the same day overwrites that day's row.
+var recordOverviewMetricsHistoryQuery = func() string {
+	params := make([]string, len(overviewMetricHistoryColumns))
+	updates := make([]string, len(overviewMetricHistoryColumns))
+	for i, column := range overviewMetricHistoryColumns {
+		params[i] = fmt.Sprintf("$%d", i+3)
+		updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO stock_overview_metrics_history (symbol, recorded_on, %s)
+		VALUES ($1, $2, %s)
+		ON CONFLICT (symbol, recorded_on)
+		DO UPDATE SET
+			%s,
+			updated_at = CURRENT_TIMESTAMP
+	`, strings.Join(overviewMetricHistoryColumns, ", "), strings.Join(params, ", "), strings.Join(updates, ",\n\t\t\t"))
+}()
+
+// pruneOverviewMetricsHistoryQuery drops the snapshot of a day again when it matches the
+// previous snapshot, so only changes are kept.
+var pruneOverviewMetricsHistoryQuery = func() string {
+	snapshot := make([]string, len(overviewMetricHistoryColumns))
+	previous := make([]string, len(overviewMetricHistoryColumns))
+	for i, column := range overviewMetricHistoryColumns {
+		snapshot[i] = "h." + column
+		previous[i] = "p." + column
+	}
+
+	return fmt.Sprintf(`
+		DELETE FROM stock_overview_metrics_history h
+		WHERE h.symbol = $1
+		  AND h.recorded_on = $2
+		  AND EXISTS (
+			SELECT 1 FROM (
+				SELECT %[1]s
+				FROM stock_overview_metrics_history
+				WHERE symbol = $1 AND recorded_on < $2
+				ORDER BY recorded_on DESC
+				LIMIT 1
+			) p
+			WHERE ROW(%[2]s) IS NOT DISTINCT FROM ROW(%[3]s)
+		  )
+	`, strings.Join(overviewMetricHistoryColumns, ", "), strings.Join(previous, ", "), strings.Join(snapshot, ", "))
+}()
+
+// overviewMetricHistoryValues returns the record's values of overviewMetricHistoryColumns, in order.
+func overviewMetricHistoryValues(record *StockOverviewMetricsRecord) []interface{} {
+	byColumn := map[string]*float64{
+		"eps":                            record.Eps,
+		"book_value_per_share":           record.BookValuePerShare,
+		"latest_revenue":                 record.LatestRevenue,
+		"latest_income":                  record.LatestIncome,
+		"debt_to_equity_ratio":           record.DebtToEquityRatio,
+		"current_ratio":                  record.CurrentRatio,
+		"quick_ratio":                    record.QuickRatio,
+		"debt_asset_ratio":               record.DebtAssetRatio,
+		"interest_coverage":              record.InterestCoverage,
+		"return_on_assets":               record.ReturnOnAssets,
+		"return_on_equity":               record.ReturnOnEquity,
+		"return_on_capital":              record.ReturnOnCapital,
+		"gross_margin":                   record.GrossMargin,
+		"operating_margin":               record.OperatingMargin,
+		"pretax_margin":                  record.PretaxMargin,
+		"net_profit_margin":              record.NetProfitMargin,
+		"payout_ratio":                   record.PayoutRatio,
+		"price_to_book_ratio":            record.PriceToBookRatio,
+		"price_to_sales_ratio":           record.PriceToSalesRatio,
+		"forward_price_to_eps":           record.ForwardPriceToEPS,
+		"dividend_yield":                 record.DividendYield,
+		"trailing_annual_dividend_yield": record.TrailingAnnualDividendYield,
+		"market_cap":                     record.MarketCap,
+		"enterprise_value":               record.EnterpriseValue,
+	}
+
+	values := make([]interface{}, len(overviewMetricHistoryColumns))
+	for i, column := range overviewMetricHistoryColumns {
+		values[i] = byColumn[column]
+	}
+	return values
+}
+
+// recordOverviewMetricsHistory snapshots the record's overview metrics as of the payload
+// day within the upsert transaction. The snapshot is written from the record rather than
+// the current row, which keeps newer data when older archived payloads are replayed.
+func recordOverviewMetricsHistory(tx *sqlx.Tx, record *StockOverviewMetricsRecord, recordedOn time.Time) error {
+	day := recordedOn.Format(SQLDateFormat)
+	args := append([]interface{}{record.Symbol, day}, overviewMetricHistoryValues(record)...)
+	if _, err := tx.Exec(recordOverviewMetricsHistoryQuery, args...); err != nil {
+		return fmt.Errorf("error recording stock overview metrics history for symbol %s: %w", record.Symbol, err)
+	}
+	if _, err := tx.Exec(pruneOverviewMetricsHistoryQuery, record.Symbol, day); err != nil {
+		return fmt.Errorf("error pruning stock overview metrics history for symbol %s: %w", record.Symbol, err)
+	}
+	return nil
+}
+
+// GetStockOverviewMetricHistory returns the dated values of one overview metric, oldest first.
+func (r *stockRepository) GetStockOverviewMetricHistory(symbol, metric string, from, to *time.Time) ([]OverviewMetricPoint, error) {
+	column, ok := ResolveOverviewMetricColumn(metric)
+	if !ok {
+		return nil, fmt.Errorf("unknown overview metric %s", metric)
+	}
+
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT recorded_on, %s AS value FROM stock_overview_metrics_history WHERE symbol = $1`, column)
+	args := []interface{}{symbol}
+	argCount := 1
+
+	if from != nil {
+		argCount++
+		query += fmt.Sprintf(" AND recorded_on >= $%d", argCount)
+		args = append(args, from.Format(SQLDateFormat))
+	}
+	if to != nil {
+		argCount++
+		query += fmt.Sprintf(" AND recorded_on <= $%d", argCount)
+		args = append(args, to.Format(SQLDateFormat))
+	}
+	query += " ORDER BY recorded_on ASC"
+
+	var points []OverviewMetricPoint
+	if err := db.Select(&points, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching %s history for symbol %s: %w", column, symbol, err)
+	}
+
+	return points, nil
+}
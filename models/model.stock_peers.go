@@ -0,0 +1,208 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Peer comparison metric names.
+const (
+	PeerMetricROE           = "roe"
+	PeerMetricNPM           = "npm"
+	PeerMetricDER           = "der"
+	PeerMetricPE            = "pe"
+	PeerMetricDividendYield = "dividend_yield"
+)
+
+// StockPeerMetrics is the per-symbol input of a sector peer comparison.
+type StockPeerMetrics struct {
+	Symbol            string   `db:"symbol"`
+	ReturnOnEquity    *float64 `db:"return_on_equity"`
+	NetProfitMargin   *float64 `db:"net_profit_margin"`
+	DebtToEquityRatio *float64 `db:"debt_to_equity_ratio"`
+	DividendYield     *float64 `db:"dividend_yield"`
+	Eps               *float64 `db:"eps"`
+	MarketCap         *float64 `db:"market_cap"`
+	SharesOutstanding *int64   `db:"shares_outstanding"`
+	PE5YHighRatio     *float64 `db:"pe_5y_high_ratio"`
+	PE5YLowRatio      *float64 `db:"pe_5y_low_ratio"`
+}
+
+// PriceToEarnings derives a trailing P/E from market cap, shares outstanding and EPS.
+// It is nil when any input is missing or EPS is not positive.
+func (m StockPeerMetrics) PriceToEarnings() *float64 {
+	if m.MarketCap == nil || m.SharesOutstanding == nil || *m.SharesOutstanding <= 0 || m.Eps == nil || *m.Eps <= 0 {
+		return nil
+	}
+	pe := *m.MarketCap / float64(*m.SharesOutstanding) / *m.Eps
+	return &pe
+}
+
+func (m StockPeerMetrics) metric(name string) *float64 {
+	switch name {
+	case PeerMetricROE:
+		return m.ReturnOnEquity
+	case PeerMetricNPM:
+		return m.NetProfitMargin
+	case PeerMetricDER:
+		return m.DebtToEquityRatio
+	case PeerMetricPE:
+		return m.PriceToEarnings()
+	case PeerMetricDividendYield:
+		return m.DividendYield
+	}
+	return nil
+}
+
+// peerMetrics are the compared metrics; DER and P/E are better when lower.
+var peerMetrics = []struct {
+	name          string
+	lowerIsBetter bool
+}{
+	{PeerMetricROE, false},
+	{PeerMetricNPM, false},
+	{PeerMetricDER, true},
+	{PeerMetricPE, true},
+	{PeerMetricDividendYield, false},
+}
+
+// PeerMetricComparison places one metric of a symbol within its sector.
+type PeerMetricComparison struct {
+	Metric       string   `json:"metric"`
+	Value        *float64 `json:"value"`
+	SectorMedian *float64 `json:"sector_median"`
+	// PercentileRank is the share of peers (0-100) with a lower value, counting ties as half.
+	PercentileRank *float64 `json:"percentile_rank"`
+	PeerCount      int      `json:"peer_count"`
+	LowerIsBetter  bool     `json:"lower_is_better"`
+}
+
+// PEBand positions the current P/E within its own five-year range.
+type PEBand struct {
+	PE     *float64 `json:"pe"`
+	Low5Y  *float64 `json:"low_5y"`
+	High5Y *float64 `json:"high_5y"`
+	// Position is 0 at the five-year low and 100 at the high; it may fall outside that range.
+	Position *float64 `json:"position"`
+}
+
+// StockPeerComparison is the response of the peers endpoint.
+type StockPeerComparison struct {
+	Symbol    string                 `json:"symbol"`
+	Name      *string                `json:"name"`
+	Sector    string                 `json:"sector"`
+	PeerCount int                    `json:"peer_count"`
+	Metrics   []PeerMetricComparison `json:"metrics"`
+	PEBand    PEBand                 `json:"pe_band"`
+}
+
+// ComputeStockPeerComparison compares symbol with the sector peers. Peers must include
+// the symbol itself; an error is returned when it is missing.
+func ComputeStockPeerComparison(profile StockProfile, peers []StockPeerMetrics) (*StockPeerComparison, error) {
+	var target *StockPeerMetrics
+	for i := range peers {
+		if peers[i].Symbol == profile.Ticker {
+			target = &peers[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("symbol %s is missing from its sector peers", profile.Ticker)
+	}
+
+	result := &StockPeerComparison{
+		Symbol:    profile.Ticker,
+		Name:      profile.Name,
+		PeerCount: len(peers),
+		Metrics:   make([]PeerMetricComparison, 0, len(peerMetrics)),
+	}
+	if profile.Sector != nil {
+		result.Sector = *profile.Sector
+	}
+
+	for _, definition := range peerMetrics {
+		values := make([]float64, 0, len(peers))
+		for _, peer := range peers {
+			if value := peer.metric(definition.name); value != nil {
+				values = append(values, *value)
+			}
+		}
+		sort.Float64s(values)
+
+		comparison := PeerMetricComparison{
+			Metric:        definition.name,
+			Value:         target.metric(definition.name),
+			SectorMedian:  medianOfSorted(values),
+			PeerCount:     len(values),
+			LowerIsBetter: definition.lowerIsBetter,
+		}
+		if comparison.Value != nil {
+			comparison.PercentileRank = percentileRank(values, *comparison.Value)
+		}
+		result.Metrics = append(result.Metrics, comparison)
+	}
+
+	result.PEBand = PEBand{
+		PE:     target.PriceToEarnings(),
+		Low5Y:  target.PE5YLowRatio,
+		High5Y: target.PE5YHighRatio,
+	}
+	band := result.PEBand
+	if band.PE != nil && band.Low5Y != nil && band.High5Y != nil && *band.High5Y > *band.Low5Y {
+		position := (*band.PE - *band.Low5Y) / (*band.High5Y - *band.Low5Y) * 100
+		result.PEBand.Position = &position
+	}
+
+	return result, nil
+}
+
+func medianOfSorted(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	mid := len(values) / 2
+	median := values[mid]
+	if len(values)%2 == 0 {
+		median = (values[mid-1] + values[mid]) / 2
+	}
+	return &median
+}
+
+func percentileRank(sorted []float64, value float64) *float64 {
+	if len(sorted) == 0 {
+		return nil
+	}
+	below, equal := 0, 0
+	for _, v := range sorted {
+		if v < value {
+			below++
+		} else if v == value {
+			equal++
+		}
+	}
+	rank := (float64(below) + float64(equal)/2) / float64(len(sorted)) * 100
+	return &rank
+}
+
+// GetSectorPeerMetrics returns the overview metrics of every stock in a sector.
+func (r *stockRepository) GetSectorPeerMetrics(sector string) ([]StockPeerMetrics, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT m.symbol, m.return_on_equity, m.net_profit_margin, m.debt_to_equity_ratio,
+			m.dividend_yield, m.eps, m.market_cap, m.shares_outstanding,
+			m.pe_5y_high_ratio, m.pe_5y_low_ratio
+		FROM stock s
+		JOIN stock_overview_metrics m ON m.symbol = s.ticker
+		WHERE s.sector::text = $1
+		ORDER BY m.symbol ASC`
+
+	var peers []StockPeerMetrics
+	if err := db.Select(&peers, query, sector); err != nil {
+		return nil, fmt.Errorf("error fetching peer metrics for sector %s: %w", sector, err)
+	}
+	return peers, nil
+}
@@ -0,0 +1,403 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StockBoard is the IDX listing board of a stock.
+type StockBoard string
+
+const (
+	StockBoardMain         StockBoard = "main"
+	StockBoardDevelopment  StockBoard = "development"
+	StockBoardAcceleration StockBoard = "acceleration"
+)
+
+// StockBoards lists every valid listing board.
+var StockBoards = []StockBoard{StockBoardMain, StockBoardDevelopment, StockBoardAcceleration}
+
+// IsValidStockBoard reports whether board is a known listing board.
+func IsValidStockBoard(board string) bool {
+	for _, valid := range StockBoards {
+		if string(valid) == board {
+			return true
+		}
+	}
+	return false
+}
+
+// StockProfile holds the master attributes of a ticker stored in public.stock.
+// Sector and industry are the public.stock_sector / public.stock_industry enum labels.
+type StockProfile struct {
+	Ticker      string  `json:"ticker" db:"ticker"`
+	Name        *string `json:"name" db:"name"`
+	Sector      *string `json:"sector" db:"sector"`
+	Industry    *string `json:"industry" db:"industry"`
+	SubIndustry *string `json:"sub_industry" db:"sub_industry"`
+	Board       *string `json:"board" db:"board"`
+}
+
+// StockClassificationOptions are the values accepted for a profile's sector, industry and board.
+type StockClassificationOptions struct {
+	Sectors    []string `json:"sectors"`
+	Industries []string `json:"industries"`
+	Boards     []string `json:"boards"`
+}
+
+// ErrInvalidStockClassification is returned when a profile uses an unknown sector, industry or board.
+var ErrInvalidStockClassification = errors.New("invalid stock classification")
+
+// Validate checks a profile's sector, industry and board against the available options.
+func (o StockClassificationOptions) Validate(profile StockProfile) error {
+	if profile.Sector != nil && !containsString(o.Sectors, *profile.Sector) {
+		return fmt.Errorf("%w: unknown sector %q", ErrInvalidStockClassification, *profile.Sector)
+	}
+	if profile.Industry != nil && !containsString(o.Industries, *profile.Industry) {
+		return fmt.Errorf("%w: unknown industry %q", ErrInvalidStockClassification, *profile.Industry)
+	}
+	if profile.Board != nil && !IsValidStockBoard(*profile.Board) {
+		return fmt.Errorf("%w: unknown board %q", ErrInvalidStockClassification, *profile.Board)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StockKeyMetrics are the headline ratios from stock_overview_metrics.
+type StockKeyMetrics struct {
+	ReturnOnEquity    *float64 `json:"return_on_equity" db:"return_on_equity"`
+	NetProfitMargin   *float64 `json:"net_profit_margin" db:"net_profit_margin"`
+	DebtToEquityRatio *float64 `json:"debt_to_equity_ratio" db:"debt_to_equity_ratio"`
+	PriceToBookRatio  *float64 `json:"price_to_book_ratio" db:"price_to_book_ratio"`
+	DividendYield     *float64 `json:"dividend_yield" db:"dividend_yield"`
+	Eps               *float64 `json:"eps" db:"eps"`
+	MarketCap         *float64 `json:"market_cap" db:"market_cap"`
+}
+
+// StockSectorAverage is the mean of the key ratios over the stocks of one sector.
+// Stocks without a value for a ratio are left out of that ratio's average.
+type StockSectorAverage struct {
+	Sector            string   `json:"sector" db:"sector"`
+	StockCount        int      `json:"stock_count" db:"stock_count"`
+	ReturnOnEquity    *float64 `json:"return_on_equity" db:"return_on_equity"`
+	NetProfitMargin   *float64 `json:"net_profit_margin" db:"net_profit_margin"`
+	DebtToEquityRatio *float64 `json:"debt_to_equity_ratio" db:"debt_to_equity_ratio"`
+	PriceToBookRatio  *float64 `json:"price_to_book_ratio" db:"price_to_book_ratio"`
+	DividendYield     *float64 `json:"dividend_yield" db:"dividend_yield"`
+}
+
+// StockOverview combines a ticker's profile, key ratios and its sector comparison.
+type StockOverview struct {
+	Profile       StockProfile        `json:"profile"`
+	Metrics       *StockKeyMetrics    `json:"metrics"`
+	SectorAverage *StockSectorAverage `json:"sector_average"`
+	// SectorRelative is each ratio minus its sector average.
+	SectorRelative *StockKeyMetrics `json:"sector_relative"`
+}
+
+// CompareToSector returns metrics minus the sector averages; market cap and EPS are not compared.
+func CompareToSector(metrics *StockKeyMetrics, average *StockSectorAverage) *StockKeyMetrics {
+	if metrics == nil || average == nil {
+		return nil
+	}
+	diff := func(value, avg *float64) *float64 {
+		if value == nil || avg == nil {
+			return nil
+		}
+		d := *value - *avg
+		return &d
+	}
+	return &StockKeyMetrics{
+		ReturnOnEquity:    diff(metrics.ReturnOnEquity, average.ReturnOnEquity),
+		NetProfitMargin:   diff(metrics.NetProfitMargin, average.NetProfitMargin),
+		DebtToEquityRatio: diff(metrics.DebtToEquityRatio, average.DebtToEquityRatio),
+		PriceToBookRatio:  diff(metrics.PriceToBookRatio, average.PriceToBookRatio),
+		DividendYield:     diff(metrics.DividendYield, average.DividendYield),
+	}
+}
+
+// StockProfileImportError describes a rejected CSV row.
+type StockProfileImportError struct {
+	Line    int    `json:"line"`
+	Ticker  string `json:"ticker"`
+	Message string `json:"message"`
+}
+
+// StockProfileImportResult summarises a CSV import.
+type StockProfileImportResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+}
+
+// stockProfileCSVColumns are the recognised CSV headers; only ticker is mandatory.
+var stockProfileCSVColumns = []string{"ticker", "name", "sector", "industry", "sub_industry", "board"}
+
+// ParseStockProfilesCSV reads profiles from a CSV with a header row and validates each
+// row against options. Empty cells are returned as nil so the import keeps the stored value.
+func ParseStockProfilesCSV(r io.Reader, options StockClassificationOptions) ([]StockProfile, []StockProfileImportError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if containsString(stockProfileCSVColumns, column) {
+			index[column] = i
+		}
+	}
+	if _, ok := index["ticker"]; !ok {
+		return nil, nil, fmt.Errorf("CSV header must contain a ticker column")
+	}
+
+	cell := func(row []string, column string) *string {
+		i, ok := index[column]
+		if !ok || i >= len(row) {
+			return nil
+		}
+		value := strings.TrimSpace(row[i])
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+
+	profiles := []StockProfile{}
+	rowErrors := []StockProfileImportError{}
+	seen := make(map[string]int)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		ticker := cell(row, "ticker")
+		if ticker == nil {
+			rowErrors = append(rowErrors, StockProfileImportError{Line: line, Message: "ticker is required"})
+			continue
+		}
+		upper := strings.ToUpper(*ticker)
+		if len(upper) != 4 {
+			rowErrors = append(rowErrors, StockProfileImportError{Line: line, Ticker: upper, Message: "ticker must be 4 characters"})
+			continue
+		}
+		if previous, ok := seen[upper]; ok {
+			rowErrors = append(rowErrors, StockProfileImportError{Line: line, Ticker: upper, Message: fmt.Sprintf("duplicate of line %d", previous)})
+			continue
+		}
+		seen[upper] = line
+
+		profile := StockProfile{
+			Ticker:      upper,
+			Name:        cell(row, "name"),
+			Sector:      cell(row, "sector"),
+			Industry:    cell(row, "industry"),
+			SubIndustry: cell(row, "sub_industry"),
+			Board:       cell(row, "board"),
+		}
+		if profile.Board != nil {
+			board := strings.ToLower(*profile.Board)
+			profile.Board = &board
+		}
+		if err := options.Validate(profile); err != nil {
+			rowErrors = append(rowErrors, StockProfileImportError{Line: line, Ticker: upper, Message: err.Error()})
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rowErrors, nil
+}
+
+const stockProfileColumns = `ticker, name, sector::text AS sector, industry::text AS industry, sub_industry, board`
+
+func (r *stockRepository) GetStockClassificationOptions() (*StockClassificationOptions, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	options := &StockClassificationOptions{Boards: make([]string, 0, len(StockBoards))}
+	if err := db.Select(&options.Sectors, `SELECT unnest(enum_range(NULL::public.stock_sector))::text`); err != nil {
+		return nil, fmt.Errorf("error fetching stock sectors: %w", err)
+	}
+	if err := db.Select(&options.Industries, `SELECT unnest(enum_range(NULL::public.stock_industry))::text`); err != nil {
+		return nil, fmt.Errorf("error fetching stock industries: %w", err)
+	}
+	for _, board := range StockBoards {
+		options.Boards = append(options.Boards, string(board))
+	}
+	return options, nil
+}
+
+func (r *stockRepository) GetStockProfile(ticker string) (*StockProfile, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var profile StockProfile
+	err = db.Get(&profile, `SELECT `+stockProfileColumns+` FROM stock WHERE ticker = $1`, ticker)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching stock profile %s: %w", ticker, err)
+	}
+	return &profile, nil
+}
+
+// UpdateStockProfile replaces the master attributes of an existing ticker.
+func (r *stockRepository) UpdateStockProfile(profile StockProfile) (*StockProfile, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE stock SET
+			name = $2,
+			sector = $3::public.stock_sector,
+			industry = $4::public.stock_industry,
+			sub_industry = $5,
+			board = $6
+		WHERE ticker = $1
+		RETURNING ` + stockProfileColumns
+
+	var updated StockProfile
+	err = db.QueryRowx(query, profile.Ticker, profile.Name, profile.Sector, profile.Industry, profile.SubIndustry, profile.Board).StructScan(&updated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error updating stock profile %s: %w", profile.Ticker, err)
+	}
+	return &updated, nil
+}
+
+// ImportStockProfiles upserts profiles in one transaction. Nil attributes keep the
+// stored value; new tickers are added like CreateTrackedStock, without an API key.
+func (r *stockRepository) ImportStockProfiles(profiles []StockProfile) (*StockProfileImportResult, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		INSERT INTO stock (ticker, name, sector, industry, sub_industry, board, enabled, last_update)
+		VALUES ($1, $2, $3::public.stock_sector, $4::public.stock_industry, $5, $6, TRUE, TIMESTAMPTZ 'epoch')
+		ON CONFLICT (ticker) DO UPDATE SET
+			name = COALESCE(EXCLUDED.name, stock.name),
+			sector = COALESCE(EXCLUDED.sector, stock.sector),
+			industry = COALESCE(EXCLUDED.industry, stock.industry),
+			sub_industry = COALESCE(EXCLUDED.sub_industry, stock.sub_industry),
+			board = COALESCE(EXCLUDED.board, stock.board)
+		RETURNING (xmax = 0) AS inserted`
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for stock profile import: %w", err)
+	}
+
+	stmt, err := tx.Preparex(query)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error preparing stock profile import statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result := &StockProfileImportResult{}
+	for _, profile := range profiles {
+		var inserted bool
+		if err := stmt.QueryRowx(profile.Ticker, profile.Name, profile.Sector, profile.Industry, profile.SubIndustry, profile.Board).Scan(&inserted); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("error importing stock profile %s: %w", profile.Ticker, err)
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing stock profile import: %w", err)
+	}
+	return result, nil
+}
+
+func (r *stockRepository) GetStockKeyMetrics(symbol string) (*StockKeyMetrics, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT return_on_equity, net_profit_margin, debt_to_equity_ratio, price_to_book_ratio,
+			dividend_yield, eps, market_cap
+		FROM stock_overview_metrics
+		WHERE symbol = $1`
+
+	var metrics StockKeyMetrics
+	if err := db.Get(&metrics, query, symbol); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching key metrics for symbol %s: %w", symbol, err)
+	}
+	return &metrics, nil
+}
+
+// GetSectorAverages returns per-sector averages of the key ratios, optionally for one sector.
+func (r *stockRepository) GetSectorAverages(sector *string) ([]StockSectorAverage, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			s.sector::text AS sector,
+			COUNT(*) AS stock_count,
+			AVG(m.return_on_equity) AS return_on_equity,
+			AVG(m.net_profit_margin) AS net_profit_margin,
+			AVG(m.debt_to_equity_ratio) AS debt_to_equity_ratio,
+			AVG(m.price_to_book_ratio) AS price_to_book_ratio,
+			AVG(m.dividend_yield) AS dividend_yield
+		FROM stock s
+		JOIN stock_overview_metrics m ON m.symbol = s.ticker
+		WHERE s.sector IS NOT NULL`
+	args := []interface{}{}
+
+	if sector != nil {
+		query += ` AND s.sector::text = $1`
+		args = append(args, *sector)
+	}
+	query += ` GROUP BY s.sector ORDER BY s.sector::text ASC`
+
+	averages := []StockSectorAverage{}
+	if err := db.Select(&averages, query, args...); err != nil {
+		return nil, fmt.Errorf("error fetching sector averages: %w", err)
+	}
+	return averages, nil
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// periodCodeFormat is the YYYYMM layout used by stock_earning_quarterly_history.period_code.
+const periodCodeFormat = "200601"
+
+// QuarterlyDerivedMetric holds TTM and growth figures derived for a single quarter.
+type QuarterlyDerivedMetric struct {
+	PeriodCode       string   `json:"period_code"`
+	EpsActual        *float64 `json:"eps_actual"`
+	RevenueActual    *float64 `json:"revenue_actual"`
+	EpsTTM           *float64 `json:"eps_ttm"`
+	RevenueTTM       *float64 `json:"revenue_ttm"`
+	EpsQoQGrowth     *float64 `json:"eps_qoq_growth"`
+	EpsYoYGrowth     *float64 `json:"eps_yoy_growth"`
+	RevenueQoQGrowth *float64 `json:"revenue_qoq_growth"`
+	RevenueYoYGrowth *float64 `json:"revenue_yoy_growth"`
+	// TTMComplete is false when one of the trailing four quarters is missing,
+	// in which case the TTM figures are left empty rather than under-counted.
+	TTMComplete bool `json:"ttm_complete"`
+}
+
+// StockQuarterlyMetrics is the derived-metrics view over a symbol's quarterly history.
+type StockQuarterlyMetrics struct {
+	Symbol           string                   `json:"symbol"`
+	LatestPeriodCode *string                  `json:"latest_period_code"`
+	LatestEpsTTM     *float64                 `json:"latest_eps_ttm"`
+	LatestRevenueTTM *float64                 `json:"latest_revenue_ttm"`
+	MissingPeriods   []string                 `json:"missing_periods"`
+	Quarters         []QuarterlyDerivedMetric `json:"quarters"`
+}
+
+// ShiftPeriodCode moves a YYYYMM period code by the given number of months.
+func ShiftPeriodCode(periodCode string, months int) (string, error) {
+	parsed, err := time.Parse(periodCodeFormat, periodCode)
+	if err != nil {
+		return "", fmt.Errorf("invalid period code %s: %w", periodCode, err)
+	}
+	return parsed.AddDate(0, months, 0).Format(periodCodeFormat), nil
+}
+
+// ComputeStockQuarterlyMetrics derives TTM, QoQ and YoY figures from quarterly history.
+// Records must be sorted by period code ascending; only reported quarters are used.
+func ComputeStockQuarterlyMetrics(symbol string, records []StockEarningQuarterlyHistoryRecord) (*StockQuarterlyMetrics, error) {
+	result := &StockQuarterlyMetrics{
+		Symbol:         symbol,
+		MissingPeriods: []string{},
+		Quarters:       []QuarterlyDerivedMetric{},
+	}
+
+	reported := make([]StockEarningQuarterlyHistoryRecord, 0, len(records))
+	byPeriod := make(map[string]StockEarningQuarterlyHistoryRecord, len(records))
+	for _, record := range records {
+		if record.EpsActual == nil && record.RevenueActual == nil {
+			continue
+		}
+		reported = append(reported, record)
+		byPeriod[record.PeriodCode] = record
+	}
+
+	if len(reported) == 0 {
+		return result, nil
+	}
+
+	// Flag gaps between the first and last reported quarter
+	first := reported[0].PeriodCode
+	last := reported[len(reported)-1].PeriodCode
+	for period := first; period < last; {
+		next, err := ShiftPeriodCode(period, 3)
+		if err != nil {
+			return nil, err
+		}
+		if next < last {
+			if _, ok := byPeriod[next]; !ok {
+				result.MissingPeriods = append(result.MissingPeriods, next)
+			}
+		}
+		period = next
+	}
+
+	for _, record := range reported {
+		metric := QuarterlyDerivedMetric{
+			PeriodCode:    record.PeriodCode,
+			EpsActual:     record.EpsActual,
+			RevenueActual: record.RevenueActual,
+		}
+
+		previousCode, err := ShiftPeriodCode(record.PeriodCode, -3)
+		if err != nil {
+			return nil, err
+		}
+		priorYearCode, err := ShiftPeriodCode(record.PeriodCode, -12)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous, ok := byPeriod[previousCode]; ok {
+			metric.EpsQoQGrowth = growthPercent(record.EpsActual, previous.EpsActual)
+			metric.RevenueQoQGrowth = growthPercent(record.RevenueActual, previous.RevenueActual)
+		}
+		if priorYear, ok := byPeriod[priorYearCode]; ok {
+			metric.EpsYoYGrowth = growthPercent(record.EpsActual, priorYear.EpsActual)
+			metric.RevenueYoYGrowth = growthPercent(record.RevenueActual, priorYear.RevenueActual)
+		}
+
+		trailing := make([]StockEarningQuarterlyHistoryRecord, 0, 4)
+		metric.TTMComplete = true
+		for offset := 0; offset > -12; offset -= 3 {
+			code, err := ShiftPeriodCode(record.PeriodCode, offset)
+			if err != nil {
+				return nil, err
+			}
+			quarter, ok := byPeriod[code]
+			if !ok {
+				metric.TTMComplete = false
+				break
+			}
+			trailing = append(trailing, quarter)
+		}
+
+		if metric.TTMComplete {
+			metric.EpsTTM = sumQuarters(trailing, func(q StockEarningQuarterlyHistoryRecord) *float64 { return q.EpsActual })
+			metric.RevenueTTM = sumQuarters(trailing, func(q StockEarningQuarterlyHistoryRecord) *float64 { return q.RevenueActual })
+		}
+
+		result.Quarters = append(result.Quarters, metric)
+	}
+
+	latest := result.Quarters[len(result.Quarters)-1]
+	result.LatestPeriodCode = &latest.PeriodCode
+	result.LatestEpsTTM = latest.EpsTTM
+	result.LatestRevenueTTM = latest.RevenueTTM
+
+	return result, nil
+}
+
+// growthPercent returns (current - base) / |base| * 100, or nil when it cannot be computed.
+func growthPercent(current, base *float64) *float64 {
+	if current == nil || base == nil || *base == 0 {
+		return nil
+	}
+	growth := (*current - *base) / math.Abs(*base) * 100
+	return &growth
+}
+
+// sumQuarters adds up a field across quarters, returning nil if any value is missing.
+func sumQuarters(quarters []StockEarningQuarterlyHistoryRecord, field func(StockEarningQuarterlyHistoryRecord) *float64) *float64 {
+	total := 0.0
+	for _, quarter := range quarters {
+		value := field(quarter)
+		if value == nil {
+			return nil
+		}
+		total += *value
+	}
+	return &total
+}
@@ -0,0 +1,297 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrSubscriptionPlanNotFound is returned for unknown plan ids.
+	ErrSubscriptionPlanNotFound = errors.New("subscription plan not found")
+	// ErrSubscriptionPlanInactive is returned when buying a plan that is no longer offered.
+	ErrSubscriptionPlanInactive = errors.New("subscription plan is not active")
+	// ErrSubscriptionPlanLevelMismatch is returned when a plan's tier differs from the requested level.
+	ErrSubscriptionPlanLevelMismatch = errors.New("subscription plan does not match the user level")
+	// ErrSubscriptionPlanExists is returned when creating a second plan with the same tier and duration.
+	ErrSubscriptionPlanExists = errors.New("subscription plan already exists")
+	// ErrSubscriptionPlanDowngrade is returned when buying a lower tier than the user's active subscription.
+	ErrSubscriptionPlanDowngrade = errors.New("subscription plan is below the active subscription")
+)
+
+// SubscriptionPlan is a purchasable premium tier and duration.
+type SubscriptionPlan struct {
+	ID             int       `json:"id" db:"id"`
+	UserLevel      UserLevel `json:"user_level" db:"user_level"`
+	Name           string    `json:"name" db:"name"`
+	DurationMonths int       `json:"duration_months" db:"duration_months"`
+	Price          float64   `json:"price" db:"price"`
+	Active         bool      `json:"active" db:"active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PricingResponse lists the plans offered on the public pricing page.
+type PricingResponse struct {
+	Currency string              `json:"currency"`
+	Plans    []*SubscriptionPlan `json:"plans"`
+}
+
+// legacyPlanDurationMonths is the duration of payments recorded before plans existed.
+func legacyPlanDurationMonths(level UserLevel) int {
+	if level == UserLevelPremium {
+		return 1
+	}
+	return 12
+}
+
+// premiumLevelRank orders the paid tiers; free and legacy levels rank lowest.
+func premiumLevelRank(level UserLevel) int {
+	switch level {
+	case UserLevelPremium:
+		return 1
+	case UserLevelPremiumPlus:
+		return 2
+	}
+	return 0
+}
+
+// activePremiumLevel returns user's paid tier while their subscription is active at now,
+// and free otherwise.
+func activePremiumLevel(user *User, now time.Time) UserLevel {
+	if premiumLevelRank(user.UserLevel) == 0 || user.PremiumExpiresAt == nil || !user.PremiumExpiresAt.After(now) {
+		return UserLevelFree
+	}
+	return user.UserLevel
+}
+
+// ExtendPremiumExpiry returns the new premium_expires_at when user buys months of a plan
+// at now. The months count from the current expiry while the user still has an active
+// premium subscription, otherwise from now. A prorated upgrade already credited the
+// remaining time, so it starts from now.
+func ExtendPremiumExpiry(user *User, months int, prorated bool, now time.Time) time.Time {
+	// Check if user has a valid (non-expired) premium subscription to extend from
+	hasValidSubscription := (user.UserLevel == UserLevelPremium || user.UserLevel == UserLevelPremiumPlus) &&
+		user.PremiumExpiresAt != nil &&
+		user.PremiumExpiresAt.After(now)
+
+	// Start from current expiration if valid, otherwise start from now
+	baseDate := now
+	if hasValidSubscription && !prorated {
+		baseDate = *user.PremiumExpiresAt
+	}
+
+	return baseDate.AddDate(0, months, 0)
+}
+
+// ProrateCredit returns the unused value of a paid period at now: paid spread evenly
+// over [periodStart, periodEnd), times the time left until expiresAt.
+func ProrateCredit(paid float64, periodStart, periodEnd, expiresAt, now time.Time) float64 {
+	period := periodEnd.Sub(periodStart)
+	remaining := expiresAt.Sub(now)
+	if period <= 0 || remaining <= 0 || paid <= 0 {
+		return 0
+	}
+	return math.Round(paid * remaining.Hours() / period.Hours())
+}
+
+// SubscriptionPlanRepository defines operations for subscription plans.
+type SubscriptionPlanRepository interface {
+	ListPlans(activeOnly bool) ([]*SubscriptionPlan, error)
+	FindPlan(id int) (*SubscriptionPlan, error)
+	CreatePlan(plan *SubscriptionPlan) (*SubscriptionPlan, error)
+	UpdatePlan(plan *SubscriptionPlan) (*SubscriptionPlan, error)
+}
+
+type subscriptionPlanRepository struct{}
+
+// NewSubscriptionPlanRepository creates a new subscription plan repository.
+func NewSubscriptionPlanRepository() SubscriptionPlanRepository {
+	return &subscriptionPlanRepository{}
+}
+
+func (r *subscriptionPlanRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *subscriptionPlanRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+// ListPlans returns plans by tier and duration, only the active ones when activeOnly.
+func (r *subscriptionPlanRepository) ListPlans(activeOnly bool) ([]*SubscriptionPlan, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT * FROM subscription_plans
+		WHERE (NOT $1 OR active)
+		ORDER BY user_level, duration_months`
+
+	var plans []*SubscriptionPlan
+	if err := db.Select(&plans, query, activeOnly); err != nil {
+		return nil, fmt.Errorf("error fetching subscription plans: %w", err)
+	}
+	if plans == nil {
+		plans = []*SubscriptionPlan{}
+	}
+	return plans, nil
+}
+
+// FindPlan returns a plan, or nil when it does not exist.
+func (r *subscriptionPlanRepository) FindPlan(id int) (*SubscriptionPlan, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+	return findSubscriptionPlan(db, id)
+}
+
+// CreatePlan inserts a plan; a tier offers one plan per duration.
+func (r *subscriptionPlanRepository) CreatePlan(plan *SubscriptionPlan) (*SubscriptionPlan, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO subscription_plans (user_level, name, duration_months, price, active)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_level, duration_months) DO NOTHING
+		RETURNING *`
+
+	var created SubscriptionPlan
+	err = db.Get(&created, query, plan.UserLevel, plan.Name, plan.DurationMonths, plan.Price, plan.Active)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionPlanExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating subscription plan: %w", err)
+	}
+	return &created, nil
+}
+
+// UpdatePlan saves a plan's name, price and active flag. Pending checkouts keep the
+// price they were opened with.
+func (r *subscriptionPlanRepository) UpdatePlan(plan *SubscriptionPlan) (*SubscriptionPlan, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE subscription_plans
+		SET name = $2, price = $3, active = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING *`
+
+	var updated SubscriptionPlan
+	err = db.Get(&updated, query, plan.ID, plan.Name, plan.Price, plan.Active)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error updating subscription plan %d: %w", plan.ID, err)
+	}
+	return &updated, nil
+}
+
+func findSubscriptionPlan(q sqlx.Queryer, id int) (*SubscriptionPlan, error) {
+	var plan SubscriptionPlan
+	if err := sqlx.Get(q, &plan, `SELECT * FROM subscription_plans WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching subscription plan %d: %w", id, err)
+	}
+	return &plan, nil
+}
+
+// purchasablePlan returns the plan with the given id when it can be bought for level.
+func purchasablePlan(tx *sqlx.Tx, id int, level UserLevel) (*SubscriptionPlan, error) {
+	plan, err := findSubscriptionPlan(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, ErrSubscriptionPlanNotFound
+	}
+	if !plan.Active {
+		return nil, ErrSubscriptionPlanInactive
+	}
+	if plan.UserLevel != level {
+		return nil, ErrSubscriptionPlanLevelMismatch
+	}
+	return plan, nil
+}
+
+// paymentPlanDurationMonths returns the subscription months a payment buys.
+func paymentPlanDurationMonths(q sqlx.Queryer, payment *PaymentRecord) (int, error) {
+	if payment.SubscriptionPlanID == nil {
+		return legacyPlanDurationMonths(payment.SubscriptionType), nil
+	}
+	plan, err := findSubscriptionPlan(q, *payment.SubscriptionPlanID)
+	if err != nil {
+		return 0, err
+	}
+	if plan == nil {
+		return 0, ErrSubscriptionPlanNotFound
+	}
+	return plan.DurationMonths, nil
+}
+
+// upgradeProrationCredit returns the credit for the unused part of user's active premium
+// subscription when they buy a plan of level premium+ at now, valued at the rate of their
+// latest completed premium payment. It is zero for any other change and when the premium
+// time was not paid for.
+func upgradeProrationCredit(tx *sqlx.Tx, user *User, level UserLevel, now time.Time) (float64, error) {
+	if level != UserLevelPremiumPlus || user.UserLevel != UserLevelPremium ||
+		user.PremiumExpiresAt == nil || !user.PremiumExpiresAt.After(now) {
+		return 0, nil
+	}
+
+	var last struct {
+		PaidPrice      float64   `db:"paid_price"`
+		ExpiresAt      time.Time `db:"expires_at"`
+		DurationMonths int       `db:"duration_months"`
+	}
+	query := `
+		SELECT p.paid_price, p.expires_at, COALESCE(sp.duration_months, $2) AS duration_months
+		FROM payment_records p
+		LEFT JOIN subscription_plans sp ON sp.id = p.subscription_plan_id
+		WHERE p.user_id = $1 AND p.subscription_type = 'premium' AND p.payment_status = 'completed'
+		ORDER BY p.expires_at DESC, p.id DESC
+		LIMIT 1`
+	if err := tx.Get(&last, query, user.ID, legacyPlanDurationMonths(UserLevelPremium)); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error fetching last premium payment of user %d: %w", user.ID, err)
+	}
+
+	periodStart := last.ExpiresAt.AddDate(0, -last.DurationMonths, 0)
+	return ProrateCredit(last.PaidPrice, periodStart, last.ExpiresAt, *user.PremiumExpiresAt, now), nil
+}
+
+// applyProrationCredit lowers the payment's paid price by credit, down to zero at most.
+func applyProrationCredit(payment *PaymentRecord, credit float64) {
+	if credit > payment.PaidPrice {
+		credit = payment.PaidPrice
+	}
+	payment.ProrationCredit = credit
+	payment.PaidPrice -= credit
+}
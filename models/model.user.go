@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/WahyuSiddarta/be_saham_go/utime"
@@ -29,8 +30,16 @@ const (
 	UserStatusInactive  UserStatus = "inactive"
 	UserStatusSuspended UserStatus = "suspended"
 	UserStatusBanned    UserStatus = "banned"
+	// UserStatusUnverified is a new account whose email is not confirmed yet (db/email_verification.sql)
+	UserStatusUnverified UserStatus = "unverified"
 )
 
+// CanSignIn reports whether users in this status may log in. Unverified users may,
+// with access restricted until they confirm their email.
+func (us UserStatus) CanSignIn() bool {
+	return us == UserStatusActive || us == UserStatusUnverified
+}
+
 // Scan implements the sql.Scanner interface
 func (us *UserStatus) Scan(value interface{}) error {
 	if value == nil {
@@ -56,7 +65,8 @@ const (
 	UserLevelFree        UserLevel = "free"
 	UserLevelPremium     UserLevel = "premium"
 	UserLevelPremiumPlus UserLevel = "premium+"
-	UserLevelAdmin       UserLevel = "admin"
+	// UserLevelAdmin is a legacy level; admin access is granted by roles (model.role.go)
+	UserLevelAdmin UserLevel = "admin"
 )
 
 // Scan implements the sql.Scanner interface
@@ -94,6 +104,24 @@ type PaymentRecord struct {
 	ProcessedByAdminID *int      `json:"processed_by_admin_id,omitempty" db:"processed_by_admin_id"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+
+	// Gateway checkout fields, empty for payments recorded by an admin
+	Gateway           *string    `json:"gateway,omitempty" db:"gateway"`
+	GatewayReference  *string    `json:"gateway_reference,omitempty" db:"gateway_reference"`
+	CheckoutURL       *string    `json:"checkout_url,omitempty" db:"checkout_url"`
+	CheckoutExpiresAt *time.Time `json:"checkout_expires_at,omitempty" db:"checkout_expires_at"`
+	FailureReason     *string    `json:"failure_reason,omitempty" db:"failure_reason"`
+
+	// PromoCodeID is the promo code whose discount the payment carries
+	PromoCodeID *int `json:"promo_code_id,omitempty" db:"promo_code_id"`
+
+	// SubscriptionPlanID is the plan bought, empty for payments recorded before plans existed
+	SubscriptionPlanID *int `json:"subscription_plan_id,omitempty" db:"subscription_plan_id"`
+	// ProrationCredit is the unused premium value deducted on an upgrade to premium+
+	ProrationCredit float64 `json:"proration_credit" db:"proration_credit"`
+
+	// InvoiceNumber is joined from payment_invoices once the payment completed
+	InvoiceNumber *string `json:"invoice_number,omitempty" db:"invoice_number"`
 }
 
 // UserWithPayment represents user data with payment information
@@ -110,15 +138,16 @@ type CreateUserRequest struct {
 	UserLevel UserLevel  `json:"user_level,omitempty"`
 }
 
-// PaymentData represents payment information for premium subscriptions
+// PaymentData represents payment information for premium subscriptions. The prices
+// come from the subscription plan.
 type PaymentData struct {
-	OriginalPrice  float64    `json:"original_price"`
-	PaidPrice      float64    `json:"paid_price"`
 	DiscountAmount *float64   `json:"discount_amount,omitempty"`
 	DiscountReason *string    `json:"discount_reason,omitempty"`
 	PaymentMethod  *string    `json:"payment_method,omitempty"`
 	PaymentDate    *time.Time `json:"payment_date,omitempty"`
 	Notes          *string    `json:"notes,omitempty"`
+	// PromoCode, when set, replaces the discount and paid price with the code's discount
+	PromoCode *string `json:"promo_code,omitempty"`
 }
 
 // UserRepository defines the interface for user data operations
@@ -133,12 +162,12 @@ type UserRepository interface {
 	UpdatePassword(userID int, newPassword string) (*User, error)
 
 	// User level and status management
-	UpdateUserLevel(userID int, userLevel UserLevel, paymentData *PaymentData, processedByAdminID *int) (*UserWithPayment, error)
+	UpdateUserLevel(userID int, userLevel UserLevel, planID *int, paymentData *PaymentData, processedByAdminID *int) (*UserWithPayment, error)
 	UpdateUserStatus(userID int, status UserStatus) (*User, error)
 
 	// Bulk operations
 	GetAllUsers(page, limit int, status *UserStatus, userLevel *UserLevel, emailFilter *string) (*UsersResponse, error)
-	DowngradeExpiredUsers() (*DowngradeResponse, error)
+	DowngradeExpiredUsers(expiredBefore time.Time) (*DowngradeResponse, error)
 	GetExpiredUsers() ([]*User, error)
 }
 
@@ -288,8 +317,10 @@ func (r *userRepository) UpdatePassword(userID int, newPassword string) (*User,
 	return &user, nil
 }
 
-// UpdateUserLevel updates user level and handles premium subscription logic
-func (r *userRepository) UpdateUserLevel(userID int, userLevel UserLevel, paymentData *PaymentData, processedByAdminID *int) (*UserWithPayment, error) {
+// UpdateUserLevel updates user level and handles premium subscription logic. Premium
+// tiers need a plan of that tier, whose duration extends the subscription; with payment
+// data the payment is priced from the plan, less any discount and upgrade proration.
+func (r *userRepository) UpdateUserLevel(userID int, userLevel UserLevel, planID *int, paymentData *PaymentData, processedByAdminID *int) (*UserWithPayment, error) {
 	db := GetDB().PostgreDBManager.RW
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
@@ -312,6 +343,9 @@ func (r *userRepository) UpdateUserLevel(userID int, userLevel UserLevel, paymen
 	if paymentData != nil && userLevel == UserLevelFree {
 		return nil, fmt.Errorf("payment data can only be provided for premium tiers")
 	}
+	if userLevel != UserLevelFree && planID == nil {
+		return nil, ErrSubscriptionPlanNotFound
+	}
 
 	tx, err := db.Beginx()
 	if err != nil {
@@ -320,40 +354,29 @@ func (r *userRepository) UpdateUserLevel(userID int, userLevel UserLevel, paymen
 	defer tx.Rollback()
 
 	var premiumExpiresAt *time.Time
+	now := utime.Utime.Now().ToTime()
 
 	// Get current user data to check existing subscription
 	var currentUser User
-	err = tx.Get(&currentUser, "SELECT user_level, premium_expires_at FROM users WHERE id = $1", userID)
+	err = tx.Get(&currentUser, "SELECT id, user_level, premium_expires_at FROM users WHERE id = $1 FOR UPDATE", userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
-	// Set expiration dates based on user level
+	// Set expiration dates based on the plan; only a recorded payment is prorated
+	var plan *SubscriptionPlan
+	prorationCredit := 0.0
 	if userLevel == UserLevelPremium || userLevel == UserLevelPremiumPlus {
-		now := utime.Utime.Now().ToTime()
-
-		// Check if user has a valid (non-expired) premium subscription to extend from
-		hasValidSubscription := (currentUser.UserLevel == UserLevelPremium || currentUser.UserLevel == UserLevelPremiumPlus) &&
-			currentUser.PremiumExpiresAt != nil &&
-			currentUser.PremiumExpiresAt.After(now)
-
-		// Start from current expiration if valid, otherwise start from now
-		var baseDate time.Time
-		if hasValidSubscription {
-			baseDate = *currentUser.PremiumExpiresAt
-		} else {
-			baseDate = now
+		if plan, err = purchasablePlan(tx, *planID, userLevel); err != nil {
+			return nil, err
 		}
-
-		// Add duration based on subscription type
-		if userLevel == UserLevelPremium {
-			baseDate = baseDate.AddDate(0, 1, 0) // Add 1 month
-		} else {
-			baseDate = baseDate.AddDate(1, 0, 0) // Add 1 year
+		if paymentData != nil {
+			if prorationCredit, err = upgradeProrationCredit(tx, &currentUser, plan.UserLevel, now); err != nil {
+				return nil, err
+			}
 		}
-
-		premiumExpiresAt = &baseDate
-
+		expiresAt := ExtendPremiumExpiry(&currentUser, plan.DurationMonths, prorationCredit > 0, now)
+		premiumExpiresAt = &expiresAt
 	}
 
 	// Update user level
@@ -375,31 +398,55 @@ func (r *userRepository) UpdateUserLevel(userID int, userLevel UserLevel, paymen
 			paymentMethod = *paymentData.PaymentMethod
 		}
 
-		paymentDate := utime.Utime.Now().ToTime()
+		paymentDate := now
 		if paymentData.PaymentDate != nil {
 			paymentDate = *paymentData.PaymentDate
 		}
 
 		discountAmount := 0.0
 		if paymentData.DiscountAmount != nil {
-			discountAmount = *paymentData.DiscountAmount
+			discountAmount = math.Min(*paymentData.DiscountAmount, plan.Price)
+		}
+
+		pr := PaymentRecord{
+			UserID:             userID,
+			SubscriptionType:   userLevel,
+			SubscriptionPlanID: &plan.ID,
+			OriginalPrice:      plan.Price,
+			PaidPrice:          plan.Price - discountAmount,
+			DiscountAmount:     discountAmount,
+			DiscountReason:     paymentData.DiscountReason,
+		}
+		if paymentData.PromoCode != nil {
+			if paymentData.DiscountAmount != nil {
+				return nil, ErrPromoCodeWithManualDiscount
+			}
+			if err := applyPromoCode(tx, &pr, *paymentData.PromoCode, now); err != nil {
+				return nil, err
+			}
 		}
+		applyProrationCredit(&pr, prorationCredit)
 
 		paymentQuery := `INSERT INTO payment_records 
 						(user_id, subscription_type, original_price, paid_price, discount_amount, 
 						 discount_reason, payment_method, payment_status, payment_date, expires_at, 
-						 notes, processed_by_admin_id) 
-						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) 
+						 notes, processed_by_admin_id, promo_code_id, subscription_plan_id, proration_credit) 
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) 
 						RETURNING *`
 
-		var pr PaymentRecord
-		err = tx.Get(&pr, paymentQuery, userID, userLevel, paymentData.OriginalPrice,
-			paymentData.PaidPrice, discountAmount, paymentData.DiscountReason,
-			paymentMethod, "completed", paymentDate, *premiumExpiresAt,
-			paymentData.Notes, processedByAdminID)
+		err = tx.Get(&pr, paymentQuery, userID, userLevel, pr.OriginalPrice,
+			pr.PaidPrice, pr.DiscountAmount, pr.DiscountReason,
+			paymentMethod, PaymentStatusCompleted, paymentDate, *premiumExpiresAt,
+			paymentData.Notes, processedByAdminID, pr.PromoCodeID, pr.SubscriptionPlanID, pr.ProrationCredit)
 		if err != nil {
 			return nil, fmt.Errorf("error creating payment record: %w", err)
 		}
+		if err := recordPromoRedemption(tx, &pr); err != nil {
+			return nil, err
+		}
+		if pr.InvoiceNumber, err = issuePaymentInvoice(tx, pr.ID, now); err != nil {
+			return nil, err
+		}
 		paymentRecord = &pr
 	}
 
@@ -421,7 +468,7 @@ func (r *userRepository) UpdateUserStatus(userID int, status UserStatus) (*User,
 		return nil, fmt.Errorf("database connection is nil")
 	}
 
-	validStatuses := []UserStatus{UserStatusActive, UserStatusInactive, UserStatusSuspended, UserStatusBanned}
+	validStatuses := []UserStatus{UserStatusActive, UserStatusInactive, UserStatusSuspended, UserStatusBanned, UserStatusUnverified}
 	isValidStatus := false
 	for _, s := range validStatuses {
 		if status == s {
@@ -511,8 +558,9 @@ func (r *userRepository) GetAllUsers(page, limit int, status *UserStatus, userLe
 	}, nil
 }
 
-// DowngradeExpiredUsers downgrades users whose premium subscription has expired
-func (r *userRepository) DowngradeExpiredUsers() (*DowngradeResponse, error) {
+// DowngradeExpiredUsers downgrades users whose premium subscription expired at or before
+// expiredBefore; pass now minus the grace period to keep users in grace on premium
+func (r *userRepository) DowngradeExpiredUsers(expiredBefore time.Time) (*DowngradeResponse, error) {
 	db := GetDB().PostgreDBManager.RW
 	if db == nil {
 		return nil, fmt.Errorf("database connection is nil")
@@ -523,10 +571,10 @@ func (r *userRepository) DowngradeExpiredUsers() (*DowngradeResponse, error) {
 			  SET user_level = 'free', premium_expires_at = NULL, updated_at = CURRENT_TIMESTAMP 
 			  WHERE user_level IN ('premium', 'premium+') 
 			  AND premium_expires_at IS NOT NULL 
-			  AND premium_expires_at <= CURRENT_TIMESTAMP
+			  AND premium_expires_at <= $1
 			  RETURNING id, email, user_level, status, premium_expires_at, created_at, updated_at`
 
-	err := db.Select(&users, query)
+	err := db.Select(&users, query, expiredBefore)
 	if err != nil {
 		return nil, fmt.Errorf("error downgrading expired users: %w", err)
 	}
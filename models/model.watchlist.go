@@ -0,0 +1,534 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Watchlist is a named, ordered list of tickers followed by a user.
+type Watchlist struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Position  int       `json:"position" db:"position"`
+	ItemCount int       `json:"item_count" db:"item_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WatchlistItem is a ticker in a watchlist, enriched with its profile and latest overview metrics.
+type WatchlistItem struct {
+	ID        int64            `json:"id"`
+	Ticker    string           `json:"ticker"`
+	Note      *string          `json:"note"`
+	Position  int              `json:"position"`
+	Name      *string          `json:"name"`
+	Sector    *string          `json:"sector"`
+	Metrics   *StockKeyMetrics `json:"metrics"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// WatchlistDetail is a watchlist with its items.
+type WatchlistDetail struct {
+	Watchlist
+	Items []WatchlistItem `json:"items"`
+}
+
+// WatchlistLimit caps how many lists a user may keep and how many tickers each may hold.
+type WatchlistLimit struct {
+	MaxLists int `json:"max_lists"`
+	MaxItems int `json:"max_items"`
+}
+
+// WatchlistLimits are the plan limits per subscription level.
+var WatchlistLimits = map[UserLevel]WatchlistLimit{
+	UserLevelFree:        {MaxLists: 1, MaxItems: 10},
+	UserLevelPremium:     {MaxLists: 5, MaxItems: 50},
+	UserLevelPremiumPlus: {MaxLists: 20, MaxItems: 100},
+	UserLevelAdmin:       {MaxLists: 20, MaxItems: 100},
+}
+
+// EffectiveUserLevel returns the level a user is entitled to at now; lapsed premium
+// subscriptions fall back to free.
+func EffectiveUserLevel(level UserLevel, premiumExpiresAt *time.Time, now time.Time) UserLevel {
+	if (level == UserLevelPremium || level == UserLevelPremiumPlus) && premiumExpiresAt != nil && premiumExpiresAt.Before(now) {
+		return UserLevelFree
+	}
+	return level
+}
+
+// WatchlistLimitFor returns the plan limit of a level, defaulting to the free plan.
+func WatchlistLimitFor(level UserLevel) WatchlistLimit {
+	if limit, ok := WatchlistLimits[level]; ok {
+		return limit
+	}
+	return WatchlistLimits[UserLevelFree]
+}
+
+var (
+	// ErrWatchlistLimitReached is returned when a user already has the maximum number of lists.
+	ErrWatchlistLimitReached = errors.New("watchlist limit reached")
+	// ErrWatchlistItemLimitReached is returned when a list already holds the maximum number of tickers.
+	ErrWatchlistItemLimitReached = errors.New("watchlist item limit reached")
+	// ErrWatchlistNameExists is returned when the user already has a list with the same name.
+	ErrWatchlistNameExists = errors.New("watchlist name already exists")
+	// ErrWatchlistItemExists is returned when the ticker is already in the list.
+	ErrWatchlistItemExists = errors.New("ticker already in watchlist")
+	// ErrWatchlistUnknownTicker is returned when the ticker is not in the stock table.
+	ErrWatchlistUnknownTicker = errors.New("unknown ticker")
+	// ErrWatchlistOrderMismatch is returned when a reorder request does not list every entry exactly once.
+	ErrWatchlistOrderMismatch = errors.New("order must list every entry exactly once")
+)
+
+// WatchlistRepository defines operations for user watchlists.
+type WatchlistRepository interface {
+	ListWatchlists(userID int) ([]Watchlist, error)
+	GetWatchlist(id int64, userID int) (*WatchlistDetail, error)
+	CreateWatchlist(userID int, name string, limit WatchlistLimit) (*Watchlist, error)
+	RenameWatchlist(id int64, userID int, name string) (*Watchlist, error)
+	DeleteWatchlist(id int64, userID int) (bool, error)
+	ReorderWatchlists(userID int, ids []int64) error
+
+	AddWatchlistItem(id int64, userID int, ticker string, note *string, limit WatchlistLimit) (*WatchlistItem, error)
+	UpdateWatchlistItemNote(id int64, userID int, ticker string, note *string) (*WatchlistItem, error)
+	RemoveWatchlistItem(id int64, userID int, ticker string) (bool, error)
+	ReorderWatchlistItems(id int64, userID int, tickers []string) (bool, error)
+}
+
+type watchlistRepository struct{}
+
+// NewWatchlistRepository creates a new watchlist repository.
+func NewWatchlistRepository() WatchlistRepository {
+	return &watchlistRepository{}
+}
+
+func (r *watchlistRepository) getDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RW
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+func (r *watchlistRepository) getReadDB() (*sqlx.DB, error) {
+	db := GetDB().PostgreDBManager.RC
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	return db, nil
+}
+
+const watchlistColumns = `w.id, w.user_id, w.name, w.position, w.created_at, w.updated_at,
+	(SELECT COUNT(*) FROM watchlist_items i WHERE i.watchlist_id = w.id) AS item_count`
+
+// watchlistItemRow is the flat scan target of an item joined with stock and overview metrics.
+type watchlistItemRow struct {
+	ID         int64     `db:"id"`
+	Ticker     string    `db:"ticker"`
+	Note       *string   `db:"note"`
+	Position   int       `db:"position"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+	Name       *string   `db:"name"`
+	Sector     *string   `db:"sector"`
+	HasMetrics bool      `db:"has_metrics"`
+	StockKeyMetrics
+}
+
+func (row watchlistItemRow) toItem() WatchlistItem {
+	item := WatchlistItem{
+		ID:        row.ID,
+		Ticker:    row.Ticker,
+		Note:      row.Note,
+		Position:  row.Position,
+		Name:      row.Name,
+		Sector:    row.Sector,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+	if row.HasMetrics {
+		metrics := row.StockKeyMetrics
+		item.Metrics = &metrics
+	}
+	return item
+}
+
+const watchlistItemQuery = `
+	SELECT i.id, i.ticker, i.note, i.position, i.created_at, i.updated_at,
+		s.name, s.sector::text AS sector,
+		m.symbol IS NOT NULL AS has_metrics,
+		m.return_on_equity, m.net_profit_margin, m.debt_to_equity_ratio, m.price_to_book_ratio,
+		m.dividend_yield, m.eps, m.market_cap
+	FROM watchlist_items i
+	LEFT JOIN stock s ON s.ticker = i.ticker
+	LEFT JOIN stock_overview_metrics m ON m.symbol = i.ticker`
+
+func (r *watchlistRepository) ListWatchlists(userID int) ([]Watchlist, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	watchlists := []Watchlist{}
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists w WHERE w.user_id = $1 ORDER BY w.position ASC, w.id ASC`
+	if err := db.Select(&watchlists, query, userID); err != nil {
+		return nil, fmt.Errorf("error fetching watchlists for user %d: %w", userID, err)
+	}
+	return watchlists, nil
+}
+
+func (r *watchlistRepository) GetWatchlist(id int64, userID int) (*WatchlistDetail, error) {
+	db, err := r.getReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var detail WatchlistDetail
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists w WHERE w.id = $1 AND w.user_id = $2`
+	if err := db.Get(&detail.Watchlist, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching watchlist %d: %w", id, err)
+	}
+
+	var rows []watchlistItemRow
+	if err := db.Select(&rows, watchlistItemQuery+` WHERE i.watchlist_id = $1 ORDER BY i.position ASC, i.id ASC`, id); err != nil {
+		return nil, fmt.Errorf("error fetching items of watchlist %d: %w", id, err)
+	}
+
+	detail.Items = make([]WatchlistItem, 0, len(rows))
+	for _, row := range rows {
+		detail.Items = append(detail.Items, row.toItem())
+	}
+	return &detail, nil
+}
+
+// CreateWatchlist appends a list for the user. The user row is locked so concurrent
+// requests cannot exceed the plan limit.
+func (r *watchlistRepository) CreateWatchlist(userID int, name string, limit WatchlistLimit) (*Watchlist, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for watchlist creation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("error locking user %d: %w", userID, err)
+	}
+
+	var count, nameTaken int
+	if err := tx.QueryRowx(`SELECT COUNT(*), COUNT(*) FILTER (WHERE LOWER(name) = LOWER($2)) FROM watchlists WHERE user_id = $1`,
+		userID, name).Scan(&count, &nameTaken); err != nil {
+		return nil, fmt.Errorf("error counting watchlists for user %d: %w", userID, err)
+	}
+	if nameTaken > 0 {
+		return nil, ErrWatchlistNameExists
+	}
+	if count >= limit.MaxLists {
+		return nil, ErrWatchlistLimitReached
+	}
+
+	var watchlist Watchlist
+	err = tx.QueryRowx(`
+		INSERT INTO watchlists (user_id, name, position)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(position), -1) + 1 FROM watchlists WHERE user_id = $1))
+		RETURNING id, user_id, name, position, 0 AS item_count, created_at, updated_at`,
+		userID, name).StructScan(&watchlist)
+	if err != nil {
+		return nil, fmt.Errorf("error creating watchlist for user %d: %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing watchlist creation: %w", err)
+	}
+	return &watchlist, nil
+}
+
+func (r *watchlistRepository) RenameWatchlist(id int64, userID int, name string) (*Watchlist, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var taken bool
+	if err := db.Get(&taken, `SELECT EXISTS (SELECT 1 FROM watchlists WHERE user_id = $1 AND LOWER(name) = LOWER($2) AND id <> $3)`,
+		userID, name, id); err != nil {
+		return nil, fmt.Errorf("error checking watchlist name: %w", err)
+	}
+	if taken {
+		return nil, ErrWatchlistNameExists
+	}
+
+	if _, err := db.Exec(`UPDATE watchlists SET name = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2`,
+		id, userID, name); err != nil {
+		return nil, fmt.Errorf("error renaming watchlist %d: %w", id, err)
+	}
+
+	var watchlist Watchlist
+	query := `SELECT ` + watchlistColumns + ` FROM watchlists w WHERE w.id = $1 AND w.user_id = $2`
+	if err := db.Get(&watchlist, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching watchlist %d: %w", id, err)
+	}
+	return &watchlist, nil
+}
+
+func (r *watchlistRepository) DeleteWatchlist(id int64, userID int) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`DELETE FROM watchlists WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting watchlist %d: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting watchlist %d: %w", id, err)
+	}
+	return affected > 0, nil
+}
+
+// ReorderWatchlists sets list positions to the order of ids, which must name every list of the user.
+func (r *watchlistRepository) ReorderWatchlists(userID int, ids []int64) error {
+	db, err := r.getDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("error starting transaction for watchlist reorder: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing []int64
+	if err := tx.Select(&existing, `SELECT id FROM watchlists WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("error fetching watchlists for user %d: %w", userID, err)
+	}
+	if !sameInt64Set(existing, ids) {
+		return ErrWatchlistOrderMismatch
+	}
+
+	for position, id := range ids {
+		if _, err := tx.Exec(`UPDATE watchlists SET position = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2`,
+			id, userID, position); err != nil {
+			return fmt.Errorf("error reordering watchlist %d: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing watchlist reorder: %w", err)
+	}
+	return nil
+}
+
+// lockWatchlist locks the user's list for the rest of tx, returning false when it does not exist.
+func lockWatchlist(tx *sqlx.Tx, id int64, userID int) (bool, error) {
+	var lockedID int64
+	err := tx.QueryRowx(`SELECT id FROM watchlists WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID).Scan(&lockedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("error locking watchlist %d: %w", id, err)
+	}
+	return true, nil
+}
+
+// AddWatchlistItem appends a ticker to the list. It returns nil when the list does not
+// belong to the user.
+func (r *watchlistRepository) AddWatchlistItem(id int64, userID int, ticker string, note *string, limit WatchlistLimit) (*WatchlistItem, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for watchlist item: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockWatchlist(tx, id, userID)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var tickerExists bool
+	if err := tx.Get(&tickerExists, `SELECT EXISTS (SELECT 1 FROM stock WHERE ticker = $1)`, ticker); err != nil {
+		return nil, fmt.Errorf("error checking ticker %s: %w", ticker, err)
+	}
+	if !tickerExists {
+		return nil, ErrWatchlistUnknownTicker
+	}
+
+	var count, present int
+	if err := tx.QueryRowx(`SELECT COUNT(*), COUNT(*) FILTER (WHERE ticker = $2) FROM watchlist_items WHERE watchlist_id = $1`,
+		id, ticker).Scan(&count, &present); err != nil {
+		return nil, fmt.Errorf("error counting items of watchlist %d: %w", id, err)
+	}
+	if present > 0 {
+		return nil, ErrWatchlistItemExists
+	}
+	if count >= limit.MaxItems {
+		return nil, ErrWatchlistItemLimitReached
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO watchlist_items (watchlist_id, ticker, note, position)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position), -1) + 1 FROM watchlist_items WHERE watchlist_id = $1))`,
+		id, ticker, note); err != nil {
+		return nil, fmt.Errorf("error adding %s to watchlist %d: %w", ticker, id, err)
+	}
+
+	var row watchlistItemRow
+	if err := tx.Get(&row, watchlistItemQuery+` WHERE i.watchlist_id = $1 AND i.ticker = $2`, id, ticker); err != nil {
+		return nil, fmt.Errorf("error fetching %s in watchlist %d: %w", ticker, id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing watchlist item: %w", err)
+	}
+	item := row.toItem()
+	return &item, nil
+}
+
+func (r *watchlistRepository) UpdateWatchlistItemNote(id int64, userID int, ticker string, note *string) (*WatchlistItem, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.Exec(`
+		UPDATE watchlist_items i SET note = $4, updated_at = CURRENT_TIMESTAMP
+		FROM watchlists w
+		WHERE w.id = i.watchlist_id AND i.watchlist_id = $1 AND w.user_id = $2 AND i.ticker = $3`,
+		id, userID, ticker, note)
+	if err != nil {
+		return nil, fmt.Errorf("error updating note of %s in watchlist %d: %w", ticker, id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error updating note of %s in watchlist %d: %w", ticker, id, err)
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+
+	var row watchlistItemRow
+	if err := db.Get(&row, watchlistItemQuery+` WHERE i.watchlist_id = $1 AND i.ticker = $2`, id, ticker); err != nil {
+		return nil, fmt.Errorf("error fetching %s in watchlist %d: %w", ticker, id, err)
+	}
+	item := row.toItem()
+	return &item, nil
+}
+
+func (r *watchlistRepository) RemoveWatchlistItem(id int64, userID int, ticker string) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(`
+		DELETE FROM watchlist_items i
+		USING watchlists w
+		WHERE w.id = i.watchlist_id AND i.watchlist_id = $1 AND w.user_id = $2 AND i.ticker = $3`,
+		id, userID, ticker)
+	if err != nil {
+		return false, fmt.Errorf("error removing %s from watchlist %d: %w", ticker, id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error removing %s from watchlist %d: %w", ticker, id, err)
+	}
+	return affected > 0, nil
+}
+
+// ReorderWatchlistItems sets item positions to the order of tickers, which must name
+// every ticker in the list. It returns false when the list does not belong to the user.
+func (r *watchlistRepository) ReorderWatchlistItems(id int64, userID int, tickers []string) (bool, error) {
+	db, err := r.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction for watchlist item reorder: %w", err)
+	}
+	defer tx.Rollback()
+
+	found, err := lockWatchlist(tx, id, userID)
+	if err != nil || !found {
+		return false, err
+	}
+
+	var existing []string
+	if err := tx.Select(&existing, `SELECT ticker FROM watchlist_items WHERE watchlist_id = $1`, id); err != nil {
+		return false, fmt.Errorf("error fetching items of watchlist %d: %w", id, err)
+	}
+	if !sameStringSet(existing, tickers) {
+		return false, ErrWatchlistOrderMismatch
+	}
+
+	for position, ticker := range tickers {
+		if _, err := tx.Exec(`UPDATE watchlist_items SET position = $3, updated_at = CURRENT_TIMESTAMP WHERE watchlist_id = $1 AND ticker = $2`,
+			id, ticker, position); err != nil {
+			return false, fmt.Errorf("error reordering %s in watchlist %d: %w", ticker, id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing watchlist item reorder: %w", err)
+	}
+	return true, nil
+}
+
+func sameInt64Set(existing, requested []int64) bool {
+	if len(existing) != len(requested) {
+		return false
+	}
+	seen := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		seen[id] = true
+	}
+	for _, id := range requested {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
+
+func sameStringSet(existing, requested []string) bool {
+	if len(existing) != len(requested) {
+		return false
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, value := range existing {
+		seen[value] = true
+	}
+	for _, value := range requested {
+		if !seen[value] {
+			return false
+		}
+		delete(seen, value)
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func timePtr(v time.Time) *time.Time { return &v }
+
+func TestComputeEarningSurpriseStats(t *testing.T) {
+	quarter := func(period string, eps, revenue *float64) StockEarningQuarterlyHistoryRecord {
+		record := StockEarningQuarterlyHistoryRecord{PeriodCode: period, EpsSurprisePercent: eps, RevenueSurprisePercent: revenue}
+		if eps != nil {
+			record.EpsActual = floatPtr(1)
+		}
+		if revenue != nil {
+			record.RevenueActual = floatPtr(1)
+		}
+		return record
+	}
+
+	tests := []struct {
+		name              string
+		records           []StockEarningQuarterlyHistoryRecord
+		wantQuarters      int
+		wantFirst         string
+		wantLast          string
+		wantEps           EarningSurpriseSummary
+		wantEpsStreak     *EarningStreak
+		wantEpsBeatRate   float64
+		wantEpsAverage    float64
+		wantRevenueBeats  int
+		wantRevenueStreak *EarningStreak
+	}{
+		{
+			name:    "no history",
+			records: nil,
+		},
+		{
+			name: "unreported quarters are skipped",
+			records: []StockEarningQuarterlyHistoryRecord{
+				quarter("2024Q1", floatPtr(5), floatPtr(2)),
+				{PeriodCode: "2024Q2", EpsSurprisePercent: floatPtr(10)},
+			},
+			wantQuarters:      1,
+			wantFirst:         "2024Q1",
+			wantLast:          "2024Q1",
+			wantEps:           EarningSurpriseSummary{Beats: 1, LongestBeatStreak: 1},
+			wantEpsStreak:     &EarningStreak{Outcome: EarningSurpriseBeat, Length: 1},
+			wantEpsBeatRate:   100,
+			wantEpsAverage:    5,
+			wantRevenueBeats:  1,
+			wantRevenueStreak: &EarningStreak{Outcome: EarningSurpriseBeat, Length: 1},
+		},
+		{
+			name: "streaks follow the newest quarters",
+			records: []StockEarningQuarterlyHistoryRecord{
+				quarter("2023Q1", floatPtr(4), floatPtr(-1)),
+				quarter("2023Q2", floatPtr(2), floatPtr(-2)),
+				quarter("2023Q3", floatPtr(-3), nil),
+				quarter("2023Q4", floatPtr(-1), floatPtr(0)),
+				quarter("2024Q1", floatPtr(0), floatPtr(3)),
+			},
+			wantQuarters:      5,
+			wantFirst:         "2023Q1",
+			wantLast:          "2024Q1",
+			wantEps:           EarningSurpriseSummary{Beats: 2, Misses: 2, InLine: 1, LongestBeatStreak: 2, LongestMissStreak: 2},
+			wantEpsStreak:     &EarningStreak{Outcome: EarningSurpriseInLine, Length: 1},
+			wantEpsBeatRate:   40,
+			wantEpsAverage:    0.4,
+			wantRevenueBeats:  1,
+			wantRevenueStreak: &EarningStreak{Outcome: EarningSurpriseBeat, Length: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := ComputeEarningSurpriseStats("BBCA", tt.records)
+
+			if stats.Symbol != "BBCA" || stats.QuartersAnalyzed != tt.wantQuarters || len(stats.QuarterlyOutcomes) != tt.wantQuarters {
+				t.Fatalf("got symbol %q, %d quarters (%d outcomes), want %d", stats.Symbol, stats.QuartersAnalyzed, len(stats.QuarterlyOutcomes), tt.wantQuarters)
+			}
+			if tt.wantQuarters == 0 {
+				if stats.FirstPeriodCode != nil || stats.Eps.BeatRate != nil || stats.Eps.CurrentStreak != nil {
+					t.Fatalf("expected empty stats, got %+v", stats)
+				}
+				return
+			}
+			if *stats.FirstPeriodCode != tt.wantFirst || *stats.LastPeriodCode != tt.wantLast {
+				t.Errorf("got periods %s..%s, want %s..%s", *stats.FirstPeriodCode, *stats.LastPeriodCode, tt.wantFirst, tt.wantLast)
+			}
+
+			eps := stats.Eps
+			if eps.Beats != tt.wantEps.Beats || eps.Misses != tt.wantEps.Misses || eps.InLine != tt.wantEps.InLine {
+				t.Errorf("got eps %d/%d/%d beats/misses/in-line, want %d/%d/%d", eps.Beats, eps.Misses, eps.InLine, tt.wantEps.Beats, tt.wantEps.Misses, tt.wantEps.InLine)
+			}
+			if eps.LongestBeatStreak != tt.wantEps.LongestBeatStreak || eps.LongestMissStreak != tt.wantEps.LongestMissStreak {
+				t.Errorf("got longest eps streaks %d/%d, want %d/%d", eps.LongestBeatStreak, eps.LongestMissStreak, tt.wantEps.LongestBeatStreak, tt.wantEps.LongestMissStreak)
+			}
+			if *eps.CurrentStreak != *tt.wantEpsStreak {
+				t.Errorf("got current eps streak %+v, want %+v", *eps.CurrentStreak, *tt.wantEpsStreak)
+			}
+			if math.Abs(*eps.BeatRate-tt.wantEpsBeatRate) > 1e-9 || math.Abs(*eps.AverageSurprisePercent-tt.wantEpsAverage) > 1e-9 {
+				t.Errorf("got eps beat rate %v and average %v, want %v and %v", *eps.BeatRate, *eps.AverageSurprisePercent, tt.wantEpsBeatRate, tt.wantEpsAverage)
+			}
+
+			if stats.Revenue.Beats != tt.wantRevenueBeats || *stats.Revenue.CurrentStreak != *tt.wantRevenueStreak {
+				t.Errorf("got revenue beats %d streak %+v, want %d %+v", stats.Revenue.Beats, *stats.Revenue.CurrentStreak, tt.wantRevenueBeats, *tt.wantRevenueStreak)
+			}
+		})
+	}
+}
+
+func TestPaymentEventApplies(t *testing.T) {
+	tests := []struct {
+		name          string
+		eventStatus   string
+		paymentStatus string
+		want          bool
+		wantErr       bool
+	}{
+		{"paid completes a pending payment", PaymentEventPaid, PaymentStatusPending, true, false},
+		{"paid revives an expired payment", PaymentEventPaid, PaymentStatusExpired, true, false},
+		{"paid replayed on a completed payment", PaymentEventPaid, PaymentStatusCompleted, false, false},
+		{"paid replayed on a payment awaiting refund", PaymentEventPaid, PaymentStatusNeedsRefund, false, false},
+		{"paid after a failure", PaymentEventPaid, PaymentStatusFailed, false, false},
+		{"failed closes a pending payment", PaymentEventFailed, PaymentStatusPending, true, false},
+		{"failed replayed on a failed payment", PaymentEventFailed, PaymentStatusFailed, false, false},
+		{"failed never undoes a completed payment", PaymentEventFailed, PaymentStatusCompleted, false, false},
+		{"expired closes a pending payment", PaymentEventExpired, PaymentStatusPending, true, false},
+		{"expired replayed on an expired payment", PaymentEventExpired, PaymentStatusExpired, false, false},
+		{"unknown event status", "refunded", PaymentStatusPending, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := paymentEventApplies(tt.eventStatus, tt.paymentStatus)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got applied %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendPremiumExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	active := now.AddDate(0, 0, 20)
+	lapsed := now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name     string
+		user     User
+		months   int
+		prorated bool
+		want     time.Time
+	}{
+		{"free user starts now", User{UserLevel: UserLevelFree}, 1, false, now.AddDate(0, 1, 0)},
+		{"active premium extends from expiry", User{UserLevel: UserLevelPremium, PremiumExpiresAt: timePtr(active)}, 3, false, active.AddDate(0, 3, 0)},
+		{"active premium+ extends from expiry", User{UserLevel: UserLevelPremiumPlus, PremiumExpiresAt: timePtr(active)}, 12, false, active.AddDate(0, 12, 0)},
+		{"lapsed premium starts now", User{UserLevel: UserLevelPremium, PremiumExpiresAt: timePtr(lapsed)}, 1, false, now.AddDate(0, 1, 0)},
+		{"premium without expiry starts now", User{UserLevel: UserLevelPremium}, 1, false, now.AddDate(0, 1, 0)},
+		{"prorated upgrade starts now", User{UserLevel: UserLevelPremium, PremiumExpiresAt: timePtr(active)}, 1, true, now.AddDate(0, 1, 0)},
+		{"free user with a stale expiry starts now", User{UserLevel: UserLevelFree, PremiumExpiresAt: timePtr(active)}, 1, false, now.AddDate(0, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtendPremiumExpiry(&tt.user, tt.months, tt.prorated, now); !got.Equal(tt.want) {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProrateCredit(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+
+	tests := []struct {
+		name      string
+		paid      float64
+		periodEnd time.Time
+		expiresAt time.Time
+		now       time.Time
+		want      float64
+	}{
+		{"untouched period", 99000, end, end, start, 99000},
+		{"half used", 99000, end, end, start.AddDate(0, 0, 15), 49500},
+		{"rounded to whole rupiah", 100000, end, end, start.AddDate(0, 0, 10), 66667},
+		{"extended expiry only counts the paid rate", 90000, end, end.AddDate(0, 0, 30), start.AddDate(0, 0, 30), 90000},
+		{"expired", 99000, end, end, end.Add(time.Hour), 0},
+		{"expires now", 99000, end, end, end, 0},
+		{"nothing paid", 0, end, end, start, 0},
+		{"empty period", 99000, start, end, start, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProrateCredit(tt.paid, start, tt.periodEnd, tt.expiresAt, tt.now); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuePremiumReminder(t *testing.T) {
+	offsets := []int{1, 7, 3}
+
+	tests := []struct {
+		name       string
+		daysLeft   int
+		lastSent   *int
+		wantOffset int
+		wantDue    bool
+	}{
+		{"too early", 10, nil, 0, false},
+		{"exactly on an offset", 7, nil, 7, true},
+		{"between offsets picks the next larger", 5, nil, 7, true},
+		{"missed days send one catch-up", 2, nil, 3, true},
+		{"same offset already sent", 6, intPtr(7), 0, false},
+		{"closer offset already sent", 5, intPtr(3), 0, false},
+		{"next offset after an earlier one", 3, intPtr(7), 3, true},
+		{"last day", 1, intPtr(3), 1, true},
+		{"expiry day", 0, intPtr(3), 1, true},
+		{"expiry day after the last reminder", 0, intPtr(1), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, due := DuePremiumReminder(tt.daysLeft, offsets, tt.lastSent)
+			if offset != tt.wantOffset || due != tt.wantDue {
+				t.Fatalf("got (%d, %v), want (%d, %v)", offset, due, tt.wantOffset, tt.wantDue)
+			}
+		})
+	}
+	if offsets[0] != 1 || offsets[1] != 7 || offsets[2] != 3 {
+		t.Fatalf("offsets were reordered in place: %v", offsets)
+	}
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+)
+
+// EmailChannel sends plain-text email through an SMTP server (STARTTLS when offered).
+type EmailChannel struct {
+	cfg config.SMTPConfig
+}
+
+// NewEmailChannel creates an email channel.
+func NewEmailChannel(cfg config.SMTPConfig) *EmailChannel {
+	return &EmailChannel{cfg: cfg}
+}
+
+func (c *EmailChannel) Name() string { return "email" }
+
+func (c *EmailChannel) Send(ctx context.Context, message Message) error {
+	if message.Recipient == "" {
+		return Permanent(errors.New("email recipient is empty"))
+	}
+
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+	}
+	addr := net.JoinHostPort(c.cfg.Host, strconv.Itoa(c.cfg.Port))
+	if err := smtp.SendMail(addr, auth, c.cfg.From, []string{message.Recipient}, buildEmail(c.cfg.From, message)); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
+
+func buildEmail(from string, message Message) []byte {
+	body := message.Body
+	if message.URL != "" {
+		body += "\r\n\r\n" + message.URL
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + message.Recipient + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,30 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+)
+
+// InAppChannel delivers messages to the user's inbox (GET /api/users/alerts).
+type InAppChannel struct {
+	repo models.AlertRepository
+}
+
+// NewInAppChannel creates an in-app channel.
+func NewInAppChannel(repo models.AlertRepository) *InAppChannel {
+	return &InAppChannel{repo: repo}
+}
+
+func (c *InAppChannel) Name() string { return string(models.NotificationChannelInApp) }
+
+func (c *InAppChannel) Send(ctx context.Context, message Message) error {
+	return c.repo.CreateInboxEntry(&models.AlertInboxEntry{
+		UserID:      message.UserID,
+		AlertType:   models.AlertType(message.Event),
+		Title:       message.Subject,
+		Message:     message.Body,
+		TriggeredAt: utime.Utime.Now().ToTime(),
+	})
+}
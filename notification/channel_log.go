@@ -0,0 +1,34 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// LogChannel writes messages to the application log instead of sending them; it stands
+// in for email, Telegram and web push during local development.
+type LogChannel struct {
+	logger *zerolog.Logger
+}
+
+// NewLogChannel creates a log channel.
+func NewLogChannel(logger *zerolog.Logger) *LogChannel {
+	return &LogChannel{logger: logger}
+}
+
+func (c *LogChannel) Name() string { return "log" }
+
+func (c *LogChannel) Send(ctx context.Context, message Message) error {
+	c.logger.Info().
+		Int64("outboxId", message.OutboxID).
+		Int("userId", message.UserID).
+		Str("event", message.Event).
+		Str("locale", message.Locale).
+		Str("recipient", message.Recipient).
+		Str("subject", message.Subject).
+		Str("body", message.Body).
+		Str("url", message.URL).
+		Msg("Notification (log delivery)")
+	return nil
+}
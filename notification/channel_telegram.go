@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+)
+
+// TelegramChannel sends messages through a Telegram bot to the user's chat id.
+type TelegramChannel struct {
+	cfg        config.TelegramConfig
+	httpClient *http.Client
+}
+
+// NewTelegramChannel creates a Telegram channel.
+func NewTelegramChannel(cfg config.TelegramConfig, httpClient *http.Client) *TelegramChannel {
+	return &TelegramChannel{cfg: cfg, httpClient: httpClient}
+}
+
+func (c *TelegramChannel) Name() string { return "telegram" }
+
+func (c *TelegramChannel) Send(ctx context.Context, message Message) error {
+	if message.Recipient == "" {
+		return Permanent(errors.New("telegram chat id is empty"))
+	}
+
+	text := message.Subject + "\n\n" + message.Body
+	if message.URL != "" {
+		text += "\n\n" + message.URL
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", c.cfg.APIURL, c.cfg.BotToken)
+	_, err := helper.DoExternalJSONRequest(ctx, c.httpClient, http.MethodPost, endpoint, helper.ExternalJSONRequestOptions{
+		JSONBody: map[string]interface{}{
+			"chat_id":                  message.Recipient,
+			"text":                     text,
+			"disable_web_page_preview": true,
+		},
+	})
+	if err != nil {
+		// Transport errors carry the request URL, which contains the bot token
+		redacted := errors.New(strings.ReplaceAll(err.Error(), c.cfg.BotToken, "***"))
+		var responseErr *helper.ExternalJSONResponseError
+		// Bad request / forbidden mean an unknown chat or a user who blocked the bot
+		if errors.As(err, &responseErr) && (responseErr.StatusCode == http.StatusBadRequest || responseErr.StatusCode == http.StatusForbidden) {
+			return Permanent(fmt.Errorf("telegram rejected message: %w", redacted))
+		}
+		return fmt.Errorf("error sending telegram message: %w", redacted)
+	}
+	return nil
+}
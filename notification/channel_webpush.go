@@ -0,0 +1,284 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/bytedance/sonic"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	webPushTTL        = "86400"
+	webPushRecordSize = 4096
+	vapidTokenTTL     = 12 * time.Hour
+)
+
+// WebPushChannel sends messages to every browser subscription of the user using the
+// Web Push protocol (RFC 8030) with VAPID (RFC 8292) and aes128gcm payloads (RFC 8291).
+type WebPushChannel struct {
+	cfg        config.WebPushConfig
+	httpClient *http.Client
+	repo       models.NotificationRepository
+	privateKey *ecdsa.PrivateKey
+	publicKey  string
+}
+
+// NewWebPushChannel creates a web push channel from the configured VAPID key pair.
+func NewWebPushChannel(cfg config.WebPushConfig, httpClient *http.Client, repo models.NotificationRepository) (*WebPushChannel, error) {
+	privateKey, publicKey, err := parseVAPIDPrivateKey(cfg.VAPIDPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.VAPIDPublicKey != "" && cfg.VAPIDPublicKey != publicKey {
+		return nil, errors.New("WEB_PUSH_VAPID_PUBLIC_KEY does not match WEB_PUSH_VAPID_PRIVATE_KEY")
+	}
+	return &WebPushChannel{
+		cfg:        cfg,
+		httpClient: httpClient,
+		repo:       repo,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// parseVAPIDPrivateKey decodes a base64url P-256 private scalar and returns the signing
+// key with its base64url uncompressed public key.
+func parseVAPIDPrivateKey(value string) (*ecdsa.PrivateKey, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid WEB_PUSH_VAPID_PRIVATE_KEY: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid WEB_PUSH_VAPID_PRIVATE_KEY: %w", err)
+	}
+	public := key.PublicKey().Bytes()
+	privateKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return privateKey, base64.RawURLEncoding.EncodeToString(public), nil
+}
+
+func (c *WebPushChannel) Name() string { return "web_push" }
+
+// Send pushes the message to all of the user's subscriptions. Subscriptions the push
+// service reports as gone are removed; the send fails if no live one received it.
+func (c *WebPushChannel) Send(ctx context.Context, message Message) error {
+	subs, err := c.repo.ListWebPushSubscriptions(message.UserID)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return Permanent(errors.New("user has no web push subscriptions"))
+	}
+
+	payload, err := sonic.Marshal(map[string]string{
+		"title": message.Subject,
+		"body":  message.Body,
+		"url":   message.URL,
+		"event": message.Event,
+	})
+	if err != nil {
+		return Permanent(fmt.Errorf("error encoding web push payload: %w", err))
+	}
+
+	var errs []error
+	delivered := false
+	for _, sub := range subs {
+		gone, err := c.push(ctx, sub, payload)
+		if gone {
+			if err := c.repo.DeleteWebPushSubscriptionByEndpoint(sub.Endpoint); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered = true
+	}
+
+	if delivered {
+		return nil
+	}
+	if len(errs) == 0 {
+		// Every subscription was gone or dropped, so retrying cannot deliver
+		return Permanent(errors.New("no live web push subscriptions"))
+	}
+	return errors.Join(errs...)
+}
+
+// push sends one encrypted payload; gone reports an expired subscription.
+func (c *WebPushChannel) push(ctx context.Context, sub models.WebPushSubscription, payload []byte) (bool, error) {
+	body, err := encryptWebPushPayload(sub, payload)
+	if err != nil {
+		// A subscription with unusable keys can never receive a push
+		return true, err
+	}
+
+	// Subscriptions stored before endpoints were checked may point anywhere; drop them
+	if !models.IsWebPushServiceEndpoint(sub.Endpoint) {
+		return true, errors.New("web push endpoint is not a known push service")
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil {
+		return true, err
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": c.cfg.Subject,
+	}).SignedString(c.privateKey)
+	if err != nil {
+		return false, fmt.Errorf("error signing VAPID token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", webPushTTL)
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, c.publicKey))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error sending web push: %w", err)
+	}
+	defer resp.Body.Close()
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, fmt.Errorf("web push failed with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+	return false, nil
+}
+
+// encryptWebPushPayload encrypts payload for a subscription with the aes128gcm content
+// coding: a single record keyed from an ephemeral ECDH exchange and the auth secret.
+func encryptWebPushPayload(sub models.WebPushSubscription, payload []byte) ([]byte, error) {
+	userAgentPublic, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription p256dh: %w", err)
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription auth: %w", err)
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(userAgentPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription p256dh: %w", err)
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+	sharedSecret, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(userAgentPublic) + string(serverPublic)
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > webPushRecordSize {
+		return nil, errors.New("web push payload too large")
+	}
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+func decodeBase64URL(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+// VAPIDPublicKey returns the base64url application server key browsers subscribe with,
+// or an empty string when web push is not configured.
+func VAPIDPublicKey(cfg config.WebPushConfig) string {
+	if cfg.VAPIDPublicKey != "" {
+		return cfg.VAPIDPublicKey
+	}
+	if cfg.VAPIDPrivateKey == "" {
+		return ""
+	}
+	_, publicKey, err := parseVAPIDPrivateKey(cfg.VAPIDPrivateKey)
+	if err != nil {
+		return ""
+	}
+	return publicKey
+}
@@ -0,0 +1,285 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/rs/zerolog"
+)
+
+// Delivery modes.
+const (
+	DeliveryLog  = "log"
+	DeliveryLive = "live"
+)
+
+const (
+	// dispatchBatchSize is the number of outbox entries claimed per dispatch pass
+	dispatchBatchSize = 100
+	// dispatchLease is how long a claimed entry stays invisible to other dispatchers
+	dispatchLease = 5 * time.Minute
+	// maxRetryDelay caps the exponential backoff between delivery attempts
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Notification is a request to notify one user about an event.
+type Notification struct {
+	UserID int
+	Event  Event
+	Data   map[string]interface{}
+	// DedupeKey makes the notification idempotent per user and channel; optional
+	DedupeKey string
+	// URL is an app-relative link opened from the notification; optional
+	URL string
+	// Channels restricts delivery; empty means every channel the user enabled
+	Channels []models.NotificationChannel
+}
+
+// Message is a rendered notification handed to a channel.
+type Message struct {
+	OutboxID  int64
+	UserID    int
+	Event     string
+	Locale    string
+	Recipient string
+	Subject   string
+	Body      string
+	// URL is absolute when set
+	URL string
+}
+
+// Channel delivers rendered messages.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, message Message) error
+}
+
+// permanentError marks a delivery failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the dispatcher gives up on the entry instead of retrying.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Service enqueues notifications in the outbox and delivers them through channels.
+type Service struct {
+	logger   *zerolog.Logger
+	cfg      config.NotificationConfig
+	repo     models.NotificationRepository
+	channels map[models.NotificationChannel]Channel
+}
+
+// NewService builds the notification service. In log delivery mode email, Telegram and
+// web push messages are written to the log instead of being sent.
+func NewService(cfg config.NotificationConfig, httpClient *http.Client, logger *zerolog.Logger) (*Service, error) {
+	s := &Service{
+		logger: logger,
+		cfg:    cfg,
+		repo:   models.NewNotificationRepository(),
+		channels: map[models.NotificationChannel]Channel{
+			models.NotificationChannelInApp: NewInAppChannel(models.NewAlertRepository()),
+		},
+	}
+
+	switch cfg.Delivery {
+	case "", DeliveryLog:
+		logChannel := NewLogChannel(logger)
+		s.channels[models.NotificationChannelEmail] = logChannel
+		s.channels[models.NotificationChannelTelegram] = logChannel
+		s.channels[models.NotificationChannelWebPush] = logChannel
+	case DeliveryLive:
+		if cfg.SMTP.Host != "" {
+			s.channels[models.NotificationChannelEmail] = NewEmailChannel(cfg.SMTP)
+		}
+		if cfg.Telegram.BotToken != "" {
+			s.channels[models.NotificationChannelTelegram] = NewTelegramChannel(cfg.Telegram, httpClient)
+		}
+		if cfg.WebPush.VAPIDPrivateKey != "" {
+			webPush, err := NewWebPushChannel(cfg.WebPush, httpClient, s.repo)
+			if err != nil {
+				return nil, err
+			}
+			s.channels[models.NotificationChannelWebPush] = webPush
+		}
+	default:
+		return nil, fmt.Errorf("unknown notification delivery %q", cfg.Delivery)
+	}
+
+	return s, nil
+}
+
+// Notify renders n in the user's locale and enqueues it on every channel the user enabled
+// and the service can deliver. It returns the number of new outbox entries.
+func (s *Service) Notify(ctx context.Context, n Notification) (int, error) {
+	prefs, err := s.repo.GetPreferences(n.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if prefs == nil {
+		return 0, fmt.Errorf("user %d not found", n.UserID)
+	}
+
+	subject, body, err := Render(n.Event, prefs.Locale, n.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	var url *string
+	if n.URL != "" {
+		absolute := s.cfg.AppURL + n.URL
+		url = &absolute
+	}
+	var dedupeKey *string
+	if n.DedupeKey != "" {
+		dedupeKey = &n.DedupeKey
+	}
+
+	now := utime.Utime.Now().ToTime()
+	var entries []models.NotificationOutboxEntry
+	for _, channel := range models.NotificationChannels {
+		if !prefs.Enabled(channel) || !allowsChannel(n.Channels, channel) {
+			continue
+		}
+		if _, ok := s.channels[channel]; !ok {
+			continue
+		}
+		entries = append(entries, models.NotificationOutboxEntry{
+			UserID:        n.UserID,
+			Channel:       channel,
+			Event:         string(n.Event),
+			DedupeKey:     dedupeKey,
+			Locale:        prefs.Locale,
+			Recipient:     prefs.Recipient(channel),
+			Subject:       subject,
+			Body:          body,
+			URL:           url,
+			NextAttemptAt: now,
+		})
+	}
+
+	return s.repo.EnqueueNotifications(entries)
+}
+
+func allowsChannel(channels []models.NotificationChannel, channel models.NotificationChannel) bool {
+	if len(channels) == 0 {
+		return true
+	}
+	for _, allowed := range channels {
+		if allowed == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// DispatchSummary reports the outcome of one dispatch pass.
+type DispatchSummary struct {
+	Sent     int `json:"sent"`
+	Retrying int `json:"retrying"`
+	Failed   int `json:"failed"`
+}
+
+// Dispatch delivers due outbox entries until none are left or ctx is done. Failed
+// deliveries are retried with exponential backoff up to MaxAttempts.
+func (s *Service) Dispatch(ctx context.Context) (*DispatchSummary, error) {
+	summary := &DispatchSummary{}
+
+	for ctx.Err() == nil {
+		now := utime.Utime.Now().ToTime()
+		entries, err := s.repo.ClaimDueNotifications(now, now.Add(dispatchLease), dispatchBatchSize)
+		if err != nil {
+			return summary, err
+		}
+
+		for _, entry := range entries {
+			if err := s.deliver(ctx, entry); err != nil {
+				retryAt := s.retryAt(entry.Attempts, err)
+				if retryAt == nil {
+					summary.Failed++
+				} else {
+					summary.Retrying++
+				}
+				s.logger.Warn().Err(err).
+					Int64("outboxId", entry.ID).
+					Str("channel", string(entry.Channel)).
+					Str("event", entry.Event).
+					Int("attempts", entry.Attempts).
+					Bool("willRetry", retryAt != nil).
+					Msg("Notification delivery failed")
+				if markErr := s.repo.MarkNotificationFailed(entry.ID, err.Error(), retryAt); markErr != nil {
+					return summary, markErr
+				}
+				continue
+			}
+
+			if err := s.repo.MarkNotificationSent(entry.ID, utime.Utime.Now().ToTime()); err != nil {
+				return summary, err
+			}
+			summary.Sent++
+		}
+
+		if len(entries) < dispatchBatchSize {
+			break
+		}
+	}
+
+	return summary, nil
+}
+
+func (s *Service) deliver(ctx context.Context, entry models.NotificationOutboxEntry) error {
+	channel, ok := s.channels[entry.Channel]
+	if !ok {
+		return Permanent(fmt.Errorf("channel %s is not configured", entry.Channel))
+	}
+
+	message := Message{
+		OutboxID: entry.ID,
+		UserID:   entry.UserID,
+		Event:    entry.Event,
+		Locale:   entry.Locale,
+		Subject:  entry.Subject,
+		Body:     entry.Body,
+	}
+	if entry.Recipient != nil {
+		message.Recipient = *entry.Recipient
+	}
+	if entry.URL != nil {
+		message.URL = *entry.URL
+	}
+	return channel.Send(ctx, message)
+}
+
+// retryAt returns when a failed entry should be retried, or nil to give up.
+func (s *Service) retryAt(attempts int, err error) *time.Time {
+	if IsPermanent(err) || attempts >= s.cfg.MaxAttempts {
+		return nil
+	}
+
+	delay := s.cfg.RetryBaseDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	retryAt := utime.Utime.Now().ToTime().Add(delay)
+	return &retryAt
+}
@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+)
+
+// Event identifies what a notification is about and selects its template.
+type Event string
+
+const (
+	// EventAlertTriggered data: AlertType, Ticker, Label, Threshold, Value, Date
+	EventAlertTriggered Event = "alert_triggered"
+	// EventDepositMaturity data: Bank, Account, Amount, MaturityDate, DaysLeft
+	EventDepositMaturity Event = "deposit_maturity"
+	// EventBondCoupon data: BondName, CouponRate, PaymentDate, DaysLeft
+	EventBondCoupon Event = "bond_coupon"
+	// EventPremiumExpiry data: Level, ExpiresAt, DaysLeft
+	EventPremiumExpiry Event = "premium_expiry"
+	// EventEmailVerification data: ExpiresAt; the message URL is the verification link
+	EventEmailVerification Event = "email_verification"
+)
+
+type messageTemplate struct {
+	subject string
+	body    string
+}
+
+// messageTemplates holds the subject and body of every event per locale.
+var messageTemplates = map[Event]map[string]messageTemplate{
+	EventAlertTriggered: {
+		models.NotificationLocaleID: {
+			subject: `{{if eq .AlertType "price_above"}}{{.Ticker}} di atas {{num .Threshold}}` +
+				`{{else if eq .AlertType "price_below"}}{{.Ticker}} di bawah {{num .Threshold}}` +
+				`{{else if eq .AlertType "dividend_yield_above"}}Dividend yield {{.Ticker}} di atas {{num .Threshold}}%` +
+				`{{else if eq .AlertType "earnings_within_days"}}Laporan keuangan {{.Ticker}} segera rilis` +
+				`{{else if eq .AlertType "bond_coupon_due"}}Kupon {{.Label}} segera dibayar` +
+				`{{else}}Deposito {{.Label}} segera jatuh tempo{{end}}`,
+			body: `{{if eq .AlertType "price_above"}}Harga {{.Ticker}} mencapai {{num .Value}}, melewati batas atas {{num .Threshold}}.` +
+				`{{else if eq .AlertType "price_below"}}Harga {{.Ticker}} turun ke {{num .Value}}, melewati batas bawah {{num .Threshold}}.` +
+				`{{else if eq .AlertType "dividend_yield_above"}}Dividend yield {{.Ticker}} mencapai {{num .Value}}%.` +
+				`{{else if eq .AlertType "earnings_within_days"}}{{.Ticker}} dijadwalkan merilis laporan keuangan pada {{.Date}}.` +
+				`{{else if eq .AlertType "bond_coupon_due"}}Kupon obligasi {{.Label}} jatuh tempo pada {{.Date}}.` +
+				`{{else}}Deposito {{.Label}} jatuh tempo pada {{.Date}}.{{end}}`,
+		},
+		models.NotificationLocaleEN: {
+			subject: `{{if eq .AlertType "price_above"}}{{.Ticker}} above {{num .Threshold}}` +
+				`{{else if eq .AlertType "price_below"}}{{.Ticker}} below {{num .Threshold}}` +
+				`{{else if eq .AlertType "dividend_yield_above"}}{{.Ticker}} dividend yield above {{num .Threshold}}%` +
+				`{{else if eq .AlertType "earnings_within_days"}}{{.Ticker}} earnings release coming up` +
+				`{{else if eq .AlertType "bond_coupon_due"}}{{.Label}} coupon due soon` +
+				`{{else}}{{.Label}} deposit maturing soon{{end}}`,
+			body: `{{if eq .AlertType "price_above"}}{{.Ticker}} reached {{num .Value}}, crossing your upper limit of {{num .Threshold}}.` +
+				`{{else if eq .AlertType "price_below"}}{{.Ticker}} fell to {{num .Value}}, crossing your lower limit of {{num .Threshold}}.` +
+				`{{else if eq .AlertType "dividend_yield_above"}}{{.Ticker}} dividend yield reached {{num .Value}}%.` +
+				`{{else if eq .AlertType "earnings_within_days"}}{{.Ticker}} is expected to release earnings on {{.Date}}.` +
+				`{{else if eq .AlertType "bond_coupon_due"}}The {{.Label}} bond coupon is due on {{.Date}}.` +
+				`{{else}}Your {{.Label}} deposit matures on {{.Date}}.{{end}}`,
+		},
+	},
+	EventDepositMaturity: {
+		models.NotificationLocaleID: {
+			subject: `Deposito {{.Bank}} jatuh tempo {{if eq .DaysLeft 0}}hari ini{{else}}dalam {{.DaysLeft}} hari{{end}}`,
+			body:    `Deposito {{.Bank}} ({{.Account}}) sebesar Rp{{money .Amount}} jatuh tempo pada {{.MaturityDate}}.`,
+		},
+		models.NotificationLocaleEN: {
+			subject: `{{.Bank}} deposit matures {{if eq .DaysLeft 0}}today{{else}}in {{.DaysLeft}} days{{end}}`,
+			body:    `Your {{.Bank}} ({{.Account}}) deposit of IDR {{money .Amount}} matures on {{.MaturityDate}}.`,
+		},
+	},
+	EventBondCoupon: {
+		models.NotificationLocaleID: {
+			subject: `Kupon {{.BondName}} dibayarkan {{if eq .DaysLeft 0}}hari ini{{else}}dalam {{.DaysLeft}} hari{{end}}`,
+			body:    `Kupon obligasi {{.BondName}} ({{num .CouponRate}}%) dijadwalkan dibayarkan pada {{.PaymentDate}}.`,
+		},
+		models.NotificationLocaleEN: {
+			subject: `{{.BondName}} coupon paid {{if eq .DaysLeft 0}}today{{else}}in {{.DaysLeft}} days{{end}}`,
+			body:    `The {{.BondName}} bond coupon ({{num .CouponRate}}%) is scheduled for payment on {{.PaymentDate}}.`,
+		},
+	},
+	EventPremiumExpiry: {
+		models.NotificationLocaleID: {
+			subject: `Langganan {{.Level}} berakhir {{if eq .DaysLeft 0}}hari ini{{else}}dalam {{.DaysLeft}} hari{{end}}`,
+			body:    `Langganan {{.Level}} Anda berakhir pada {{.ExpiresAt}}. Perpanjang sekarang agar fitur premium tetap aktif.`,
+		},
+		models.NotificationLocaleEN: {
+			subject: `Your {{.Level}} subscription ends {{if eq .DaysLeft 0}}today{{else}}in {{.DaysLeft}} days{{end}}`,
+			body:    `Your {{.Level}} subscription ends on {{.ExpiresAt}}. Renew now to keep premium features active.`,
+		},
+	},
+	EventEmailVerification: {
+		models.NotificationLocaleID: {
+			subject: `Verifikasi alamat email Anda`,
+			body:    `Buka tautan di bawah untuk memverifikasi alamat email dan mengaktifkan seluruh fitur akun Anda. Tautan berlaku hingga {{.ExpiresAt}}.`,
+		},
+		models.NotificationLocaleEN: {
+			subject: `Verify your email address`,
+			body:    `Open the link below to verify your email address and unlock every feature of your account. The link is valid until {{.ExpiresAt}}.`,
+		},
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	"num": func(value interface{}) string {
+		if f, ok := toFloat(value); ok {
+			return fmt.Sprintf("%.2f", f)
+		}
+		return "-"
+	},
+	"money": func(value interface{}) string {
+		if f, ok := toFloat(value); ok {
+			return helper.FormatThousands(f)
+		}
+		return "-"
+	},
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case *float64:
+		if v != nil {
+			return *v, true
+		}
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Render returns the subject and body of event in locale, falling back to Indonesian.
+func Render(event Event, locale string, data map[string]interface{}) (string, string, error) {
+	locales, ok := messageTemplates[event]
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification event %s", event)
+	}
+	tmpl, ok := locales[locale]
+	if !ok {
+		tmpl = locales[models.NotificationLocaleID]
+	}
+
+	subject, err := execute(string(event)+".subject", tmpl.subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := execute(string(event)+".body", tmpl.body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func execute(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing notification template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering notification template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
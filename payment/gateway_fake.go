@@ -0,0 +1,147 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/bytedance/sonic"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a fake gateway webhook body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeCheckoutPath is where fake gateway checkouts are paid, followed by the reference.
+const FakeCheckoutPath = "/api/public/payments/fake/"
+
+// fakeWebhookPayload is the webhook body sent by the fake gateway.
+type fakeWebhookPayload struct {
+	EventID       string    `json:"event_id"`
+	OrderID       string    `json:"order_id"`
+	Reference     string    `json:"reference"`
+	Status        string    `json:"status"`
+	Amount        float64   `json:"amount"`
+	PaymentMethod string    `json:"payment_method"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// FakeGateway is a local gateway for development and testing. Charges are kept in
+// memory and paid by calling Simulate, which produces a webhook signed exactly like a
+// real delivery.
+type FakeGateway struct {
+	secret []byte
+
+	mu      sync.Mutex
+	charges map[string]Charge
+}
+
+// NewFakeGateway creates a fake gateway signing webhooks with secret, or with a random
+// per-process secret when it is empty.
+func NewFakeGateway(secret string) *FakeGateway {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = []byte(randomHex(32))
+	}
+	return &FakeGateway{
+		secret:  key,
+		charges: make(map[string]Charge),
+	}
+}
+
+func (g *FakeGateway) Name() string { return GatewayFake }
+
+// CreateCharge opens an in-memory charge payable at FakeCheckoutPath.
+func (g *FakeGateway) CreateCharge(ctx context.Context, charge Charge) (*ChargeResult, error) {
+	reference := "fake_" + randomHex(8)
+
+	g.mu.Lock()
+	g.charges[reference] = charge
+	g.mu.Unlock()
+
+	return &ChargeResult{
+		Reference:   reference,
+		CheckoutURL: FakeCheckoutPath + reference,
+	}, nil
+}
+
+// ParseWebhook verifies the HMAC signature and decodes a fake gateway webhook.
+func (g *FakeGateway) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, g.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var payload fakeWebhookPayload
+	if err := sonic.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if payload.EventID == "" || payload.OrderID == "" || payload.OccurredAt.IsZero() {
+		return nil, fmt.Errorf("%w: event_id, order_id and occurred_at are required", ErrInvalidWebhook)
+	}
+	switch payload.Status {
+	case models.PaymentEventPaid, models.PaymentEventFailed, models.PaymentEventExpired:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWebhook, payload.Status)
+	}
+
+	return &WebhookEvent{
+		EventID:       payload.EventID,
+		OrderID:       payload.OrderID,
+		Reference:     payload.Reference,
+		Status:        payload.Status,
+		Amount:        payload.Amount,
+		PaymentMethod: payload.PaymentMethod,
+		OccurredAt:    payload.OccurredAt,
+	}, nil
+}
+
+// Simulate settles the charge with reference as status and returns the signed webhook
+// request the gateway would deliver for it.
+func (g *FakeGateway) Simulate(reference, status, paymentMethod string) (http.Header, []byte, error) {
+	g.mu.Lock()
+	charge, ok := g.charges[reference]
+	g.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("fake charge %s not found", reference)
+	}
+
+	body, err := sonic.Marshal(fakeWebhookPayload{
+		EventID:       "evt_" + randomHex(8),
+		OrderID:       charge.OrderID,
+		Reference:     reference,
+		Status:        status,
+		Amount:        charge.Amount,
+		PaymentMethod: paymentMethod,
+		OccurredAt:    utime.Utime.Now().ToTime(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, hex.EncodeToString(g.sign(body)))
+	return header, body, nil
+}
+
+func (g *FakeGateway) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
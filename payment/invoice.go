@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+)
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": func(value float64) string { return "Rp" + helper.FormatThousands(value) },
+	"date": func(t time.Time) string {
+		local, _ := helper.TimeInWIB(t)
+		return local.Format("02 Jan 2006")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="id">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Invoice.InvoiceNumber}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 720px; margin: 40px auto; }
+h1 { margin-bottom: 0; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.amount, th.amount { text-align: right; }
+.meta td { border: none; padding: 2px 8px 2px 0; }
+.total td { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>INVOICE</h1>
+<p>{{.Issuer}}</p>
+<table class="meta">
+<tr><td>Nomor Invoice</td><td>{{.Invoice.InvoiceNumber}}</td></tr>
+<tr><td>Tanggal Terbit</td><td>{{date .Invoice.IssuedAt}}</td></tr>
+<tr><td>Tanggal Pembayaran</td><td>{{date .Invoice.Payment.PaymentDate}}</td></tr>
+<tr><td>Ditagihkan kepada</td><td>{{.Invoice.UserEmail}}</td></tr>
+<tr><td>Metode Pembayaran</td><td>{{.Invoice.Payment.PaymentMethod}}</td></tr>
+<tr><td>Status</td><td>{{.Invoice.Payment.PaymentStatus}}</td></tr>
+</table>
+<table>
+<tr><th>Deskripsi</th><th class="amount">Jumlah</th></tr>
+<tr><td>Langganan {{.Invoice.Payment.SubscriptionType}} s.d. {{date .Invoice.Payment.ExpiresAt}}</td><td class="amount">{{money .Invoice.Payment.OriginalPrice}}</td></tr>
+{{- if gt .Invoice.Payment.DiscountAmount 0.0}}
+<tr><td>Diskon{{with .Invoice.Payment.DiscountReason}} ({{.}}){{end}}</td><td class="amount">-{{money .Invoice.Payment.DiscountAmount}}</td></tr>
+{{- end}}
+{{- if gt .Invoice.Payment.ProrationCredit 0.0}}
+<tr><td>Kredit prorata (sisa langganan premium)</td><td class="amount">-{{money .Invoice.Payment.ProrationCredit}}</td></tr>
+{{- end}}
+<tr class="total"><td>Total Dibayar</td><td class="amount">{{money .Invoice.Payment.PaidPrice}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML renders a printable HTML invoice issued by issuer.
+func RenderInvoiceHTML(invoice *models.PaymentInvoice, issuer string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := invoiceTemplate.Execute(&buf, map[string]interface{}{
+		"Invoice": invoice,
+		"Issuer":  issuer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error rendering invoice %s: %w", invoice.InvoiceNumber, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// InvoiceFilename returns the download file name of an invoice.
+func InvoiceFilename(invoice *models.PaymentInvoice) string {
+	return strings.ReplaceAll(invoice.InvoiceNumber, "/", "-") + ".html"
+}
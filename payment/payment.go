@@ -0,0 +1,216 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/rs/zerolog"
+)
+
+// Gateway names.
+const (
+	GatewayFake = "fake"
+)
+
+var (
+	ErrUnknownGateway   = errors.New("unknown payment gateway")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidWebhook   = errors.New("invalid webhook payload")
+
+	// ErrPaymentsDisabled is returned by NewService in production when only the fake
+	// gateway is configured; the API then runs without payments.
+	ErrPaymentsDisabled = errors.New("payments are disabled: no real payment gateway configured")
+)
+
+// Charge is a payment request opened at a gateway for one pending payment record.
+type Charge struct {
+	OrderID       string
+	Amount        float64
+	Description   string
+	CustomerEmail string
+	ExpiresAt     time.Time
+}
+
+// ChargeResult identifies an opened charge at the gateway.
+type ChargeResult struct {
+	Reference   string
+	CheckoutURL string
+}
+
+// WebhookEvent is a gateway notification whose signature has been verified.
+type WebhookEvent struct {
+	// EventID is unique per notification at the gateway and makes processing idempotent
+	EventID       string
+	OrderID       string
+	Reference     string
+	Status        string
+	Amount        float64
+	PaymentMethod string
+	OccurredAt    time.Time
+}
+
+// Gateway is a payment gateway adapter (Midtrans, Xendit, ...). It opens hosted
+// checkouts and turns signed webhook requests into events.
+type Gateway interface {
+	Name() string
+	CreateCharge(ctx context.Context, charge Charge) (*ChargeResult, error)
+	// ParseWebhook verifies the request signature and decodes the event. It returns
+	// ErrInvalidSignature or ErrInvalidWebhook for requests that must be rejected.
+	ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// Service runs checkouts through the configured gateway and applies its webhooks.
+type Service struct {
+	logger  *zerolog.Logger
+	cfg     config.PaymentConfig
+	gateway Gateway
+	repo    models.PaymentRepository
+	plans   models.SubscriptionPlanRepository
+}
+
+// NewService builds the payment service for the configured gateway. In the production
+// env it returns ErrPaymentsDisabled for the fake gateway, which settles charges without
+// money, and refuses a real gateway without a webhook secret.
+func NewService(cfg config.PaymentConfig, env string, logger *zerolog.Logger) (*Service, error) {
+	s := &Service{
+		logger: logger,
+		cfg:    cfg,
+		repo:   models.NewPaymentRepository(),
+		plans:  models.NewSubscriptionPlanRepository(),
+	}
+
+	switch cfg.Gateway {
+	case "", GatewayFake:
+		if env == "production" {
+			return nil, ErrPaymentsDisabled
+		}
+		s.gateway = NewFakeGateway(cfg.WebhookSecret)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownGateway, cfg.Gateway)
+	}
+
+	// Only the fake gateway may generate a secret per process
+	if env == "production" && cfg.WebhookSecret == "" {
+		return nil, fmt.Errorf("payment gateway %q requires PAYMENT_WEBHOOK_SECRET in production", s.gateway.Name())
+	}
+
+	return s, nil
+}
+
+// Gateway returns the configured gateway adapter.
+func (s *Service) Gateway() Gateway {
+	return s.gateway
+}
+
+// Checkout opens a gateway charge for a subscription plan, discounted by promoCode when
+// set, and records it as a pending payment. An unexpired pending checkout for the same
+// plan and promo code is returned instead of a new one. A payment that ends up free
+// completes without a charge.
+func (s *Service) Checkout(ctx context.Context, user *models.User, planID int, promoCode *string) (*models.PaymentRecord, error) {
+	plan, err := s.plans.FindPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, models.ErrSubscriptionPlanNotFound
+	}
+	if !plan.Active {
+		return nil, models.ErrSubscriptionPlanInactive
+	}
+
+	now := utime.Utime.Now().ToTime()
+	existing, err := s.repo.FindPendingCheckout(user.ID, plan.ID, promoCode, now)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	gateway := s.gateway.Name()
+	checkoutExpiresAt := now.Add(s.cfg.CheckoutTTL)
+	payment, err := s.repo.CreateCheckoutPayment(&models.PaymentRecord{
+		UserID:             user.ID,
+		SubscriptionType:   plan.UserLevel,
+		SubscriptionPlanID: &plan.ID,
+		PaymentMethod:      gateway,
+		PaymentDate:        now,
+		Gateway:            &gateway,
+		CheckoutExpiresAt:  &checkoutExpiresAt,
+	}, promoCode, now)
+	if err != nil {
+		return nil, err
+	}
+	if payment.PaymentStatus == models.PaymentStatusCompleted {
+		return payment, nil
+	}
+
+	result, err := s.gateway.CreateCharge(ctx, Charge{
+		OrderID:       models.PaymentOrderID(payment.ID),
+		Amount:        payment.PaidPrice,
+		Description:   fmt.Sprintf("Langganan %s", plan.Name),
+		CustomerEmail: user.Email,
+		ExpiresAt:     checkoutExpiresAt,
+	})
+	if err != nil {
+		if failErr := s.repo.FailCheckout(payment.ID, err.Error()); failErr != nil {
+			s.logger.Error().Err(failErr).Int("paymentId", payment.ID).Msg("Failed to mark checkout as failed")
+		}
+		return nil, fmt.Errorf("error creating %s charge: %w", gateway, err)
+	}
+
+	return s.repo.AttachCheckout(payment.ID, gateway, result.Reference, result.CheckoutURL)
+}
+
+// HandleWebhook verifies a webhook for gatewayName and applies it to its payment.
+// Redelivered events are reported as duplicates and change nothing.
+func (s *Service) HandleWebhook(ctx context.Context, gatewayName string, header http.Header, body []byte) (*models.PaymentEventResult, error) {
+	if gatewayName != s.gateway.Name() {
+		return nil, ErrUnknownGateway
+	}
+
+	event, err := s.gateway.ParseWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+	paymentID, ok := models.ParsePaymentOrderID(event.OrderID)
+	if !ok {
+		return nil, models.ErrPaymentNotFound
+	}
+
+	gatewayEvent := &models.PaymentGatewayEvent{
+		Gateway:         s.gateway.Name(),
+		EventID:         event.EventID,
+		PaymentRecordID: &paymentID,
+		EventStatus:     event.Status,
+		Payload:         body,
+		OccurredAt:      event.OccurredAt,
+	}
+	if event.Amount > 0 {
+		gatewayEvent.Amount = &event.Amount
+	}
+	if event.PaymentMethod != "" {
+		gatewayEvent.PaymentMethod = &event.PaymentMethod
+	}
+
+	result, err := s.repo.ApplyGatewayEvent(gatewayEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("gateway", gatewayEvent.Gateway).
+		Str("eventId", event.EventID).
+		Str("status", event.Status).
+		Int("paymentId", paymentID).
+		Bool("duplicate", result.Duplicate).
+		Bool("applied", result.Applied).
+		Msg("Payment webhook processed")
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package payment
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/rs/zerolog"
+)
+
+func TestFakeGatewayParseWebhook(t *testing.T) {
+	gateway := NewFakeGateway("secret")
+	charge, err := gateway.CreateCharge(context.Background(), Charge{OrderID: models.PaymentOrderID(42), Amount: 99000})
+	if err != nil {
+		t.Fatalf("error creating charge: %v", err)
+	}
+	header, body, err := gateway.Simulate(charge.Reference, models.PaymentEventPaid, "qris")
+	if err != nil {
+		t.Fatalf("error simulating payment: %v", err)
+	}
+
+	signed := func(g *FakeGateway, body string) http.Header {
+		h := http.Header{}
+		h.Set(FakeSignatureHeader, hex.EncodeToString(g.sign([]byte(body))))
+		return h
+	}
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr error
+	}{
+		{name: "valid signature", header: header, body: body},
+		{name: "missing signature", header: http.Header{}, body: body, wantErr: ErrInvalidSignature},
+		{name: "signature is not hex", header: http.Header{FakeSignatureHeader: {"zz"}}, body: body, wantErr: ErrInvalidSignature},
+		{name: "tampered body", header: header, body: tampered, wantErr: ErrInvalidSignature},
+		{name: "signed with another secret", header: signed(NewFakeGateway("other"), string(body)), body: body, wantErr: ErrInvalidSignature},
+		{name: "signed but not json", header: signed(gateway, "nope"), body: []byte("nope"), wantErr: ErrInvalidWebhook},
+		{
+			name:    "signed without event id",
+			header:  signed(gateway, `{"order_id":"x","status":"paid","occurred_at":"2026-01-01T00:00:00Z"}`),
+			body:    []byte(`{"order_id":"x","status":"paid","occurred_at":"2026-01-01T00:00:00Z"}`),
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "signed with unknown status",
+			header:  signed(gateway, `{"event_id":"e","order_id":"x","status":"refunded","occurred_at":"2026-01-01T00:00:00Z"}`),
+			body:    []byte(`{"event_id":"e","order_id":"x","status":"refunded","occurred_at":"2026-01-01T00:00:00Z"}`),
+			wantErr: ErrInvalidWebhook,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := gateway.ParseWebhook(tt.header, tt.body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if event.OrderID != models.PaymentOrderID(42) || event.Reference != charge.Reference || event.Status != models.PaymentEventPaid || event.Amount != 99000 {
+				t.Fatalf("got event %+v", event)
+			}
+		})
+	}
+}
+
+// A redelivered webhook must verify again and carry the same event id, which is what
+// ApplyGatewayEvent deduplicates on; a new simulation is a different event.
+func TestFakeGatewayReplayKeepsEventID(t *testing.T) {
+	gateway := NewFakeGateway("")
+	charge, err := gateway.CreateCharge(context.Background(), Charge{OrderID: models.PaymentOrderID(7), Amount: 50000})
+	if err != nil {
+		t.Fatalf("error creating charge: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		status string
+	}{
+		{name: "paid", status: models.PaymentEventPaid},
+		{name: "failed", status: models.PaymentEventFailed},
+		{name: "expired", status: models.PaymentEventExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, body, err := gateway.Simulate(charge.Reference, tt.status, "")
+			if err != nil {
+				t.Fatalf("error simulating payment: %v", err)
+			}
+
+			first, err := gateway.ParseWebhook(header, body)
+			if err != nil {
+				t.Fatalf("error parsing delivery: %v", err)
+			}
+			replay, err := gateway.ParseWebhook(header, body)
+			if err != nil {
+				t.Fatalf("error parsing redelivery: %v", err)
+			}
+			if *first != *replay {
+				t.Fatalf("redelivery decoded to %+v, want %+v", replay, first)
+			}
+
+			header, body, err = gateway.Simulate(charge.Reference, tt.status, "")
+			if err != nil {
+				t.Fatalf("error simulating payment: %v", err)
+			}
+			other, err := gateway.ParseWebhook(header, body)
+			if err != nil {
+				t.Fatalf("error parsing second event: %v", err)
+			}
+			if other.EventID == first.EventID {
+				t.Fatalf("second simulation reused event id %s", first.EventID)
+			}
+		})
+	}
+
+	if _, _, err := gateway.Simulate("fake_unknown", models.PaymentEventPaid, ""); err == nil {
+		t.Fatal("expected an error simulating an unknown charge")
+	}
+}
+
+func TestNewService(t *testing.T) {
+	logger := zerolog.Nop()
+
+	tests := []struct {
+		name    string
+		cfg     config.PaymentConfig
+		env     string
+		wantErr error
+	}{
+		{name: "fake gateway in development", cfg: config.PaymentConfig{}, env: "development"},
+		{name: "fake gateway with secret in development", cfg: config.PaymentConfig{Gateway: GatewayFake, WebhookSecret: "s"}, env: "development"},
+		{name: "fake gateway in production", cfg: config.PaymentConfig{Gateway: GatewayFake, WebhookSecret: "s"}, env: "production", wantErr: ErrPaymentsDisabled},
+		{name: "default gateway in production", cfg: config.PaymentConfig{}, env: "production", wantErr: ErrPaymentsDisabled},
+		{name: "unknown gateway", cfg: config.PaymentConfig{Gateway: "acme"}, env: "development", wantErr: ErrUnknownGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewService(tt.cfg, tt.env, &logger)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if service.Gateway().Name() != GatewayFake {
+				t.Fatalf("got gateway %s, want %s", service.Gateway().Name(), GatewayFake)
+			}
+
+			// Webhooks for another gateway are refused before touching the database
+			if _, err := service.HandleWebhook(context.Background(), "acme", http.Header{}, nil); !errors.Is(err, ErrUnknownGateway) {
+				t.Fatalf("got error %v for another gateway, want %v", err, ErrUnknownGateway)
+			}
+		})
+	}
+}
@@ -12,7 +12,7 @@ func (r *Router) setupAuthRoutes(apiGroup *echo.Group) {
 
 	// Initialize auth handlers
 	userRepo := models.NewUserRepository()
-	authHandlers := api.NewAuthHandlers(userRepo)
+	authHandlers := api.NewAuthHandlers(userRepo, r.Verification)
 
 	// Authentication routes (no auth required)
 	authGroup := apiGroup.Group("/auth")
@@ -22,4 +22,7 @@ func (r *Router) setupAuthRoutes(apiGroup *echo.Group) {
 
 	// Register endpoint - accessible at /api/public/auth/register
 	authGroup.POST("/register", authHandlers.Register, validator.ValidateRequest(&validator.RegisterRequest{}))
+
+	// Email verification endpoint, with the token emailed on sign-up - accessible at /api/auth/verify-email
+	authGroup.POST("/verify-email", authHandlers.VerifyEmail, validator.ValidateRequest(&validator.VerifyEmailRequest{}))
 }
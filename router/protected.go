@@ -4,6 +4,7 @@ import (
 	"github.com/WahyuSiddarta/be_saham_go/api"
 	"github.com/WahyuSiddarta/be_saham_go/middleware"
 	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
 	"github.com/WahyuSiddarta/be_saham_go/validator"
 	"github.com/labstack/echo/v4"
 )
@@ -12,31 +13,185 @@ import (
 func (r *Router) setupProtectedRoutes(apiGroup *echo.Group) {
 	// Initialize auth handlers
 	userRepo := models.NewUserRepository()
-	authHandlers := api.NewAuthHandlers(userRepo)
+	authHandlers := api.NewAuthHandlers(userRepo, r.Verification)
 
 	userGroup := apiGroup.Group("/users")
 	userGroup.Use(middleware.RequireAuth()) // Add authentication middleware to protected routes
 	userGroup.GET("/profile", authHandlers.GetProfile)
-	portfolioGroup := userGroup.Group("/portfolio")
-	setupCashPortfolioRoutes(portfolioGroup) // Setup CashPortfolio routes (includes PnL)
-	setupBondPortfolioRoutes(portfolioGroup) // Setup BondPortfolio routes
+	userGroup.PUT("/password", authHandlers.ChangePassword, middleware.BlockImpersonation(), validator.ValidateRequest(&validator.ChangePasswordRequest{}))
+	userGroup.POST("/verify-email/resend", authHandlers.ResendVerification, middleware.BlockImpersonation())
+
+	// Features below require a verified email
+	portfolioGroup := userGroup.Group("/portfolio", middleware.RequireVerified())
+	setupCashPortfolioRoutes(portfolioGroup)  // Setup CashPortfolio routes (includes PnL)
+	setupBondPortfolioRoutes(portfolioGroup)  // Setup BondPortfolio routes
+	setupWatchlistRoutes(userGroup)           // Setup Watchlist routes
+	setupAlertRoutes(userGroup)               // Setup Alert routes
+	setupNotificationRoutes(userGroup)        // Setup Notification routes
+	setupPaymentRoutes(userGroup, r.Payments) // Setup Payment routes
 
 	// Setup admin routes
-	setupAdminRoutes(apiGroup, authHandlers)
+	setupAdminRoutes(apiGroup, authHandlers, r.Ingestor)
 
 }
 
-// setupAdminRoutes configures admin routes (admin authentication required)
-func setupAdminRoutes(rprotected *echo.Group, authHandlers *api.AuthHandlers) {
+// setupAdminRoutes configures admin routes. Staff are users holding any permission; each
+// route requires the permission it needs (db/roles_permissions.sql)
+func setupAdminRoutes(rprotected *echo.Group, authHandlers *api.AuthHandlers, ingestor api.StockIngestor) {
 	adminGroup := rprotected.Group("/admin")
-	adminGroup.Use(middleware.AdminRequired())
+	adminGroup.Use(middleware.RequireAuth(), middleware.AdminRequired())
+
+	// Permission checks shared by the routes below
+	usersRead := middleware.RequirePermission(models.PermissionUsersRead)
+	usersWrite := middleware.RequirePermission(models.PermissionUsersWrite)
+	usersImpersonate := middleware.RequirePermission(models.PermissionUsersImpersonate)
+	paymentsRead := middleware.RequirePermission(models.PermissionPaymentsRead)
+	paymentsWrite := middleware.RequirePermission(models.PermissionPaymentsWrite)
+	plansRead := middleware.RequirePermission(models.PermissionPlansRead)
+	plansWrite := middleware.RequirePermission(models.PermissionPlansWrite)
+	promoCodesRead := middleware.RequirePermission(models.PermissionPromoCodesRead)
+	promoCodesWrite := middleware.RequirePermission(models.PermissionPromoCodesWrite)
+	reportsRead := middleware.RequirePermission(models.PermissionReportsRead)
+	stocksRead := middleware.RequirePermission(models.PermissionStocksRead)
+	stocksWrite := middleware.RequirePermission(models.PermissionStocksWrite)
+	auditRead := middleware.RequirePermission(models.PermissionAuditRead)
+	rolesRead := middleware.RequirePermission(models.PermissionRolesRead)
+	rolesWrite := middleware.RequirePermission(models.PermissionRolesWrite)
 
-	// User management endpoints, accessible at /api/admin/users
+	// User management endpoints, accessible at /api/admin/users. Level changes record
+	// subscription payments, so they need payments:write
+	roleHandlers := api.NewRoleHandlers(models.NewRoleRepository())
 	usersGroup := adminGroup.Group("/users")
-	usersGroup.GET("", authHandlers.GetAllUsers, validator.ValidateQuery(&validator.GetUsersQuery{}))
-	usersGroup.PUT("/:id/level", authHandlers.UpdateUserLevel, validator.ValidateRequest(&validator.UpdateUserLevelRequest{}))
-	usersGroup.PUT("/:id/status", authHandlers.UpdateUserStatus, validator.ValidateRequest(&validator.UpdateUserStatusRequest{}))
-	usersGroup.GET("/expired", authHandlers.GetExpiredUsers)
+	usersGroup.GET("", authHandlers.GetAllUsers, usersRead, validator.ValidateQuery(&validator.GetUsersQuery{}))
+	usersGroup.PUT("/:id/level", authHandlers.UpdateUserLevel, paymentsWrite, validator.ValidateRequest(&validator.UpdateUserLevelRequest{}))
+	usersGroup.PUT("/:id/status", authHandlers.UpdateUserStatus, usersWrite, validator.ValidateRequest(&validator.UpdateUserStatusRequest{}))
+	usersGroup.GET("/expired", authHandlers.GetExpiredUsers, usersRead)
+	usersGroup.GET("/:id/roles", roleHandlers.GetUserRoles, rolesRead)
+	usersGroup.PUT("/:id/roles", roleHandlers.SetUserRoles, rolesWrite, validator.ValidateRequest(&validator.SetUserRolesRequest{}))
+
+	// Support impersonation, accessible at /api/admin/users/:id/impersonate
+	impersonationHandlers := api.NewImpersonationHandlers(models.NewUserRepository(), models.NewRoleRepository())
+	usersGroup.POST("/:id/impersonate", impersonationHandlers.ImpersonateUser, usersImpersonate, validator.ValidateRequest(&validator.ImpersonateUserRequest{}))
+
+	// Role and permission management, accessible at /api/admin/roles and /api/admin/permissions
+	adminGroup.GET("/permissions", roleHandlers.GetPermissions, rolesRead)
+	rolesGroup := adminGroup.Group("/roles")
+	rolesGroup.GET("", roleHandlers.GetRoles, rolesRead)
+	rolesGroup.POST("", roleHandlers.CreateRole, rolesWrite, validator.ValidateRequest(&validator.CreateRoleRequest{}))
+	rolesGroup.PUT("/:id", roleHandlers.UpdateRole, rolesWrite, validator.ValidateRequest(&validator.UpdateRoleRequest{}))
+
+	// Audit log search, accessible at /api/admin/audit
+	auditHandlers := api.NewAuditHandlers(models.NewAuditRepository())
+	adminGroup.GET("/audit", auditHandlers.GetAuditLogs, auditRead, validator.ValidateQuery(&validator.GetAuditLogsQuery{}))
+
+	// Payment history and invoices, accessible at /api/admin/payments
+	paymentHistoryHandlers := api.NewPaymentHistoryHandlers(models.NewPaymentHistoryRepository())
+	paymentsGroup := adminGroup.Group("/payments")
+	paymentsGroup.GET("", paymentHistoryHandlers.GetPayments, paymentsRead, validator.ValidateQuery(&validator.GetAdminPaymentsQuery{}))
+	paymentsGroup.GET("/:id/invoice", paymentHistoryHandlers.GetPaymentInvoice, paymentsRead)
+
+	// Subscription and revenue reports, accessible at /api/admin/reports
+	reportHandlers := api.NewReportHandlers(models.NewReportRepository())
+	reportsGroup := adminGroup.Group("/reports", reportsRead)
+	reportsGroup.GET("/subscriptions", reportHandlers.GetSubscriptionMetrics)
+	reportsGroup.GET("/monthly", reportHandlers.GetMonthlySubscriptionStats, validator.ValidateQuery(&validator.GetReportRangeQuery{}))
+	reportsGroup.GET("/revenue", reportHandlers.GetRevenueReport, validator.ValidateQuery(&validator.GetReportRangeQuery{}))
+
+	// Subscription plan management, accessible at /api/admin/subscription-plans
+	subscriptionPlanHandlers := api.NewSubscriptionPlanHandlers(models.NewSubscriptionPlanRepository())
+	plansGroup := adminGroup.Group("/subscription-plans")
+	plansGroup.GET("", subscriptionPlanHandlers.GetSubscriptionPlans, plansRead)
+	plansGroup.POST("", subscriptionPlanHandlers.CreateSubscriptionPlan, plansWrite, validator.ValidateRequest(&validator.CreateSubscriptionPlanRequest{}))
+	plansGroup.PUT("/:id", subscriptionPlanHandlers.UpdateSubscriptionPlan, plansWrite, validator.ValidateRequest(&validator.UpdateSubscriptionPlanRequest{}))
+
+	// Promo code management, accessible at /api/admin/promo-codes
+	promoCodeHandlers := api.NewPromoCodeHandlers(models.NewPromoCodeRepository(), models.NewSubscriptionPlanRepository())
+	promoCodesGroup := adminGroup.Group("/promo-codes")
+	promoCodesGroup.GET("", promoCodeHandlers.GetPromoCodes, promoCodesRead, validator.ValidateQuery(&validator.GetPromoCodesQuery{}))
+	promoCodesGroup.POST("", promoCodeHandlers.CreatePromoCode, promoCodesWrite, validator.ValidateRequest(&validator.CreatePromoCodeRequest{}))
+	promoCodesGroup.GET("/:id", promoCodeHandlers.GetPromoCode, promoCodesRead)
+	promoCodesGroup.PUT("/:id", promoCodeHandlers.UpdatePromoCode, promoCodesWrite, validator.ValidateRequest(&validator.UpdatePromoCodeRequest{}))
+	promoCodesGroup.GET("/:id/redemptions", promoCodeHandlers.GetPromoCodeRedemptions, promoCodesRead, validator.ValidateQuery(&validator.GetPromoCodeRedemptionsQuery{}))
+
+	// Stock ingestion run history, accessible at /api/admin/ingestion-runs
+	ingestionHandlers := api.NewIngestionHandlers(models.NewIngestionRunRepository())
+	ingestionGroup := adminGroup.Group("/ingestion-runs", stocksRead)
+	ingestionGroup.GET("", ingestionHandlers.GetIngestionRuns, validator.ValidateQuery(&validator.GetIngestionRunsQuery{}))
+	ingestionGroup.GET("/:id", ingestionHandlers.GetIngestionRun, validator.ValidateQuery(&validator.GetIngestionRunQuery{}))
+
+	// Tracked ticker management, accessible at /api/admin/stocks
+	adminStockHandlers := api.NewAdminStockHandlers(models.NewStockRepository(), ingestor)
+	stocksGroup := adminGroup.Group("/stocks")
+	stocksGroup.GET("", adminStockHandlers.GetTrackedStocks, stocksRead, validator.ValidateQuery(&validator.GetTrackedStocksQuery{}))
+	stocksGroup.POST("", adminStockHandlers.CreateTrackedStock, stocksWrite, validator.ValidateRequest(&validator.CreateTrackedStockRequest{}))
+	stocksGroup.GET("/classifications", adminStockHandlers.GetStockClassifications, stocksRead)
+	stocksGroup.POST("/import", adminStockHandlers.ImportStockProfiles, stocksWrite)
+	stocksGroup.GET("/:ticker", adminStockHandlers.GetTrackedStock, stocksRead)
+	stocksGroup.PUT("/:ticker/status", adminStockHandlers.UpdateTrackedStockStatus, stocksWrite, validator.ValidateRequest(&validator.UpdateTrackedStockStatusRequest{}))
+	stocksGroup.PUT("/:ticker/profile", adminStockHandlers.UpdateStockProfile, stocksWrite, validator.ValidateRequest(&validator.UpdateStockProfileRequest{}))
+	stocksGroup.PUT("/:ticker/api-key", adminStockHandlers.UpdateTrackedStockApiKey, stocksWrite, validator.ValidateRequest(&validator.UpdateTrackedStockApiKeyRequest{}))
+	stocksGroup.DELETE("/:ticker", adminStockHandlers.DeleteTrackedStock, stocksWrite)
+	stocksGroup.POST("/:ticker/ingest", adminStockHandlers.IngestTrackedStock, stocksWrite)
+}
+
+// setupWatchlistRoutes configures watchlist routes, accessible at /api/users/watchlists
+func setupWatchlistRoutes(userGroup *echo.Group) {
+	watchlistHandlers := api.NewWatchlistHandlers(models.NewWatchlistRepository())
+
+	watchlistGroup := userGroup.Group("/watchlists", middleware.RequireVerified())
+	watchlistGroup.GET("", watchlistHandlers.GetWatchlists)
+	watchlistGroup.POST("", watchlistHandlers.CreateWatchlist, validator.ValidateRequest(&validator.CreateWatchlistRequest{}))
+	watchlistGroup.PUT("/order", watchlistHandlers.ReorderWatchlists, validator.ValidateRequest(&validator.ReorderWatchlistsRequest{}))
+	watchlistGroup.GET("/:id", watchlistHandlers.GetWatchlist)
+	watchlistGroup.PUT("/:id", watchlistHandlers.RenameWatchlist, validator.ValidateRequest(&validator.RenameWatchlistRequest{}))
+	watchlistGroup.DELETE("/:id", watchlistHandlers.DeleteWatchlist)
+
+	watchlistGroup.POST("/:id/items", watchlistHandlers.AddWatchlistItem, validator.ValidateRequest(&validator.AddWatchlistItemRequest{}))
+	watchlistGroup.PUT("/:id/items/order", watchlistHandlers.ReorderWatchlistItems, validator.ValidateRequest(&validator.ReorderWatchlistItemsRequest{}))
+	watchlistGroup.PUT("/:id/items/:ticker", watchlistHandlers.UpdateWatchlistItem, validator.ValidateRequest(&validator.UpdateWatchlistItemRequest{}))
+	watchlistGroup.DELETE("/:id/items/:ticker", watchlistHandlers.RemoveWatchlistItem)
+}
+
+// setupAlertRoutes configures alert routes, accessible at /api/users/alerts
+func setupAlertRoutes(userGroup *echo.Group) {
+	alertHandlers := api.NewAlertHandlers(models.NewAlertRepository())
+
+	alertGroup := userGroup.Group("/alerts", middleware.RequireVerified())
+	alertGroup.GET("", alertHandlers.GetAlertInbox, validator.ValidateQuery(&validator.GetAlertInboxQuery{}))
+	alertGroup.PUT("/read-all", alertHandlers.MarkAllAlertsRead)
+	alertGroup.PUT("/:id/read", alertHandlers.MarkAlertRead)
+
+	alertGroup.GET("/rules", alertHandlers.GetAlertRules)
+	alertGroup.POST("/rules", alertHandlers.CreateAlertRule, validator.ValidateRequest(&validator.CreateAlertRequest{}))
+	alertGroup.PUT("/rules/:id", alertHandlers.UpdateAlertRule, validator.ValidateRequest(&validator.UpdateAlertRequest{}))
+	alertGroup.DELETE("/rules/:id", alertHandlers.DeleteAlertRule)
+}
+
+// setupNotificationRoutes configures notification routes, accessible at /api/users/notifications
+func setupNotificationRoutes(userGroup *echo.Group) {
+	notificationHandlers := api.NewNotificationHandlers(models.NewNotificationRepository())
+
+	notificationGroup := userGroup.Group("/notifications", middleware.RequireVerified())
+	notificationGroup.GET("/preferences", notificationHandlers.GetNotificationPreferences)
+	notificationGroup.PUT("/preferences", notificationHandlers.UpdateNotificationPreferences, validator.ValidateRequest(&validator.UpdateNotificationPreferencesRequest{}))
+	notificationGroup.GET("/web-push", notificationHandlers.GetWebPushConfig)
+	notificationGroup.POST("/web-push", notificationHandlers.SubscribeWebPush, validator.ValidateRequest(&validator.SubscribeWebPushRequest{}))
+	notificationGroup.DELETE("/web-push", notificationHandlers.UnsubscribeWebPush, validator.ValidateRequest(&validator.UnsubscribeWebPushRequest{}))
+}
+
+// setupPaymentRoutes configures checkout and payment history routes, accessible at /api/users/payments
+func setupPaymentRoutes(userGroup *echo.Group, payments *payment.Service) {
+	paymentHandlers := api.NewPaymentHandlers(payments, models.NewPaymentRepository())
+
+	paymentHistoryHandlers := api.NewPaymentHistoryHandlers(models.NewPaymentHistoryRepository())
+	promoCodeHandlers := api.NewPromoCodeHandlers(models.NewPromoCodeRepository(), models.NewSubscriptionPlanRepository())
+
+	paymentGroup := userGroup.Group("/payments", middleware.RequireVerified())
+	paymentGroup.GET("", paymentHistoryHandlers.GetMyPayments, validator.ValidateQuery(&validator.GetPaymentsQuery{}))
+	paymentGroup.POST("/checkout", paymentHandlers.Checkout, middleware.BlockImpersonation(), validator.ValidateRequest(&validator.CheckoutRequest{}))
+	paymentGroup.POST("/promo-check", promoCodeHandlers.CheckPromoCode, validator.ValidateRequest(&validator.CheckPromoCodeRequest{}))
+	paymentGroup.GET("/:id", paymentHandlers.GetPayment)
+	paymentGroup.GET("/:id/invoice", paymentHistoryHandlers.GetMyPaymentInvoice)
 }
 
 // setupCashPortfolioRoutes configures portfolio cash routes
@@ -3,8 +3,12 @@ package router
 import (
 	"net/http"
 
+	"github.com/WahyuSiddarta/be_saham_go/api"
 	"github.com/WahyuSiddarta/be_saham_go/config"
 	"github.com/WahyuSiddarta/be_saham_go/helper"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
 	"github.com/labstack/echo/v4"
 )
 
@@ -25,6 +29,18 @@ func (r *Router) setupPublicRoutes(apiGroup *echo.Group) {
 	// TEST endpoint - accessible at /api/public/test
 	rpub.GET("/test", r.API.Test)
 
+	// Subscription plan prices - accessible at /api/public/pricing
+	subscriptionPlanHandlers := api.NewSubscriptionPlanHandlers(models.NewSubscriptionPlanRepository())
+	rpub.GET("/pricing", subscriptionPlanHandlers.GetPricing)
+
+	// Payment gateway webhooks - accessible at /api/public/payments/webhook/:gateway
+	paymentHandlers := api.NewPaymentHandlers(r.Payments, models.NewPaymentRepository())
+	rpub.POST("/payments/webhook/:gateway", paymentHandlers.PaymentWebhook)
+	if r.Payments != nil && r.Payments.Gateway().Name() == payment.GatewayFake && config.Get().Env != "production" {
+		// Fake gateway checkout - accessible at /api/public/payments/fake/:reference
+		rpub.POST("/payments/fake/:reference", paymentHandlers.SimulateFakePayment, validator.ValidateRequest(&validator.SimulateFakePaymentRequest{}))
+	}
+
 	// Test panic recovery - accessible at /api/public/test-panic (for testing only)
 	rpub.GET("/test-panic", func(c echo.Context) error {
 		// This endpoint intentionally panics to test the recover middleware
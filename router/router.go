@@ -6,6 +6,8 @@ import (
 	"github.com/WahyuSiddarta/be_saham_go/api"
 	"github.com/WahyuSiddarta/be_saham_go/config"
 	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/payment"
+	"github.com/WahyuSiddarta/be_saham_go/verification"
 
 	"github.com/rs/zerolog"
 )
@@ -14,13 +16,19 @@ var Logger *zerolog.Logger
 
 // Router handles all route setup and configuration
 type Router struct {
-	API *api.API
+	API          *api.API
+	Ingestor     api.StockIngestor
+	Payments     *payment.Service // nil when payments are disabled
+	Verification *verification.Service
 }
 
 // New creates a new Router instance
-func New(apiInstance *api.API, logger *zerolog.Logger) *Router {
+func New(apiInstance *api.API, logger *zerolog.Logger, ingestor api.StockIngestor, payments *payment.Service, verification *verification.Service) *Router {
 	return &Router{
-		API: apiInstance,
+		API:          apiInstance,
+		Ingestor:     ingestor,
+		Payments:     payments,
+		Verification: verification,
 	}
 }
 
@@ -41,6 +49,7 @@ func (r *Router) SetupRoutes() error {
 	r.setupAuthRoutes(apiGroup)
 	r.setupPublicRoutes(apiGroup)
 	r.setupProtectedRoutes(apiGroup) // Future routes that require authentication
+	r.setupStockRoutes(apiGroup)
 
 	Logger.Info().Msg("All routes configured successfully")
 
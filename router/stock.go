@@ -0,0 +1,40 @@
+package router
+
+import (
+	"github.com/WahyuSiddarta/be_saham_go/api"
+	"github.com/WahyuSiddarta/be_saham_go/middleware"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// setupStockRoutes configures stock market data routes (authentication required)
+func (r *Router) setupStockRoutes(apiGroup *echo.Group) {
+	// Initialize stock handlers
+	stockRepo := models.NewStockRepository()
+	stockHandlers := api.NewStockHandlers(stockRepo)
+
+	stockGroup := apiGroup.Group("/stocks")
+	stockGroup.Use(middleware.RequireAuth(), middleware.RequireVerified())
+
+	// Market-wide earnings calendar - accessible at /api/stocks/earnings-calendar
+	stockGroup.GET("/earnings-calendar", stockHandlers.GetEarningsCalendar, validator.ValidateQuery(&validator.EarningsCalendarQuery{}))
+
+	// Average key ratios per sector - accessible at /api/stocks/sectors
+	stockGroup.GET("/sectors", stockHandlers.GetSectorAverages, validator.ValidateQuery(&validator.SectorAveragesQuery{}))
+
+	// Master attributes and sector comparison - accessible at /api/stocks/:symbol
+	stockGroup.GET("/:symbol", stockHandlers.GetStockOverview)
+
+	// Per-symbol beat/miss statistics - accessible at /api/stocks/:symbol/earnings-surprise
+	stockGroup.GET("/:symbol/earnings-surprise", stockHandlers.GetEarningSurpriseStats)
+
+	// Derived TTM / QoQ / YoY metrics - accessible at /api/stocks/:symbol/quarterly-metrics
+	stockGroup.GET("/:symbol/quarterly-metrics", stockHandlers.GetQuarterlyMetrics)
+
+	// Key ratios against sector median and percentile rank - accessible at /api/stocks/:symbol/peers
+	stockGroup.GET("/:symbol/peers", stockHandlers.GetStockPeers)
+
+	// Overview metric time series - accessible at /api/stocks/:symbol/metrics/:metric/history
+	stockGroup.GET("/:symbol/metrics/:metric/history", stockHandlers.GetOverviewMetricHistory, validator.ValidateQuery(&validator.OverviewMetricHistoryQuery{}))
+}
@@ -0,0 +1,28 @@
+package validator
+
+// GetAlertInboxQuery represents query params for the alert inbox.
+type GetAlertInboxQuery struct {
+	Page   int  `query:"page" validate:"omitempty,min=1"`
+	Limit  int  `query:"limit" validate:"omitempty,min=1,max=100"`
+	Unread bool `query:"unread"`
+}
+
+// CreateAlertRequest represents request to create an alert rule. Which target and
+// parameter are required depends on alert_type (see models.ValidateAlert).
+type CreateAlertRequest struct {
+	AlertType       string   `json:"alert_type" validate:"required,oneof=price_above price_below dividend_yield_above earnings_within_days bond_coupon_due deposit_maturing"`
+	Ticker          *string  `json:"ticker" validate:"omitempty,len=4,alphanum"`
+	PortfolioBondID *int     `json:"portfolio_bond_id" validate:"omitempty,min=1"`
+	PortfolioCashID *int     `json:"portfolio_cash_id" validate:"omitempty,min=1"`
+	Threshold       *float64 `json:"threshold" validate:"omitempty,gt=0"`
+	Days            *int     `json:"days" validate:"omitempty,min=0,max=365"`
+	Note            *string  `json:"note" validate:"omitempty,max=1000"`
+}
+
+// UpdateAlertRequest represents request to change an alert rule.
+type UpdateAlertRequest struct {
+	Threshold *float64 `json:"threshold" validate:"omitempty,gt=0"`
+	Days      *int     `json:"days" validate:"omitempty,min=0,max=365"`
+	Note      *string  `json:"note" validate:"omitempty,max=1000"`
+	Active    *bool    `json:"active"`
+}
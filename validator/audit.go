@@ -0,0 +1,15 @@
+package validator
+
+// GetAuditLogsQuery represents query parameters for searching the audit log.
+type GetAuditLogsQuery struct {
+	Page               int     `query:"page" validate:"omitempty,min=1"`
+	Limit              int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	ActorID            *int    `query:"actor_id" validate:"omitempty,min=1"`
+	ImpersonatedUserID *int    `query:"impersonated_user_id" validate:"omitempty,min=1"`
+	Action             *string `query:"action" validate:"omitempty,max=100"`
+	TargetType         *string `query:"target_type" validate:"omitempty,max=50"`
+	TargetID           *string `query:"target_id" validate:"omitempty,max=100"`
+	RequestID          *string `query:"request_id" validate:"omitempty,max=100"`
+	From               string  `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To                 string  `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"time"
+
 	"github.com/WahyuSiddarta/be_saham_go/middleware"
 	"github.com/WahyuSiddarta/be_saham_go/models"
 )
@@ -11,30 +13,43 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=6" example:"password123"`
 }
 
-// RegisterRequest represents registration request payload.
+// RegisterRequest represents registration request payload. New accounts are always
+// free and unverified until their email is confirmed; premium tiers come from payments.
 type RegisterRequest struct {
-	Email           string             `json:"email" validate:"required,email"`
-	Password        string             `json:"password" validate:"required,min=6"`
-	ConfirmPassword string             `json:"confirmPassword" validate:"required,eqfield=Password"`
-	Status          *models.UserStatus `json:"status,omitempty" validate:"omitempty,user_status"`
-	UserLevel       *models.UserLevel  `json:"user_level,omitempty" validate:"omitempty,user_level"`
+	Email           string `json:"email" validate:"required,email"`
+	Password        string `json:"password" validate:"required,min=6"`
+	ConfirmPassword string `json:"confirmPassword" validate:"required,eqfield=Password"`
+}
+
+// VerifyEmailRequest represents request to confirm an email address with an emailed token.
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,len=64,hexadecimal"`
+}
+
+// ChangePasswordRequest represents request to change the current user's password.
+type ChangePasswordRequest struct {
+	CurrentPassword    string `json:"current_password" validate:"required"`
+	NewPassword        string `json:"new_password" validate:"required,min=6,nefield=CurrentPassword"`
+	ConfirmNewPassword string `json:"confirm_new_password" validate:"required,eqfield=NewPassword"`
 }
 
-// UpdateUserLevelRequest represents request to update user level.
+// UpdateUserLevelRequest represents request to update user level. Premium tiers need
+// the id of a subscription plan of that tier.
 type UpdateUserLevelRequest struct {
 	UserLevel   models.UserLevel    `json:"user_level" validate:"required,user_level"`
+	PlanID      *int                `json:"plan_id,omitempty" validate:"omitempty,min=1"`
 	PaymentData *PaymentDataRequest `json:"payment_data,omitempty"`
 }
 
-// PaymentDataRequest represents payment data nested inside other requests.
+// PaymentDataRequest represents payment data nested inside other requests. Prices come
+// from the subscription plan.
 type PaymentDataRequest struct {
-	OriginalPrice  float64  `json:"original_price" validate:"required,gt=0"`
-	PaidPrice      float64  `json:"paid_price" validate:"required,gte=0"`
 	DiscountAmount *float64 `json:"discount_amount,omitempty" validate:"omitempty,gte=0"`
 	DiscountReason *string  `json:"discount_reason,omitempty" validate:"omitempty,max=255"`
 	PaymentMethod  *string  `json:"payment_method,omitempty" validate:"omitempty,max=50"`
 	PaymentDate    *string  `json:"payment_date,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	Notes          *string  `json:"notes,omitempty" validate:"omitempty,max=1000"`
+	PromoCode      *string  `json:"promo_code,omitempty" validate:"omitempty,min=3,max=32"`
 }
 
 // UpdateUserStatusRequest represents request to update user status.
@@ -42,6 +57,13 @@ type UpdateUserStatusRequest struct {
 	Status models.UserStatus `json:"status" validate:"required,user_status"`
 }
 
+// ImpersonateUserRequest represents request to obtain an impersonation token for a user.
+// Sessions are read-only unless AllowWrite is set.
+type ImpersonateUserRequest struct {
+	Reason     string `json:"reason" validate:"required,min=5,max=255"`
+	AllowWrite bool   `json:"allow_write"`
+}
+
 // GetUsersQuery represents query parameters for fetching users.
 type GetUsersQuery struct {
 	Page        int                `query:"page" validate:"omitempty,min=1"`
@@ -63,6 +85,14 @@ type RegisterData struct {
 	Token string       `json:"token"`
 }
 
+// ImpersonationData contains an impersonation token and the user it acts as.
+type ImpersonationData struct {
+	User      *models.User `json:"user"`
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	ReadOnly  bool         `json:"read_only"`
+}
+
 // ProfileData contains authenticated user profile data.
 type ProfileData struct {
 	User *middleware.AuthUser `json:"user"`
@@ -0,0 +1,14 @@
+package validator
+
+// GetIngestionRunsQuery represents query parameters for listing ingestion runs.
+type GetIngestionRunsQuery struct {
+	Page    int     `query:"page" validate:"omitempty,min=1"`
+	Limit   int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status  *string `query:"status" validate:"omitempty,oneof=running completed canceled"`
+	Trigger *string `query:"trigger" validate:"omitempty,oneof=schedule retry_failed manual"`
+}
+
+// GetIngestionRunQuery represents query parameters for a single ingestion run.
+type GetIngestionRunQuery struct {
+	Status *string `query:"status" validate:"omitempty,oneof=succeeded failed skipped"`
+}
@@ -0,0 +1,29 @@
+package validator
+
+// UpdateNotificationPreferencesRequest represents request to change notification preferences.
+// Omitted fields keep their current value.
+type UpdateNotificationPreferencesRequest struct {
+	Locale          *string `json:"locale" validate:"omitempty,oneof=id en"`
+	EmailEnabled    *bool   `json:"email_enabled"`
+	TelegramEnabled *bool   `json:"telegram_enabled"`
+	TelegramChatID  *string `json:"telegram_chat_id" validate:"omitempty,max=64"`
+	WebPushEnabled  *bool   `json:"web_push_enabled"`
+	InAppEnabled    *bool   `json:"in_app_enabled"`
+}
+
+// WebPushKeys are the encryption keys of a browser push subscription.
+type WebPushKeys struct {
+	P256dh string `json:"p256dh" validate:"required,max=255"`
+	Auth   string `json:"auth" validate:"required,max=255"`
+}
+
+// SubscribeWebPushRequest represents a browser PushSubscription as serialized by toJSON().
+type SubscribeWebPushRequest struct {
+	Endpoint string      `json:"endpoint" validate:"required,max=2000,web_push_endpoint"`
+	Keys     WebPushKeys `json:"keys" validate:"required"`
+}
+
+// UnsubscribeWebPushRequest represents request to remove a browser push subscription.
+type UnsubscribeWebPushRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,max=2000"`
+}
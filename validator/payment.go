@@ -0,0 +1,32 @@
+package validator
+
+// CheckoutRequest represents request to buy a subscription plan through the payment gateway.
+type CheckoutRequest struct {
+	PlanID    int     `json:"plan_id" validate:"required,min=1"`
+	PromoCode *string `json:"promo_code,omitempty" validate:"omitempty,min=3,max=32"`
+}
+
+// SimulateFakePaymentRequest represents request to settle a fake gateway checkout.
+type SimulateFakePaymentRequest struct {
+	Status        string `json:"status" validate:"required,oneof=paid failed expired"`
+	PaymentMethod string `json:"payment_method" validate:"omitempty,max=50"`
+}
+
+// GetPaymentsQuery represents query parameters for listing the user's payments.
+type GetPaymentsQuery struct {
+	Page   int     `query:"page" validate:"omitempty,min=1"`
+	Limit  int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status *string `query:"status" validate:"omitempty,oneof=pending completed failed refunded expired needs_refund"`
+}
+
+// GetAdminPaymentsQuery represents query parameters for listing payments across users.
+type GetAdminPaymentsQuery struct {
+	Page               int     `query:"page" validate:"omitempty,min=1"`
+	Limit              int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status             *string `query:"status" validate:"omitempty,oneof=pending completed failed refunded expired needs_refund"`
+	PaymentMethod      *string `query:"payment_method" validate:"omitempty,max=50"`
+	ProcessedByAdminID *int    `query:"processed_by_admin_id" validate:"omitempty,min=1"`
+	UserID             *int    `query:"user_id" validate:"omitempty,min=1"`
+	From               string  `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To                 string  `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,52 @@
+package validator
+
+import "github.com/WahyuSiddarta/be_saham_go/models"
+
+// GetPromoCodesQuery represents query parameters for listing promo codes.
+type GetPromoCodesQuery struct {
+	Page   int   `query:"page" validate:"omitempty,min=1"`
+	Limit  int   `query:"limit" validate:"omitempty,min=1,max=100"`
+	Active *bool `query:"active"`
+}
+
+// GetPromoCodeRedemptionsQuery represents query parameters for listing a promo code's redemptions.
+type GetPromoCodeRedemptionsQuery struct {
+	Page  int `query:"page" validate:"omitempty,min=1"`
+	Limit int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// CreatePromoCodeRequest represents request to create a promo code. A percentage
+// discount_value is between 0 and 100, a fixed one is an IDR amount.
+type CreatePromoCodeRequest struct {
+	Code              string             `json:"code" validate:"required,min=3,max=32,alphanum"`
+	Description       *string            `json:"description,omitempty" validate:"omitempty,max=255"`
+	DiscountType      string             `json:"discount_type" validate:"required,oneof=percentage fixed"`
+	DiscountValue     float64            `json:"discount_value" validate:"required,gt=0"`
+	MaxDiscountAmount *float64           `json:"max_discount_amount,omitempty" validate:"omitempty,gt=0"`
+	ValidFrom         *string            `json:"valid_from,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ValidUntil        *string            `json:"valid_until,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxRedemptions    *int               `json:"max_redemptions,omitempty" validate:"omitempty,min=1"`
+	PerUserLimit      *int               `json:"per_user_limit,omitempty" validate:"omitempty,min=1"`
+	EligibleLevels    []models.UserLevel `json:"eligible_levels,omitempty" validate:"omitempty,dive,oneof=premium premium+"`
+	Active            *bool              `json:"active,omitempty"`
+}
+
+// UpdatePromoCodeRequest represents request to replace a promo code's terms. The code and
+// discount cannot change; omitted optional fields are cleared, except valid_from, which
+// keeps its current value.
+type UpdatePromoCodeRequest struct {
+	Description       *string            `json:"description,omitempty" validate:"omitempty,max=255"`
+	MaxDiscountAmount *float64           `json:"max_discount_amount,omitempty" validate:"omitempty,gt=0"`
+	ValidFrom         *string            `json:"valid_from,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ValidUntil        *string            `json:"valid_until,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxRedemptions    *int               `json:"max_redemptions,omitempty" validate:"omitempty,min=1"`
+	PerUserLimit      int                `json:"per_user_limit" validate:"required,min=1"`
+	EligibleLevels    []models.UserLevel `json:"eligible_levels,omitempty" validate:"omitempty,dive,oneof=premium premium+"`
+	Active            bool               `json:"active"`
+}
+
+// CheckPromoCodeRequest represents request to preview a promo code's discount at checkout.
+type CheckPromoCodeRequest struct {
+	PlanID    int    `json:"plan_id" validate:"required,min=1"`
+	PromoCode string `json:"promo_code" validate:"required,min=3,max=32"`
+}
@@ -0,0 +1,7 @@
+package validator
+
+// GetReportRangeQuery represents the date range of a report (WIB dates, to inclusive).
+type GetReportRangeQuery struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
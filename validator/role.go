@@ -0,0 +1,21 @@
+package validator
+
+// CreateRoleRequest represents request to create a role granting permissions.
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	Permissions []string `json:"permissions" validate:"required,dive,required,max=50"`
+}
+
+// UpdateRoleRequest represents request to replace a role's name, description and permissions.
+type UpdateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	Permissions []string `json:"permissions" validate:"required,dive,required,max=50"`
+}
+
+// SetUserRolesRequest represents request to replace the roles of a user; an empty list
+// revokes all of them.
+type SetUserRolesRequest struct {
+	RoleIDs []int `json:"role_ids" validate:"required,max=20,dive,min=1"`
+}
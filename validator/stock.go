@@ -0,0 +1,53 @@
+package validator
+
+// EarningsCalendarQuery represents query parameters for the earnings calendar.
+type EarningsCalendarQuery struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// GetTrackedStocksQuery represents query parameters for listing tracked stocks.
+type GetTrackedStocksQuery struct {
+	Page    int     `query:"page" validate:"omitempty,min=1"`
+	Limit   int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Enabled *bool   `query:"enabled"`
+	Ticker  *string `query:"ticker" validate:"omitempty,max=4,alphanum"`
+}
+
+// CreateTrackedStockRequest represents request to start tracking a ticker.
+type CreateTrackedStockRequest struct {
+	Ticker  string  `json:"ticker" validate:"required,len=4,alphanum"`
+	Name    *string `json:"name,omitempty" validate:"omitempty,max=255"`
+	ApiKey  *string `json:"api_key,omitempty" validate:"omitempty,min=8,max=255"`
+	Enabled *bool   `json:"enabled,omitempty"`
+}
+
+// UpdateTrackedStockStatusRequest represents request to enable or disable a ticker.
+type UpdateTrackedStockStatusRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+// UpdateTrackedStockApiKeyRequest represents request to assign or clear a ticker's API key.
+type UpdateTrackedStockApiKeyRequest struct {
+	ApiKey *string `json:"api_key" validate:"omitempty,min=8,max=255"`
+}
+
+// OverviewMetricHistoryQuery represents query parameters for an overview metric time series.
+type OverviewMetricHistoryQuery struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// SectorAveragesQuery represents query parameters for sector averages.
+type SectorAveragesQuery struct {
+	Sector *string `query:"sector" validate:"omitempty,max=255"`
+}
+
+// UpdateStockProfileRequest represents request to replace a ticker's master attributes.
+type UpdateStockProfileRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=255"`
+	Sector      *string `json:"sector" validate:"omitempty,max=255"`
+	Industry    *string `json:"industry" validate:"omitempty,max=255"`
+	SubIndustry *string `json:"sub_industry" validate:"omitempty,max=255"`
+	Board       *string `json:"board" validate:"omitempty,stock_board"`
+}
@@ -0,0 +1,20 @@
+package validator
+
+import "github.com/WahyuSiddarta/be_saham_go/models"
+
+// CreateSubscriptionPlanRequest represents request to offer a subscription plan.
+type CreateSubscriptionPlanRequest struct {
+	UserLevel      models.UserLevel `json:"user_level" validate:"required,oneof=premium premium+"`
+	Name           string           `json:"name" validate:"required,max=100"`
+	DurationMonths int              `json:"duration_months" validate:"required,min=1,max=120"`
+	Price          float64          `json:"price" validate:"gte=0"`
+	Active         *bool            `json:"active,omitempty"`
+}
+
+// UpdateSubscriptionPlanRequest represents request to change a plan's name, price or
+// availability; its tier and duration are fixed.
+type UpdateSubscriptionPlanRequest struct {
+	Name   string  `json:"name" validate:"required,max=100"`
+	Price  float64 `json:"price" validate:"gte=0"`
+	Active bool    `json:"active"`
+}
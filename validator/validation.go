@@ -29,6 +29,8 @@ func init() {
 	// Register custom validators
 	validate.RegisterValidation("user_status", validateUserStatus)
 	validate.RegisterValidation("user_level", validateUserLevel)
+	validate.RegisterValidation("stock_board", validateStockBoard)
+	validate.RegisterValidation("web_push_endpoint", validateWebPushEndpoint)
 }
 
 // validateUserStatus validates user status enum
@@ -39,6 +41,7 @@ func validateUserStatus(fl validator.FieldLevel) bool {
 		string(models.UserStatusInactive),
 		string(models.UserStatusSuspended),
 		string(models.UserStatusBanned),
+		string(models.UserStatusUnverified),
 	}
 
 	for _, validStatus := range validStatuses {
@@ -49,6 +52,16 @@ func validateUserStatus(fl validator.FieldLevel) bool {
 	return false
 }
 
+// validateStockBoard validates stock listing board
+func validateStockBoard(fl validator.FieldLevel) bool {
+	return models.IsValidStockBoard(fl.Field().String())
+}
+
+// validateWebPushEndpoint validates a push subscription endpoint against the known push services
+func validateWebPushEndpoint(fl validator.FieldLevel) bool {
+	return models.IsWebPushServiceEndpoint(fl.Field().String())
+}
+
 // validateUserLevel validates user level enum
 func validateUserLevel(fl validator.FieldLevel) bool {
 	level := fl.Field().String()
@@ -112,9 +125,11 @@ func getValidationMessage(err validator.FieldError) string {
 	case "gte":
 		return fmt.Sprintf("%s harus lebih besar atau sama dengan %s", field, param)
 	case "user_status":
-		return "Status harus salah satu dari: active, inactive, suspended, banned"
+		return "Status harus salah satu dari: active, inactive, suspended, banned, unverified"
 	case "user_level":
 		return "Level pengguna harus salah satu dari: free, premium, premium+"
+	case "web_push_endpoint":
+		return "Endpoint harus berupa URL https dari layanan push browser yang dikenal"
 	case "datetime":
 		return "Format tanggal tidak valid. Gunakan format ISO 8601 (YYYY-MM-DDTHH:MM:SSZ)"
 	default:
@@ -208,21 +223,29 @@ func CustomValidation(req interface{}) []ValidationError {
 			})
 		}
 
-		// If user level is premium, validate payment data is provided
-		if (v.UserLevel == models.UserLevelPremium || v.UserLevel == models.UserLevelPremiumPlus) &&
-			v.PaymentData != nil {
-			// Additional payment validation logic can be added here
-			if v.PaymentData.OriginalPrice <= 0 {
-				errors = append(errors, ValidationError{
-					Field:   "payment_data.original_price",
-					Message: "Harga asli harus lebih besar dari 0",
-					Tag:     "custom",
-				})
-			}
-			if v.PaymentData.PaidPrice < 0 {
+		// Premium tiers are granted through a subscription plan
+		isPremium := v.UserLevel == models.UserLevelPremium || v.UserLevel == models.UserLevelPremiumPlus
+		if isPremium && v.PlanID == nil {
+			errors = append(errors, ValidationError{
+				Field:   "plan_id",
+				Message: "Paket langganan wajib diisi untuk tier premium",
+				Tag:     "custom",
+			})
+		}
+		if !isPremium && v.PlanID != nil {
+			errors = append(errors, ValidationError{
+				Field:   "plan_id",
+				Message: "Paket langganan hanya dapat diberikan untuk tier premium",
+				Tag:     "custom",
+			})
+		}
+
+		// Validate payment data for premium tiers
+		if isPremium && v.PaymentData != nil {
+			if v.PaymentData.PromoCode != nil && v.PaymentData.DiscountAmount != nil {
 				errors = append(errors, ValidationError{
-					Field:   "payment_data.paid_price",
-					Message: "Harga yang dibayar tidak boleh negatif",
+					Field:   "payment_data.promo_code",
+					Message: "Kode promo tidak dapat digabung dengan diskon manual",
 					Tag:     "custom",
 				})
 			}
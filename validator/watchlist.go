@@ -0,0 +1,32 @@
+package validator
+
+// CreateWatchlistRequest represents request to create a watchlist.
+type CreateWatchlistRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// RenameWatchlistRequest represents request to rename a watchlist.
+type RenameWatchlistRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// ReorderWatchlistsRequest represents the new order of all of a user's watchlists.
+type ReorderWatchlistsRequest struct {
+	WatchlistIDs []int64 `json:"watchlist_ids" validate:"required,min=1,dive,min=1"`
+}
+
+// AddWatchlistItemRequest represents request to add a ticker to a watchlist.
+type AddWatchlistItemRequest struct {
+	Ticker string  `json:"ticker" validate:"required,len=4,alphanum"`
+	Note   *string `json:"note" validate:"omitempty,max=1000"`
+}
+
+// UpdateWatchlistItemRequest represents request to change the note of a watchlist ticker.
+type UpdateWatchlistItemRequest struct {
+	Note *string `json:"note" validate:"omitempty,max=1000"`
+}
+
+// ReorderWatchlistItemsRequest represents the new order of every ticker in a watchlist.
+type ReorderWatchlistItemsRequest struct {
+	Tickers []string `json:"tickers" validate:"required,min=1,dive,len=4,alphanum"`
+}
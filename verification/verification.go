@@ -0,0 +1,162 @@
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/WahyuSiddarta/be_saham_go/config"
+	"github.com/WahyuSiddarta/be_saham_go/models"
+	"github.com/WahyuSiddarta/be_saham_go/notification"
+	"github.com/WahyuSiddarta/be_saham_go/utime"
+	"github.com/rs/zerolog"
+)
+
+// Sender names.
+const (
+	SenderLog  = "log"
+	SenderSMTP = "smtp"
+)
+
+// throttleWindow is the period EmailVerificationConfig.ResendMaxPerHour applies to
+const throttleWindow = time.Hour
+
+var (
+	ErrUnknownSender   = errors.New("unknown email verification sender")
+	ErrAlreadyVerified = errors.New("email already verified")
+)
+
+// ThrottledError is returned when a verification email was requested too soon after
+// the previous ones.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("verification email throttled, retry after %s", e.RetryAfter)
+}
+
+// Service issues email verification tokens, emails them through the configured sender
+// and verifies them. Senders are notification channels: the log channel stands in for
+// email during local development.
+type Service struct {
+	logger *zerolog.Logger
+	cfg    config.EmailVerificationConfig
+	appURL string
+	sender notification.Channel
+	repo   models.EmailVerificationRepository
+}
+
+// NewService builds the email verification service for the configured sender. The smtp
+// sender uses the notification SMTP server and links to the notification app URL.
+func NewService(cfg config.EmailVerificationConfig, notificationCfg config.NotificationConfig, logger *zerolog.Logger) (*Service, error) {
+	s := &Service{
+		logger: logger,
+		cfg:    cfg,
+		appURL: notificationCfg.AppURL,
+		repo:   models.NewEmailVerificationRepository(),
+	}
+
+	switch cfg.Sender {
+	case "", SenderLog:
+		s.sender = notification.NewLogChannel(logger)
+	case SenderSMTP:
+		if notificationCfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("email verification sender %q requires SMTP_HOST", cfg.Sender)
+		}
+		s.sender = notification.NewEmailChannel(notificationCfg.SMTP)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownSender, cfg.Sender)
+	}
+
+	return s, nil
+}
+
+// Sender returns the configured sender.
+func (s *Service) Sender() notification.Channel {
+	return s.sender
+}
+
+// SendVerification issues a verification token for user and emails its link.
+func (s *Service) SendVerification(ctx context.Context, user *models.User) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	now := utime.Utime.Now().ToTime()
+	expiresAt := now.Add(s.cfg.TokenTTL)
+	if err := s.repo.CreateVerificationToken(user.ID, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	subject, body, err := notification.Render(notification.EventEmailVerification, models.NotificationLocaleID, map[string]interface{}{
+		"ExpiresAt": expiresAt.In(now.Location()).Format("2006-01-02 15:04 MST"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sender.Send(ctx, notification.Message{
+		UserID:    user.ID,
+		Event:     string(notification.EventEmailVerification),
+		Locale:    models.NotificationLocaleID,
+		Recipient: user.Email,
+		Subject:   subject,
+		Body:      body,
+		URL:       s.appURL + "/verify-email?token=" + url.QueryEscape(token),
+	})
+}
+
+// ResendVerification sends a new verification email to an unverified user. It returns a
+// *ThrottledError when the previous email was sent less than ResendInterval ago or
+// ResendMaxPerHour emails were sent in the last hour.
+func (s *Service) ResendVerification(ctx context.Context, user *models.User) error {
+	if user.Status != models.UserStatusUnverified {
+		return ErrAlreadyVerified
+	}
+
+	now := utime.Utime.Now().ToTime()
+	stats, err := s.repo.GetVerificationStats(user.ID, now.Add(-throttleWindow))
+	if err != nil {
+		return err
+	}
+	if stats.LastSentAt != nil {
+		if wait := stats.LastSentAt.Add(s.cfg.ResendInterval).Sub(now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+	if stats.SentCount >= s.cfg.ResendMaxPerHour && stats.FirstSentAt != nil {
+		if wait := stats.FirstSentAt.Add(throttleWindow).Sub(now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// Verify consumes a verification token and returns the user it activated. It returns
+// models.ErrVerificationTokenInvalid or models.ErrVerificationTokenExpired for tokens
+// that cannot be used.
+func (s *Service) Verify(token string) (*models.User, error) {
+	return s.repo.VerifyEmail(hashToken(token), utime.Utime.Now().ToTime())
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating verification token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken returns the stored form of a token; the token itself is only ever emailed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}